@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 
+	"language-exchange/internal/cache"
 	"language-exchange/internal/config"
 	"language-exchange/internal/database"
 	"language-exchange/internal/handlers"
+	"language-exchange/internal/models"
 	"language-exchange/internal/repository/postgres"
 	"language-exchange/internal/services"
 	"language-exchange/internal/websocket"
@@ -50,30 +53,69 @@ func main() {
 	connectionRepo := postgres.NewConnectionRepository(db.DB)
 	profileVisitRepo := postgres.NewProfileVisitRepository(db.DB.DB)
 	gamificationRepo := postgres.NewGamificationRepository(db.DB)
+	vocabularyRepo := postgres.NewVocabularyRepository(db.DB)
+	entitlementRepo := postgres.NewEntitlementRepository(db.DB)
+	billingRepo := postgres.NewBillingRepository(db.DB)
 
 	// Initialize WebSocket hub
 	wsHub := websocket.NewHub()
 	go wsHub.Run() // Start the hub in a goroutine
 
+	// Connect the hub to other API replicas through Redis when configured
+	// and share the cache with them; otherwise keep both in-process
+	var backplane websocket.Backplane
+	var appCache cache.Cache
+	if cfg.RedisAddr != "" {
+		redisCache := cache.NewRedisCache(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+		if err := redisCache.Health(context.Background()); err != nil {
+			log.Fatal("Failed to connect to Redis:", err)
+		}
+		defer redisCache.Close()
+		backplane = websocket.NewRedisBackplane(redisCache)
+		wsHub.SetEventLog(websocket.NewRedisEventLog(redisCache))
+		appCache = redisCache
+	} else {
+		backplane = websocket.NewMemoryBackplane()
+		wsHub.SetEventLog(websocket.NewMemoryEventLog())
+		appCache = cache.NewMemoryCache()
+	}
+	if err := wsHub.SetBackplane(backplane); err != nil {
+		log.Fatal("Failed to set up WebSocket backplane:", err)
+	}
+	defer backplane.Close()
+
 	// Initialize services
 	authService := services.NewAuthService(userRepo, tokenService, cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL)
 	userService := services.NewUserService(userRepo)
 	gamificationService := services.NewGamificationService(gamificationRepo, userRepo)
 	matchService := services.NewMatchService(matchRepo, userRepo, gamificationService)
 	conversationService := services.NewConversationService(conversationRepo, userRepo, messageRepo, matchRepo)
-	messageService := services.NewMessageService(messageRepo, conversationRepo, userRepo, wsHub)
-	sessionService := services.NewSessionService(sessionRepo, userRepo, matchRepo, gamificationService)
-	postService := services.NewPostService(postRepo, commentRepo, reactionRepo, userRepo, gamificationService)
-	bookmarkService := services.NewBookmarkService(bookmarkRepo, postRepo)
-	connectionService := services.NewConnectionService(connectionRepo, userRepo)
-	profileVisitService := services.NewProfileVisitService(profileVisitRepo)
 	log.Println("DEBUG: Creating translation service with URL:", cfg.LibreTranslateURL)
-	translationService := services.NewTranslationService(cfg.LibreTranslateURL, cfg.LibreTranslateAPIKey)
+	translationService := services.NewTranslationService(translationProviders(cfg), appCache, cfg.TranslationCacheTTL)
 	log.Println("DEBUG: Creating upload service with dir:", cfg.UploadsDir, "max size:", cfg.MaxUploadSize)
 	uploadService := services.NewUploadService(cfg.UploadsDir, cfg.MaxUploadSize)
+	entitlementsService := services.NewEntitlementsService(entitlementRepo, uploadService)
+	messageService := services.NewMessageService(messageRepo, conversationRepo, userRepo, wsHub, translationService, entitlementsService)
+	sessionService := services.NewSessionService(sessionRepo, userRepo, matchRepo, gamificationService, wsHub, cfg.SessionIdleTimeout, translationService, entitlementsService)
+	postService := services.NewPostService(postRepo, commentRepo, reactionRepo, userRepo, gamificationService, translationService)
+	bookmarkService := services.NewBookmarkService(bookmarkRepo, postRepo)
+	connectionService := services.NewConnectionService(connectionRepo, userRepo)
+	profileVisitService := services.NewProfileVisitService(profileVisitRepo)
+	billingService := services.NewBillingService(billingProvider(cfg), billingRepo, userRepo, cfg.BillingSuccessURL, cfg.BillingCancelURL)
+	calendarService := services.NewCalendarService(sessionService, userRepo)
+	webRTCService := services.NewWebRTCService(sessionService, cfg.TURNURLs, cfg.STUNURLs, cfg.TURNSecret, cfg.TURNCredentialTTL)
+	sessionExportService := services.NewSessionExportService(sessionService)
+	vocabularyService := services.NewVocabularyService(vocabularyRepo, gamificationService)
+	var llmProvider services.LLMProvider
+	if cfg.AIAPIURL != "" {
+		llmProvider = services.NewOpenAICompatibleProvider(cfg.AIAPIURL, cfg.AIAPIKey, cfg.AIModel, cfg.AITimeout)
+	}
+	aiService := services.NewAIService(llmProvider, userRepo, matchRepo, conversationRepo, appCache)
 	
-	// Set session service on the hub for database operations
+	// Set session and message services on the hub for database operations
+	// and inbound client commands
 	wsHub.SetSessionService(sessionService)
+	wsHub.SetMessageService(messageService)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, userService)
@@ -82,21 +124,33 @@ func main() {
 	conversationHandler := handlers.NewConversationHandler(conversationService)
 	messageHandler := handlers.NewMessageHandler(messageService, conversationService, wsHub)
 	sessionHandler := handlers.NewSessionHandler(sessionService, wsHub)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	webRTCHandler := handlers.NewWebRTCHandler(webRTCService)
+	sessionExportHandler := handlers.NewSessionExportHandler(sessionExportService)
 	wsHandler := handlers.NewWebSocketHandler(wsHub, sessionService)
 	postHandler := handlers.NewPostHandler(postService)
 	bookmarkHandler := handlers.NewBookmarkHandler(bookmarkService)
 	connectionHandler := handlers.NewConnectionHandler(connectionService)
 	profileVisitHandler := handlers.NewProfileVisitHandler(profileVisitService)
 	gamificationHandler := handlers.NewGamificationHandler(gamificationService)
+	vocabularyHandler := handlers.NewVocabularyHandler(vocabularyService)
 	log.Println("DEBUG: Creating translation handler")
-	translationHandler := handlers.NewTranslationHandler(translationService)
+	translationHandler := handlers.NewTranslationHandler(translationService, vocabularyService)
 	log.Println("DEBUG: Creating upload handler")
 	uploadHandler := handlers.NewUploadHandler(uploadService, userService)
-	aiHandler := handlers.NewAIHandler(db.DB)
+	aiHandler := handlers.NewAIHandler(aiService, entitlementsService, userService)
+	entitlementsHandler := handlers.NewEntitlementsHandler(entitlementsService)
+	billingHandler := handlers.NewBillingHandler(billingService)
 	
 	// Start rate limit cleanup goroutine
 	go handlers.CleanupRateLimits()
 
+	// Send session reminders and expire scheduled sessions nobody started
+	go sessionService.RunScheduler(context.Background())
+
+	// Move users whose paid plan expired back to the free plan
+	go entitlementsService.RunPlanExpiry(context.Background())
+
 	// Setup Gin router
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -131,6 +185,12 @@ func main() {
 			auth.GET("/google/callback", authHandler.GoogleCallback)
 		}
 
+		// Calendar feed (public, authorized by the user's feed token)
+		api.GET("/calendar/:userId/:token", calendarHandler.GetCalendarFeed)
+
+		// Billing provider webhooks (public, authorized by their signature)
+		api.POST("/billing/webhook", billingHandler.HandleWebhook)
+
 		// Protected routes
 		protected := api.Group("/")
 		protected.Use(handlers.AuthMiddleware(authService))
@@ -143,6 +203,8 @@ func main() {
 				users.PUT("/me/profile", userHandler.UpdateProfile)
 				users.PUT("/me/preferences", userHandler.UpdatePreferences)
 				users.PUT("/me/onboarding-step", userHandler.UpdateOnboardingStep)
+				users.GET("/me/calendar", calendarHandler.GetCalendarFeedURL)
+				users.POST("/me/calendar/rotate", calendarHandler.RotateCalendarFeedURL)
 				users.GET("/:id", userHandler.GetUserByID)
 				users.GET("", userHandler.SearchPartners)
 			}
@@ -180,35 +242,59 @@ func main() {
 			// Session routes
 			sessions := protected.Group("/sessions")
 			{
-				sessions.POST("", sessionHandler.CreateSession)
+				sessions.POST("", handlers.QuotaMiddleware(entitlementsService, models.MeterSessions), sessionHandler.CreateSession)
 				sessions.GET("/active", sessionHandler.GetActiveSessions)
 				sessions.GET("/my", sessionHandler.GetUserSessions)
+				sessions.GET("/upcoming", sessionHandler.GetUpcomingSessions)
+				sessions.GET("/calendar.ics", calendarHandler.GetMyCalendar)
+				sessions.GET("/series/:seriesId", sessionHandler.GetSeries)
+				sessions.PUT("/series/:seriesId", sessionHandler.UpdateSeries)
+				sessions.DELETE("/series/:seriesId", sessionHandler.EndSeries)
 				sessions.GET("/:sessionId", sessionHandler.GetSession)
+				sessions.POST("/:sessionId/rsvp", sessionHandler.RespondToInvitation)
 				sessions.POST("/:sessionId/join", sessionHandler.JoinSession)
 				sessions.POST("/:sessionId/leave", sessionHandler.LeaveSession)
 				sessions.POST("/:sessionId/end", sessionHandler.EndSession)
 				sessions.GET("/:sessionId/participants", sessionHandler.GetSessionParticipants)
+				sessions.GET("/:sessionId/attendance", sessionHandler.GetSessionAttendance)
+				sessions.GET("/:sessionId/turn-credentials", webRTCHandler.GetTURNCredentials)
+				sessions.GET("/:sessionId/export", handlers.FeatureMiddleware(entitlementsService, models.FeatureSessionExport), sessionExportHandler.ExportSession)
 				sessions.GET("/:sessionId/messages", sessionHandler.GetSessionMessages)
 				sessions.POST("/:sessionId/messages", sessionHandler.SendMessage)
 				sessions.GET("/:sessionId/canvas", sessionHandler.GetCanvasOperations)
+				sessions.GET("/:sessionId/canvas/state", sessionHandler.GetCanvasState)
 				sessions.POST("/:sessionId/canvas", sessionHandler.SaveCanvasOperation)
 			}
 
 			// Translation routes
 			translate := protected.Group("/translate")
 			{
-				translate.POST("", translationHandler.Translate)
+				translate.POST("", handlers.QuotaMiddleware(entitlementsService, models.MeterTranslations), translationHandler.Translate)
+				translate.POST("/batch", handlers.QuotaMiddleware(entitlementsService, models.MeterTranslations), translationHandler.TranslateBatch)
+				translate.POST("/detect", handlers.QuotaMiddleware(entitlementsService, models.MeterTranslations), translationHandler.DetectLanguage)
 				translate.GET("/languages", translationHandler.GetSupportedLanguages)
 				translate.GET("/languages/check", translationHandler.CheckLanguageSupport)
 				translate.GET("/health", translationHandler.Health)
 				translate.GET("/info", translationHandler.GetServiceInfo)
 			}
 
+			// Vocabulary routes
+			vocabulary := protected.Group("/vocabulary")
+			{
+				vocabulary.GET("", vocabularyHandler.GetVocabulary)
+				vocabulary.POST("", vocabularyHandler.SaveVocabulary)
+				vocabulary.GET("/review", vocabularyHandler.GetReviewQueue)
+				vocabulary.GET("/:itemId", vocabularyHandler.GetVocabularyItem)
+				vocabulary.PUT("/:itemId", vocabularyHandler.UpdateVocabularyItem)
+				vocabulary.DELETE("/:itemId", vocabularyHandler.DeleteVocabularyItem)
+				vocabulary.POST("/:itemId/review", vocabularyHandler.ReviewVocabularyItem)
+			}
+
 			// Upload routes
 			upload := protected.Group("/upload")
 			{
-				upload.POST("/image", uploadHandler.UploadImage)
-				upload.POST("/images", uploadHandler.UploadMultipleImages)
+				upload.POST("/image", handlers.QuotaMiddleware(entitlementsService, models.MeterUploadStorage), uploadHandler.UploadImage)
+				upload.POST("/images", handlers.QuotaMiddleware(entitlementsService, models.MeterUploadStorage), uploadHandler.UploadMultipleImages)
 			}
 
 			// Connection routes
@@ -244,8 +330,25 @@ func main() {
 			// AI routes
 			ai := protected.Group("/ai")
 			{
-				ai.POST("/improve", aiHandler.ImproveMessage)
+				ai.POST("/improve", handlers.FeatureMiddleware(entitlementsService, models.FeatureAIImprovement), aiHandler.ImproveMessage)
+				ai.POST("/improve/stream", handlers.FeatureMiddleware(entitlementsService, models.FeatureAIStreaming), aiHandler.StreamImproveMessage)
 				ai.GET("/usage", aiHandler.GetUsageStats)
+				ai.GET("/starters", handlers.FeatureMiddleware(entitlementsService, models.FeatureConversationStarters), aiHandler.GetConversationStarters)
+			}
+
+			// Plan and usage routes
+			entitlements := protected.Group("/entitlements")
+			{
+				entitlements.GET("/plans", entitlementsHandler.GetPlans)
+				entitlements.GET("/usage", entitlementsHandler.GetUsage)
+			}
+
+			// Billing routes
+			billing := protected.Group("/billing")
+			{
+				billing.POST("/checkout", billingHandler.CreateCheckout)
+				billing.GET("/subscription", billingHandler.GetSubscription)
+				billing.GET("/invoices", billingHandler.GetInvoices)
 			}
 
 			// WebSocket routes (except main WebSocket connection)
@@ -281,4 +384,68 @@ func main() {
 	if err := router.Run(port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
-}
\ No newline at end of file
+}
+
+// translationProviders builds the translation fallback chain. Without
+// TRANSLATION_PROVIDERS every configured provider is used, LibreTranslate
+// first since it is self-hosted.
+func translationProviders(cfg *config.Config) []services.TranslationProvider {
+	names := cfg.TranslationProviders
+	if len(names) == 0 {
+		names = []string{services.ProviderLibreTranslate}
+		if cfg.DeepLAPIKey != "" {
+			names = append(names, services.ProviderDeepL)
+		}
+		if cfg.GoogleTranslateAPIKey != "" {
+			names = append(names, services.ProviderGoogle)
+		}
+	}
+
+	providers := make([]services.TranslationProvider, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case services.ProviderLibreTranslate:
+			providers = append(providers, services.NewLibreTranslateProvider(cfg.LibreTranslateURL, cfg.LibreTranslateAPIKey))
+		case services.ProviderDeepL:
+			if cfg.DeepLAPIKey == "" {
+				log.Fatal("DEEPL_API_KEY is required to use the deepl translation provider")
+			}
+			providers = append(providers, services.NewDeepLProvider(cfg.DeepLAPIURL, cfg.DeepLAPIKey))
+		case services.ProviderGoogle:
+			if cfg.GoogleTranslateAPIKey == "" {
+				log.Fatal("GOOGLE_TRANSLATE_API_KEY is required to use the google translation provider")
+			}
+			providers = append(providers, services.NewGoogleProvider(cfg.GoogleTranslateURL, cfg.GoogleTranslateAPIKey))
+		default:
+			log.Fatalf("Unknown translation provider %q", name)
+		}
+	}
+	return providers
+}
+
+// billingProvider builds the configured billing provider, or nil when
+// billing is not configured
+func billingProvider(cfg *config.Config) services.BillingProvider {
+	switch cfg.BillingProvider {
+	case "":
+		return nil
+	case services.BillingProviderStripe:
+		if cfg.StripeSecretKey == "" || cfg.BillingWebhookSecret == "" {
+			log.Fatal("STRIPE_SECRET_KEY and BILLING_WEBHOOK_SECRET are required to use the stripe billing provider")
+		}
+		return services.NewStripeProvider(cfg.StripeAPIURL, cfg.StripeSecretKey, cfg.BillingWebhookSecret, map[string]string{
+			models.PlanPro: cfg.StripeProPriceID,
+		})
+	case services.BillingProviderFake:
+		if cfg.Environment == "production" {
+			log.Fatal("The fake billing provider cannot be used in production")
+		}
+		if cfg.BillingWebhookSecret == "" {
+			log.Fatal("BILLING_WEBHOOK_SECRET is required to use the fake billing provider")
+		}
+		return services.NewFakeBillingProvider(cfg.BillingWebhookSecret)
+	default:
+		log.Fatalf("Unknown billing provider %q", cfg.BillingProvider)
+		return nil
+	}
+}
@@ -44,6 +44,12 @@ const (
 	ConversationsKey = "conversations:%s:%d:%d" // userID:limit:offset
 	MessagesKey     = "messages:%s:%d:%d" // conversationID:limit:offset
 	
+	// Translations
+	TranslationKey = "translation:%s:%s:%s:%s" // textHash:source:target:format
+
+	// AI conversation starters
+	ConversationStartersKey = "conversation_starters:%s:%s:%s:%s" // userID:partnerID:level:partnerLevel
+
 	// Rate limiting
 	RateLimitKey   = "rate_limit:%s:%s" // endpoint:userID
 )
@@ -95,6 +101,14 @@ func (c *CacheKeyBuilder) MessagesKey(conversationID string, limit, offset int)
 	return fmt.Sprintf(MessagesKey, conversationID, limit, offset)
 }
 
+func (c *CacheKeyBuilder) TranslationKey(textHash, source, target, format string) string {
+	return fmt.Sprintf(TranslationKey, textHash, source, target, format)
+}
+
+func (c *CacheKeyBuilder) ConversationStartersKey(userID, partnerID, level, partnerLevel string) string {
+	return fmt.Sprintf(ConversationStartersKey, userID, partnerID, level, partnerLevel)
+}
+
 func (c *CacheKeyBuilder) RateLimitKey(endpoint, userID string) string {
 	return fmt.Sprintf(RateLimitKey, endpoint, userID)
 }
\ No newline at end of file
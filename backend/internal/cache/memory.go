@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process Cache for single-instance deployments and
+// development without Redis. Values are stored JSON-encoded like RedisCache,
+// so both behave the same for callers.
+type MemoryCache struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// Expired entries are dropped when read, and swept at most this often on
+// writes so unread keys do not accumulate
+const memorySweepInterval = time.Minute
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time // zero means no expiration
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+// store writes an entry, sweeping expired ones first if due. Callers hold mu.
+func (m *MemoryCache) store(key string, entry memoryEntry) {
+	now := time.Now()
+	if now.Sub(m.lastSweep) >= memorySweepInterval {
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.entries[key] = entry
+}
+
+// entry returns a live entry, dropping it if it has expired. Callers hold mu.
+func (m *MemoryCache) entry(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if ok && entry.expired(time.Now()) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, ok
+}
+
+func expiresAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(expiration)
+}
+
+// Set stores a value in cache with expiration
+func (m *MemoryCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(key, memoryEntry{data: data, expiresAt: expiresAt(expiration)})
+	return nil
+}
+
+// Get retrieves a value from cache
+func (m *MemoryCache) Get(ctx context.Context, key string, dest interface{}) error {
+	m.mu.Lock()
+	entry, ok := m.entry(key)
+	m.mu.Unlock()
+	if !ok {
+		return ErrCacheMiss
+	}
+
+	return json.Unmarshal(entry.data, dest)
+}
+
+// Delete removes a key from cache
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// DeletePattern removes all keys matching a glob pattern
+func (m *MemoryCache) DeletePattern(ctx context.Context, pattern string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.entries {
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if matched {
+			delete(m.entries, key)
+		}
+	}
+	return nil
+}
+
+// Exists checks if a key exists
+func (m *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.entry(key)
+	return ok, nil
+}
+
+// SetNX sets a key only if it doesn't exist (for locks)
+func (m *MemoryCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entry(key); ok {
+		return false, nil
+	}
+	m.store(key, memoryEntry{data: data, expiresAt: expiresAt(expiration)})
+	return true, nil
+}
+
+// Increment increments a numeric value
+func (m *MemoryCache) Increment(ctx context.Context, key string) (int64, error) {
+	return m.IncrementBy(ctx, key, 1)
+}
+
+// IncrementBy increments a numeric value by amount, keeping its expiration
+func (m *MemoryCache) IncrementBy(ctx context.Context, key string, amount int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entry(key)
+	var value int64
+	if ok {
+		parsed, err := strconv.ParseInt(string(entry.data), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value is not an integer: %w", err)
+		}
+		value = parsed
+	}
+
+	value += amount
+	entry.data = []byte(strconv.FormatInt(value, 10))
+	m.store(key, entry)
+	return value, nil
+}
+
+// Expire sets expiration on an existing key
+func (m *MemoryCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.entry(key); ok {
+		entry.expiresAt = expiresAt(expiration)
+		m.entries[key] = entry
+	}
+	return nil
+}
+
+// TTL returns the time to live for a key, using Redis' conventions: -2 if
+// the key does not exist and -1 if it has no expiration
+func (m *MemoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entry(key)
+	if !ok {
+		return -2, nil
+	}
+	if entry.expiresAt.IsZero() {
+		return -1, nil
+	}
+	return time.Until(entry.expiresAt), nil
+}
+
+// Health always succeeds for the in-process cache
+func (m *MemoryCache) Health(ctx context.Context) error {
+	return nil
+}
@@ -23,6 +23,12 @@ func NewRedisCache(addr, password string, db int) *RedisCache {
 	return &RedisCache{client: rdb}
 }
 
+// Client returns the underlying Redis client for features beyond key/value
+// caching, such as pub/sub
+func (r *RedisCache) Client() *redis.Client {
+	return r.client
+}
+
 // Set stores a value in cache with expiration
 func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	data, err := json.Marshal(value)
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -16,8 +18,33 @@ type Config struct {
 	GoogleRedirectURL     string
 	LibreTranslateURL     string
 	LibreTranslateAPIKey  string
+	DeepLAPIKey           string
+	DeepLAPIURL           string
+	GoogleTranslateAPIKey string
+	GoogleTranslateURL    string
+	TranslationProviders  []string
+	TranslationCacheTTL   time.Duration
+	AIAPIURL              string
+	AIAPIKey              string
+	AIModel               string
+	AITimeout             time.Duration
 	UploadsDir            string
 	MaxUploadSize         int64
+	RedisAddr             string
+	RedisPassword         string
+	RedisDB               int
+	SessionIdleTimeout    time.Duration
+	TURNURLs              []string
+	STUNURLs              []string
+	TURNSecret            string
+	TURNCredentialTTL     time.Duration
+	BillingProvider       string
+	BillingWebhookSecret  string
+	BillingSuccessURL     string
+	BillingCancelURL      string
+	StripeAPIURL          string
+	StripeSecretKey       string
+	StripeProPriceID      string
 }
 
 func LoadConfig() (*Config, error) {
@@ -31,8 +58,33 @@ func LoadConfig() (*Config, error) {
 		GoogleRedirectURL:     getEnv("GOOGLE_REDIRECT_URL", "http://localhost:3000/auth/google/callback"),
 		LibreTranslateURL:     getEnv("LIBRETRANSLATE_URL", "http://localhost:5000"),
 		LibreTranslateAPIKey:  getEnv("LIBRETRANSLATE_API_KEY", ""),
+		DeepLAPIKey:           getEnv("DEEPL_API_KEY", ""),
+		DeepLAPIURL:           getEnv("DEEPL_API_URL", ""), // empty picks the free or pro endpoint from the key
+		GoogleTranslateAPIKey: getEnv("GOOGLE_TRANSLATE_API_KEY", ""),
+		GoogleTranslateURL:    getEnv("GOOGLE_TRANSLATE_URL", ""),
+		TranslationProviders:  getEnvList("TRANSLATION_PROVIDERS"), // fallback order; empty uses every configured provider
+		TranslationCacheTTL:   time.Duration(getEnvInt64("TRANSLATION_CACHE_TTL_HOURS", 168)) * time.Hour,
+		AIAPIURL:              getEnv("AI_API_URL", ""), // OpenAI-compatible base URL; empty disables AI features
+		AIAPIKey:              getEnv("AI_API_KEY", ""),
+		AIModel:               getEnv("AI_MODEL", "llama3.1"),
+		AITimeout:             time.Duration(getEnvInt64("AI_TIMEOUT_SECONDS", 60)) * time.Second,
 		UploadsDir:            getEnv("UPLOADS_DIR", "./uploads"),
 		MaxUploadSize:         getEnvInt64("MAX_UPLOAD_SIZE", 5*1024*1024), // 5MB default
+		RedisAddr:             getEnv("REDIS_ADDR", ""), // empty keeps WebSocket delivery in-process
+		RedisPassword:         getEnv("REDIS_PASSWORD", ""),
+		RedisDB:               int(getEnvInt64("REDIS_DB", 0)),
+		SessionIdleTimeout:    time.Duration(getEnvInt64("SESSION_IDLE_TIMEOUT_MINUTES", 15)) * time.Minute,
+		TURNURLs:              getEnvList("TURN_URLS"), // empty disables TURN credentials
+		STUNURLs:              getEnvList("STUN_URLS"),
+		TURNSecret:            getEnv("TURN_SECRET", ""),
+		TURNCredentialTTL:     time.Duration(getEnvInt64("TURN_CREDENTIAL_TTL_SECONDS", 3600)) * time.Second,
+		BillingProvider:       getEnv("BILLING_PROVIDER", ""), // stripe or fake; empty disables checkout
+		BillingWebhookSecret:  getEnv("BILLING_WEBHOOK_SECRET", ""),
+		BillingSuccessURL:     getEnv("BILLING_SUCCESS_URL", "http://localhost:3000/billing/success"),
+		BillingCancelURL:      getEnv("BILLING_CANCEL_URL", "http://localhost:3000/billing/cancel"),
+		StripeAPIURL:          getEnv("STRIPE_API_URL", ""), // empty uses https://api.stripe.com
+		StripeSecretKey:       getEnv("STRIPE_SECRET_KEY", ""),
+		StripeProPriceID:      getEnv("STRIPE_PRO_PRICE_ID", ""),
 	}
 
 	if config.DatabaseURL == "" {
@@ -68,4 +120,15 @@ func getEnvInt64(key string, defaultValue int64) int64 {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvList splits a comma-separated variable, skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
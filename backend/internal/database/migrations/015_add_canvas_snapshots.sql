@@ -0,0 +1,13 @@
+-- Materialized whiteboard state so sessions can be loaded from a snapshot
+-- plus the operations recorded after it
+CREATE TABLE IF NOT EXISTS canvas_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES language_sessions(id) ON DELETE CASCADE,
+    sequence_number BIGINT NOT NULL,
+    state JSONB NOT NULL,
+    operation_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(session_id, sequence_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_canvas_snapshots_session_sequence ON canvas_snapshots(session_id, sequence_number DESC);
@@ -0,0 +1,14 @@
+-- Server-assigned canvas versions for conflict detection and per-user undo
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS canvas_version BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE canvas_operations ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE canvas_operations ADD COLUMN IF NOT EXISTS base_version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE canvas_operations ADD COLUMN IF NOT EXISTS element_id VARCHAR(255);
+ALTER TABLE canvas_operations ADD COLUMN IF NOT EXISTS previous_element JSONB;
+
+-- Undo and redo reference operations that may since have been compacted, so
+-- these are not foreign keys
+ALTER TABLE canvas_operations ADD COLUMN IF NOT EXISTS undo_of UUID;
+ALTER TABLE canvas_operations ADD COLUMN IF NOT EXISTS redo_of UUID;
+
+CREATE INDEX IF NOT EXISTS idx_canvas_operations_version ON canvas_operations(session_id, version);
@@ -0,0 +1,39 @@
+-- Session scheduling and multi-invitee RSVP
+-- Sessions can be booked ahead of time; they stay 'scheduled' until the first
+-- participant joins near the start time
+
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS scheduled_start_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS scheduled_end_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS started_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS reminder_sent_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE language_sessions DROP CONSTRAINT IF EXISTS language_sessions_status_check;
+ALTER TABLE language_sessions ADD CONSTRAINT language_sessions_status_check
+    CHECK (status IN ('scheduled', 'active', 'ended'));
+
+CREATE INDEX IF NOT EXISTS idx_language_sessions_scheduled_start ON language_sessions(status, scheduled_start_at);
+
+-- Session invitations table
+CREATE TABLE IF NOT EXISTS session_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES language_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'tentative')),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(session_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_invitations_user_id ON session_invitations(user_id, status);
+
+-- Existing single invitations become pending RSVPs
+INSERT INTO session_invitations (session_id, user_id)
+SELECT id, invited_user_id FROM language_sessions WHERE invited_user_id IS NOT NULL
+ON CONFLICT (session_id, user_id) DO NOTHING;
+
+CREATE TRIGGER update_session_invitations_updated_at
+    BEFORE UPDATE ON session_invitations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,56 @@
+-- Recurring session series
+-- A series stores the recurrence rule; concrete language_sessions rows are
+-- materialized shortly before each occurrence
+
+CREATE TABLE IF NOT EXISTS session_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    max_participants INTEGER DEFAULT 2 CHECK (max_participants > 0 AND max_participants <= 10),
+    session_type VARCHAR(20) DEFAULT 'practice' CHECK (session_type IN ('practice', 'lesson', 'conversation')),
+    target_language VARCHAR(50),
+    invitee_ids UUID[] NOT NULL DEFAULT '{}',
+    timezone VARCHAR(64) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    rrule TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'ended')),
+    materialized_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_series_created_by ON session_series(created_by);
+CREATE INDEX IF NOT EXISTS idx_session_series_materialize ON session_series(status, materialized_until);
+
+-- Per-occurrence changes, keyed by the occurrence's original start time
+CREATE TABLE IF NOT EXISTS session_series_exceptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    series_id UUID NOT NULL REFERENCES session_series(id) ON DELETE CASCADE,
+    occurrence_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    cancelled BOOLEAN NOT NULL DEFAULT false,
+    name VARCHAR(255),
+    description TEXT,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(series_id, occurrence_start)
+);
+
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES session_series(id) ON DELETE SET NULL;
+ALTER TABLE language_sessions ADD COLUMN IF NOT EXISTS occurrence_start TIMESTAMP WITH TIME ZONE;
+
+-- Lets every server run the materializer without creating duplicates
+CREATE UNIQUE INDEX IF NOT EXISTS idx_language_sessions_series_occurrence ON language_sessions(series_id, occurrence_start);
+
+CREATE TRIGGER update_session_series_updated_at
+    BEFORE UPDATE ON session_series
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_session_series_exceptions_updated_at
+    BEFORE UPDATE ON session_series_exceptions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
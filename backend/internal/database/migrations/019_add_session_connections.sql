@@ -0,0 +1,17 @@
+-- Session connection tracking
+-- One row per WebSocket connection to a session room. Nodes refresh
+-- last_seen_at for their open connections, so connections of a crashed node
+-- stop counting once they go stale.
+
+CREATE TABLE IF NOT EXISTS session_connections (
+    id UUID PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES language_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    node_id VARCHAR(64) NOT NULL,
+    connected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    disconnected_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_connections_session_id ON session_connections(session_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_session_connections_open ON session_connections(node_id) WHERE disconnected_at IS NULL;
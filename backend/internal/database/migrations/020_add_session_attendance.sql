@@ -0,0 +1,9 @@
+-- Session attendance
+-- Session connections also record REST join/leave intervals so attendance
+-- covers participants without a session WebSocket. REST intervals belong to
+-- no hub node and are not heartbeated.
+
+ALTER TABLE session_connections ADD COLUMN IF NOT EXISTS source VARCHAR(16) NOT NULL DEFAULT 'websocket'
+    CHECK (source IN ('websocket', 'rest'));
+
+CREATE INDEX IF NOT EXISTS idx_session_connections_open_user ON session_connections(session_id, user_id, source) WHERE disconnected_at IS NULL;
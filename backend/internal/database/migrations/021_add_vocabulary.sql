@@ -0,0 +1,43 @@
+-- Vocabulary notebook
+-- Saved words and phrases are reviewed on an SM-2 schedule. A word counts
+-- towards user_stats.words_learned once, the first time it is learned.
+
+CREATE TABLE IF NOT EXISTS vocabulary_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    term TEXT NOT NULL,
+    translation TEXT NOT NULL,
+    source_language VARCHAR(10) NOT NULL,
+    target_language VARCHAR(10) NOT NULL,
+    example_sentence TEXT,
+    origin_type VARCHAR(20) CHECK (origin_type IN ('message', 'session', 'post')),
+    origin_id UUID, -- Message, session or post the word was saved from
+    ease_factor NUMERIC(4, 2) NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_reviewed_at TIMESTAMP WITH TIME ZONE,
+    learned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((origin_type IS NULL) = (origin_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vocabulary_items_user_term
+    ON vocabulary_items(user_id, lower(term), source_language, target_language);
+CREATE INDEX IF NOT EXISTS idx_vocabulary_items_user_due ON vocabulary_items(user_id, due_at);
+
+-- Review history, one row per answered flashcard
+CREATE TABLE IF NOT EXISTS vocabulary_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    item_id UUID NOT NULL REFERENCES vocabulary_items(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    quality SMALLINT NOT NULL CHECK (quality BETWEEN 0 AND 5),
+    ease_factor NUMERIC(4, 2) NOT NULL,
+    interval_days INTEGER NOT NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vocabulary_reviews_item ON vocabulary_reviews(item_id, reviewed_at);
+CREATE INDEX IF NOT EXISTS idx_vocabulary_reviews_user ON vocabulary_reviews(user_id, reviewed_at);
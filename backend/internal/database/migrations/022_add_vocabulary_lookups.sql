@@ -0,0 +1,9 @@
+-- Vocabulary saved from translations
+-- Words can be linked to the session chat message they were translated from,
+-- and repeated lookups of a saved word are counted instead of duplicated.
+
+ALTER TABLE vocabulary_items DROP CONSTRAINT IF EXISTS vocabulary_items_origin_type_check;
+ALTER TABLE vocabulary_items ADD CONSTRAINT vocabulary_items_origin_type_check
+    CHECK (origin_type IN ('message', 'session', 'session_message', 'post'));
+
+ALTER TABLE vocabulary_items ADD COLUMN IF NOT EXISTS lookup_count INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+-- Detected content language
+-- ISO 639-1 code of the language messages, session chat, posts and comments
+-- are written in, detected when they are saved. NULL when detection was not
+-- confident enough or the translation service was unavailable.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS language VARCHAR(10);
+ALTER TABLE session_messages ADD COLUMN IF NOT EXISTS language VARCHAR(10);
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS language VARCHAR(10);
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS language VARCHAR(10);
+
+CREATE INDEX IF NOT EXISTS idx_posts_language ON posts(language, cursor_id DESC);
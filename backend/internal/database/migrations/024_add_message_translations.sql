@@ -0,0 +1,5 @@
+-- Inline message translations
+-- Translations of a message are stored on its row, keyed by target language
+-- code, so each one is computed once and is removed along with the message.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
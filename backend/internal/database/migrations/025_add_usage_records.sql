@@ -0,0 +1,23 @@
+-- Metered usage for plan entitlements
+-- One row per use of a recorded meter (AI improvements, translations,
+-- sessions). Storage is measured from the uploads directory instead.
+
+CREATE TABLE IF NOT EXISTS usage_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    meter VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_user_meter ON usage_records(user_id, meter, created_at);
+
+-- AI improvements used to be counted in ai_usage_logs, which is no longer
+-- written to. Carry over this month's usage so quotas don't reset early.
+INSERT INTO usage_records (user_id, meter, amount, created_at)
+SELECT user_id, 'ai_improvements', 1, created_at
+FROM ai_usage_logs
+WHERE created_at >= date_trunc('month', NOW())
+  AND NOT EXISTS (SELECT 1 FROM usage_records WHERE meter = 'ai_improvements');
+
+CREATE INDEX IF NOT EXISTS idx_users_plan_expires_at ON users(plan_expires_at) WHERE plan_expires_at IS NOT NULL;
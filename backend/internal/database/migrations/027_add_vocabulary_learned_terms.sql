@@ -0,0 +1,18 @@
+-- Learned vocabulary terms
+-- Records each term a user has learned, so deleting a word and saving it
+-- again cannot earn the word learned reward a second time.
+
+CREATE TABLE IF NOT EXISTS vocabulary_learned_terms (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    term TEXT NOT NULL, -- Lowercased, like idx_vocabulary_items_user_term
+    source_language VARCHAR(10) NOT NULL,
+    target_language VARCHAR(10) NOT NULL,
+    learned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, term, source_language, target_language)
+);
+
+INSERT INTO vocabulary_learned_terms (user_id, term, source_language, target_language, learned_at)
+SELECT user_id, lower(term), source_language, target_language, learned_at
+FROM vocabulary_items
+WHERE learned_at IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Calendar feed tokens
+-- Each user's calendar feed URL carries a random token of their own instead
+-- of one derived from the server's signing secret, so a leaked URL can be
+-- revoked by rotating the token. Tokens are created on first use.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_feed_token VARCHAR(64);
@@ -0,0 +1,8 @@
+-- Cancelled sessions
+-- A scheduled session that is called off before it starts, such as a
+-- cancelled occurrence of a series, is 'cancelled' rather than 'ended', so it
+-- is not mistaken for a session that was held
+
+ALTER TABLE language_sessions DROP CONSTRAINT IF EXISTS language_sessions_status_check;
+ALTER TABLE language_sessions ADD CONSTRAINT language_sessions_status_check
+    CHECK (status IN ('scheduled', 'active', 'ended', 'cancelled'));
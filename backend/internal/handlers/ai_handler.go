@@ -1,26 +1,48 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
-	"time"
 
 	"language-exchange/internal/models"
+	"language-exchange/internal/services"
 	"language-exchange/pkg/errors"
 	
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
 )
 
 type AIHandler struct {
-	db *sqlx.DB
+	aiService    services.AIService
+	entitlements services.EntitlementsService
+	userService  services.UserServiceInterface
 }
 
-func NewAIHandler(db *sqlx.DB) *AIHandler {
-	return &AIHandler{db: db}
+func NewAIHandler(aiService services.AIService, entitlements services.EntitlementsService, userService services.UserServiceInterface) *AIHandler {
+	return &AIHandler{
+		aiService:    aiService,
+		entitlements: entitlements,
+		userService:  userService,
+	}
+}
+
+// improvementQuota is what an improvement request needs to know about the
+// user's plan
+type improvementQuota struct {
+	user        *models.User
+	isPro       bool
+	usage       *models.MeterUsage
+	reservation *models.UsageReservation
 }
 
-const FREE_MONTHLY_LIMIT = 50
+// releaseImprovement gives back the improvement reserved for a request that failed
+func (h *AIHandler) releaseImprovement(ctx context.Context, userID string, quota *improvementQuota) {
+	if err := h.entitlements.ReleaseUsage(ctx, quota.reservation); err != nil {
+		log.Printf("Failed to release AI improvement usage for user %s: %v", userID, err)
+	}
+}
 
 // ImproveMessage handles AI-powered message improvement
 func (h *AIHandler) ImproveMessage(c *gin.Context) {
@@ -37,72 +59,235 @@ func (h *AIHandler) ImproveMessage(c *gin.Context) {
 		return
 	}
 
-	// Check user
-	var user models.User
-	err := h.db.Get(&user, "SELECT * FROM users WHERE id = $1", userID)
+	quota, ok := h.checkImprovementQuota(c, userID, req.Text)
+	if !ok {
+		return
+	}
+
+	improvement, err := h.aiService.ImproveMessage(c.Request.Context(), improvementInput(req, quota.user))
 	if err != nil {
-		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get user")
+		h.releaseImprovement(c.Request.Context(), userID, quota)
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to improve message")
+		}
+		return
+	}
+
+	errors.SendSuccess(c, improvementResponse(quota, improvement))
+}
+
+// StreamImproveMessage handles AI-powered message improvement streamed over
+// Server-Sent Events
+// @Summary Stream an AI message improvement
+// @Description Streams the improved message as "token" events with {"text": "..."} while the model writes it, then sends a "done" event with the full improvement and its corrections, or an "error" event with {"code": "...", "error": "..."}. Quota used by a stream that fails is given back.
+// @Tags ai
+// @Accept json
+// @Produce text/event-stream
+// @Param request body models.ImproveMessageRequest true "Message to improve"
+// @Success 200 {object} models.ImproveMessageResponse "Payload of the final done event"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} models.ImproveMessageResponse
+// @Router /ai/improve/stream [post]
+func (h *AIHandler) StreamImproveMessage(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req models.ImproveMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+		return
+	}
+
+	quota, ok := h.checkImprovementQuota(c, userID, req.Text)
+	if !ok {
 		return
 	}
 
-	// Count usage for non-pro users
-	var count int
-	if user.PlanType != "pro" {
-		// Get start of current month
-		now := time.Now()
-		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		
-		// Count this month's usage
-		err = h.db.Get(&count, 
-			"SELECT COUNT(*) FROM ai_usage_logs WHERE user_id = $1 AND created_at >= $2",
-			userID, startOfMonth)
+	streamAI(c, func(ctx context.Context, onToken func(string) error) (interface{}, error) {
+		improvement, err := h.aiService.StreamImprovement(ctx, improvementInput(req, quota.user), onToken)
 		if err != nil {
-			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to count usage")
-			return
+			// The client may be gone, so don't release with its context
+			h.releaseImprovement(context.Background(), userID, quota)
+			return nil, err
 		}
 
-		// Check if limit exceeded
-		if count >= FREE_MONTHLY_LIMIT {
-			// Return upgrade prompt
-			c.JSON(http.StatusTooManyRequests, models.ImproveMessageResponse{
-				NeedsUpgrade: true,
-				Original:     req.Text,
-				Preview:      h.generatePreview(req.Text),
-				Message:      "You've used all 50 free improvements this month! Upgrade to Pro for unlimited improvements.",
-				Used:         int64(count),
-				Limit:        FREE_MONTHLY_LIMIT,
-			})
-			return
+		return improvementResponse(quota, improvement), nil
+	})
+}
+
+// GetConversationStarters suggests opening messages for a match
+// @Summary Suggest conversation starters
+// @Description Suggests opening messages for both partners of a match or conversation, each in the language that partner is practicing. Uses the AI model when configured and a curated template library otherwise. AI suggestions are reused for a day for the same partner and levels.
+// @Tags ai
+// @Produce json
+// @Param match_id query string false "Match ID (this or conversation_id is required)"
+// @Param conversation_id query string false "Conversation ID"
+// @Param level query string false "Requester's CEFR level in the language they practice" Enums(A1, A2, B1, B2, C1, C2)
+// @Param partner_level query string false "Partner's CEFR level in the language they practice" Enums(A1, A2, B1, B2, C1, C2)
+// @Success 200 {object} models.ConversationStarters
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /ai/starters [get]
+func (h *AIHandler) GetConversationStarters(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var req models.ConversationStartersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid query parameters")
+		return
+	}
+
+	starters, err := h.aiService.SuggestConversationStarters(c.Request.Context(), models.ConversationStarterInput{
+		UserID:         userID,
+		MatchID:        req.MatchID,
+		ConversationID: req.ConversationID,
+		Level:          req.Level,
+		PartnerLevel:   req.PartnerLevel,
+	})
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to suggest conversation starters")
 		}
+		return
 	}
 
-	// Log usage
-	_, err = h.db.Exec(
-		"INSERT INTO ai_usage_logs (user_id, type, created_at) VALUES ($1, $2, $3)",
-		userID, "improvement", time.Now())
+	errors.SendSuccess(c, starters)
+}
+
+// streamAI runs an AI call and streams its output to the client as
+// Server-Sent Events: a "token" event per piece of generated text, then a
+// "done" event with run's result or an "error" event. Errors found before
+// streaming starts should be sent as regular JSON responses instead.
+func streamAI(c *gin.Context, run func(ctx context.Context, onToken func(string) error) (interface{}, error)) {
+	ctx := c.Request.Context()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	onToken := func(token string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.SSEvent("token", gin.H{"text": token})
+		c.Writer.Flush()
+		return nil
+	}
+
+	result, err := run(ctx, onToken)
 	if err != nil {
-		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to log usage")
+		if ctx.Err() != nil {
+			return // client went away
+		}
+
+		response := errors.ErrorResponse{Code: "INTERNAL_ERROR", Error: "AI request failed"}
+		if appErr, ok := err.(*models.AppError); ok {
+			response = errors.ErrorResponse{Code: appErr.Code, Error: appErr.Message}
+		}
+		c.SSEvent("error", response)
+		c.Writer.Flush()
 		return
 	}
 
-	// Generate improvement
-	improved := h.getMockImprovement(req.Text)
+	c.SSEvent("done", result)
+	c.Writer.Flush()
+}
+
+// checkImprovementQuota loads the user and reserves one AI improvement,
+// which is given back if the improvement fails. When the request cannot go ahead it sends the response itself and returns
+// false.
+func (h *AIHandler) checkImprovementQuota(c *gin.Context, userID, text string) (*improvementQuota, bool) {
+	ctx := c.Request.Context()
 
-	// Return response
+	user, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get user")
+		return nil, false
+	}
+
+	plan, _, err := h.entitlements.GetPlan(ctx, userID)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get plan")
+		return nil, false
+	}
+
+	// Reserve the improvement up front so parallel requests cannot go over
+	// the limit together
+	reservation, err := h.entitlements.ReserveUsage(ctx, userID, models.MeterAIImprovements, 1)
+	if err != nil {
+		appErr, ok := err.(*models.AppError)
+		if !ok || appErr.Code != "QUOTA_EXCEEDED" {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to count usage")
+			return nil, false
+		}
+
+		// Return upgrade prompt
+		c.JSON(http.StatusTooManyRequests, models.ImproveMessageResponse{
+			NeedsUpgrade: true,
+			Original:     text,
+			Preview:      h.generatePreview(text),
+			Message:      fmt.Sprintf("You've used all %d improvements in your plan this month! Upgrade to Pro for unlimited improvements.", reservation.Usage.Limit),
+			Used:         reservation.Usage.Used,
+			Limit:        reservation.Usage.Limit,
+		})
+		return nil, false
+	}
+
+	return &improvementQuota{
+		user:        user,
+		isPro:       plan.Name == models.PlanPro,
+		usage:       &reservation.Usage,
+		reservation: reservation,
+	}, true
+}
+
+// improvementInput builds the service input, with explanations in the
+// user's native language
+func improvementInput(req models.ImproveMessageRequest, user *models.User) models.ImproveMessageInput {
+	input := models.ImproveMessageInput{
+		Text:     req.Text,
+		Language: req.Language,
+		Level:    req.Level,
+	}
+	if len(user.NativeLanguages) > 0 {
+		input.ExplanationLanguage = user.NativeLanguages[0]
+	}
+	return input
+}
+
+// improvementResponse builds the response for a delivered improvement
+func improvementResponse(quota *improvementQuota, improvement *models.MessageImprovement) models.ImproveMessageResponse {
 	response := models.ImproveMessageResponse{
-		Original: req.Text,
-		Improved: improved,
-		IsPro:    user.PlanType == "pro",
+		Original:    improvement.Original,
+		Improved:    improvement.Improved,
+		Corrections: improvement.Corrections,
+		IsPro:       quota.isPro,
 	}
 
-	// Add usage info for free users
-	if user.PlanType != "pro" {
-		response.Remaining = int64(FREE_MONTHLY_LIMIT - count - 1)
-		response.Used = int64(count + 1)
-		response.Limit = int64(FREE_MONTHLY_LIMIT)
+	// Add usage info for limited plans, counting this improvement
+	if quota.usage.Limit != models.UnlimitedQuota {
+		response.Used = quota.usage.Used + 1
+		response.Limit = quota.usage.Limit
+		response.Remaining = max(quota.usage.Limit-response.Used, 0)
 	}
 
-	errors.SendSuccess(c, response)
+	return response
 }
 
 // GetUsageStats returns the user's AI usage statistics
@@ -113,44 +298,28 @@ func (h *AIHandler) GetUsageStats(c *gin.Context) {
 		return
 	}
 
-	// Get user
-	var user models.User
-	err := h.db.Get(&user, "SELECT * FROM users WHERE id = $1", userID)
+	plan, _, err := h.entitlements.GetPlan(c.Request.Context(), userID)
 	if err != nil {
-		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get user")
+		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get plan")
 		return
 	}
 
 	stats := models.AIUsageStats{
-		IsPro:    user.PlanType == "pro",
-		PlanType: user.PlanType,
-	}
-
-	// Count usage for non-pro users
-	if user.PlanType != "pro" {
-		now := time.Now()
-		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		
-		var count int64
-		err = h.db.Get(&count,
-			"SELECT COUNT(*) FROM ai_usage_logs WHERE user_id = $1 AND created_at >= $2",
-			userID, startOfMonth)
-		if err != nil {
-			// Log error but continue with zero count
-			count = 0
-		}
+		IsPro:    plan.Name == models.PlanPro,
+		PlanType: plan.Name,
+	}
 
-		stats.Used = count
-		stats.Limit = FREE_MONTHLY_LIMIT
-		stats.Remaining = FREE_MONTHLY_LIMIT - count
-		if stats.Remaining < 0 {
-			stats.Remaining = 0
-		}
-		// stats.LastResetDate = startOfMonth.Format("2006-01-02")
-		
-		// Calculate next reset date (first day of next month)
-		// nextMonth := startOfMonth.AddDate(0, 1, 0)
-		// stats.NextResetDate = nextMonth.Format("2006-01-02")
+	// Report usage for limited plans
+	usage, err := h.entitlements.GetMeterUsage(c.Request.Context(), userID, models.MeterAIImprovements)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to count usage")
+		return
+	}
+	if usage.Limit != models.UnlimitedQuota {
+		stats.Used = usage.Used
+		stats.Limit = usage.Limit
+		stats.Remaining = usage.Remaining
+		stats.ResetsAt = usage.ResetsAt
 	}
 
 	errors.SendSuccess(c, stats)
@@ -177,34 +346,3 @@ func (h *AIHandler) generatePreview(text string) string {
 	
 	return preview
 }
-
-// getMockImprovement returns a mock improved version of the text
-func (h *AIHandler) getMockImprovement(text string) string {
-	// This is a mock implementation
-	// In production, this would call an actual AI service
-	
-	// Simple text replacements for demo
-	improved := text
-	
-	// Common abbreviations
-	improved = strings.ReplaceAll(improved, " u ", " you ")
-	improved = strings.ReplaceAll(improved, " ur ", " your ")
-	improved = strings.ReplaceAll(improved, " r ", " are ")
-	improved = strings.ReplaceAll(improved, " thx ", " thanks ")
-	improved = strings.ReplaceAll(improved, " pls ", " please ")
-	
-	// Capitalization
-	if len(improved) > 0 {
-		improved = strings.ToUpper(improved[:1]) + improved[1:]
-	}
-	
-	// Add punctuation if missing
-	if len(improved) > 0 {
-		lastChar := improved[len(improved)-1]
-		if lastChar != '.' && lastChar != '!' && lastChar != '?' {
-			improved += "."
-		}
-	}
-	
-	return improved
-}
\ No newline at end of file
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/services"
+	"language-exchange/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookSize limits the body of a billing webhook
+const maxWebhookSize = 1 << 20
+
+type BillingHandler struct {
+	billingService services.BillingService
+}
+
+func NewBillingHandler(billingService services.BillingService) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+	}
+}
+
+// CreateCheckout starts paying for a plan
+// @Summary Start checkout
+// @Description Create a checkout session for a paid plan and return the payment page to send the user to. The plan changes once the payment is confirmed.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param request body models.CheckoutRequest true "Plan to buy"
+// @Success 200 {object} models.CheckoutSession
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /billing/checkout [post]
+func (h *BillingHandler) CreateCheckout(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var input models.CheckoutRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+		return
+	}
+
+	session, err := h.billingService.CreateCheckout(c.Request.Context(), userID, input.Plan)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to start checkout")
+		}
+		return
+	}
+
+	errors.SendSuccess(c, session)
+}
+
+// GetSubscription returns the user's subscription
+// @Summary Get subscription
+// @Description Get the authenticated user's current or most recent subscription
+// @Tags billing
+// @Produce json
+// @Success 200 {object} models.Subscription
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /billing/subscription [get]
+func (h *BillingHandler) GetSubscription(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	subscription, err := h.billingService.GetSubscription(c.Request.Context(), userID)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get subscription")
+		}
+		return
+	}
+
+	errors.SendSuccess(c, subscription)
+}
+
+// GetInvoices lists the user's invoices
+// @Summary List invoices
+// @Description List the authenticated user's invoices, newest first. Amounts are in the smallest currency unit.
+// @Tags billing
+// @Produce json
+// @Param limit query int false "Page size" default(20)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {array} models.Invoice
+// @Failure 401 {object} ErrorResponse
+// @Router /billing/invoices [get]
+func (h *BillingHandler) GetInvoices(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	invoices, err := h.billingService.GetInvoices(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get invoices")
+		return
+	}
+
+	errors.SendSuccess(c, invoices)
+}
+
+// HandleWebhook receives events from the billing provider
+// @Summary Billing webhook
+// @Description Receive a signed subscription event from the billing provider. Redelivered events are acknowledged without being applied again; failures return an error so the provider retries.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /billing/webhook [post]
+func (h *BillingHandler) HandleWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize))
+	if err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Failed to read webhook")
+		return
+	}
+
+	if err := h.billingService.HandleWebhook(c.Request.Context(), payload, c.Request.Header); err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process webhook")
+		}
+		return
+	}
+
+	errors.SendSuccess(c, gin.H{"received": true})
+}
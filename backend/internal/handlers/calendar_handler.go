@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"language-exchange/internal/services"
+	"language-exchange/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const calendarContentType = "text/calendar; charset=utf-8"
+
+type CalendarHandler struct {
+	calendarService services.CalendarService
+}
+
+func NewCalendarHandler(calendarService services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+	}
+}
+
+// GetMyCalendar downloads the current user's upcoming sessions as an .ics file
+// @Summary Download session calendar
+// @Description Get the current user's upcoming sessions as an iCalendar (RFC 5545) file
+// @Tags calendar
+// @Produce text/calendar
+// @Success 200 {string} string "iCalendar data"
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions/calendar.ics [get]
+func (h *CalendarHandler) GetMyCalendar(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	h.sendCalendar(c, userID.(string))
+}
+
+// GetCalendarFeedURL returns the URL calendar apps can subscribe to
+// @Summary Get calendar feed URL
+// @Description Get the private URL of the current user's session calendar feed
+// @Tags calendar
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/calendar [get]
+func (h *CalendarHandler) GetCalendarFeedURL(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	token, err := h.calendarService.FeedToken(c.Request.Context(), userID.(string))
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "CALENDAR_FEED_FAILED", "Failed to get calendar feed URL")
+		return
+	}
+
+	errors.SendSuccess(c, gin.H{"url": calendarFeedURL(c, userID.(string), token)})
+}
+
+// RotateCalendarFeedURL replaces the calendar feed URL
+// @Summary Rotate calendar feed URL
+// @Description Replace the private URL of the current user's session calendar feed. Calendar apps subscribed with the old URL lose access.
+// @Tags calendar
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/calendar/rotate [post]
+func (h *CalendarHandler) RotateCalendarFeedURL(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	token, err := h.calendarService.RotateFeedToken(c.Request.Context(), userID.(string))
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "CALENDAR_FEED_FAILED", "Failed to rotate calendar feed URL")
+		return
+	}
+
+	errors.SendSuccess(c, gin.H{"url": calendarFeedURL(c, userID.(string), token)})
+}
+
+// calendarFeedURL builds the feed URL calendar apps subscribe to
+func calendarFeedURL(c *gin.Context, userID, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/calendar/" + userID + "/" + token + ".ics"
+}
+
+// GetCalendarFeed serves a user's calendar feed to calendar apps. The feed
+// URL carries the user's feed token instead of a login.
+// @Summary Subscribe to session calendar
+// @Description Get a user's upcoming sessions as an iCalendar feed, authorized by the feed token
+// @Tags calendar
+// @Produce text/calendar
+// @Param userId path string true "User ID"
+// @Param token path string true "Feed token followed by .ics"
+// @Success 200 {string} string "iCalendar data"
+// @Failure 404 {object} ErrorResponse
+// @Router /calendar/{userId}/{token} [get]
+func (h *CalendarHandler) GetCalendarFeed(c *gin.Context) {
+	userID := c.Param("userId")
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	if userID == "" || !h.calendarService.ValidateFeedToken(c.Request.Context(), userID, token) {
+		// Do not reveal whether the user exists
+		errors.SendError(c, http.StatusNotFound, "CALENDAR_NOT_FOUND", "Calendar not found")
+		return
+	}
+
+	h.sendCalendar(c, userID)
+}
+
+func (h *CalendarHandler) sendCalendar(c *gin.Context, userID string) {
+	calendar, err := h.calendarService.GetUserCalendar(context.Background(), userID)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "CALENDAR_FETCH_FAILED", "Failed to build calendar")
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="sessions.ics"`)
+	c.Data(http.StatusOK, calendarContentType, []byte(calendar))
+}
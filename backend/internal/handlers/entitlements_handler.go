@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/services"
+	"language-exchange/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EntitlementsHandler struct {
+	entitlementsService services.EntitlementsService
+}
+
+func NewEntitlementsHandler(entitlementsService services.EntitlementsService) *EntitlementsHandler {
+	return &EntitlementsHandler{
+		entitlementsService: entitlementsService,
+	}
+}
+
+// GetPlans lists the available plans
+// @Summary List plans
+// @Description List the plans with their features and limits. A limit of -1 means unlimited; upload_storage is in bytes.
+// @Tags entitlements
+// @Produce json
+// @Success 200 {array} models.Plan
+// @Router /entitlements/plans [get]
+func (h *EntitlementsHandler) GetPlans(c *gin.Context) {
+	errors.SendSuccess(c, h.entitlementsService.GetPlans())
+}
+
+// GetUsage returns the user's plan and metered usage
+// @Summary Get plan usage
+// @Description Get the authenticated user's plan, its features and the usage of each metered limit with when it resets. Limit and remaining are -1 when unlimited.
+// @Tags entitlements
+// @Produce json
+// @Success 200 {object} models.EntitlementUsage
+// @Failure 401 {object} ErrorResponse
+// @Router /entitlements/usage [get]
+func (h *EntitlementsHandler) GetUsage(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	usage, err := h.entitlementsService.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get usage")
+		}
+		return
+	}
+
+	errors.SendSuccess(c, usage)
+}
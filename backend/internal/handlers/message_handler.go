@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
@@ -29,18 +28,20 @@ func NewMessageHandler(messageService services.MessageService, conversationServi
 
 // GetMessages godoc
 // @Summary Get messages in a conversation
-// @Description Get a list of messages in a specific conversation
+// @Description Get a list of messages in a specific conversation. With translate_to, received messages also carry a translation into that language; each newly translated message counts against the translations quota.
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param conversationId path string true "Conversation ID"
 // @Param limit query int false "Limit number of results" default(50)
 // @Param offset query int false "Offset for pagination" default(0)
+// @Param translate_to query string false "Language code to translate received messages into"
 // @Success 200 {object} models.MessageListResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /conversations/{conversationId}/messages [get]
 func (h *MessageHandler) GetMessages(c *gin.Context) {
@@ -73,9 +74,26 @@ func (h *MessageHandler) GetMessages(c *gin.Context) {
 		return
 	}
 
-	// Get messages
-	messages, err := h.messageService.GetMessages(c.Request.Context(), conversationID, userID.(string), limit, offset)
+	// Get messages, translated if requested
+	var messages []*models.Message
+	if translateTo := c.Query("translate_to"); translateTo != "" {
+		messages, err = h.messageService.GetTranslatedMessages(c.Request.Context(), conversationID, userID.(string), translateTo, limit, offset)
+	} else {
+		messages, err = h.messageService.GetMessages(c.Request.Context(), conversationID, userID.(string), limit, offset)
+	}
 	if err != nil {
+		if translationErr, ok := err.(*models.TranslationError); ok {
+			status := http.StatusBadRequest
+			if translationErr == models.ErrTranslationServiceUnavailable {
+				status = http.StatusServiceUnavailable
+			}
+			errors.SendError(c, status, translationErr.Code, translationErr.Message)
+			return
+		}
+		if appErr, ok := err.(*models.AppError); ok {
+			sendAppError(c, appErr)
+			return
+		}
 		if err.Error() == "conversation not found" {
 			errors.SendError(c, http.StatusNotFound, "NOT_FOUND", "Conversation not found")
 			return
@@ -158,45 +176,7 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 		return
 	}
 
-	// Broadcast message via WebSocket to conversation participants
-	if h.wsHub != nil {
-		fmt.Printf("DEBUG: wsHub is not nil, attempting to broadcast message\n")
-		
-		// Get conversation to find all participants
-		conversation, err := h.conversationService.GetConversationByID(c.Request.Context(), conversationID, userID.(string))
-		if err != nil {
-			fmt.Printf("DEBUG: Error getting conversation for broadcast: %v\n", err)
-		} else if conversation == nil {
-			fmt.Printf("DEBUG: Conversation is nil\n")
-		} else {
-			fmt.Printf("DEBUG: Got conversation, participants: User1ID=%s, User2ID=%s\n", conversation.User1ID, conversation.User2ID)
-			
-			messageData := map[string]interface{}{
-				"id":              message.ID,
-				"conversation_id": message.ConversationID,
-				"sender_id":       message.SenderID,
-				"content":         message.Content,
-				"message_type":    message.MessageType,
-				"status":          message.Status,
-				"created_at":      message.CreatedAt,
-				"sender":          message.Sender,
-			}
-			
-			wsMessage := map[string]interface{}{
-				"type": "new_message",
-				"data": messageData,
-			}
-			
-			// Send to all participants
-			participantIDs := []string{conversation.User1ID, conversation.User2ID}
-			fmt.Printf("DEBUG: Broadcasting to participants: %v\n", participantIDs)
-			h.wsHub.SendToUsers(participantIDs, wsMessage)
-			fmt.Printf("DEBUG: Broadcast complete\n")
-		}
-	} else {
-		fmt.Printf("DEBUG: wsHub is nil, cannot broadcast message\n")
-	}
-
+	// Participants are notified over WebSocket by the message service
 	errors.SendCreated(c, message)
 }
 
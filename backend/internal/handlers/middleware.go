@@ -153,4 +153,118 @@ func ErrorHandlingMiddleware() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
 		errors.SendError(c, 500, "INTERNAL_SERVER_ERROR", "Internal server error")
 	})
-}
\ No newline at end of file
+}
+
+// FeatureMiddleware rejects requests from users whose plan does not include
+// feature
+func FeatureMiddleware(entitlements services.EntitlementsService, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, err := entitlements.HasFeature(c.Request.Context(), c.GetString("userID"), feature)
+		if err != nil {
+			errors.HandleError(c, err)
+			c.Abort()
+			return
+		}
+		if !allowed {
+			sendAppError(c, models.NewFeatureNotAvailableError(feature))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// quotaContextKey holds the request's quota reservations
+const quotaContextKey = "quotaReservations"
+
+// quotaReservations is the usage a request has reserved against a meter
+type quotaReservations struct {
+	entitlements services.EntitlementsService
+	userID       string
+	meter        string
+	reserved     []*models.UsageReservation
+}
+
+// reserve reserves amount more uses, sending the quota error if the user
+// does not have enough left
+func (q *quotaReservations) reserve(c *gin.Context, amount int64) bool {
+	reservation, err := q.entitlements.ReserveUsage(c.Request.Context(), q.userID, q.meter, amount)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			sendAppError(c, appErr)
+		} else {
+			errors.HandleError(c, err)
+		}
+		return false
+	}
+	q.reserved = append(q.reserved, reservation)
+	return true
+}
+
+func (q *quotaReservations) release(c *gin.Context) {
+	for _, reservation := range q.reserved {
+		if err := q.entitlements.ReleaseUsage(c.Request.Context(), reservation); err != nil {
+			log.Printf("Failed to release %s usage for user %s: %v", q.meter, q.userID, err)
+		}
+	}
+}
+
+// QuotaMiddleware reserves one use of meter for each request, rejecting
+// requests from users who have used it up. Handlers whose requests use more,
+// such as batches, reserve the rest with ReserveQuota. Reservations are given
+// back if the handler fails. Storage is measured from the files stored rather
+// than recorded, so uploads are only checked against it with their size.
+func QuotaMiddleware(entitlements services.EntitlementsService, meter string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+
+		if meter == models.MeterUploadStorage {
+			amount := max(c.Request.ContentLength, 0)
+			if _, err := entitlements.CheckQuota(c.Request.Context(), userID, meter, amount); err != nil {
+				if appErr, ok := err.(*models.AppError); ok {
+					sendAppError(c, appErr)
+				} else {
+					errors.HandleError(c, err)
+				}
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		reservations := &quotaReservations{entitlements: entitlements, userID: userID, meter: meter}
+		if !reservations.reserve(c, 1) {
+			c.Abort()
+			return
+		}
+		c.Set(quotaContextKey, reservations)
+
+		c.Next()
+
+		if c.Writer.Status() >= 400 {
+			reservations.release(c)
+		}
+	}
+}
+
+// ReserveQuota reserves amount more uses of the meter of the route's
+// QuotaMiddleware, on top of the one use reserved for every request. If the
+// user does not have enough left it sends the quota error and returns false.
+func ReserveQuota(c *gin.Context, amount int64) bool {
+	value, ok := c.Get(quotaContextKey)
+	if !ok || amount <= 0 {
+		return true
+	}
+	return value.(*quotaReservations).reserve(c, amount)
+}
+
+// sendAppError sends an AppError including its details
+func sendAppError(c *gin.Context, appErr *models.AppError) {
+	c.JSON(appErr.Status, gin.H{
+		"error":   appErr.Message,
+		"code":    appErr.Code,
+		"details": appErr.Details,
+	})
+}
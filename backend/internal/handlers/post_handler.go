@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"language-exchange/internal/models"
 	"language-exchange/internal/services"
@@ -145,6 +146,7 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 // @Param limit query int false "Number of posts to return (max 100)"
 // @Param category query string false "Filter by category"
 // @Param search query string false "Search query"
+// @Param language query string false "Filter by detected language code"
 // @Param sort query string false "Sort by: created_at, reactions, comments, trending"
 // @Success 200 {object} models.PostListResponse
 // @Router /posts [get]
@@ -180,6 +182,10 @@ func (h *PostHandler) ListPosts(c *gin.Context) {
 		filters.SearchQuery = search
 	}
 
+	if language := c.Query("language"); language != "" {
+		filters.Language = strings.ToLower(language)
+	}
+
 	if userID := c.Query("user_id"); userID != "" {
 		filters.UserID = userID
 	}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/services"
+	"language-exchange/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Content types served for each export format
+var exportContentTypes = map[string]string{
+	models.ExportFormatJSON:     "application/json; charset=utf-8",
+	models.ExportFormatMarkdown: "text/markdown; charset=utf-8",
+	models.ExportFormatHTML:     "text/html; charset=utf-8",
+}
+
+var exportFilenameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type SessionExportHandler struct {
+	exportService services.SessionExportService
+}
+
+func NewSessionExportHandler(exportService services.SessionExportService) *SessionExportHandler {
+	return &SessionExportHandler{
+		exportService: exportService,
+	}
+}
+
+// ExportSession downloads an ended session's transcript, attendance and canvas
+// history. The format comes from the format query parameter, or else the
+// Accept header, and defaults to JSON.
+// @Summary Export a session
+// @Description Export an ended session as JSON, Markdown or a self-contained HTML replay. The canvas is compacted while the session runs, so only its most recent operations keep their timestamps: the replay starts from a canvas with the earlier operations already applied, and compacted_operations says how many there were.
+// @Tags sessions
+// @Produce json
+// @Produce text/markdown
+// @Produce html
+// @Param sessionId path string true "Session ID"
+// @Param format query string false "Export format (json, markdown, html)"
+// @Success 200 {object} models.SessionExport
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 406 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sessions/{sessionId}/export [get]
+func (h *SessionExportHandler) ExportSession(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Session ID is required")
+		return
+	}
+
+	format, ok := negotiateExportFormat(c)
+	if !ok {
+		errors.SendError(c, http.StatusNotAcceptable, "UNSUPPORTED_FORMAT", "Export format must be json, markdown or html")
+		return
+	}
+
+	export, err := h.exportService.ExportSession(context.Background(), sessionID, userID.(string))
+	if err != nil {
+		sendExportError(c, err)
+		return
+	}
+
+	body, err := h.exportService.RenderSessionExport(export, format)
+	if err != nil {
+		sendExportError(c, err)
+		return
+	}
+
+	extension := format
+	if format == models.ExportFormatMarkdown {
+		extension = "md"
+	}
+	name := strings.Trim(exportFilenameUnsafe.ReplaceAllString(export.Session.Name, "-"), "-")
+	if name == "" {
+		name = "session"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, extension))
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Vary", "Accept")
+	c.Data(http.StatusOK, exportContentTypes[format], body)
+}
+
+func sendExportError(c *gin.Context, err error) {
+	if appErr, ok := err.(*models.AppError); ok {
+		errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+	} else {
+		errors.SendError(c, http.StatusInternalServerError, "EXPORT_FAILED", "Failed to export session")
+	}
+}
+
+// negotiateExportFormat picks the export format from the format query
+// parameter or the Accept header
+func negotiateExportFormat(c *gin.Context) (string, bool) {
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		switch format {
+		case "json":
+			return models.ExportFormatJSON, true
+		case "markdown", "md":
+			return models.ExportFormatMarkdown, true
+		case "html":
+			return models.ExportFormatHTML, true
+		}
+		return "", false
+	}
+
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return models.ExportFormatJSON, true
+	}
+
+	switch c.NegotiateFormat("application/json", "text/markdown", "text/html") {
+	case "application/json":
+		return models.ExportFormatJSON, true
+	case "text/markdown":
+		return models.ExportFormatMarkdown, true
+	case "text/html":
+		return models.ExportFormatHTML, true
+	}
+	return "", false
+}
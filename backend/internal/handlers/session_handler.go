@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strconv"
 
@@ -34,11 +33,8 @@ func (h *SessionHandler) isUserAuthorizedForSession(ctx context.Context, session
 		return false, err
 	}
 
-	// Verify user is allowed to access (creator or invited user)
-	isAuthorized := session.CreatedBy == userID
-	if !isAuthorized && session.InvitedUserID != nil {
-		isAuthorized = *session.InvitedUserID == userID
-	}
+	// Verify user is allowed to access (creator or invited users)
+	isAuthorized := session.CreatedBy == userID || session.IsInvited(userID)
 	// If no invited user, session is open to participants
 	if !isAuthorized && session.InvitedUserID == nil {
 		participants, err := h.sessionService.GetSessionParticipants(ctx, sessionID)
@@ -209,13 +205,88 @@ func (h *SessionHandler) GetUserSessions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": sessions})
 }
 
+// GetUpcomingSessions retrieves the scheduled sessions the user is attending
+// @Summary Get upcoming sessions
+// @Description Get scheduled sessions the current user created or has not declined, soonest first
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.LanguageSession
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions/upcoming [get]
+func (h *SessionHandler) GetUpcomingSessions(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	sessions, err := h.sessionService.GetUpcomingSessions(context.Background(), userID.(string))
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "SESSIONS_FETCH_FAILED", "Failed to fetch upcoming sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+// RespondToInvitation records the current user's RSVP to a session invitation
+// @Summary RSVP to a session
+// @Description Accept, decline or tentatively accept a session invitation
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Param rsvp body models.RSVPInput true "RSVP status"
+// @Success 200 {object} models.SessionInvitation
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Router /sessions/{sessionId}/rsvp [post]
+func (h *SessionHandler) RespondToInvitation(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Session ID is required")
+		return
+	}
+
+	var req models.RSVPInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+		return
+	}
+
+	invitation, err := h.sessionService.RespondToInvitation(context.Background(), sessionID, userID.(string), req.Status)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "RSVP_FAILED", "Failed to save RSVP")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invitation})
+}
+
 // JoinSession allows a user to join a session
 // @Summary Join a session
-// @Description Join an existing session as a participant
+// @Description Join an existing session as a participant, or as an observer who cannot change the canvas
 // @Tags sessions
 // @Accept json
 // @Produce json
 // @Param sessionId path string true "Session ID"
+// @Param join body models.JoinSessionInput false "Role to join as"
 // @Success 200 {object} models.SessionParticipant
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
@@ -236,7 +307,16 @@ func (h *SessionHandler) JoinSession(c *gin.Context) {
 		return
 	}
 
-	participant, err := h.sessionService.JoinSession(context.Background(), sessionID, userID.(string))
+	// The body is optional; an empty one joins as a participant
+	var req models.JoinSessionInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+			return
+		}
+	}
+
+	participant, err := h.sessionService.JoinSession(context.Background(), sessionID, userID.(string), req.Role)
 	if err != nil {
 		if appErr, ok := err.(*models.AppError); ok {
 			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
@@ -367,6 +447,44 @@ func (h *SessionHandler) GetSessionParticipants(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": participants})
 }
 
+// GetSessionAttendance gets each participant's attendance in a session
+// @Summary Get session attendance
+// @Description Get each participant's join/leave intervals, total connected time, reconnects and talk-time share
+// @Tags sessions
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} models.SessionAttendance
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sessions/{sessionId}/attendance [get]
+func (h *SessionHandler) GetSessionAttendance(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Session ID is required")
+		return
+	}
+
+	attendance, err := h.sessionService.GetSessionAttendance(context.Background(), sessionID, userID.(string))
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "ATTENDANCE_FETCH_FAILED", "Failed to fetch session attendance")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": attendance})
+}
+
 // GetSessionMessages retrieves messages from a session
 // @Summary Get session messages
 // @Description Get chat messages from a session
@@ -579,6 +697,54 @@ func (h *SessionHandler) GetCanvasOperations(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": operations})
 }
 
+// GetCanvasState retrieves the latest canvas snapshot and the operations after it
+// @Summary Get canvas state
+// @Description Get the latest canvas snapshot plus the operations recorded since, for joining a session
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} models.CanvasStateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sessions/{sessionId}/canvas/state [get]
+func (h *SessionHandler) GetCanvasState(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Session ID is required")
+		return
+	}
+
+	// Verify user is authorized to access this session
+	isAuthorized, err := h.isUserAuthorizedForSession(context.Background(), sessionID, userID.(string))
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "SESSION_FETCH_FAILED", "Failed to verify session access")
+		return
+	}
+
+	if !isAuthorized {
+		errors.SendError(c, http.StatusForbidden, "NOT_AUTHORIZED", "User is not authorized to access this session")
+		return
+	}
+
+	state, err := h.sessionService.GetCanvasState(context.Background(), sessionID)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "CANVAS_STATE_FETCH_FAILED", "Failed to fetch canvas state")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": state})
+}
+
 // SaveCanvasOperation saves a canvas operation to a session
 // @Summary Save canvas operation
 // @Description Save a canvas operation (text, drawing, etc.) to a session
@@ -592,6 +758,7 @@ func (h *SessionHandler) GetCanvasOperations(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Router /sessions/{sessionId}/canvas [post]
 func (h *SessionHandler) SaveCanvasOperation(c *gin.Context) {
 	// Get authenticated user ID from context
@@ -630,34 +797,148 @@ func (h *SessionHandler) SaveCanvasOperation(c *gin.Context) {
 		return
 	}
 
-	// Create canvas operation
+	// Create canvas operation; the session service assigns its version and
+	// broadcasts it to the session
 	operation := &models.CanvasOperation{
 		SessionID:     sessionID,
 		UserID:        userID.(string),
 		OperationType: req.OperationType,
 		OperationData: req.OperationData,
+		BaseVersion:   req.BaseVersion,
 	}
 
 	err = h.sessionService.SaveCanvasOperation(context.Background(), operation)
 	if err != nil {
-		fmt.Printf("DEBUG: Canvas save error: %v\n", err)
-		errors.SendError(c, http.StatusInternalServerError, "OPERATION_SAVE_FAILED", "Failed to save canvas operation: "+err.Error())
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "OPERATION_SAVE_FAILED", "Failed to save canvas operation")
+		}
 		return
 	}
-	fmt.Printf("DEBUG: Canvas operation saved successfully, type: %s\n", operation.OperationType)
 
-	// Broadcast operation via WebSocket to all session participants
-	wsMessage := models.WebSocketMessage{
-		Type: models.WSMessageTypeCanvasOperation,
-		Data: map[string]interface{}{
-			"operation_type": operation.OperationType,
-			"data":           req.OperationData,
-			"user_id":        operation.UserID,
-			"session_id":     operation.SessionID,
-		},
+	c.JSON(http.StatusCreated, gin.H{"data": operation})
+}
+
+// GetSeries gets a recurring session series with its upcoming occurrences
+// @Summary Get session series
+// @Description Get a recurring session series, its exceptions and upcoming occurrences
+// @Tags sessions
+// @Produce json
+// @Param seriesId path string true "Series ID"
+// @Success 200 {object} models.SessionSeries
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sessions/series/{seriesId} [get]
+func (h *SessionHandler) GetSeries(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
 	}
-	
-	h.hub.SendToSession(sessionID, wsMessage, nil)
 
-	c.JSON(http.StatusCreated, gin.H{"data": operation})
-}
\ No newline at end of file
+	seriesID := c.Param("seriesId")
+	if seriesID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Series ID is required")
+		return
+	}
+
+	series, err := h.sessionService.GetSeries(context.Background(), seriesID, userID.(string))
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "SERIES_FETCH_FAILED", "Failed to fetch session series")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// UpdateSeries edits one occurrence of a series or all future occurrences
+// @Description Edit, cancel or uncancel a single occurrence (scope "this"), or change an occurrence and all later ones (scope "future"). Set cancelled to true or false to cancel or restore an occurrence; leave it out to keep it as it is.
+// @Description Edit or cancel a single occurrence (scope "this"), or change an occurrence and all later ones (scope "future")
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param seriesId path string true "Series ID"
+// @Param series body models.UpdateSeriesInput true "Series changes"
+// @Success 200 {object} models.SessionSeries
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sessions/series/{seriesId} [put]
+func (h *SessionHandler) UpdateSeries(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	seriesID := c.Param("seriesId")
+	if seriesID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Series ID is required")
+		return
+	}
+
+	var req models.UpdateSeriesInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+		return
+	}
+
+	series, err := h.sessionService.UpdateSeries(context.Background(), seriesID, userID.(string), req)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "SERIES_UPDATE_FAILED", "Failed to update session series")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// EndSeries stops a recurring series and cancels its upcoming sessions
+// @Summary End session series
+// @Description Stop a recurring session series and cancel its upcoming sessions
+// @Tags sessions
+// @Produce json
+// @Param seriesId path string true "Series ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sessions/series/{seriesId} [delete]
+func (h *SessionHandler) EndSeries(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	seriesID := c.Param("seriesId")
+	if seriesID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Series ID is required")
+		return
+	}
+
+	if err := h.sessionService.EndSeries(context.Background(), seriesID, userID.(string)); err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "SERIES_END_FAILED", "Failed to end session series")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session series ended"})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"language-exchange/internal/models"
@@ -12,17 +13,19 @@ import (
 
 type TranslationHandler struct {
 	translationService services.TranslationService
+	vocabularyService  services.VocabularyService
 }
 
-func NewTranslationHandler(translationService services.TranslationService) *TranslationHandler {
+func NewTranslationHandler(translationService services.TranslationService, vocabularyService services.VocabularyService) *TranslationHandler {
 	return &TranslationHandler{
 		translationService: translationService,
+		vocabularyService:  vocabularyService,
 	}
 }
 
 // Translate godoc
 // @Summary Translate text
-// @Description Translate text from one language to another using LibreTranslate
+// @Description Translate text from one language to another using the configured translation providers, optionally saving the pair to the user's vocabulary
 // @Tags translation
 // @Accept json
 // @Produce json
@@ -34,7 +37,7 @@ func NewTranslationHandler(translationService services.TranslationService) *Tran
 // @Router /translate [post]
 func (h *TranslationHandler) Translate(c *gin.Context) {
 	// Check if user is authenticated (optional - you might want to allow anonymous translation)
-	_, exists := c.Get("userID")
+	userID, exists := c.Get("userID")
 	if !exists {
 		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
 		return
@@ -68,28 +71,25 @@ func (h *TranslationHandler) Translate(c *gin.Context) {
 		request.SourceLang = "auto"
 	}
 
-	// Perform translation
-	response, err := h.translationService.Translate(c.Request.Context(), request)
-	if err != nil {
-		// Handle specific translation errors
-		if translationErr, ok := err.(*models.TranslationError); ok {
-			switch translationErr.Code {
-			case "UNSUPPORTED_LANGUAGE":
-				errors.SendError(c, http.StatusBadRequest, translationErr.Code, translationErr.Message)
-			case "SAME_LANGUAGE":
-				errors.SendError(c, http.StatusBadRequest, translationErr.Code, translationErr.Message)
-			case "INVALID_TEXT_LENGTH":
-				errors.SendError(c, http.StatusBadRequest, translationErr.Code, translationErr.Message)
-			case "SERVICE_UNAVAILABLE":
-				errors.SendError(c, http.StatusServiceUnavailable, translationErr.Code, translationErr.Message)
-			default:
-				errors.SendError(c, http.StatusInternalServerError, "TRANSLATION_ERROR", translationErr.Message)
-			}
+	if request.SaveToVocabulary {
+		if request.Format == models.TranslationFormatHTML {
+			errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "HTML translations cannot be saved to vocabulary")
+			return
+		}
+		if len(request.Text) > 500 {
+			errors.SendError(c, http.StatusBadRequest, "TEXT_TOO_LONG", "Only text up to 500 characters can be saved to vocabulary")
+			return
+		}
+		if request.MessageID != "" && request.SessionMessageID != "" {
+			errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Provide either message_id or session_message_id, not both")
 			return
 		}
+	}
 
-		// Generic error
-		errors.SendError(c, http.StatusInternalServerError, "TRANSLATION_FAILED", "Failed to translate text: "+err.Error())
+	// Perform translation
+	response, err := h.translationService.Translate(c.Request.Context(), request)
+	if err != nil {
+		sendTranslationError(c, err)
 		return
 	}
 
@@ -97,9 +97,157 @@ func (h *TranslationHandler) Translate(c *gin.Context) {
 	// You might want to log this for analytics or debugging
 	// log.Printf("User %v translated text from %s to %s", userID, request.SourceLang, request.TargetLang)
 
+	if request.SaveToVocabulary {
+		// The translation has succeeded, so a failed save is not fatal
+		if input, ok := vocabularyLookupInput(request, response); !ok {
+			log.Printf("Not saving translation to vocabulary for user %v: source language was not detected", userID)
+		} else if item, err := h.vocabularyService.SaveLookup(c.Request.Context(), userID.(string), input); err != nil {
+			log.Printf("Failed to save translation to vocabulary for user %v: %v", userID, err)
+		} else {
+			response.VocabularyItem = item
+		}
+	}
+
+	errors.SendSuccess(c, response)
+}
+
+// sendTranslationError maps translation errors to HTTP responses
+func sendTranslationError(c *gin.Context, err error) {
+	// Handle specific translation errors
+	if translationErr, ok := err.(*models.TranslationError); ok {
+		switch translationErr.Code {
+		case "UNSUPPORTED_LANGUAGE":
+			errors.SendError(c, http.StatusBadRequest, translationErr.Code, translationErr.Message)
+		case "SAME_LANGUAGE":
+			errors.SendError(c, http.StatusBadRequest, translationErr.Code, translationErr.Message)
+		case "INVALID_TEXT_LENGTH":
+			errors.SendError(c, http.StatusBadRequest, translationErr.Code, translationErr.Message)
+		case "LANGUAGE_NOT_DETECTED":
+			errors.SendError(c, http.StatusUnprocessableEntity, translationErr.Code, translationErr.Message)
+		case "SERVICE_UNAVAILABLE":
+			errors.SendError(c, http.StatusServiceUnavailable, translationErr.Code, translationErr.Message)
+		default:
+			errors.SendError(c, http.StatusInternalServerError, "TRANSLATION_ERROR", translationErr.Message)
+		}
+		return
+	}
+
+	if appErr, ok := err.(*models.AppError); ok {
+		errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		return
+	}
+
+	// Generic error
+	errors.SendError(c, http.StatusInternalServerError, "TRANSLATION_FAILED", "Failed to translate text: "+err.Error())
+}
+
+// TranslateBatch godoc
+// @Summary Translate several texts
+// @Description Translate up to 50 segments between the same languages in one call. Each segment succeeds or fails on its own; failed segments carry an error in their result. With format "html" markup is preserved, so post content can be translated in place. Each segment counts as one translation against the quota.
+// @Tags translation
+// @Accept json
+// @Produce json
+// @Param request body models.BatchTranslateRequest true "Batch translation request"
+// @Success 200 {object} models.BatchTranslateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /translate/batch [post]
+func (h *TranslationHandler) TranslateBatch(c *gin.Context) {
+	if _, exists := c.Get("userID"); !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var request models.BatchTranslateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body: "+err.Error())
+		return
+	}
+
+	// Every segment counts against the quota; one was reserved already
+	if !ReserveQuota(c, int64(len(request.Segments))-1) {
+		return
+	}
+
+	// Allow empty source language to default to "auto"
+	if request.SourceLang == "" {
+		request.SourceLang = "auto"
+	}
+
+	response, err := h.translationService.TranslateBatch(c.Request.Context(), request)
+	if err != nil {
+		sendTranslationError(c, err)
+		return
+	}
+
 	errors.SendSuccess(c, response)
 }
 
+// DetectLanguage godoc
+// @Summary Detect the language of a text
+// @Description Detect which language a text is written in, with confidence scores from 0 to 1 for each candidate language
+// @Tags translation
+// @Accept json
+// @Produce json
+// @Param request body models.DetectLanguageRequest true "Text to detect"
+// @Success 200 {object} models.DetectLanguageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /translate/detect [post]
+func (h *TranslationHandler) DetectLanguage(c *gin.Context) {
+	if _, exists := c.Get("userID"); !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var request models.DetectLanguageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := h.translationService.DetectLanguage(c.Request.Context(), request.Text)
+	if err != nil {
+		sendTranslationError(c, err)
+		return
+	}
+
+	errors.SendSuccess(c, response)
+}
+
+// vocabularyLookupInput builds the vocabulary entry for a translated phrase.
+// It reports false when the source language was "auto" and the provider did
+// not say which language it detected.
+func vocabularyLookupInput(request models.TranslateRequest, response *models.TranslateResponse) (models.CreateVocabularyInput, bool) {
+	sourceLanguage := response.SourceLang
+	if sourceLanguage == "auto" {
+		if response.DetectedLanguage == nil {
+			return models.CreateVocabularyInput{}, false
+		}
+		sourceLanguage = response.DetectedLanguage.Language
+	}
+
+	input := models.CreateVocabularyInput{
+		Term:           response.OriginalText,
+		Translation:    response.TranslatedText,
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: response.TargetLang,
+	}
+
+	if request.MessageID != "" {
+		input.OriginType = models.VocabularyOriginMessage
+		input.OriginID = request.MessageID
+	} else if request.SessionMessageID != "" {
+		input.OriginType = models.VocabularyOriginSessionMessage
+		input.OriginID = request.SessionMessageID
+	}
+
+	return input, true
+}
+
 // GetSupportedLanguages godoc
 // @Summary Get supported languages
 // @Description Get list of languages supported by the translation service
@@ -150,7 +298,7 @@ func (h *TranslationHandler) CheckLanguageSupport(c *gin.Context) {
 
 // Health godoc
 // @Summary Check translation service health
-// @Description Check if the translation service is available and responding
+// @Description Report the health of each translation provider as recorded from recent requests
 // @Tags translation
 // @Accept json
 // @Produce json
@@ -158,27 +306,36 @@ func (h *TranslationHandler) CheckLanguageSupport(c *gin.Context) {
 // @Failure 503 {object} ErrorResponse
 // @Router /translate/health [get]
 func (h *TranslationHandler) Health(c *gin.Context) {
-	// Try a simple translation to check if the service is working
-	testRequest := models.TranslateRequest{
-		Text:       "Hello",
-		SourceLang: "en",
-		TargetLang: "es",
+	// Report what recent translations recorded rather than probing the
+	// providers, which would spend paid requests on every call
+	providers := h.translationService.GetProviderStatus()
+
+	healthy := 0
+	for _, provider := range providers {
+		if provider.Healthy {
+			healthy++
+		}
 	}
 
-	_, err := h.translationService.Translate(c.Request.Context(), testRequest)
-	if err != nil {
+	if healthy == 0 {
 		response := map[string]interface{}{
-			"status":  "unhealthy",
-			"error":   err.Error(),
-			"message": "Translation service is not responding",
+			"status":    "unhealthy",
+			"message":   "No translation provider is responding",
+			"providers": providers,
 		}
 		c.JSON(http.StatusServiceUnavailable, response)
 		return
 	}
 
+	status := "healthy"
+	if healthy < len(providers) {
+		status = "degraded"
+	}
+
 	response := map[string]interface{}{
-		"status":  "healthy",
-		"message": "Translation service is working",
+		"status":    status,
+		"message":   "Translation service is working",
+		"providers": providers,
 	}
 
 	errors.SendSuccess(c, response)
@@ -197,17 +354,21 @@ func (h *TranslationHandler) GetServiceInfo(c *gin.Context) {
 	// without exposing sensitive configuration details
 
 	response := map[string]interface{}{
-		"provider":        "libretranslate",
-		"max_text_length": 5000,
-		"description":     "Self-hosted LibreTranslate service with support for premium APIs",
+		"providers":          h.translationService.GetProviderNames(),
+		"provider_status":    h.translationService.GetProviderStatus(),
+		"max_text_length":    5000,
+		"max_batch_segments": models.MaxBatchSegments,
+		"description":        "Translation through an ordered chain of providers, falling back when one is unavailable",
 		"features": []string{
 			"Multiple language support",
 			"Text translation",
-			"Language detection (future)",
-			"Batch translation (future)",
+			"Provider fallback",
+			"Translation caching",
+			"Batch translation",
+			"HTML translation",
+			"Language detection",
 		},
-		"note": "Service supports easy integration with premium APIs like Google Translate or DeepL",
 	}
 
 	errors.SendSuccess(c, response)
-}
\ No newline at end of file
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/services"
+	"language-exchange/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VocabularyHandler struct {
+	vocabularyService services.VocabularyService
+}
+
+func NewVocabularyHandler(vocabularyService services.VocabularyService) *VocabularyHandler {
+	return &VocabularyHandler{
+		vocabularyService: vocabularyService,
+	}
+}
+
+// GetVocabulary lists the user's saved words
+// @Summary List vocabulary
+// @Description List the authenticated user's saved words and phrases
+// @Tags vocabulary
+// @Produce json
+// @Param source_language query string false "Source language code"
+// @Param target_language query string false "Target language code"
+// @Param q query string false "Search term or translation"
+// @Param due query bool false "Only words due for review"
+// @Param limit query int false "Page size" default(50)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {array} models.VocabularyItem
+// @Failure 401 {object} ErrorResponse
+// @Router /vocabulary [get]
+func (h *VocabularyHandler) GetVocabulary(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filters := models.VocabularyFilters{
+		SourceLanguage: c.Query("source_language"),
+		TargetLanguage: c.Query("target_language"),
+		Search:         c.Query("q"),
+		DueOnly:        c.Query("due") == "true",
+		Limit:          limit,
+		Offset:         offset,
+	}
+
+	items, err := h.vocabularyService.GetVocabulary(context.Background(), userID.(string), filters)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_FETCH_FAILED", "Failed to fetch vocabulary")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// SaveVocabulary adds a word or phrase to the user's notebook
+// @Summary Save vocabulary
+// @Description Save a word or phrase with its translation for spaced-repetition review
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param item body models.CreateVocabularyInput true "Word data"
+// @Success 201 {object} models.VocabularyItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /vocabulary [post]
+func (h *VocabularyHandler) SaveVocabulary(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var input models.CreateVocabularyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+		return
+	}
+
+	item, err := h.vocabularyService.SaveVocabulary(context.Background(), userID.(string), input)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_SAVE_FAILED", "Failed to save vocabulary")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": item})
+}
+
+// GetReviewQueue returns the words due for review, most overdue first
+// @Summary Get review queue
+// @Description Get the words due for spaced-repetition review and how many are due in total
+// @Tags vocabulary
+// @Produce json
+// @Param limit query int false "Number of words" default(20)
+// @Success 200 {array} models.VocabularyItem
+// @Failure 401 {object} ErrorResponse
+// @Router /vocabulary/review [get]
+func (h *VocabularyHandler) GetReviewQueue(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	items, due, err := h.vocabularyService.GetReviewQueue(context.Background(), userID.(string), limit)
+	if err != nil {
+		errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_FETCH_FAILED", "Failed to fetch review queue")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": items, "due_count": due})
+}
+
+// GetVocabularyItem returns a single saved word
+// @Summary Get vocabulary item
+// @Description Get a saved word and its review schedule
+// @Tags vocabulary
+// @Produce json
+// @Param itemId path string true "Vocabulary item ID"
+// @Success 200 {object} models.VocabularyItem
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /vocabulary/{itemId} [get]
+func (h *VocabularyHandler) GetVocabularyItem(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	item, err := h.vocabularyService.GetVocabularyItem(context.Background(), userID.(string), c.Param("itemId"))
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_FETCH_FAILED", "Failed to fetch vocabulary item")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// UpdateVocabularyItem edits a saved word
+// @Summary Update vocabulary item
+// @Description Edit a saved word's term, translation or example sentence
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param itemId path string true "Vocabulary item ID"
+// @Param item body models.UpdateVocabularyInput true "Changes"
+// @Success 200 {object} models.VocabularyItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /vocabulary/{itemId} [put]
+func (h *VocabularyHandler) UpdateVocabularyItem(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var input models.UpdateVocabularyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+		return
+	}
+
+	item, err := h.vocabularyService.UpdateVocabularyItem(context.Background(), userID.(string), c.Param("itemId"), input)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_UPDATE_FAILED", "Failed to update vocabulary item")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// DeleteVocabularyItem removes a saved word
+// @Summary Delete vocabulary item
+// @Description Remove a word from the notebook along with its review history
+// @Tags vocabulary
+// @Produce json
+// @Param itemId path string true "Vocabulary item ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /vocabulary/{itemId} [delete]
+func (h *VocabularyHandler) DeleteVocabularyItem(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	if err := h.vocabularyService.DeleteVocabularyItem(context.Background(), userID.(string), c.Param("itemId")); err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_DELETE_FAILED", "Failed to delete vocabulary item")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vocabulary item deleted"})
+}
+
+// ReviewVocabularyItem records a flashcard answer and reschedules the word
+// @Summary Review vocabulary item
+// @Description Grade recall of a word from 0 (forgotten) to 5 (perfect) and schedule its next review
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param itemId path string true "Vocabulary item ID"
+// @Param review body models.ReviewVocabularyInput true "Answer quality"
+// @Success 200 {object} models.VocabularyReviewResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /vocabulary/{itemId}/review [post]
+func (h *VocabularyHandler) ReviewVocabularyItem(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var input models.ReviewVocabularyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request data: "+err.Error())
+		return
+	}
+
+	result, err := h.vocabularyService.ReviewVocabularyItem(context.Background(), userID.(string), c.Param("itemId"), *input.Quality)
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "VOCABULARY_REVIEW_FAILED", "Failed to record review")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
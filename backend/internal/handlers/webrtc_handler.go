@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/services"
+	"language-exchange/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebRTCHandler struct {
+	webRTCService services.WebRTCService
+}
+
+func NewWebRTCHandler(webRTCService services.WebRTCService) *WebRTCHandler {
+	return &WebRTCHandler{
+		webRTCService: webRTCService,
+	}
+}
+
+// GetTURNCredentials issues short-lived TURN credentials for a session's voice
+// and video calls. Signaling runs over the session WebSocket.
+// @Summary Get TURN credentials
+// @Description Get time-limited TURN credentials and ICE servers for a session participant
+// @Tags sessions
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} models.TURNCredentials
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /sessions/{sessionId}/turn-credentials [get]
+func (h *WebRTCHandler) GetTURNCredentials(c *gin.Context) {
+	// Get authenticated user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		errors.SendError(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Session ID is required")
+		return
+	}
+
+	credentials, err := h.webRTCService.IssueTURNCredentials(context.Background(), sessionID, userID.(string))
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "TURN_CREDENTIALS_FAILED", "Failed to issue TURN credentials")
+		}
+		return
+	}
+
+	// Credentials are per user and expire; never cache them
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"data": credentials})
+}
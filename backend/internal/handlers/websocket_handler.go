@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strconv"
 
 	"language-exchange/internal/models"
 	"language-exchange/internal/services"
@@ -42,6 +43,7 @@ var upgrader = ws.Upgrader{
 // @Tags websocket
 // @Accept json
 // @Produce json
+// @Param since query int false "Last event sequence received, to replay missed events"
 // @Success 101 {string} string "Switching Protocols"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
@@ -55,6 +57,18 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// A reconnecting client passes the last event sequence it received
+	var since int64
+	sinceStr := c.Query("since")
+	if sinceStr != "" {
+		parsed, err := strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || parsed < 0 {
+			errors.SendError(c, http.StatusBadRequest, "INVALID_PARAMETER", "Invalid since parameter")
+			return
+		}
+		since = parsed
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -65,6 +79,9 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 
 	// Create a new client
 	client := websocket.NewClient(h.hub, conn, userID.(string))
+	if sinceStr != "" {
+		client.ResumeFrom(since)
+	}
 
 	// Start the client (this will register it with the hub and start read/write pumps)
 	client.Start()
@@ -146,6 +163,9 @@ func (h *WebSocketHandler) CheckUserOnline(c *gin.Context) {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /ws/sessions/{sessionId} [get]
 func (h *WebSocketHandler) HandleSessionWebSocket(c *gin.Context) {
@@ -162,9 +182,15 @@ func (h *WebSocketHandler) HandleSessionWebSocket(c *gin.Context) {
 		return
 	}
 
-	// TODO: Verify user is a participant in the session
-	// This would require injecting the session service into the WebSocket handler
-	// For now, we'll assume the user is authorized
+	// Only participants who joined the session may enter its room
+	if _, err := h.sessionService.AuthorizeSessionConnection(context.Background(), sessionID, userID.(string)); err != nil {
+		if appErr, ok := err.(*models.AppError); ok {
+			errors.SendError(c, appErr.Status, appErr.Code, appErr.Message)
+		} else {
+			errors.SendError(c, http.StatusInternalServerError, "SESSION_AUTHORIZATION_FAILED", "Failed to authorize session connection")
+		}
+		return
+	}
 
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -0,0 +1,175 @@
+package models
+
+// Correction categories
+const (
+	CorrectionGrammar  = "grammar"
+	CorrectionSpelling = "spelling"
+	CorrectionStyle    = "style"
+	CorrectionRegister = "register"
+)
+
+// IsValidCorrectionCategory checks if the correction category is valid
+func IsValidCorrectionCategory(category string) bool {
+	switch category {
+	case CorrectionGrammar, CorrectionSpelling, CorrectionStyle, CorrectionRegister:
+		return true
+	default:
+		return false
+	}
+}
+
+// CEFR levels, from beginner to proficient
+const (
+	CEFRA1 = "A1"
+	CEFRA2 = "A2"
+	CEFRB1 = "B1"
+	CEFRB2 = "B2"
+	CEFRC1 = "C1"
+	CEFRC2 = "C2"
+)
+
+// IsValidCEFRLevel checks if the CEFR level is valid
+func IsValidCEFRLevel(level string) bool {
+	switch level {
+	case CEFRA1, CEFRA2, CEFRB1, CEFRB2, CEFRC1, CEFRC2:
+		return true
+	default:
+		return false
+	}
+}
+
+// TextSpan is a range of a text counted in characters (Unicode code points),
+// with End exclusive
+type TextSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// MessageCorrection is one change between a message and its improved
+// version, computed as a token-level diff. Span locates Original in the
+// message and ImprovedSpan locates Replacement in the improved text; an
+// insertion has an empty Original and a deletion an empty Replacement.
+// CEFRLevel is the level at which the point is usually taught, when known.
+type MessageCorrection struct {
+	Span         TextSpan `json:"span"`
+	ImprovedSpan TextSpan `json:"improved_span"`
+	Original     string   `json:"original"`
+	Replacement  string   `json:"replacement"`
+	Category     string   `json:"category"`
+	Explanation  string   `json:"explanation"`
+	CEFRLevel    string   `json:"cefr_level,omitempty"`
+}
+
+// ImproveMessageInput is a message to improve. Explanations of the
+// corrections are written in ExplanationLanguage, normally the user's native
+// language.
+type ImproveMessageInput struct {
+	Text                string
+	Language            string // language the text is written in; empty to infer it
+	ExplanationLanguage string
+	Level               string // learner's CEFR level explanations are pitched at; may be empty
+}
+
+// MessageImprovement is an improved message with the corrections that were
+// made to it. Original is the message as it was improved, without
+// surrounding whitespace, and is the text the correction spans refer to.
+type MessageImprovement struct {
+	Original    string              `json:"original"`
+	Improved    string              `json:"improved"`
+	Corrections []MessageCorrection `json:"corrections"`
+	Provider    string              `json:"provider"`
+	Model       string              `json:"model"`
+}
+
+// Sources of conversation starters
+const (
+	StarterSourceAI       = "ai"
+	StarterSourceTemplate = "template"
+)
+
+// ConversationStartersRequest selects the partnership to suggest
+// conversation starters for, by match or by conversation
+type ConversationStartersRequest struct {
+	MatchID        string `form:"match_id"`
+	ConversationID string `form:"conversation_id"`
+	Level          string `form:"level" binding:"omitempty,oneof=A1 A2 B1 B2 C1 C2"`         // requester's CEFR level in the language they practice
+	PartnerLevel   string `form:"partner_level" binding:"omitempty,oneof=A1 A2 B1 B2 C1 C2"` // partner's CEFR level in the language they practice
+}
+
+// ConversationStarterInput is a request for conversation starters made by
+// UserID
+type ConversationStarterInput struct {
+	UserID         string
+	MatchID        string
+	ConversationID string
+	Level          string
+	PartnerLevel   string
+}
+
+// ConversationStarter is one suggested opening message
+type ConversationStarter struct {
+	Text        string `json:"text"`
+	Topic       string `json:"topic"`
+	Translation string `json:"translation,omitempty"` // in the writer's native language
+}
+
+// ConversationStarterSet holds starters for one partner to send, written in
+// the language that partner is practicing
+type ConversationStarterSet struct {
+	UserID   string                `json:"userId"`
+	Language string                `json:"language"`
+	Level    string                `json:"level"`
+	Starters []ConversationStarter `json:"starters"`
+}
+
+// ConversationStarters are suggested openers for both partners of a match
+type ConversationStarters struct {
+	PartnerID string                   `json:"partnerId"`
+	Source    string                   `json:"source"` // "ai" or "template"
+	Sets      []ConversationStarterSet `json:"sets"`
+}
+
+// ChatMessage is one message of a chat completion conversation
+type ChatMessage struct {
+	Role    string `json:"role"` // "system", "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// CompletionOptions tune a chat completion
+type CompletionOptions struct {
+	Temperature float64
+	JSON        bool // ask for a JSON object response
+}
+
+// OpenAIChatRequest represents the request format for OpenAI-compatible chat
+// completion APIs
+type OpenAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []ChatMessage         `json:"messages"`
+	Temperature    float64               `json:"temperature"`
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+}
+
+// OpenAIResponseFormat selects the response format of a chat completion
+type OpenAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// OpenAIChatResponse represents the response format from OpenAI-compatible
+// chat completion APIs
+type OpenAIChatResponse struct {
+	Choices []struct {
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// OpenAIChatStreamChunk is one server-sent event of a streamed
+// OpenAI-compatible chat completion
+type OpenAIChatStreamChunk struct {
+	Choices []struct {
+		Delta        ChatMessage `json:"delta"`
+		FinishReason *string     `json:"finish_reason"`
+	} `json:"choices"`
+}
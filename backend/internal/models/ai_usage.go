@@ -15,27 +15,31 @@ type AIUsageLog struct {
 
 // AIUsageStats represents usage statistics for a user
 type AIUsageStats struct {
-	Used      int64  `json:"used"`
-	Limit     int64  `json:"limit"`
-	Remaining int64  `json:"remaining"`
-	IsPro     bool   `json:"is_pro"`
-	PlanType  string `json:"plan_type"`
+	Used      int64      `json:"used"`
+	Limit     int64      `json:"limit"`
+	Remaining int64      `json:"remaining"`
+	IsPro     bool       `json:"is_pro"`
+	PlanType  string     `json:"plan_type"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
 }
 
 // ImproveMessageRequest represents a request to improve a message
 type ImproveMessageRequest struct {
-	Text string `json:"text" binding:"required"`
+	Text     string `json:"text" binding:"required,max=2000"`
+	Language string `json:"language,omitempty" binding:"max=50"`                         // language of the text, inferred when empty
+	Level    string `json:"level,omitempty" binding:"omitempty,oneof=A1 A2 B1 B2 C1 C2"` // learner's CEFR level
 }
 
 // ImproveMessageResponse represents the improvement response
 type ImproveMessageResponse struct {
-	Original      string        `json:"original"`
-	Improved      string        `json:"improved,omitempty"`
-	Remaining     int64         `json:"remaining,omitempty"`
-	IsPro         bool          `json:"is_pro"`
-	NeedsUpgrade  bool          `json:"needs_upgrade,omitempty"`
-	Preview       string        `json:"preview,omitempty"`
-	Message       string        `json:"message,omitempty"`
-	Used          int64         `json:"used,omitempty"`
-	Limit         int64         `json:"limit,omitempty"`
-}
\ No newline at end of file
+	Original     string              `json:"original"`
+	Improved     string              `json:"improved,omitempty"`
+	Corrections  []MessageCorrection `json:"corrections,omitempty"`
+	Remaining    int64               `json:"remaining,omitempty"`
+	IsPro        bool                `json:"is_pro"`
+	NeedsUpgrade bool                `json:"needs_upgrade,omitempty"`
+	Preview      string              `json:"preview,omitempty"`
+	Message      string              `json:"message,omitempty"`
+	Used         int64               `json:"used,omitempty"`
+	Limit        int64               `json:"limit,omitempty"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Billing events a provider's webhooks are translated into
+const (
+	BillingEventSubscriptionCreated   = "subscription.created"
+	BillingEventSubscriptionRenewed   = "subscription.renewed"
+	BillingEventSubscriptionCancelled = "subscription.cancelled"
+	BillingEventSubscriptionPastDue   = "subscription.past_due"
+)
+
+// Subscription statuses
+const (
+	SubscriptionActive    = "active"
+	SubscriptionPastDue   = "past_due"
+	SubscriptionCancelled = "cancelled"
+)
+
+// Subscription is a user's paid plan subscription with a billing provider
+type Subscription struct {
+	ID                     string     `json:"id" db:"id"`
+	UserID                 string     `json:"userId" db:"user_id"`
+	Provider               string     `json:"provider" db:"provider"`
+	ProviderCustomerID     string     `json:"-" db:"provider_customer_id"`
+	ProviderSubscriptionID string     `json:"-" db:"provider_subscription_id"`
+	PlanType               string     `json:"planType" db:"plan_type"`
+	Status                 string     `json:"status" db:"status"`
+	CurrentPeriodEnd       *time.Time `json:"currentPeriodEnd,omitempty" db:"current_period_end"`
+	CancelledAt            *time.Time `json:"cancelledAt,omitempty" db:"cancelled_at"`
+	CreatedAt              time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt              time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Invoice is a bill for a subscription period. Amounts are in the smallest
+// unit of the currency, such as cents.
+type Invoice struct {
+	ID                string     `json:"id" db:"id"`
+	UserID            string     `json:"userId" db:"user_id"`
+	SubscriptionID    *string    `json:"subscriptionId,omitempty" db:"subscription_id"`
+	Provider          string     `json:"provider" db:"provider"`
+	ProviderInvoiceID string     `json:"providerInvoiceId" db:"provider_invoice_id"`
+	AmountCents       int64      `json:"amountCents" db:"amount_cents"`
+	Currency          string     `json:"currency" db:"currency"`
+	Status            string     `json:"status" db:"status"`
+	HostedURL         *string    `json:"hostedUrl,omitempty" db:"hosted_url"`
+	PeriodStart       *time.Time `json:"periodStart,omitempty" db:"period_start"`
+	PeriodEnd         *time.Time `json:"periodEnd,omitempty" db:"period_end"`
+	PaidAt            *time.Time `json:"paidAt,omitempty" db:"paid_at"`
+	CreatedAt         time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// CheckoutRequest represents a request to pay for a plan
+type CheckoutRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
+
+// CheckoutInput is what a billing provider needs to start a checkout.
+// CustomerID is set when the user already has a customer with the provider.
+type CheckoutInput struct {
+	UserID     string
+	Email      string
+	CustomerID string
+	Plan       string
+	SuccessURL string
+	CancelURL  string
+}
+
+// CheckoutSession is a hosted payment page the user is sent to
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// BillingEvent is a verified webhook event. Type is one of the
+// BillingEvent* types, or empty for provider events that need no action.
+// UserID and PlanType are only known when the provider carries them, and
+// Invoice is set for events about a payment.
+type BillingEvent struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"type"`
+	ProviderType   string     `json:"-"`
+	UserID         string     `json:"userId,omitempty"`
+	PlanType       string     `json:"planType,omitempty"`
+	CustomerID     string     `json:"customerId,omitempty"`
+	SubscriptionID string     `json:"subscriptionId,omitempty"`
+	PeriodEnd      *time.Time `json:"periodEnd,omitempty"`
+	Invoice        *Invoice   `json:"invoice,omitempty"`
+}
+
+// SubscriptionChange is what a billing event changes: the subscription and
+// invoice to store, and the plan to move the user to. An empty PlanType
+// leaves the user's plan alone.
+type SubscriptionChange struct {
+	Subscription  *Subscription
+	Invoice       *Invoice
+	PlanType      string
+	PlanExpiresAt *time.Time
+}
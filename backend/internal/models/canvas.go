@@ -0,0 +1,281 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CanvasSnapshot is the materialized whiteboard state of a session as of a
+// canvas operation sequence number. Operations up to SequenceNumber are
+// compacted away once the snapshot is saved.
+type CanvasSnapshot struct {
+	ID             string      `json:"id" db:"id"`
+	SessionID      string      `json:"session_id" db:"session_id"`
+	SequenceNumber int64       `json:"sequence_number" db:"sequence_number"`
+	State          CanvasState `json:"state" db:"state"`
+	OperationCount int         `json:"operation_count" db:"operation_count"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+}
+
+// CanvasState is the result of applying canvas operations in sequence order
+type CanvasState struct {
+	// Version is the session canvas version of the last applied operation
+	Version int64 `json:"version"`
+
+	// Elements in drawing order
+	Elements []*CanvasElement `json:"elements"`
+
+	// Deleted records when and by whom removed elements were deleted, so
+	// stale edits cannot bring them back
+	Deleted map[string]CanvasTombstone `json:"deleted,omitempty"`
+
+	// Scene is the latest full Excalidraw scene, if the whiteboard uses one
+	Scene json.RawMessage `json:"scene,omitempty"`
+
+	// SceneOperation records who produced Scene and when
+	SceneUserID   string `json:"scene_user_id,omitempty"`
+	SceneSequence int64  `json:"scene_sequence,omitempty"`
+	SceneVersion  int64  `json:"scene_version,omitempty"`
+}
+
+// CanvasElement is a single element on the whiteboard along with the
+// operation that last produced it
+type CanvasElement struct {
+	ID             string          `json:"id"`
+	OperationType  string          `json:"operation_type"`
+	UserID         string          `json:"user_id"`
+	Data           json.RawMessage `json:"data"`
+	SequenceNumber int64           `json:"sequence_number"`
+
+	// Versions of the last content and position changes, used to detect
+	// conflicting concurrent edits
+	ContentVersion  int64  `json:"content_version"`
+	PositionVersion int64  `json:"position_version"`
+	PositionUserID  string `json:"position_user_id,omitempty"`
+}
+
+// CanvasTombstone records the deletion of an element
+type CanvasTombstone struct {
+	Version int64  `json:"version"`
+	UserID  string `json:"user_id"`
+}
+
+// MoveOperation moves an existing element to a new position
+type MoveOperation struct {
+	Type      string  `json:"type"`
+	ElementID string  `json:"elementId"` // ID of element to move
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+}
+
+// CanvasStateResponse is returned to clients joining a session: the latest
+// snapshot plus the operations recorded after it
+type CanvasStateResponse struct {
+	Snapshot       *CanvasSnapshot    `json:"snapshot"`
+	Operations     []*CanvasOperation `json:"operations"`
+	LatestSequence int64              `json:"latest_sequence"`
+
+	// Version is the canvas version to send as base_version with new operations
+	Version int64 `json:"version"`
+}
+
+// canvasElementRef is used to find the element an operation refers to. Older
+// whiteboard clients nest the element under "element".
+type canvasElementRef struct {
+	ID        string `json:"id"`
+	ElementID string `json:"elementId"`
+	Element   *struct {
+		ID string `json:"id"`
+	} `json:"element"`
+}
+
+// Apply folds a single operation into the state
+func (s *CanvasState) Apply(operation *CanvasOperation) {
+	if operation.Version > s.Version {
+		s.Version = operation.Version
+	}
+
+	switch operation.OperationType {
+	case OperationTypeClear:
+		s.Elements = nil
+		s.Deleted = nil
+		s.Scene = nil
+		s.SceneUserID = ""
+		s.SceneSequence = 0
+		s.SceneVersion = 0
+
+	case OperationTypeExcalidrawUpdate:
+		// Excalidraw sends the whole scene on every change
+		s.Scene = operation.OperationData
+		s.SceneUserID = operation.UserID
+		s.SceneSequence = operation.SequenceNumber
+		s.SceneVersion = operation.Version
+
+	case OperationTypeDelete:
+		elementID := CanvasOperationElementID(operation)
+		if i := s.indexOf(elementID); i >= 0 {
+			s.Elements = append(s.Elements[:i], s.Elements[i+1:]...)
+			if s.Deleted == nil {
+				s.Deleted = make(map[string]CanvasTombstone)
+			}
+			s.Deleted[elementID] = CanvasTombstone{Version: operation.Version, UserID: operation.UserID}
+		}
+
+	case OperationTypeMove:
+		var moveOp MoveOperation
+		if err := json.Unmarshal(operation.OperationData, &moveOp); err != nil {
+			return
+		}
+		if i := s.indexOf(CanvasOperationElementID(operation)); i >= 0 {
+			element := s.Elements[i]
+			if data, ok := movedElementData(element.Data, moveOp.X, moveOp.Y); ok {
+				element.Data = data
+				element.SequenceNumber = operation.SequenceNumber
+				element.PositionVersion = operation.Version
+				element.PositionUserID = operation.UserID
+			}
+		}
+
+	default:
+		// text, text_update, draw and erase add or replace an element
+		elementID := CanvasOperationElementID(operation)
+		element := &CanvasElement{
+			ID:              elementID,
+			OperationType:   operation.OperationType,
+			UserID:          operation.UserID,
+			Data:            operation.OperationData,
+			SequenceNumber:  operation.SequenceNumber,
+			ContentVersion:  operation.Version,
+			PositionVersion: operation.Version,
+			PositionUserID:  operation.UserID,
+		}
+		i := s.indexOf(elementID)
+		if i >= 0 && operation.UndoOf == nil && operation.RedoOf == nil {
+			// A plain edit keeps the element's position history
+			element.PositionVersion = s.Elements[i].PositionVersion
+			element.PositionUserID = s.Elements[i].PositionUserID
+		}
+		if i >= 0 {
+			s.Elements[i] = element
+		} else {
+			s.Elements = append(s.Elements, element)
+			delete(s.Deleted, elementID)
+		}
+	}
+}
+
+// Element returns the element with the given ID, or nil
+func (s *CanvasState) Element(elementID string) *CanvasElement {
+	if i := s.indexOf(elementID); i >= 0 {
+		return s.Elements[i]
+	}
+	return nil
+}
+
+// Operations converts the state back into operations that recreate it, for
+// clients that only understand the operation log
+func (s *CanvasState) Operations(sessionID string) []*CanvasOperation {
+	operations := make([]*CanvasOperation, 0, len(s.Elements)+1)
+	for _, element := range s.Elements {
+		elementID := element.ID
+		operations = append(operations, &CanvasOperation{
+			ID:             element.ID,
+			SessionID:      sessionID,
+			UserID:         element.UserID,
+			OperationType:  element.OperationType,
+			OperationData:  element.Data,
+			SequenceNumber: element.SequenceNumber,
+			Version:        element.ContentVersion,
+			ElementID:      &elementID,
+		})
+	}
+
+	if len(s.Scene) > 0 {
+		operations = append(operations, &CanvasOperation{
+			SessionID:      sessionID,
+			UserID:         s.SceneUserID,
+			OperationType:  OperationTypeExcalidrawUpdate,
+			OperationData:  s.Scene,
+			SequenceNumber: s.SceneSequence,
+			Version:        s.SceneVersion,
+		})
+	}
+
+	return operations
+}
+
+func (s *CanvasState) indexOf(elementID string) int {
+	if elementID == "" {
+		return -1
+	}
+	for i, element := range s.Elements {
+		if element.ID == elementID {
+			return i
+		}
+	}
+	return -1
+}
+
+// CanvasOperationElementID returns the element an operation applies to. The
+// server-assigned ElementID wins; otherwise it is read from the operation data,
+// falling back to the operation ID for new elements without one.
+func CanvasOperationElementID(operation *CanvasOperation) string {
+	if operation.ElementID != nil && *operation.ElementID != "" {
+		return *operation.ElementID
+	}
+
+	var ref canvasElementRef
+	if err := json.Unmarshal(operation.OperationData, &ref); err == nil {
+		switch {
+		case ref.ID != "":
+			return ref.ID
+		case ref.ElementID != "":
+			return ref.ElementID
+		case ref.Element != nil && ref.Element.ID != "":
+			return ref.Element.ID
+		}
+	}
+	return operation.ID
+}
+
+// ElementPosition returns the x/y position stored in an element's data
+func ElementPosition(data json.RawMessage) (float64, float64, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, 0, false
+	}
+
+	target := fields
+	if nested, ok := fields["element"].(map[string]interface{}); ok {
+		target = nested
+	}
+	x, xOK := target["x"].(float64)
+	y, yOK := target["y"].(float64)
+	return x, y, xOK && yOK
+}
+
+// WithElementPosition returns element data with its x/y position replaced
+func WithElementPosition(data json.RawMessage, x, y float64) (json.RawMessage, bool) {
+	return movedElementData(data, x, y)
+}
+
+// movedElementData sets the x/y position of an element's data
+func movedElementData(data json.RawMessage, x, y float64) (json.RawMessage, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+
+	target := fields
+	if nested, ok := fields["element"].(map[string]interface{}); ok {
+		target = nested
+	}
+	target["x"] = x
+	target["y"] = y
+
+	moved, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return moved, true
+}
@@ -0,0 +1,109 @@
+package models
+
+import "time"
+
+// Plans
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+)
+
+// Features that can be switched on or off per plan
+const (
+	FeatureAIImprovement        = "ai_improvement"
+	FeatureAIStreaming          = "ai_streaming"
+	FeatureConversationStarters = "conversation_starters"
+	FeatureSessionExport        = "session_export"
+)
+
+// Metered limits
+const (
+	MeterAIImprovements = "ai_improvements"
+	MeterTranslations   = "translations"
+	MeterSessions       = "sessions"
+	MeterUploadStorage  = "upload_storage"
+)
+
+// Periods metered usage is counted over. PeriodNone meters current usage,
+// such as storage, and never resets.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodNone  = ""
+)
+
+// Units of metered usage
+const (
+	UnitCount = "count"
+	UnitBytes = "bytes"
+)
+
+// UnlimitedQuota is the limit of a meter a plan does not limit
+const UnlimitedQuota int64 = -1
+
+// Meter describes a metered limit. Recorded meters are counted from usage
+// records; the others are measured when needed.
+type Meter struct {
+	Name     string
+	Period   string
+	Unit     string
+	Recorded bool
+}
+
+// Plan is a subscription plan with its features and limits. Limits maps
+// meter names to the amount allowed per period, or UnlimitedQuota.
+type Plan struct {
+	Name     string           `json:"name"`
+	Features map[string]bool  `json:"features"`
+	Limits   map[string]int64 `json:"limits"`
+}
+
+// UserPlan is the plan a user is on
+type UserPlan struct {
+	UserID        string     `db:"id"`
+	PlanType      string     `db:"plan_type"`
+	PlanExpiresAt *time.Time `db:"plan_expires_at"`
+}
+
+// UsageRecord is one use of a recorded meter
+type UsageRecord struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Meter     string    `json:"meter" db:"meter"`
+	Amount    int64     `json:"amount" db:"amount"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UsageReservation is usage recorded before the work it pays for, so that
+// concurrent requests cannot together go over a limit. Usage is the meter as
+// it was before the reservation. RecordID is empty if nothing was reserved.
+type UsageReservation struct {
+	RecordID string
+	Usage    MeterUsage
+}
+
+// MeterUsage is a user's usage of one meter in its current period. Limit
+// and Remaining are UnlimitedQuota when the plan does not limit the meter.
+type MeterUsage struct {
+	Meter     string     `json:"meter"`
+	Unit      string     `json:"unit"`
+	Period    string     `json:"period,omitempty"`
+	Used      int64      `json:"used"`
+	Limit     int64      `json:"limit"`
+	Remaining int64      `json:"remaining"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
+}
+
+// Allows reports whether amount more can be used within the limit
+func (u *MeterUsage) Allows(amount int64) bool {
+	return u.Limit == UnlimitedQuota || u.Used+amount <= u.Limit
+}
+
+// EntitlementUsage is a user's plan, features and metered usage
+type EntitlementUsage struct {
+	Plan          string          `json:"plan"`
+	PlanExpiresAt *time.Time      `json:"plan_expires_at,omitempty"`
+	Features      map[string]bool `json:"features"`
+	Meters        []MeterUsage    `json:"meters"`
+}
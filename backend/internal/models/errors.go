@@ -1,6 +1,10 @@
 package models
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
 
 type AppError struct {
 	Code    string            `json:"code"`
@@ -43,6 +47,9 @@ var (
 	ErrRequestNotFound    = NewAppError("REQUEST_NOT_FOUND", "Match request not found", http.StatusNotFound)
 	ErrInvalidRequestStatus = NewAppError("INVALID_REQUEST_STATUS", "Invalid request status", http.StatusBadRequest)
 	ErrCannotMatchSelf    = NewAppError("CANNOT_MATCH_SELF", "Cannot send match request to yourself", http.StatusBadRequest)
+	ErrMatchNotFound      = NewAppError("MATCH_NOT_FOUND", "Match not found", http.StatusNotFound)
+	ErrConversationNotFound = NewAppError("CONVERSATION_NOT_FOUND", "Conversation not found", http.StatusNotFound)
+	ErrNotPartner         = NewAppError("NOT_PARTNER", "You are not part of this match or conversation", http.StatusForbidden)
 	ErrInternalServer     = NewAppError("INTERNAL_SERVER_ERROR", "Internal server error", http.StatusInternalServerError)
 	ErrValidation         = NewAppError("VALIDATION_ERROR", "Validation failed", http.StatusBadRequest)
 	
@@ -51,6 +58,38 @@ var (
 	ErrParticipantNotFound  = NewAppError("PARTICIPANT_NOT_FOUND", "Participant not found", http.StatusNotFound)
 	ErrSessionFull          = NewAppError("SESSION_FULL", "Session has reached maximum capacity", http.StatusConflict)
 	ErrSessionEnded         = NewAppError("SESSION_ENDED", "Session has ended", http.StatusGone)
+	ErrSessionCancelled     = NewAppError("SESSION_CANCELLED", "Session was cancelled", http.StatusGone)
+	ErrNotInSession         = NewAppError("NOT_IN_SESSION", "Join the session before connecting to it", http.StatusForbidden)
+	ErrObserverReadOnly     = NewAppError("OBSERVER_READ_ONLY", "Observers cannot change the canvas", http.StatusForbidden)
+	ErrCanvasVersionConflict = NewAppError("CANVAS_VERSION_CONFLICT", "Canvas changed while saving the operation", http.StatusConflict)
+	ErrCanvasElementNotFound = NewAppError("CANVAS_ELEMENT_NOT_FOUND", "Canvas element not found", http.StatusNotFound)
+	ErrNothingToUndo        = NewAppError("NOTHING_TO_UNDO", "No canvas operation to undo", http.StatusConflict)
+	ErrNothingToRedo        = NewAppError("NOTHING_TO_REDO", "No canvas operation to redo", http.StatusConflict)
+	ErrSessionNotStarted    = NewAppError("SESSION_NOT_STARTED", "Session has not started yet", http.StatusConflict)
+	ErrInvitationNotFound   = NewAppError("INVITATION_NOT_FOUND", "Session invitation not found", http.StatusNotFound)
+	ErrInvalidSchedule      = NewAppError("INVALID_SCHEDULE", "Invalid session schedule", http.StatusBadRequest)
+	ErrSeriesNotFound       = NewAppError("SERIES_NOT_FOUND", "Session series not found", http.StatusNotFound)
+	ErrOccurrenceNotFound   = NewAppError("OCCURRENCE_NOT_FOUND", "Session series has no occurrence at that time", http.StatusNotFound)
+	ErrOccurrenceStarted    = NewAppError("OCCURRENCE_STARTED", "This occurrence has already started", http.StatusConflict)
+	ErrInvalidRecurrence    = NewAppError("INVALID_RECURRENCE", "Invalid recurrence rule", http.StatusBadRequest)
+	
+	// Vocabulary errors
+	ErrVocabularyNotFound   = NewAppError("VOCABULARY_NOT_FOUND", "Vocabulary item not found", http.StatusNotFound)
+	ErrDuplicateVocabulary  = NewAppError("DUPLICATE_VOCABULARY", "This word is already in your vocabulary", http.StatusConflict)
+	ErrVocabularyReviewConflict = NewAppError("VOCABULARY_REVIEW_CONFLICT", "This word was reviewed again while saving the answer", http.StatusConflict)
+	ErrVocabularyNotDue = NewAppError("VOCABULARY_NOT_DUE", "This word is not due for review yet", http.StatusConflict)
+
+	// AI errors
+	ErrAIUnavailable     = NewAppError("AI_UNAVAILABLE", "The AI service is currently unavailable", http.StatusServiceUnavailable)
+	ErrAIInvalidResponse = NewAppError("AI_INVALID_RESPONSE", "The AI service returned an unusable response", http.StatusBadGateway)
+
+	// Billing errors
+	ErrBillingUnavailable      = NewAppError("BILLING_UNAVAILABLE", "Billing is currently unavailable", http.StatusServiceUnavailable)
+	ErrPlanNotPurchasable      = NewAppError("PLAN_NOT_PURCHASABLE", "This plan cannot be purchased", http.StatusBadRequest)
+	ErrAlreadySubscribed       = NewAppError("ALREADY_SUBSCRIBED", "You already have an active subscription", http.StatusConflict)
+	ErrSubscriptionNotFound    = NewAppError("SUBSCRIPTION_NOT_FOUND", "Subscription not found", http.StatusNotFound)
+	ErrInvalidWebhookSignature = NewAppError("INVALID_WEBHOOK_SIGNATURE", "Invalid webhook signature", http.StatusBadRequest)
+	ErrInvalidWebhookPayload   = NewAppError("INVALID_WEBHOOK_PAYLOAD", "Invalid webhook payload", http.StatusBadRequest)
 	
 	// Post errors
 	ErrPostNotFound         = NewAppError("POST_NOT_FOUND", "Post not found", http.StatusNotFound)
@@ -58,4 +97,38 @@ var (
 	ErrReactionNotFound     = NewAppError("REACTION_NOT_FOUND", "Reaction not found", http.StatusNotFound)
 	ErrInvalidReaction      = NewAppError("INVALID_REACTION", "Invalid reaction", http.StatusBadRequest)
 	ErrDuplicateReaction    = NewAppError("DUPLICATE_REACTION", "Reaction already exists", http.StatusConflict)
-)
\ No newline at end of file
+)
+
+// NewCanvasConflictError reports an operation that is stale against a newer
+// change by another participant
+func NewCanvasConflictError(elementID string, currentVersion int64) *AppError {
+	err := NewAppError("CANVAS_CONFLICT", "Canvas operation conflicts with a newer change", http.StatusConflict)
+	err.Details = map[string]string{
+		"element_id":      elementID,
+		"current_version": strconv.FormatInt(currentVersion, 10),
+	}
+	return err
+}
+
+// NewQuotaExceededError reports a metered limit of the user's plan that has
+// been used up
+func NewQuotaExceededError(usage *MeterUsage) *AppError {
+	err := NewAppError("QUOTA_EXCEEDED", "You have reached your plan's limit for "+usage.Meter, http.StatusTooManyRequests)
+	err.Details = map[string]string{
+		"meter": usage.Meter,
+		"used":  strconv.FormatInt(usage.Used, 10),
+		"limit": strconv.FormatInt(usage.Limit, 10),
+	}
+	if usage.ResetsAt != nil {
+		err.Details["resets_at"] = usage.ResetsAt.Format(time.RFC3339)
+	}
+	return err
+}
+
+// NewFeatureNotAvailableError reports a feature the user's plan does not
+// include
+func NewFeatureNotAvailableError(feature string) *AppError {
+	err := NewAppError("FEATURE_NOT_AVAILABLE", "Your plan does not include this feature", http.StatusForbidden)
+	err.Details = map[string]string{"feature": feature}
+	return err
+}
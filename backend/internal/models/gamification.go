@@ -118,6 +118,7 @@ const (
 	XPActionBadgeEarned        = "badge_earned"
 	XPActionChallengeComplete  = "challenge_complete"
 	XPActionStreakBonus        = "streak_bonus"
+	XPActionWordLearned        = "word_learned"
 )
 
 // XP reward amounts
@@ -130,6 +131,7 @@ const (
 	XPRewardDailyLogin        = 5
 	XPRewardProfileComplete   = 100
 	XPRewardStreakBonus       = 10 // per day of streak
+	XPRewardWordLearned       = 5
 )
 
 // UserLevel represents a level definition
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -30,11 +31,22 @@ type Message struct {
 	Content        string        `json:"content" db:"content"`
 	MessageType    MessageType   `json:"message_type" db:"message_type"`
 	Status         MessageStatus `json:"status" db:"status"`
+	Language       *string       `json:"language,omitempty" db:"language"` // detected language code
 	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
 	
 	// Extended fields for API responses
-	Sender *User `json:"sender,omitempty"`
+	Sender      *User               `json:"sender,omitempty"`
+	Translation *MessageTranslation `json:"translation,omitempty"` // set when a translation was requested
+}
+
+// MessageTranslation is a message's body translated into another language.
+// Translations are stored on the message row keyed by language.
+type MessageTranslation struct {
+	Language     string    `json:"language"`
+	Text         string    `json:"text"`
+	Provider     string    `json:"provider"`
+	TranslatedAt time.Time `json:"translated_at"`
 }
 
 // SendMessageRequest represents the request to send a new message
@@ -105,6 +117,8 @@ type WebSocketMessage struct {
 const (
 	WSMessageTypeNewMessage      = "new_message"
 	WSMessageTypeMessageRead     = "message_read"
+	WSMessageTypeMessageStatus   = "message_status"
+	WSMessageTypeMessageDeleted  = "message_deleted"
 	WSMessageTypeTyping          = "typing"
 	WSMessageTypeStopTyping      = "stop_typing"
 	WSMessageTypeUserOnline      = "user_online"
@@ -117,6 +131,18 @@ const (
 	WSMessageTypeCursorPosition  = "cursor_position"
 	WSMessageTypeUserJoined      = "user_joined"
 	WSMessageTypeUserLeft        = "user_left"
+	WSMessageTypeSessionEnded    = "session_ended"
+	// WebRTC signaling message types, relayed between session participants
+	WSMessageTypeWebRTCOffer        = "webrtc_offer"
+	WSMessageTypeWebRTCAnswer       = "webrtc_answer"
+	WSMessageTypeWebRTCICECandidate = "webrtc_ice_candidate"
+	WSMessageTypeWebRTCHangup       = "webrtc_hangup"
+	// Session scheduling message types
+	WSMessageTypeSessionInvitation = "session_invitation"
+	WSMessageTypeSessionRSVP       = "session_rsvp"
+	WSMessageTypeSessionReminder   = "session_reminder"
+	WSMessageTypeSessionUpdated    = "session_updated"
+	WSMessageTypeSessionCancelled  = "session_cancelled"
 )
 
 // TypingIndicator represents typing status
@@ -126,6 +152,28 @@ type TypingIndicator struct {
 	IsTyping       bool   `json:"is_typing"`
 }
 
+// MessageStatusEvent is pushed when a single message changes status
+type MessageStatusEvent struct {
+	MessageID      string        `json:"message_id"`
+	ConversationID string        `json:"conversation_id"`
+	Status         MessageStatus `json:"status"`
+	UpdatedBy      string        `json:"updated_by"`
+}
+
+// MessageReadEvent is pushed when a participant reads a conversation
+type MessageReadEvent struct {
+	ConversationID string    `json:"conversation_id"`
+	ReaderID       string    `json:"reader_id"`
+	ReadAt         time.Time `json:"read_at"`
+}
+
+// MessageDeletedEvent is pushed when a message is unsent
+type MessageDeletedEvent struct {
+	MessageID      string `json:"message_id"`
+	ConversationID string `json:"conversation_id"`
+	DeletedBy      string `json:"deleted_by"`
+}
+
 // OnlineStatus represents user online status
 type OnlineStatus struct {
 	UserID   string `json:"user_id"`
@@ -154,4 +202,14 @@ type CursorPosition struct {
 	UserID    string  `json:"user_id"`
 	X         float64 `json:"x"`
 	Y         float64 `json:"y"`
-}
\ No newline at end of file
+}
+
+// WebRTCSignal is a signaling message relayed from one session participant
+// to another. SDP carries offers and answers; Candidate is an
+// RTCIceCandidateInit passed through unchanged.
+type WebRTCSignal struct {
+	SessionID  string          `json:"session_id"`
+	FromUserID string          `json:"from_user_id"`
+	SDP        string          `json:"sdp,omitempty"`
+	Candidate  json.RawMessage `json:"candidate,omitempty"`
+}
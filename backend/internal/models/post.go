@@ -17,6 +17,7 @@ type Post struct {
 	ReactionCount int       `json:"reaction_count" db:"reaction_count"`
 	BookmarkCount int       `json:"bookmark_count" db:"bookmark_count"`
 	CursorID      int64     `json:"cursor_id" db:"cursor_id"`
+	Language      *string   `json:"language,omitempty" db:"language"` // detected language code
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 
@@ -42,6 +43,7 @@ type Comment struct {
 	UserID          string    `json:"user_id" db:"user_id"`
 	ParentCommentID *string   `json:"parent_comment_id" db:"parent_comment_id"`
 	Content         string    `json:"content" db:"content"`
+	Language        *string   `json:"language,omitempty" db:"language"` // detected language code
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 
@@ -105,6 +107,7 @@ type PostFilters struct {
 	UserID       string
 	Category     string
 	SearchQuery  string
+	Language     string // detected language code
 	Limit        int
 	// Cursor-based pagination
 	CursorID     int64  // For efficient pagination
@@ -20,13 +20,95 @@ type LanguageSession struct {
 	EndedAt         *time.Time `json:"ended_at" db:"ended_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 	
+	// Scheduling; sessions created without a start time are active immediately
+	ScheduledStartAt *time.Time `json:"scheduled_start_at" db:"scheduled_start_at"`
+	ScheduledEndAt   *time.Time `json:"scheduled_end_at" db:"scheduled_end_at"`
+	Timezone         *string    `json:"timezone" db:"timezone"`
+	StartedAt        *time.Time `json:"started_at" db:"started_at"`
+	ReminderSentAt   *time.Time `json:"-" db:"reminder_sent_at"`
+	
+	// SeriesID links an occurrence of a recurring session to its series;
+	// OccurrenceStart is the occurrence's start before any exception
+	SeriesID        *string    `json:"series_id,omitempty" db:"series_id"`
+	OccurrenceStart *time.Time `json:"occurrence_start,omitempty" db:"occurrence_start"`
+	
 	// Joined fields
 	Creator      *User                 `json:"creator,omitempty"`
 	InvitedUser  *User                 `json:"invited_user,omitempty"`
 	Participants []SessionParticipant  `json:"participants,omitempty"`
 	ParticipantCount int               `json:"participant_count,omitempty"`
+	Invitations  []SessionInvitation   `json:"invitations,omitempty"`
+}
+
+// CheckOpen returns ErrSessionEnded or ErrSessionCancelled if the session
+// can no longer be joined or changed
+func (s *LanguageSession) CheckOpen() error {
+	switch s.Status {
+	case SessionStatusEnded:
+		return ErrSessionEnded
+	case SessionStatusCancelled:
+		return ErrSessionCancelled
+	}
+	return nil
+}
+
+// IsInvited checks if the user has an invitation they have not declined
+func (s *LanguageSession) IsInvited(userID string) bool {
+	for _, invitation := range s.Invitations {
+		if invitation.UserID == userID {
+			return invitation.Status != InvitationStatusDeclined
+		}
+	}
+	return s.InvitedUserID != nil && *s.InvitedUserID == userID
+}
+
+// SessionInvitation is an invitee's RSVP to a session
+type SessionInvitation struct {
+	ID          string     `json:"id" db:"id"`
+	SessionID   string     `json:"session_id" db:"session_id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Status      string     `json:"status" db:"status"`
+	RespondedAt *time.Time `json:"responded_at" db:"responded_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	
+	// Joined fields
+	User *User `json:"user,omitempty"`
+}
+
+// SessionInvitationEvent is pushed to invitees when a session is scheduled
+type SessionInvitationEvent struct {
+	Session   *LanguageSession `json:"session"`
+	InvitedBy string           `json:"invited_by"`
+}
+
+// SessionRSVPEvent is pushed to the session creator when an invitee responds
+type SessionRSVPEvent struct {
+	SessionID string `json:"session_id"`
+	UserID    string `json:"user_id"`
+	Status    string `json:"status"`
 }
 
+// SessionReminderEvent is pushed to attendees shortly before a session starts
+type SessionReminderEvent struct {
+	SessionID        string    `json:"session_id"`
+	Name             string    `json:"name"`
+	ScheduledStartAt time.Time `json:"scheduled_start_at"`
+	StartsInMinutes  int       `json:"starts_in_minutes"`
+}
+
+// SessionEndedEvent is pushed to a session room when the session ends
+type SessionEndedEvent struct {
+	SessionID string `json:"session_id"`
+	Reason    string `json:"reason"`
+}
+
+// Reasons a session ended
+const (
+	SessionEndReasonCreator = "ended_by_creator"
+	SessionEndReasonIdle    = "idle"
+)
+
 // SessionParticipant represents a user participating in a session
 type SessionParticipant struct {
 	ID        string     `json:"id" db:"id"`
@@ -41,6 +123,84 @@ type SessionParticipant struct {
 	User *User `json:"user,omitempty"`
 }
 
+// SessionConnection is a WebSocket connection to a session room
+type SessionConnection struct {
+	ID             string     `json:"id" db:"id"`
+	SessionID      string     `json:"session_id" db:"session_id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	Source         string     `json:"source" db:"source"`
+	NodeID         string     `json:"-" db:"node_id"`
+	ConnectedAt    time.Time  `json:"connected_at" db:"connected_at"`
+	LastSeenAt     time.Time  `json:"-" db:"last_seen_at"`
+	DisconnectedAt *time.Time `json:"disconnected_at" db:"disconnected_at"`
+}
+
+// Where a session connection was recorded from
+const (
+	SessionConnectionSourceWebSocket = "websocket"
+	SessionConnectionSourceREST      = "rest"
+)
+
+// Open session connections are refreshed by their hub node every
+// SessionConnectionHeartbeat and are treated as gone once their last refresh
+// is older than SessionConnectionStaleAfter, e.g. after the node crashed
+const (
+	SessionConnectionHeartbeat  = 30 * time.Second
+	SessionConnectionStaleAfter = 3 * SessionConnectionHeartbeat
+)
+
+// Until returns when the connection ended, or when it was last known to be
+// open if it is still open. Open REST intervals last until the participant
+// leaves.
+func (c *SessionConnection) Until(now time.Time) time.Time {
+	if c.DisconnectedAt != nil {
+		return *c.DisconnectedAt
+	}
+	if c.Source == SessionConnectionSourceREST {
+		return now
+	}
+	if staleAt := c.LastSeenAt.Add(SessionConnectionStaleAfter); staleAt.Before(now) {
+		return c.LastSeenAt
+	}
+	return now
+}
+
+// SessionAttendance summarizes how each participant took part in a session
+type SessionAttendance struct {
+	SessionID    string                  `json:"session_id"`
+	Participants []ParticipantAttendance `json:"participants"`
+}
+
+// ParticipantAttendance is a participant's presence in a session. Intervals
+// from several tabs or devices are merged, and a reconnect is a new interval
+// after a gap. ChatShare is the participant's share of the characters
+// written in the session chat, not of the time spent talking.
+type ParticipantAttendance struct {
+	UserID           string               `json:"user_id"`
+	User             *User                `json:"user,omitempty"`
+	Role             string               `json:"role,omitempty"`
+	IsPresent        bool                 `json:"is_present"`
+	ConnectedSeconds int64                `json:"connected_seconds"`
+	Reconnects       int                  `json:"reconnects"`
+	MessageCount     int                  `json:"message_count"`
+	ChatShare        float64              `json:"chat_share"`
+	Intervals        []AttendanceInterval `json:"intervals"`
+}
+
+// AttendanceInterval is a period a participant was present; LeftAt is nil
+// while they still are
+type AttendanceInterval struct {
+	JoinedAt time.Time  `json:"joined_at"`
+	LeftAt   *time.Time `json:"left_at"`
+}
+
+// SessionMessageTotals counts a user's chat messages in a session
+type SessionMessageTotals struct {
+	UserID     string `db:"user_id"`
+	Messages   int    `db:"messages"`
+	Characters int    `db:"characters"`
+}
+
 // CanvasOperation represents a whiteboard operation
 type CanvasOperation struct {
 	ID             string          `json:"id" db:"id"`
@@ -51,6 +211,21 @@ type CanvasOperation struct {
 	SequenceNumber int64           `json:"sequence_number" db:"sequence_number"`
 	Timestamp      time.Time       `json:"timestamp" db:"timestamp"`
 	
+	// Version is the server-assigned per-session canvas version. BaseVersion
+	// is the version the client had seen when it created the operation.
+	Version     int64 `json:"version" db:"version"`
+	BaseVersion int64 `json:"base_version,omitempty" db:"base_version"`
+	
+	// ElementID is the element the operation applies to
+	ElementID *string `json:"element_id,omitempty" db:"element_id"`
+	
+	// PreviousElement is the element as it was before this operation, used for undo
+	PreviousElement json.RawMessage `json:"-" db:"previous_element"`
+	
+	// UndoOf and RedoOf reference the operation this one undoes or redoes
+	UndoOf *string `json:"undo_of,omitempty" db:"undo_of"`
+	RedoOf *string `json:"redo_of,omitempty" db:"redo_of"`
+	
 	// Joined fields
 	User *User `json:"user,omitempty"`
 }
@@ -62,6 +237,7 @@ type SessionMessage struct {
 	UserID      string    `json:"user_id" db:"user_id"`
 	Content     string    `json:"content" db:"message_text"`
 	MessageType string    `json:"message_type" db:"message_type"`
+	Language    *string   `json:"language,omitempty" db:"language"` // detected language code
 	CreatedAt   time.Time `json:"created_at" db:"timestamp"`
 	
 	// Joined fields
@@ -131,8 +307,16 @@ type CreateSessionInput struct {
 	TargetLanguage  *string `json:"target_language"`
 }
 
+// RSVPInput is an invitee's response to a session invitation
+type RSVPInput struct {
+	Status string `json:"status" binding:"required,oneof=accepted declined tentative"`
+}
+
+// JoinSessionInput is the optional body of a join request. Invitees can join
+// as observers, who follow the session without changing the canvas and do
+// not count towards MaxParticipants.
 type JoinSessionInput struct {
-	SessionID string `json:"session_id" binding:"required"`
+	Role string `json:"role" binding:"omitempty,oneof=participant observer"`
 }
 
 type SendMessageInput struct {
@@ -141,16 +325,39 @@ type SendMessageInput struct {
 }
 
 type CanvasOperationInput struct {
-	OperationType string          `json:"operation_type" binding:"required,oneof=text draw erase clear move delete excalidraw_update text_update"`
-	OperationData json.RawMessage `json:"operation_data" binding:"required"`
+	OperationType string          `json:"operation_type" binding:"required,oneof=text draw erase clear move delete excalidraw_update text_update undo redo"`
+	OperationData json.RawMessage `json:"operation_data"`
+	BaseVersion   int64           `json:"base_version"`
 }
 
 // Session status constants
 const (
-	SessionStatusActive = "active"
-	SessionStatusEnded  = "ended"
+	SessionStatusScheduled = "scheduled"
+	SessionStatusActive    = "active"
+	SessionStatusEnded     = "ended"
+	// SessionStatusCancelled is a scheduled session called off before it
+	// started, such as a cancelled occurrence of a series
+	SessionStatusCancelled = "cancelled"
+)
+
+// Invitation statuses
+const (
+	InvitationStatusPending   = "pending"
+	InvitationStatusAccepted  = "accepted"
+	InvitationStatusDeclined  = "declined"
+	InvitationStatusTentative = "tentative"
 )
 
+// IsValidRSVPStatus checks if the status is a valid invitee response
+func IsValidRSVPStatus(status string) bool {
+	switch status {
+	case InvitationStatusAccepted, InvitationStatusDeclined, InvitationStatusTentative:
+		return true
+	default:
+		return false
+	}
+}
+
 // Session types
 const (
 	SessionTypePractice     = "practice"
@@ -175,8 +382,24 @@ const (
 	OperationTypeDelete          = "delete"
 	OperationTypeExcalidrawUpdate = "excalidraw_update"
 	OperationTypeTextUpdate      = "text_update"
+	
+	// Undo and redo are requests; the server stores the operation they produce
+	OperationTypeUndo = "undo"
+	OperationTypeRedo = "redo"
 )
 
+// IsValidOperationType checks if the canvas operation type is valid
+func IsValidOperationType(operationType string) bool {
+	switch operationType {
+	case OperationTypeText, OperationTypeDraw, OperationTypeErase, OperationTypeClear,
+		OperationTypeMove, OperationTypeDelete, OperationTypeExcalidrawUpdate, OperationTypeTextUpdate,
+		OperationTypeUndo, OperationTypeRedo:
+		return true
+	default:
+		return false
+	}
+}
+
 // Session message types
 const (
 	SessionMessageTypeText   = "text"
@@ -185,6 +408,16 @@ const (
 	SessionMessageTypeVoice  = "voice"
 )
 
+// IsValidSessionMessageType checks if the session message type is valid
+func IsValidSessionMessageType(messageType string) bool {
+	switch messageType {
+	case SessionMessageTypeText, SessionMessageTypeSystem, SessionMessageTypeFile, SessionMessageTypeVoice:
+		return true
+	default:
+		return false
+	}
+}
+
 // Request and Response models
 
 // CreateSessionRequest represents the request to create a new session
@@ -194,7 +427,33 @@ type CreateSessionRequest struct {
 	SessionType     string  `json:"session_type" validate:"required,oneof=practice lesson conversation"`
 	TargetLanguage  string  `json:"target_language" validate:"max=10"`
 	MaxParticipants int     `json:"max_participants" validate:"min=2,max=50"`
-	InvitedUserID   string  `json:"invited_user_id" validate:"required"`
+	InvitedUserID   string  `json:"invited_user_id"`
+	InvitedUserIDs  []string `json:"invited_user_ids"`
+	
+	// ScheduledStartAt and ScheduledEndAt book the session ahead of time. They
+	// are RFC 3339 timestamps, or local times such as "2024-05-01T18:00" that
+	// are read in Timezone (default: the creator's timezone).
+	ScheduledStartAt string `json:"scheduled_start_at"`
+	ScheduledEndAt   string `json:"scheduled_end_at"`
+	Timezone         string `json:"timezone"`
+	
+	// RRule makes the session recurring, e.g. "FREQ=WEEKLY;INTERVAL=2;COUNT=10".
+	// Weekly and monthly rules with COUNT or UNTIL are supported.
+	RRule string `json:"rrule"`
+}
+
+// Invitees returns the invited user IDs without duplicates
+func (r *CreateSessionRequest) Invitees() []string {
+	seen := make(map[string]bool)
+	var invitees []string
+	for _, userID := range append([]string{r.InvitedUserID}, r.InvitedUserIDs...) {
+		if userID == "" || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		invitees = append(invitees, userID)
+	}
+	return invitees
 }
 
 // SendSessionMessageRequest represents the request to send a session message
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session export formats
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatMarkdown = "markdown"
+	ExportFormatHTML     = "html"
+)
+
+// SessionExport bundles an ended session for review: its chat transcript,
+// attendance and canvas history
+type SessionExport struct {
+	Session    *LanguageSession    `json:"session"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Messages   []*SessionMessage   `json:"messages"`
+	Attendance *SessionAttendance  `json:"attendance"`
+	Canvas     SessionCanvasExport `json:"canvas"`
+}
+
+// SessionCanvasExport is the canvas history available for replay. Older
+// operations are compacted into snapshots, so a replay starts from Initial,
+// which already has the CompactedOperations that came before Operations
+// applied. It is only empty if the canvas was never snapshotted.
+type SessionCanvasExport struct {
+	Initial             *CanvasState       `json:"initial"`
+	CompactedOperations int                `json:"compacted_operations"`
+	Operations          []*CanvasOperation `json:"operations"`
+	Final               *CanvasState       `json:"final"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// SessionSeries is a recurring session. Concrete LanguageSession rows are
+// created for each occurrence shortly before it starts.
+type SessionSeries struct {
+	ID              string    `json:"id" db:"id"`
+	CreatedBy       string    `json:"created_by" db:"created_by"`
+	Name            string    `json:"name" db:"name"`
+	Description     *string   `json:"description" db:"description"`
+	MaxParticipants int       `json:"max_participants" db:"max_participants"`
+	SessionType     string    `json:"session_type" db:"session_type"`
+	TargetLanguage  *string   `json:"target_language" db:"target_language"`
+	InviteeIDs      []string  `json:"invitee_ids" db:"invitee_ids"`
+	Timezone        string    `json:"timezone" db:"timezone"`
+	StartsAt        time.Time `json:"starts_at" db:"starts_at"`
+	DurationMinutes int       `json:"duration_minutes" db:"duration_minutes"`
+	RRule           string    `json:"rrule" db:"rrule"`
+	Status          string    `json:"status" db:"status"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+
+	// MaterializedUntil is the end of the window sessions have been created for
+	MaterializedUntil *time.Time `json:"-" db:"materialized_until"`
+
+	// Joined fields
+	Exceptions  []SessionSeriesException `json:"exceptions,omitempty"`
+	Occurrences []SeriesOccurrence       `json:"occurrences,omitempty"`
+}
+
+// Duration returns the length of each occurrence
+func (s *SessionSeries) Duration() time.Duration {
+	return time.Duration(s.DurationMinutes) * time.Minute
+}
+
+// SessionSeriesException changes or cancels a single occurrence of a series
+type SessionSeriesException struct {
+	ID              string     `json:"id" db:"id"`
+	SeriesID        string     `json:"series_id" db:"series_id"`
+	OccurrenceStart time.Time  `json:"occurrence_start" db:"occurrence_start"`
+	Cancelled       bool       `json:"cancelled" db:"cancelled"`
+	Name            *string    `json:"name,omitempty" db:"name"`
+	Description     *string    `json:"description,omitempty" db:"description"`
+	StartsAt        *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SeriesOccurrence is a single occurrence of a series with its exception applied
+type SeriesOccurrence struct {
+	OccurrenceStart time.Time `json:"occurrence_start"`
+	StartsAt        time.Time `json:"starts_at"`
+	EndsAt          time.Time `json:"ends_at"`
+	Name            string    `json:"name"`
+	Description     *string   `json:"description,omitempty"`
+	Cancelled       bool      `json:"cancelled"`
+	SessionID       *string   `json:"session_id,omitempty"`
+}
+
+// UpdateSeriesInput edits one occurrence ("this") or the occurrence and every
+// later one ("future"). OccurrenceStart is the occurrence's original start.
+type UpdateSeriesInput struct {
+	Scope            string  `json:"scope" binding:"required,oneof=this future"`
+	OccurrenceStart  string  `json:"occurrence_start" binding:"required"`
+	Name             *string `json:"name"`
+	Description      *string `json:"description"`
+	ScheduledStartAt string  `json:"scheduled_start_at"`
+	ScheduledEndAt   string  `json:"scheduled_end_at"`
+	Cancelled        *bool   `json:"cancelled"` // nil leaves the occurrence as it is
+
+	// RRule replaces the recurrence of future occurrences
+	RRule string `json:"rrule"`
+}
+
+// Series statuses
+const (
+	SeriesStatusActive = "active"
+	SeriesStatusEnded  = "ended"
+)
+
+// Series edit scopes
+const (
+	SeriesScopeThis   = "this"
+	SeriesScopeFuture = "future"
+)
@@ -1,10 +1,22 @@
 package models
 
+import "time"
+
 // TranslateRequest represents a translation request from the frontend
 type TranslateRequest struct {
 	Text       string `json:"text" binding:"required" validate:"min=1,max=5000"`
 	SourceLang string `json:"source_lang" binding:"required" validate:"min=2,max=5"`
 	TargetLang string `json:"target_lang" binding:"required" validate:"min=2,max=5"`
+
+	// Format is "text" (default) or "html"; HTML keeps its markup and only
+	// the text between tags is translated
+	Format string `json:"format,omitempty" binding:"omitempty,oneof=text html"`
+
+	// SaveToVocabulary stores the text and its translation in the user's
+	// vocabulary, linked to the conversation or session message it came from
+	SaveToVocabulary bool   `json:"save_to_vocabulary"`
+	MessageID        string `json:"message_id" binding:"omitempty,uuid"`
+	SessionMessageID string `json:"session_message_id" binding:"omitempty,uuid"`
 }
 
 // TranslateResponse represents the response from the translation API
@@ -14,6 +26,81 @@ type TranslateResponse struct {
 	SourceLang     string `json:"source_lang"`
 	TargetLang     string `json:"target_lang"`
 	Provider       string `json:"provider"` // "libretranslate", "google", "deepl"
+	Cached         bool   `json:"cached,omitempty"`
+
+	// DetectedLanguage is the language the provider detected when the source
+	// language was "auto"
+	DetectedLanguage *DetectedLanguage `json:"detected_language,omitempty"`
+
+	// VocabularyItem is the saved word when the request asked to save it
+	VocabularyItem *VocabularyItem `json:"vocabulary_item,omitempty"`
+}
+
+// DetectedLanguage is a candidate language for a text. Confidence ranges
+// from 0 to 1 and is zero when the provider does not report one.
+type DetectedLanguage struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// DetectLanguageRequest asks which language a text is written in
+type DetectLanguageRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// DetectLanguageResponse is the most likely language of a text along with
+// every candidate the provider considered, most likely first
+type DetectLanguageResponse struct {
+	Language   string             `json:"language"`
+	Confidence float64            `json:"confidence"`
+	Candidates []DetectedLanguage `json:"candidates"`
+	Provider   string             `json:"provider"`
+}
+
+// Translation formats
+const (
+	TranslationFormatText = "text"
+	TranslationFormatHTML = "html"
+)
+
+// MaxBatchSegments is the most segments a batch translation accepts
+const MaxBatchSegments = 50
+
+// BatchTranslateSegment is one text in a batch. ID is echoed back so callers
+// can match results to, for example, the messages they came from.
+type BatchTranslateSegment struct {
+	ID   string `json:"id,omitempty" binding:"max=100"`
+	Text string `json:"text"`
+}
+
+// BatchTranslateRequest translates several texts between the same languages
+type BatchTranslateRequest struct {
+	Segments   []BatchTranslateSegment `json:"segments" binding:"required,min=1,max=50,dive"`
+	SourceLang string                  `json:"source_lang"`
+	TargetLang string                  `json:"target_lang" binding:"required"`
+	Format     string                  `json:"format,omitempty" binding:"omitempty,oneof=text html"`
+}
+
+// BatchTranslateResult is the outcome for one segment, in request order.
+// Error is set instead of TranslatedText when the segment failed.
+type BatchTranslateResult struct {
+	Index          int               `json:"index"`
+	ID             string            `json:"id,omitempty"`
+	TranslatedText string            `json:"translated_text,omitempty"`
+	Provider       string            `json:"provider,omitempty"`
+	Cached         bool              `json:"cached,omitempty"`
+	Error          *TranslationError `json:"error,omitempty"`
+}
+
+// BatchTranslateResponse reports every segment of a batch; a batch with
+// failed segments still succeeds as a whole
+type BatchTranslateResponse struct {
+	SourceLang string                 `json:"source_lang"`
+	TargetLang string                 `json:"target_lang"`
+	Format     string                 `json:"format"`
+	Results    []BatchTranslateResult `json:"results"`
+	Succeeded  int                    `json:"succeeded"`
+	Failed     int                    `json:"failed"`
 }
 
 // LibreTranslateRequest represents the request format for LibreTranslate API
@@ -28,6 +115,106 @@ type LibreTranslateRequest struct {
 // LibreTranslateResponse represents the response format from LibreTranslate API
 type LibreTranslateResponse struct {
 	TranslatedText string `json:"translatedText"`
+
+	// DetectedLanguage is set when the source language was "auto"
+	DetectedLanguage *LibreTranslateDetection `json:"detectedLanguage,omitempty"`
+}
+
+// LibreTranslateDetection is a detected language from LibreTranslate, with a
+// confidence from 0 to 100
+type LibreTranslateDetection struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+// LibreTranslateDetectRequest represents the request format for
+// LibreTranslate's detect API
+type LibreTranslateDetectRequest struct {
+	Q      string `json:"q"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// DeepLTranslateRequest represents the request format for the DeepL API
+type DeepLTranslateRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang,omitempty"`
+	TargetLang string   `json:"target_lang"`
+
+	// TagHandling is "html" to translate HTML without touching its markup
+	TagHandling string `json:"tag_handling,omitempty"`
+}
+
+// DeepLTranslateResponse represents the response format from the DeepL API
+type DeepLTranslateResponse struct {
+	Translations []struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	} `json:"translations"`
+}
+
+// DeepLLanguage is an entry in DeepL's language list
+type DeepLLanguage struct {
+	Language string `json:"language"`
+	Name     string `json:"name"`
+}
+
+// GoogleTranslateRequest represents the request format for the Google Cloud
+// Translation basic (v2) API
+type GoogleTranslateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source,omitempty"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+}
+
+// GoogleTranslateResponse represents the response format from the Google
+// Cloud Translation basic (v2) API
+type GoogleTranslateResponse struct {
+	Data struct {
+		Translations []struct {
+			TranslatedText         string `json:"translatedText"`
+			DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+		} `json:"translations"`
+	} `json:"data"`
+}
+
+// GoogleDetectRequest represents the request format for Google's detect API
+type GoogleDetectRequest struct {
+	Q []string `json:"q"`
+}
+
+// GoogleDetectResponse represents Google's detections, one list of
+// candidates per input text, with confidence from 0 to 1
+type GoogleDetectResponse struct {
+	Data struct {
+		Detections [][]struct {
+			Language   string  `json:"language"`
+			Confidence float64 `json:"confidence"`
+		} `json:"detections"`
+	} `json:"data"`
+}
+
+// GoogleLanguagesResponse represents Google's supported language list
+type GoogleLanguagesResponse struct {
+	Data struct {
+		Languages []struct {
+			Language string `json:"language"`
+			Name     string `json:"name"`
+		} `json:"languages"`
+	} `json:"data"`
+}
+
+// TranslationProviderStatus reports the health of a translation provider in
+// the fallback chain
+type TranslationProviderStatus struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+
+	// RetryAt is when a provider that kept failing is tried again
+	RetryAt *time.Time `json:"retry_at,omitempty"`
 }
 
 // SupportedLanguage represents a language supported by the translation service
@@ -70,4 +257,12 @@ var (
 		Code:    "SAME_LANGUAGE",
 		Message: "Source and target languages are the same",
 	}
+	ErrLanguageNotDetected = &TranslationError{
+		Code:    "LANGUAGE_NOT_DETECTED",
+		Message: "Could not detect the language of the text",
+	}
+	ErrTranslationFailed = &TranslationError{
+		Code:    "TRANSLATION_FAILED",
+		Message: "Failed to translate text",
+	}
 )
\ No newline at end of file
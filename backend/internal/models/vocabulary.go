@@ -0,0 +1,152 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// VocabularyItem is a word or phrase saved to a user's notebook along with
+// its SM-2 review schedule
+type VocabularyItem struct {
+	ID              string  `json:"id" db:"id"`
+	UserID          string  `json:"user_id" db:"user_id"`
+	Term            string  `json:"term" db:"term"`
+	Translation     string  `json:"translation" db:"translation"`
+	SourceLanguage  string  `json:"source_language" db:"source_language"`
+	TargetLanguage  string  `json:"target_language" db:"target_language"`
+	ExampleSentence *string `json:"example_sentence,omitempty" db:"example_sentence"`
+
+	// OriginType and OriginID reference the message, session, session
+	// message or post the word was saved from
+	OriginType *string `json:"origin_type,omitempty" db:"origin_type"`
+	OriginID   *string `json:"origin_id,omitempty" db:"origin_id"`
+
+	// LookupCount is how many times the word was saved from a translation.
+	// Each repeat lookup makes the word harder, see VocabularyLookupPenalty.
+	LookupCount int `json:"lookup_count" db:"lookup_count"`
+
+	// Review schedule
+	EaseFactor     float64    `json:"ease_factor" db:"ease_factor"`
+	IntervalDays   int        `json:"interval_days" db:"interval_days"`
+	Repetitions    int        `json:"repetitions" db:"repetitions"`
+	Lapses         int        `json:"lapses" db:"lapses"`
+	DueAt          time.Time  `json:"due_at" db:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty" db:"last_reviewed_at"`
+
+	// LearnedAt is when the word first reached VocabularyLearnedRepetitions
+	LearnedAt *time.Time `json:"learned_at,omitempty" db:"learned_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Vocabulary origins
+const (
+	VocabularyOriginMessage        = "message"
+	VocabularyOriginSession        = "session"
+	VocabularyOriginSessionMessage = "session_message"
+	VocabularyOriginPost           = "post"
+)
+
+// SM-2 parameters
+const (
+	VocabularyInitialEase = 2.5
+	VocabularyMinimumEase = 1.3
+
+	// VocabularyPassingQuality is the lowest answer quality that counts as
+	// remembering the word
+	VocabularyPassingQuality = 3
+
+	// VocabularyLearnedRepetitions is the number of consecutive successful
+	// reviews after which a word counts as learned
+	VocabularyLearnedRepetitions = 3
+
+	// VocabularyMaxIntervalDays caps how far apart reviews are scheduled
+	VocabularyMaxIntervalDays = 365
+
+	// VocabularyLookupPenalty is taken off the ease factor each time a saved
+	// word is translated again, since needing to look it up means it was not
+	// remembered
+	VocabularyLookupPenalty = 0.15
+)
+
+// Review applies an answer of the given quality (0-5) to the item's schedule
+// using SM-2. It reports whether this review learned the word for the first
+// time.
+func (v *VocabularyItem) Review(quality int, now time.Time) bool {
+	if quality < VocabularyPassingQuality {
+		// Forgotten: start over, but keep the ease factor so the word stays
+		// as hard as it has proven to be
+		v.Repetitions = 0
+		v.IntervalDays = 1
+		v.Lapses++
+	} else {
+		switch v.Repetitions {
+		case 0:
+			v.IntervalDays = 1
+		case 1:
+			v.IntervalDays = 6
+		default:
+			v.IntervalDays = int(math.Round(float64(v.IntervalDays) * v.EaseFactor))
+		}
+		v.Repetitions++
+	}
+	if v.IntervalDays > VocabularyMaxIntervalDays {
+		v.IntervalDays = VocabularyMaxIntervalDays
+	}
+
+	miss := float64(5 - quality)
+	v.EaseFactor += 0.1 - miss*(0.08+miss*0.02)
+	if v.EaseFactor < VocabularyMinimumEase {
+		v.EaseFactor = VocabularyMinimumEase
+	}
+
+	v.LastReviewedAt = &now
+	v.DueAt = now.AddDate(0, 0, v.IntervalDays)
+
+	if v.LearnedAt == nil && v.Repetitions >= VocabularyLearnedRepetitions {
+		v.LearnedAt = &now
+		return true
+	}
+	return false
+}
+
+// CreateVocabularyInput saves a word or phrase to the notebook
+type CreateVocabularyInput struct {
+	Term            string  `json:"term" binding:"required,max=500"`
+	Translation     string  `json:"translation" binding:"required,max=500"`
+	SourceLanguage  string  `json:"source_language" binding:"required,max=10"`
+	TargetLanguage  string  `json:"target_language" binding:"required,max=10"`
+	ExampleSentence *string `json:"example_sentence" binding:"omitempty,max=1000"`
+	OriginType      string  `json:"origin_type" binding:"omitempty,oneof=message session session_message post"`
+	OriginID        string  `json:"origin_id" binding:"required_with=OriginType,omitempty,uuid"`
+}
+
+// UpdateVocabularyInput edits a saved word; nil fields are left unchanged
+type UpdateVocabularyInput struct {
+	Term            *string `json:"term" binding:"omitempty,min=1,max=500"`
+	Translation     *string `json:"translation" binding:"omitempty,min=1,max=500"`
+	ExampleSentence *string `json:"example_sentence" binding:"omitempty,max=1000"`
+}
+
+// ReviewVocabularyInput records the answer to a flashcard, graded 0 (no
+// recall) to 5 (perfect recall)
+type ReviewVocabularyInput struct {
+	Quality *int `json:"quality" binding:"required,min=0,max=5"`
+}
+
+// VocabularyFilters narrows a notebook listing
+type VocabularyFilters struct {
+	SourceLanguage string
+	TargetLanguage string
+	Search         string
+	DueOnly        bool
+	Limit          int
+	Offset         int
+}
+
+// VocabularyReviewResult is returned after a review
+type VocabularyReviewResult struct {
+	Item    *VocabularyItem `json:"item"`
+	Learned bool            `json:"learned"`
+}
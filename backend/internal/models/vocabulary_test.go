@@ -0,0 +1,116 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newVocabularyItem() *VocabularyItem {
+	return &VocabularyItem{EaseFactor: VocabularyInitialEase}
+}
+
+func TestVocabularyItemReview(t *testing.T) {
+	tests := []struct {
+		name          string
+		qualities     []int
+		wantIntervals []int
+		wantEase      float64
+		wantLapses    int
+	}{
+		{
+			name:          "1, 6, then interval times ease",
+			qualities:     []int{4, 4, 4, 4},
+			wantIntervals: []int{1, 6, 15, 38},
+			wantEase:      2.5,
+		},
+		{
+			name:          "perfect answers raise the ease",
+			qualities:     []int{5, 5, 5, 5},
+			wantIntervals: []int{1, 6, 16, 45},
+			wantEase:      2.9,
+		},
+		{
+			name:          "a lapse starts the schedule over with a lower ease",
+			qualities:     []int{4, 4, 4, 2, 4, 4, 4},
+			wantIntervals: []int{1, 6, 15, 1, 1, 6, 13},
+			wantEase:      2.18,
+			wantLapses:    1,
+		},
+		{
+			name:          "the ease does not drop below the floor",
+			qualities:     []int{0, 0, 0, 0},
+			wantIntervals: []int{1, 1, 1, 1},
+			wantEase:      VocabularyMinimumEase,
+			wantLapses:    4,
+		},
+		{
+			name:          "a hard pass at the floor keeps the floor",
+			qualities:     []int{0, 0, 0, 3, 3, 3},
+			wantIntervals: []int{1, 1, 1, 1, 6, 8},
+			wantEase:      VocabularyMinimumEase,
+			wantLapses:    3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := newVocabularyItem()
+			now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+			var intervals []int
+			for _, quality := range tt.qualities {
+				item.Review(quality, now)
+				intervals = append(intervals, item.IntervalDays)
+				if want := now.AddDate(0, 0, item.IntervalDays); !item.DueAt.Equal(want) {
+					t.Errorf("due at %v, want %v", item.DueAt, want)
+				}
+				now = item.DueAt
+			}
+
+			if !reflect.DeepEqual(intervals, tt.wantIntervals) {
+				t.Errorf("intervals = %v, want %v", intervals, tt.wantIntervals)
+			}
+			if math.Abs(item.EaseFactor-tt.wantEase) > 1e-9 {
+				t.Errorf("ease factor = %v, want %v", item.EaseFactor, tt.wantEase)
+			}
+			if item.Lapses != tt.wantLapses {
+				t.Errorf("lapses = %d, want %d", item.Lapses, tt.wantLapses)
+			}
+		})
+	}
+}
+
+func TestVocabularyItemReviewCapsInterval(t *testing.T) {
+	item := &VocabularyItem{EaseFactor: 2.5, IntervalDays: 200, Repetitions: 5}
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	item.Review(5, now)
+
+	if item.IntervalDays != VocabularyMaxIntervalDays {
+		t.Errorf("interval = %d days, want %d", item.IntervalDays, VocabularyMaxIntervalDays)
+	}
+	if want := now.AddDate(0, 0, VocabularyMaxIntervalDays); !item.DueAt.Equal(want) {
+		t.Errorf("due at %v, want %v", item.DueAt, want)
+	}
+}
+
+func TestVocabularyItemReviewLearnsOnce(t *testing.T) {
+	item := newVocabularyItem()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	qualities := []int{4, 4, 4, 5, 1, 4, 4, 4}
+	var learned []int
+	for i, quality := range qualities {
+		if item.Review(quality, now.AddDate(0, 0, i)) {
+			learned = append(learned, i)
+		}
+	}
+
+	if !reflect.DeepEqual(learned, []int{2}) {
+		t.Errorf("learned at reviews %v, want only the third", learned)
+	}
+	if want := now.AddDate(0, 0, 2); item.LearnedAt == nil || !item.LearnedAt.Equal(want) {
+		t.Errorf("learned at %v, want %v", item.LearnedAt, want)
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ICEServer is an RTCIceServer entry clients pass to RTCPeerConnection
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// TURNCredentials are time-limited credentials for the TURN relay, in the
+// format of coturn's REST API (use-auth-secret)
+type TURNCredentials struct {
+	Username   string      `json:"username"`
+	Credential string      `json:"credential"`
+	TTL        int         `json:"ttl"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	ICEServers []ICEServer `json:"ice_servers"`
+}
@@ -14,6 +14,13 @@ type UserRepository interface {
 	Update(ctx context.Context, user *models.User) error
 	Search(ctx context.Context, filters models.SearchFilters) ([]*models.User, error)
 	GetTotalCount(ctx context.Context) (int, error)
+	// GetCalendarFeedToken returns the user's calendar feed token, or "" if
+	// they have none yet
+	GetCalendarFeedToken(ctx context.Context, userID string) (string, error)
+	// EnsureCalendarFeedToken stores token unless the user already has one,
+	// and returns the token they end up with
+	EnsureCalendarFeedToken(ctx context.Context, userID, token string) (string, error)
+	SetCalendarFeedToken(ctx context.Context, userID, token string) error
 }
 
 type MatchRepository interface {
@@ -43,9 +50,12 @@ type MessageRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Message, error)
 	GetByConversationID(ctx context.Context, conversationID string, limit, offset int) ([]*models.Message, error)
 	UpdateStatus(ctx context.Context, messageID string, status models.MessageStatus) error
+	UpdateLanguage(ctx context.Context, messageID, language string) error
 	MarkAsRead(ctx context.Context, conversationID, userID string) error
 	GetLastMessage(ctx context.Context, conversationID string) (*models.Message, error)
 	Delete(ctx context.Context, messageID string) error
+	GetTranslations(ctx context.Context, messageIDs []string, language string) (map[string]*models.MessageTranslation, error)
+	SaveTranslation(ctx context.Context, messageID string, translation *models.MessageTranslation) error
 }
 
 type PostRepository interface {
@@ -53,6 +63,9 @@ type PostRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Post, error)
 	GetByIDWithDetails(ctx context.Context, id, currentUserID string) (*models.Post, error)
 	Update(ctx context.Context, post *models.Post) error
+	// UpdateLanguage sets the language of post, unless its title or content
+	// changed since
+	UpdateLanguage(ctx context.Context, post *models.Post, language string) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, filters models.PostFilters) ([]*models.Post, error)
 	GetUserPosts(ctx context.Context, userID string, limit, offset int) ([]*models.Post, error)
@@ -63,6 +76,7 @@ type CommentRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Comment, error)
 	GetByPostID(ctx context.Context, postID string) ([]*models.Comment, error)
 	Update(ctx context.Context, comment *models.Comment) error
+	UpdateLanguage(ctx context.Context, commentID, language string) error
 	Delete(ctx context.Context, id string) error
 }
 
@@ -136,4 +150,40 @@ type GamificationRepository interface {
 	
 	// Complete Gamification Data
 	GetUserGamificationData(ctx context.Context, userID string) (*models.UserGamificationData, error)
-}
\ No newline at end of file
+}
+
+type VocabularyRepository interface {
+	Create(ctx context.Context, item *models.VocabularyItem) error
+	SaveLookup(ctx context.Context, item *models.VocabularyItem) error
+	GetByID(ctx context.Context, userID, itemID string) (*models.VocabularyItem, error)
+	List(ctx context.Context, userID string, filters models.VocabularyFilters) ([]*models.VocabularyItem, error)
+	CountDue(ctx context.Context, userID string) (int, error)
+	Update(ctx context.Context, item *models.VocabularyItem) error
+	Delete(ctx context.Context, userID, itemID string) error
+	SaveReview(ctx context.Context, item *models.VocabularyItem, quality int, previousReview *time.Time, learned bool) (bool, error)
+}
+
+type EntitlementRepository interface {
+	GetUserPlan(ctx context.Context, userID string) (*models.UserPlan, error)
+	UpdateUserPlan(ctx context.Context, userID, planType string, expiresAt *time.Time) error
+	DowngradeExpiredPlan(ctx context.Context, userID string, now time.Time, planType string) (bool, error)
+	DowngradeExpiredPlans(ctx context.Context, now time.Time, planType string) (int64, error)
+	RecordUsage(ctx context.Context, record *models.UsageRecord) error
+	// ReserveUsage records usage unless it would take the user's usage of
+	// the meter since the given time over limit. It returns the usage before
+	// the record and whether the record was made.
+	ReserveUsage(ctx context.Context, record *models.UsageRecord, since time.Time, limit int64) (int64, bool, error)
+	DeleteUsage(ctx context.Context, id string) error
+	SumUsage(ctx context.Context, userID, meter string, since time.Time) (int64, error)
+}
+
+type BillingRepository interface {
+	GetLatestSubscription(ctx context.Context, userID string) (*models.Subscription, error)
+	// ProcessEvent records a webhook event and applies the change decide
+	// works out in one transaction. decide is given the event's subscription,
+	// locked until the transaction ends, or nil if it is not stored yet, and
+	// may return a nil change to only record the event. ProcessEvent returns
+	// false, changing nothing, if the event was already processed.
+	ProcessEvent(ctx context.Context, provider, eventID, eventType, providerSubscriptionID string, decide func(existing *models.Subscription) (*models.SubscriptionChange, error)) (bool, error)
+	GetInvoices(ctx context.Context, userID string, limit, offset int) ([]*models.Invoice, error)
+}
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const subscriptionColumns = `id, user_id, provider, provider_customer_id, provider_subscription_id,
+	plan_type, status, current_period_end, cancelled_at, created_at, updated_at`
+
+type billingRepository struct {
+	db *sqlx.DB
+}
+
+func NewBillingRepository(db *sqlx.DB) repository.BillingRepository {
+	return &billingRepository{db: db}
+}
+
+// GetLatestSubscription returns the user's most recently updated subscription
+func (r *billingRepository) GetLatestSubscription(ctx context.Context, userID string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := r.db.GetContext(ctx, &subscription, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions
+		WHERE user_id = $1
+		ORDER BY updated_at DESC
+		LIMIT 1`, userID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+func (r *billingRepository) ProcessEvent(ctx context.Context, provider, eventID, eventType, providerSubscriptionID string, decide func(existing *models.Subscription) (*models.SubscriptionChange, error)) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Concurrent deliveries of the same event wait here on the primary key
+	// until the first one commits or rolls back
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO billing_webhook_events (provider, event_id, event_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, event_id) DO NOTHING`, provider, eventID, eventType)
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	// Lock the subscription so events about it are applied one at a time,
+	// each to the state the previous one left
+	var existing *models.Subscription
+	if providerSubscriptionID != "" {
+		var subscription models.Subscription
+		err := tx.GetContext(ctx, &subscription, `
+			SELECT `+subscriptionColumns+`
+			FROM subscriptions
+			WHERE provider = $1 AND provider_subscription_id = $2
+			FOR UPDATE`, provider, providerSubscriptionID)
+		if err == nil {
+			existing = &subscription
+		} else if err != sql.ErrNoRows {
+			return false, fmt.Errorf("failed to get subscription: %w", err)
+		}
+	}
+
+	change, err := decide(existing)
+	if err != nil {
+		return false, err
+	}
+	if change != nil {
+		if err := applySubscriptionChange(ctx, tx, change); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func applySubscriptionChange(ctx context.Context, tx *sqlx.Tx, change *models.SubscriptionChange) error {
+	if sub := change.Subscription; sub != nil {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO subscriptions (user_id, provider, provider_customer_id, provider_subscription_id,
+				plan_type, status, current_period_end, cancelled_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (provider, provider_subscription_id) DO UPDATE SET
+				provider_customer_id = EXCLUDED.provider_customer_id,
+				plan_type = EXCLUDED.plan_type,
+				status = EXCLUDED.status,
+				current_period_end = EXCLUDED.current_period_end,
+				cancelled_at = EXCLUDED.cancelled_at,
+				updated_at = NOW()
+			RETURNING id, created_at, updated_at`,
+			sub.UserID, sub.Provider, sub.ProviderCustomerID, sub.ProviderSubscriptionID,
+			sub.PlanType, sub.Status, sub.CurrentPeriodEnd, sub.CancelledAt,
+		).Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save subscription: %w", err)
+		}
+		if change.Invoice != nil {
+			change.Invoice.SubscriptionID = &sub.ID
+		}
+	}
+
+	if invoice := change.Invoice; invoice != nil {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO invoices (user_id, subscription_id, provider, provider_invoice_id, amount_cents,
+				currency, status, hosted_url, period_start, period_end, paid_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (provider, provider_invoice_id) DO UPDATE SET
+				subscription_id = COALESCE(EXCLUDED.subscription_id, invoices.subscription_id),
+				amount_cents = EXCLUDED.amount_cents,
+				currency = EXCLUDED.currency,
+				status = EXCLUDED.status,
+				hosted_url = COALESCE(EXCLUDED.hosted_url, invoices.hosted_url),
+				period_start = EXCLUDED.period_start,
+				period_end = EXCLUDED.period_end,
+				paid_at = COALESCE(EXCLUDED.paid_at, invoices.paid_at)
+			RETURNING id, created_at`,
+			invoice.UserID, invoice.SubscriptionID, invoice.Provider, invoice.ProviderInvoiceID, invoice.AmountCents,
+			invoice.Currency, invoice.Status, invoice.HostedURL, invoice.PeriodStart, invoice.PeriodEnd, invoice.PaidAt,
+		).Scan(&invoice.ID, &invoice.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save invoice: %w", err)
+		}
+	}
+
+	if change.PlanType != "" && change.Subscription != nil {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users SET plan_type = $2, plan_expires_at = $3, updated_at = NOW()
+			WHERE id = $1`, change.Subscription.UserID, change.PlanType, change.PlanExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to update user plan: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return models.ErrUserNotFound
+		}
+	}
+
+	return nil
+}
+
+// GetInvoices returns the user's invoices, newest first
+func (r *billingRepository) GetInvoices(ctx context.Context, userID string, limit, offset int) ([]*models.Invoice, error) {
+	invoices := []*models.Invoice{}
+	err := r.db.SelectContext(ctx, &invoices, `
+		SELECT id, user_id, subscription_id, provider, provider_invoice_id, amount_cents, currency,
+			status, hosted_url, period_start, period_end, paid_at, created_at
+		FROM invoices
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+	return invoices, nil
+}
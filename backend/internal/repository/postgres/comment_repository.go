@@ -20,8 +20,8 @@ func NewCommentRepository(db *sqlx.DB) repository.CommentRepository {
 
 func (r *commentRepository) Create(ctx context.Context, comment *models.Comment) error {
 	query := `
-		INSERT INTO comments (post_id, user_id, parent_comment_id, content)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO comments (post_id, user_id, parent_comment_id, content, language)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at`
 
 	err := r.db.QueryRowContext(ctx, query,
@@ -29,6 +29,7 @@ func (r *commentRepository) Create(ctx context.Context, comment *models.Comment)
 		comment.UserID,
 		comment.ParentCommentID,
 		comment.Content,
+		comment.Language,
 	).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
 
 	return err
@@ -37,7 +38,7 @@ func (r *commentRepository) Create(ctx context.Context, comment *models.Comment)
 func (r *commentRepository) GetByID(ctx context.Context, id string) (*models.Comment, error) {
 	comment := &models.Comment{}
 	query := `
-		SELECT id, post_id, user_id, parent_comment_id, content, created_at, updated_at
+		SELECT id, post_id, user_id, parent_comment_id, content, language, created_at, updated_at
 		FROM comments
 		WHERE id = $1`
 
@@ -55,7 +56,7 @@ func (r *commentRepository) GetByPostID(ctx context.Context, postID string) ([]*
 	query := `
 		SELECT 
 			c.id, c.post_id, c.user_id, c.parent_comment_id, c.content, 
-			c.created_at, c.updated_at,
+			c.language, c.created_at, c.updated_at,
 			u.id as "user.id", u.name as "user.name", u.email as "user.email",
 			u.profile_image as "user.profile_image"
 		FROM comments c
@@ -127,10 +128,10 @@ func (r *commentRepository) GetByPostID(ctx context.Context, postID string) ([]*
 func (r *commentRepository) Update(ctx context.Context, comment *models.Comment) error {
 	query := `
 		UPDATE comments
-		SET content = $2, updated_at = NOW()
+		SET content = $2, language = $3, updated_at = NOW()
 		WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, comment.ID, comment.Content)
+	result, err := r.db.ExecContext(ctx, query, comment.ID, comment.Content, comment.Language)
 	if err != nil {
 		return err
 	}
@@ -147,6 +148,13 @@ func (r *commentRepository) Update(ctx context.Context, comment *models.Comment)
 	return nil
 }
 
+// UpdateLanguage records the language a comment was detected to be written in
+func (r *commentRepository) UpdateLanguage(ctx context.Context, commentID, language string) error {
+	query := `UPDATE comments SET language = $2 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, commentID, language)
+	return err
+}
+
 func (r *commentRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM comments WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type entitlementRepository struct {
+	db *sqlx.DB
+}
+
+func NewEntitlementRepository(db *sqlx.DB) repository.EntitlementRepository {
+	return &entitlementRepository{db: db}
+}
+
+func (r *entitlementRepository) GetUserPlan(ctx context.Context, userID string) (*models.UserPlan, error) {
+	var plan models.UserPlan
+	err := r.db.GetContext(ctx, &plan, `
+		SELECT id, COALESCE(plan_type, 'free') AS plan_type, plan_expires_at
+		FROM users
+		WHERE id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user plan: %w", err)
+	}
+	return &plan, nil
+}
+
+func (r *entitlementRepository) UpdateUserPlan(ctx context.Context, userID, planType string, expiresAt *time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET plan_type = $2, plan_expires_at = $3, updated_at = NOW()
+		WHERE id = $1`, userID, planType, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update user plan: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// DowngradeExpiredPlan moves the user to planType if their plan expired
+// before now. It reports false when the plan had not expired, for example
+// because it was renewed since it was read.
+func (r *entitlementRepository) DowngradeExpiredPlan(ctx context.Context, userID string, now time.Time, planType string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET plan_type = $3, plan_expires_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND plan_expires_at IS NOT NULL AND plan_expires_at <= $2`, userID, now, planType)
+	if err != nil {
+		return false, fmt.Errorf("failed to downgrade expired plan: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// DowngradeExpiredPlans moves every user whose plan expired before now to
+// planType and returns how many were moved
+func (r *entitlementRepository) DowngradeExpiredPlans(ctx context.Context, now time.Time, planType string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET plan_type = $2, plan_expires_at = NULL, updated_at = NOW()
+		WHERE plan_expires_at IS NOT NULL AND plan_expires_at <= $1`, now, planType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to downgrade expired plans: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *entitlementRepository) RecordUsage(ctx context.Context, record *models.UsageRecord) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO usage_records (user_id, meter, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		record.UserID, record.Meter, record.Amount,
+	).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// ReserveUsage holds a transaction-level advisory lock on the user and meter
+// while it adds up their usage and records more, so concurrent reservations
+// cannot together go over limit
+func (r *entitlementRepository) ReserveUsage(ctx context.Context, record *models.UsageRecord, since time.Time, limit int64) (int64, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`, record.UserID, record.Meter); err != nil {
+		return 0, false, fmt.Errorf("failed to lock usage: %w", err)
+	}
+
+	var used int64
+	err = tx.GetContext(ctx, &used, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM usage_records
+		WHERE user_id = $1 AND meter = $2 AND created_at >= $3`, record.UserID, record.Meter, since)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to sum usage: %w", err)
+	}
+	if limit != models.UnlimitedQuota && used+record.Amount > limit {
+		return used, false, nil
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO usage_records (user_id, meter, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		record.UserID, record.Meter, record.Amount,
+	).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return used, true, nil
+}
+
+// DeleteUsage removes a usage record, giving back usage that was reserved
+// for work that failed
+func (r *entitlementRepository) DeleteUsage(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM usage_records WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete usage: %w", err)
+	}
+	return nil
+}
+
+// SumUsage adds up a user's usage of meter since the given time
+func (r *entitlementRepository) SumUsage(ctx context.Context, userID, meter string, since time.Time) (int64, error) {
+	var total int64
+	err := r.db.GetContext(ctx, &total, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM usage_records
+		WHERE user_id = $1 AND meter = $2 AND created_at >= $3`, userID, meter, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum usage: %w", err)
+	}
+	return total, nil
+}
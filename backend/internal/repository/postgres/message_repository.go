@@ -3,10 +3,12 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"language-exchange/internal/models"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type MessageRepository struct {
@@ -19,8 +21,8 @@ func NewMessageRepository(db *sqlx.DB) *MessageRepository {
 
 func (r *MessageRepository) Create(ctx context.Context, message *models.Message) error {
 	query := `
-		INSERT INTO messages (id, conversation_id, sender_id, content, message_type, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		INSERT INTO messages (id, conversation_id, sender_id, content, message_type, status, language, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 	
 	_, err := r.db.ExecContext(ctx, query,
 		message.ID,
@@ -29,6 +31,7 @@ func (r *MessageRepository) Create(ctx context.Context, message *models.Message)
 		message.Content,
 		message.MessageType,
 		message.Status,
+		message.Language,
 		message.CreatedAt,
 		message.UpdatedAt,
 	)
@@ -39,7 +42,7 @@ func (r *MessageRepository) Create(ctx context.Context, message *models.Message)
 func (r *MessageRepository) GetByID(ctx context.Context, id string) (*models.Message, error) {
 	query := `
 		SELECT m.id, m.conversation_id, m.sender_id, m.content, m.message_type, 
-		       m.status, m.created_at, m.updated_at,
+		       m.status, m.language, m.created_at, m.updated_at,
 		       u.name as sender_name, u.profile_image as sender_image
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
@@ -56,6 +59,7 @@ func (r *MessageRepository) GetByID(ctx context.Context, id string) (*models.Mes
 		&message.Content,
 		&message.MessageType,
 		&message.Status,
+		&message.Language,
 		&message.CreatedAt,
 		&message.UpdatedAt,
 		&senderName,
@@ -82,7 +86,7 @@ func (r *MessageRepository) GetByID(ctx context.Context, id string) (*models.Mes
 func (r *MessageRepository) GetByConversationID(ctx context.Context, conversationID string, limit, offset int) ([]*models.Message, error) {
 	query := `
 		SELECT m.id, m.conversation_id, m.sender_id, m.content, m.message_type, 
-		       m.status, m.created_at, m.updated_at,
+		       m.status, m.language, m.created_at, m.updated_at,
 		       u.name as sender_name, u.profile_image as sender_image
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
@@ -109,6 +113,7 @@ func (r *MessageRepository) GetByConversationID(ctx context.Context, conversatio
 			&message.Content,
 			&message.MessageType,
 			&message.Status,
+			&message.Language,
 			&message.CreatedAt,
 			&message.UpdatedAt,
 			&senderName,
@@ -154,6 +159,15 @@ func (r *MessageRepository) UpdateStatus(ctx context.Context, messageID string,
 	return nil
 }
 
+// UpdateLanguage records the language a message was detected to be written in
+func (r *MessageRepository) UpdateLanguage(ctx context.Context, messageID, language string) error {
+	query := `UPDATE messages SET language = $2 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, messageID, language); err != nil {
+		return fmt.Errorf("failed to update message language: %w", err)
+	}
+	return nil
+}
+
 func (r *MessageRepository) MarkAsRead(ctx context.Context, conversationID, userID string) error {
 	query := `
 		UPDATE messages 
@@ -169,7 +183,7 @@ func (r *MessageRepository) MarkAsRead(ctx context.Context, conversationID, user
 func (r *MessageRepository) GetLastMessage(ctx context.Context, conversationID string) (*models.Message, error) {
 	query := `
 		SELECT m.id, m.conversation_id, m.sender_id, m.content, m.message_type, 
-		       m.status, m.created_at, m.updated_at,
+		       m.status, m.language, m.created_at, m.updated_at,
 		       u.name as sender_name, u.profile_image as sender_image
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
@@ -188,6 +202,7 @@ func (r *MessageRepository) GetLastMessage(ctx context.Context, conversationID s
 		&message.Content,
 		&message.MessageType,
 		&message.Status,
+		&message.Language,
 		&message.CreatedAt,
 		&message.UpdatedAt,
 		&senderName,
@@ -229,4 +244,53 @@ func (r *MessageRepository) Delete(ctx context.Context, messageID string) error
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// GetTranslations returns the stored translations into language of the given
+// messages, keyed by message ID. Messages without one are left out.
+func (r *MessageRepository) GetTranslations(ctx context.Context, messageIDs []string, language string) (map[string]*models.MessageTranslation, error) {
+	query := `
+		SELECT id, translations -> $2
+		FROM messages
+		WHERE id = ANY($1) AND translations ? $2`
+	
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(messageIDs), language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	translations := make(map[string]*models.MessageTranslation)
+	for rows.Next() {
+		var messageID string
+		var data []byte
+		if err := rows.Scan(&messageID, &data); err != nil {
+			return nil, err
+		}
+		
+		translation := &models.MessageTranslation{}
+		if err := json.Unmarshal(data, translation); err != nil {
+			return nil, fmt.Errorf("failed to decode translation of message %s: %w", messageID, err)
+		}
+		translations[messageID] = translation
+	}
+	
+	return translations, rows.Err()
+}
+
+// SaveTranslation stores a translation on the message row, replacing any
+// earlier translation into the same language
+func (r *MessageRepository) SaveTranslation(ctx context.Context, messageID string, translation *models.MessageTranslation) error {
+	data, err := json.Marshal(translation)
+	if err != nil {
+		return fmt.Errorf("failed to encode translation: %w", err)
+	}
+	
+	query := `
+		UPDATE messages
+		SET translations = jsonb_set(translations, ARRAY[$2], $3::jsonb)
+		WHERE id = $1`
+	
+	_, err = r.db.ExecContext(ctx, query, messageID, translation.Language, string(data))
+	return err
+}
@@ -23,8 +23,8 @@ func NewPostRepository(db *sqlx.DB) repository.PostRepository {
 
 func (r *postRepository) Create(ctx context.Context, post *models.Post) error {
 	query := `
-		INSERT INTO posts (user_id, title, content, category, category_emoji, asking_for)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO posts (user_id, title, content, category, category_emoji, asking_for, language)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, cursor_id, created_at, updated_at`
 
 	err := r.db.QueryRowContext(ctx, query,
@@ -34,6 +34,7 @@ func (r *postRepository) Create(ctx context.Context, post *models.Post) error {
 		post.Category,
 		post.CategoryEmoji,
 		post.AskingFor,
+		post.Language,
 	).Scan(&post.ID, &post.CursorID, &post.CreatedAt, &post.UpdatedAt)
 
 	return err
@@ -43,7 +44,7 @@ func (r *postRepository) GetByID(ctx context.Context, id string) (*models.Post,
 	post := &models.Post{}
 	query := `
 		SELECT id, user_id, title, content, category, category_emoji, asking_for,
-		       comment_count, reaction_count, cursor_id, created_at, updated_at
+		       comment_count, reaction_count, cursor_id, language, created_at, updated_at
 		FROM posts
 		WHERE id = $1`
 
@@ -60,7 +61,7 @@ func (r *postRepository) GetByIDWithDetails(ctx context.Context, id, currentUser
 	query := `
 		SELECT 
 			p.id, p.user_id, p.title, p.content, p.category, p.category_emoji, p.asking_for,
-			p.comment_count, p.reaction_count, p.cursor_id, p.created_at, p.updated_at,
+			p.comment_count, p.reaction_count, p.cursor_id, p.language, p.created_at, p.updated_at,
 			u.id as "user.id", u.name as "user.name", u.email as "user.email",
 			u.profile_image as "user.profile_image", u.city as "user.city", 
 			u.country as "user.country", u.native_languages as "user.native_languages",
@@ -127,7 +128,7 @@ func (r *postRepository) Update(ctx context.Context, post *models.Post) error {
 	query := `
 		UPDATE posts
 		SET title = $2, content = $3, category = $4, category_emoji = $5, 
-		    asking_for = $6, updated_at = NOW()
+		    asking_for = $6, language = $7, updated_at = NOW()
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -137,6 +138,7 @@ func (r *postRepository) Update(ctx context.Context, post *models.Post) error {
 		post.Category,
 		post.CategoryEmoji,
 		post.AskingFor,
+		post.Language,
 	)
 
 	if err != nil {
@@ -155,6 +157,18 @@ func (r *postRepository) Update(ctx context.Context, post *models.Post) error {
 	return nil
 }
 
+// UpdateLanguage records the language post was detected to be written in. A
+// post edited since keeps the language detected for its new text.
+func (r *postRepository) UpdateLanguage(ctx context.Context, post *models.Post, language string) error {
+	query := `
+		UPDATE posts SET language = $4
+		WHERE id = $1 AND title = $2 AND content = $3`
+	if _, err := r.db.ExecContext(ctx, query, post.ID, post.Title, post.Content, language); err != nil {
+		return fmt.Errorf("failed to update post language: %w", err)
+	}
+	return nil
+}
+
 func (r *postRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM posts WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -183,7 +197,7 @@ func (r *postRepository) List(ctx context.Context, filters models.PostFilters) (
 	query := `
 		SELECT 
 			p.id, p.user_id, p.title, p.content, p.category, p.category_emoji, p.asking_for,
-			p.comment_count, p.reaction_count, p.cursor_id, p.created_at, p.updated_at
+			p.comment_count, p.reaction_count, p.cursor_id, p.language, p.created_at, p.updated_at
 		FROM posts p
 		WHERE 1=1`
 
@@ -200,6 +214,12 @@ func (r *postRepository) List(ctx context.Context, filters models.PostFilters) (
 		args = append(args, filters.Category)
 	}
 
+	if filters.Language != "" {
+		argCount++
+		query += fmt.Sprintf(" AND p.language = $%d", argCount)
+		args = append(args, filters.Language)
+	}
+
 	if filters.SearchQuery != "" {
 		argCount++
 		query += fmt.Sprintf(" AND to_tsvector('english', p.title || ' ' || p.content) @@ plainto_tsquery('english', $%d)", argCount)
@@ -3,11 +3,15 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"language-exchange/internal/database"
 	"language-exchange/internal/models"
 	"language-exchange/internal/repository"
+
+	"github.com/lib/pq"
 )
 
 type sessionRepository struct {
@@ -21,14 +25,12 @@ func NewSessionRepository(db *database.DB) repository.SessionRepository {
 // Session management
 func (r *sessionRepository) CreateSession(ctx context.Context, session *models.LanguageSession) error {
 	query := `
-		INSERT INTO language_sessions (id, name, description, created_by, invited_user_id, max_participants, session_type, target_language)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO language_sessions (id, name, description, created_by, invited_user_id, status, max_participants, session_type, target_language,
+			scheduled_start_at, scheduled_end_at, timezone, started_at, series_id, occurrence_start)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING created_at, updated_at`
 	
-	err := r.db.QueryRowContext(ctx, query,
-		session.ID, session.Name, session.Description, session.CreatedBy, session.InvitedUserID,
-		session.MaxParticipants, session.SessionType, session.TargetLanguage,
-	).Scan(&session.CreatedAt, &session.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, sessionInsertArgs(session)...).Scan(&session.CreatedAt, &session.UpdatedAt)
 	
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
@@ -45,17 +47,56 @@ func (r *sessionRepository) CreateSession(ctx context.Context, session *models.L
 	return r.AddParticipant(ctx, participant)
 }
 
+// CreateSeriesOccurrence creates the session for an occurrence of a series.
+// It returns false if the occurrence already has a session.
+func (r *sessionRepository) CreateSeriesOccurrence(ctx context.Context, session *models.LanguageSession) (bool, error) {
+	query := `
+		INSERT INTO language_sessions (id, name, description, created_by, invited_user_id, status, max_participants, session_type, target_language,
+			scheduled_start_at, scheduled_end_at, timezone, started_at, series_id, occurrence_start)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (series_id, occurrence_start) DO NOTHING
+		RETURNING created_at, updated_at`
+	
+	err := r.db.QueryRowContext(ctx, query, sessionInsertArgs(session)...).Scan(&session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create series occurrence: %w", err)
+	}
+	
+	participant := &models.SessionParticipant{
+		SessionID: session.ID,
+		UserID:    session.CreatedBy,
+		Role:      models.RoleCreator,
+		IsActive:  true,
+	}
+	
+	return true, r.AddParticipant(ctx, participant)
+}
+
+func sessionInsertArgs(session *models.LanguageSession) []interface{} {
+	return []interface{}{
+		session.ID, session.Name, session.Description, session.CreatedBy, session.InvitedUserID, session.Status,
+		session.MaxParticipants, session.SessionType, session.TargetLanguage,
+		session.ScheduledStartAt, session.ScheduledEndAt, session.Timezone, session.StartedAt,
+		session.SeriesID, session.OccurrenceStart,
+	}
+}
+
 func (r *sessionRepository) GetSessionByID(ctx context.Context, sessionID string) (*models.LanguageSession, error) {
 	query := `
 		SELECT s.id, s.name, s.description, s.created_by, s.invited_user_id, s.status, s.max_participants,
 			   s.session_type, s.target_language, s.created_at, s.ended_at, s.updated_at,
+			   s.scheduled_start_at, s.scheduled_end_at, s.timezone, s.started_at, s.reminder_sent_at,
+			   s.series_id, s.occurrence_start,
 			   u.name as creator_name, u.email as creator_email,
 			   iu.name as invited_user_name, iu.email as invited_user_email,
 			   COUNT(DISTINCT sp.id) as participant_count
 		FROM language_sessions s
 		LEFT JOIN users u ON s.created_by = u.id
 		LEFT JOIN users iu ON s.invited_user_id = iu.id
-		LEFT JOIN session_participants sp ON s.id = sp.session_id AND sp.is_active = true
+		LEFT JOIN session_participants sp ON s.id = sp.session_id AND sp.is_active = true AND sp.role <> 'observer'
 		WHERE s.id = $1
 		GROUP BY s.id, u.name, u.email, iu.name, iu.email`
 	
@@ -66,6 +107,8 @@ func (r *sessionRepository) GetSessionByID(ctx context.Context, sessionID string
 		&session.ID, &session.Name, &session.Description, &session.CreatedBy, &session.InvitedUserID,
 		&session.Status, &session.MaxParticipants, &session.SessionType,
 		&session.TargetLanguage, &session.CreatedAt, &session.EndedAt, &session.UpdatedAt,
+		&session.ScheduledStartAt, &session.ScheduledEndAt, &session.Timezone, &session.StartedAt, &session.ReminderSentAt,
+		&session.SeriesID, &session.OccurrenceStart,
 		&creatorName, &creatorEmail, &invitedUserName, &invitedUserEmail, &session.ParticipantCount,
 	)
 	
@@ -101,18 +144,21 @@ func (r *sessionRepository) GetSessionsByUser(ctx context.Context, userID string
 	query := `
 		SELECT DISTINCT s.id, s.name, s.description, s.created_by, s.status, s.max_participants,
 			   s.session_type, s.target_language, s.created_at, s.ended_at, s.updated_at,
+			   s.scheduled_start_at, s.scheduled_end_at, s.timezone, s.started_at,
 			   u.name as creator_name,
 			   COUNT(DISTINCT sp.id) as participant_count
 		FROM language_sessions s
 		LEFT JOIN users u ON s.created_by = u.id
 		LEFT JOIN session_participants sp ON s.id = sp.session_id AND sp.is_active = true
-		WHERE s.created_by = $1 OR s.id IN (
+		WHERE s.status != $2 AND (s.created_by = $1 OR s.id IN (
 			SELECT session_id FROM session_participants WHERE user_id = $1
-		)
+		) OR s.id IN (
+			SELECT session_id FROM session_invitations WHERE user_id = $1 AND status != 'declined'
+		))
 		GROUP BY s.id, u.name
 		ORDER BY s.updated_at DESC`
 	
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, models.SessionStatusCancelled)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user sessions: %w", err)
 	}
@@ -127,6 +173,7 @@ func (r *sessionRepository) GetSessionsByUser(ctx context.Context, userID string
 			&session.ID, &session.Name, &session.Description, &session.CreatedBy,
 			&session.Status, &session.MaxParticipants, &session.SessionType,
 			&session.TargetLanguage, &session.CreatedAt, &session.EndedAt, &session.UpdatedAt,
+			&session.ScheduledStartAt, &session.ScheduledEndAt, &session.Timezone, &session.StartedAt,
 			&creatorName, &session.ParticipantCount,
 		)
 		if err != nil {
@@ -215,12 +262,14 @@ func (r *sessionRepository) UpdateSessionStatus(ctx context.Context, sessionID s
 }
 
 func (r *sessionRepository) EndSession(ctx context.Context, sessionID string) error {
+	// Only one caller may end a session, so XP is settled once when the
+	// creator and the idle reaper race
 	query := `
 		UPDATE language_sessions 
 		SET status = $1, ended_at = NOW(), updated_at = NOW() 
-		WHERE id = $2`
+		WHERE id = $2 AND status NOT IN ($1, $3)`
 	
-	result, err := r.db.ExecContext(ctx, query, models.SessionStatusEnded, sessionID)
+	result, err := r.db.ExecContext(ctx, query, models.SessionStatusEnded, sessionID, models.SessionStatusCancelled)
 	if err != nil {
 		return fmt.Errorf("failed to end session: %w", err)
 	}
@@ -231,6 +280,13 @@ func (r *sessionRepository) EndSession(ctx context.Context, sessionID string) er
 	}
 	
 	if rowsAffected == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM language_sessions WHERE id = $1)`, sessionID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check session: %w", err)
+		}
+		if exists {
+			return models.ErrSessionEnded
+		}
 		return models.ErrSessionNotFound
 	}
 	
@@ -242,6 +298,536 @@ func (r *sessionRepository) EndSession(ctx context.Context, sessionID string) er
 		return fmt.Errorf("failed to update participants: %w", err)
 	}
 	
+	// Attendance stops counting when the session ends
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE session_connections SET disconnected_at = NOW() WHERE session_id = $1 AND disconnected_at IS NULL`,
+		sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to close session connections: %w", err)
+	}
+	
+	return nil
+}
+
+// CancelSession calls off a scheduled session that has not started. It
+// returns ErrOccurrenceStarted if the session has started in the meantime.
+func (r *sessionRepository) CancelSession(ctx context.Context, sessionID string) error {
+	query := `
+		UPDATE language_sessions
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3`
+	
+	result, err := r.db.ExecContext(ctx, query, models.SessionStatusCancelled, sessionID, models.SessionStatusScheduled)
+	if err != nil {
+		return fmt.Errorf("failed to cancel session: %w", err)
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.ErrOccurrenceStarted
+	}
+	
+	return nil
+}
+
+// ReopenSession schedules a cancelled session again
+func (r *sessionRepository) ReopenSession(ctx context.Context, sessionID string) error {
+	query := `
+		UPDATE language_sessions
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3`
+	
+	_, err := r.db.ExecContext(ctx, query, models.SessionStatusScheduled, sessionID, models.SessionStatusCancelled)
+	if err != nil {
+		return fmt.Errorf("failed to reopen session: %w", err)
+	}
+	
+	return nil
+}
+
+// Scheduling
+
+// StartSession moves a scheduled session to active. It is a no-op if the
+// session has already been started.
+func (r *sessionRepository) StartSession(ctx context.Context, sessionID string) error {
+	query := `
+		UPDATE language_sessions
+		SET status = $1, started_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status = $3`
+	
+	_, err := r.db.ExecContext(ctx, query, models.SessionStatusActive, sessionID, models.SessionStatusScheduled)
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	
+	return nil
+}
+
+const scheduledSessionColumns = `
+	s.id, s.name, s.description, s.created_by, s.invited_user_id, s.status, s.max_participants,
+	s.session_type, s.target_language, s.created_at, s.ended_at, s.updated_at,
+	s.scheduled_start_at, s.scheduled_end_at, s.timezone, s.started_at, s.reminder_sent_at,
+	s.series_id, s.occurrence_start`
+
+func (r *sessionRepository) queryScheduledSessions(ctx context.Context, query string, args ...interface{}) ([]*models.LanguageSession, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled sessions: %w", err)
+	}
+	defer rows.Close()
+	
+	var sessions []*models.LanguageSession
+	for rows.Next() {
+		session := &models.LanguageSession{}
+		err := rows.Scan(
+			&session.ID, &session.Name, &session.Description, &session.CreatedBy, &session.InvitedUserID,
+			&session.Status, &session.MaxParticipants, &session.SessionType,
+			&session.TargetLanguage, &session.CreatedAt, &session.EndedAt, &session.UpdatedAt,
+			&session.ScheduledStartAt, &session.ScheduledEndAt, &session.Timezone, &session.StartedAt, &session.ReminderSentAt,
+			&session.SeriesID, &session.OccurrenceStart,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		
+		sessions = append(sessions, session)
+	}
+	
+	return sessions, rows.Err()
+}
+
+// GetUpcomingSessionsByUser returns sessions with a schedule that end after
+// from and that the user created or has not declined
+func (r *sessionRepository) GetUpcomingSessionsByUser(ctx context.Context, userID string, from time.Time) ([]*models.LanguageSession, error) {
+	query := `
+		SELECT ` + scheduledSessionColumns + `
+		FROM language_sessions s
+		WHERE s.scheduled_start_at IS NOT NULL
+		  AND s.status NOT IN ($2, $5)
+		  AND COALESCE(s.scheduled_end_at, s.scheduled_start_at) >= $3
+		  AND (s.created_by = $1 OR s.id IN (
+			SELECT session_id FROM session_invitations WHERE user_id = $1 AND status != $4
+		  ))
+		ORDER BY s.scheduled_start_at ASC`
+	
+	return r.queryScheduledSessions(ctx, query, userID, models.SessionStatusEnded, from, models.InvitationStatusDeclined, models.SessionStatusCancelled)
+}
+
+// GetSessionsDueForReminder returns scheduled sessions starting before the
+// given time that have not had a reminder sent
+func (r *sessionRepository) GetSessionsDueForReminder(ctx context.Context, before time.Time) ([]*models.LanguageSession, error) {
+	query := `
+		SELECT ` + scheduledSessionColumns + `
+		FROM language_sessions s
+		WHERE s.status = $1 AND s.reminder_sent_at IS NULL
+		  AND s.scheduled_start_at IS NOT NULL AND s.scheduled_start_at <= $2
+		ORDER BY s.scheduled_start_at ASC`
+	
+	return r.queryScheduledSessions(ctx, query, models.SessionStatusScheduled, before)
+}
+
+// MarkReminderSent records that a session's reminder was sent. It returns
+// false if another server already claimed the reminder.
+func (r *sessionRepository) MarkReminderSent(ctx context.Context, sessionID string) (bool, error) {
+	query := `UPDATE language_sessions SET reminder_sent_at = NOW() WHERE id = $1 AND reminder_sent_at IS NULL`
+	
+	result, err := r.db.ExecContext(ctx, query, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reminder sent: %w", err)
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	
+	return rowsAffected > 0, nil
+}
+
+// EndExpiredScheduledSessions ends scheduled sessions that were never started
+// and whose scheduled end has passed
+func (r *sessionRepository) EndExpiredScheduledSessions(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		UPDATE language_sessions
+		SET status = $1, ended_at = NOW(), updated_at = NOW()
+		WHERE status = $2 AND scheduled_end_at IS NOT NULL AND scheduled_end_at < $3`
+	
+	result, err := r.db.ExecContext(ctx, query, models.SessionStatusEnded, models.SessionStatusScheduled, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to end expired sessions: %w", err)
+	}
+	
+	return result.RowsAffected()
+}
+
+// Invitations
+func (r *sessionRepository) CreateInvitation(ctx context.Context, invitation *models.SessionInvitation) error {
+	query := `
+		INSERT INTO session_invitations (id, session_id, user_id, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at`
+	
+	err := r.db.QueryRowContext(ctx, query,
+		invitation.ID, invitation.SessionID, invitation.UserID, invitation.Status,
+	).Scan(&invitation.CreatedAt, &invitation.UpdatedAt)
+	
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	
+	return nil
+}
+
+func (r *sessionRepository) GetSessionInvitations(ctx context.Context, sessionID string) ([]*models.SessionInvitation, error) {
+	query := `
+		SELECT si.id, si.session_id, si.user_id, si.status, si.responded_at, si.created_at, si.updated_at,
+			   u.name, u.email, u.timezone
+		FROM session_invitations si
+		LEFT JOIN users u ON si.user_id = u.id
+		WHERE si.session_id = $1
+		ORDER BY si.created_at ASC`
+	
+	rows, err := r.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session invitations: %w", err)
+	}
+	defer rows.Close()
+	
+	var invitations []*models.SessionInvitation
+	for rows.Next() {
+		invitation := &models.SessionInvitation{}
+		var userName, userEmail, userTimezone sql.NullString
+		
+		err := rows.Scan(
+			&invitation.ID, &invitation.SessionID, &invitation.UserID, &invitation.Status,
+			&invitation.RespondedAt, &invitation.CreatedAt, &invitation.UpdatedAt,
+			&userName, &userEmail, &userTimezone,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		
+		if userName.Valid {
+			invitation.User = &models.User{
+				ID:    invitation.UserID,
+				Name:  userName.String,
+				Email: userEmail.String,
+			}
+			if userTimezone.Valid {
+				invitation.User.Timezone = &userTimezone.String
+			}
+		}
+		
+		invitations = append(invitations, invitation)
+	}
+	
+	return invitations, rows.Err()
+}
+
+func (r *sessionRepository) UpdateInvitationStatus(ctx context.Context, sessionID, userID, status string) (*models.SessionInvitation, error) {
+	query := `
+		UPDATE session_invitations
+		SET status = $1, responded_at = NOW()
+		WHERE session_id = $2 AND user_id = $3
+		RETURNING id, session_id, user_id, status, responded_at, created_at, updated_at`
+	
+	invitation := &models.SessionInvitation{}
+	err := r.db.QueryRowContext(ctx, query, status, sessionID, userID).Scan(
+		&invitation.ID, &invitation.SessionID, &invitation.UserID, &invitation.Status,
+		&invitation.RespondedAt, &invitation.CreatedAt, &invitation.UpdatedAt,
+	)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to update invitation: %w", err)
+	}
+	
+	return invitation, nil
+}
+
+// Session series
+func (r *sessionRepository) CreateSeries(ctx context.Context, series *models.SessionSeries) error {
+	query := `
+		INSERT INTO session_series (id, created_by, name, description, max_participants, session_type, target_language,
+			invitee_ids, timezone, starts_at, duration_minutes, rrule, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at, updated_at`
+	
+	err := r.db.QueryRowContext(ctx, query, seriesInsertArgs(series)...).Scan(&series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session series: %w", err)
+	}
+	
+	return nil
+}
+
+func seriesInsertArgs(series *models.SessionSeries) []interface{} {
+	return []interface{}{
+		series.ID, series.CreatedBy, series.Name, series.Description, series.MaxParticipants, series.SessionType,
+		series.TargetLanguage, pq.Array(series.InviteeIDs), series.Timezone, series.StartsAt,
+		series.DurationMinutes, series.RRule, series.Status,
+	}
+}
+
+const seriesColumns = `
+	id, created_by, name, description, max_participants, session_type, target_language,
+	invitee_ids, timezone, starts_at, duration_minutes, rrule, status, materialized_until,
+	created_at, updated_at`
+
+func scanSeries(scanner interface{ Scan(...interface{}) error }) (*models.SessionSeries, error) {
+	series := &models.SessionSeries{}
+	err := scanner.Scan(
+		&series.ID, &series.CreatedBy, &series.Name, &series.Description, &series.MaxParticipants,
+		&series.SessionType, &series.TargetLanguage, pq.Array(&series.InviteeIDs), &series.Timezone,
+		&series.StartsAt, &series.DurationMinutes, &series.RRule, &series.Status, &series.MaterializedUntil,
+		&series.CreatedAt, &series.UpdatedAt,
+	)
+	return series, err
+}
+
+func (r *sessionRepository) GetSeriesByID(ctx context.Context, seriesID string) (*models.SessionSeries, error) {
+	query := `SELECT ` + seriesColumns + ` FROM session_series WHERE id = $1`
+	
+	series, err := scanSeries(r.db.QueryRowContext(ctx, query, seriesID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSeriesNotFound
+		}
+		return nil, fmt.Errorf("failed to get session series: %w", err)
+	}
+	
+	return series, nil
+}
+
+// GetSeriesToMaterialize returns active series whose sessions have not been
+// created up to the given time
+func (r *sessionRepository) GetSeriesToMaterialize(ctx context.Context, until time.Time) ([]*models.SessionSeries, error) {
+	query := `
+		SELECT ` + seriesColumns + `
+		FROM session_series
+		WHERE status = $1 AND (materialized_until IS NULL OR materialized_until < $2)
+		ORDER BY starts_at ASC`
+	
+	rows, err := r.db.QueryContext(ctx, query, models.SeriesStatusActive, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series to materialize: %w", err)
+	}
+	defer rows.Close()
+	
+	var seriesList []*models.SessionSeries
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session series: %w", err)
+		}
+		seriesList = append(seriesList, series)
+	}
+	
+	return seriesList, rows.Err()
+}
+
+func (r *sessionRepository) UpdateSeries(ctx context.Context, series *models.SessionSeries) error {
+	query := `
+		UPDATE session_series
+		SET name = $1, description = $2, starts_at = $3, duration_minutes = $4, rrule = $5, status = $6,
+			materialized_until = $7
+		WHERE id = $8
+		RETURNING updated_at`
+	
+	err := r.db.QueryRowContext(ctx, query,
+		series.Name, series.Description, series.StartsAt, series.DurationMinutes, series.RRule, series.Status,
+		series.MaterializedUntil, series.ID,
+	).Scan(&series.UpdatedAt)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrSeriesNotFound
+		}
+		return fmt.Errorf("failed to update session series: %w", err)
+	}
+	
+	return nil
+}
+
+// MarkSeriesMaterialized records that sessions exist for the series up to
+// until, ending the series if it has no later occurrences
+func (r *sessionRepository) MarkSeriesMaterialized(ctx context.Context, seriesID string, until time.Time, ended bool) error {
+	status := models.SeriesStatusActive
+	if ended {
+		status = models.SeriesStatusEnded
+	}
+	
+	query := `
+		UPDATE session_series
+		SET materialized_until = GREATEST(COALESCE(materialized_until, $1), $1), status = $2
+		WHERE id = $3 AND status = $4`
+	
+	_, err := r.db.ExecContext(ctx, query, until, status, seriesID, models.SeriesStatusActive)
+	if err != nil {
+		return fmt.Errorf("failed to mark series materialized: %w", err)
+	}
+	
+	return nil
+}
+
+// SplitSeries ends series before splitAt and continues it as next. When the
+// occurrence times are unchanged (keepSchedule), the exceptions and unstarted
+// sessions from splitAt on move to next; otherwise they are removed and next
+// creates new sessions.
+func (r *sessionRepository) SplitSeries(ctx context.Context, series, next *models.SessionSeries, splitAt time.Time, keepSchedule bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	_, err = tx.ExecContext(ctx,
+		`UPDATE session_series SET rrule = $1, status = $2 WHERE id = $3`,
+		series.RRule, series.Status, series.ID)
+	if err != nil {
+		return fmt.Errorf("failed to end session series: %w", err)
+	}
+	
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO session_series (id, created_by, name, description, max_participants, session_type, target_language,
+			invitee_ids, timezone, starts_at, duration_minutes, rrule, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		seriesInsertArgs(next)...)
+	if err != nil {
+		return fmt.Errorf("failed to create session series: %w", err)
+	}
+	
+	if keepSchedule {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE session_series_exceptions SET series_id = $3 WHERE series_id = $1 AND occurrence_start >= $2`,
+			series.ID, splitAt, next.ID)
+		if err != nil {
+			return fmt.Errorf("failed to move series exceptions: %w", err)
+		}
+		
+		_, err = tx.ExecContext(ctx,
+			`UPDATE language_sessions SET series_id = $3 WHERE series_id = $1 AND occurrence_start >= $2 AND status IN ($4, $5)`,
+			series.ID, splitAt, next.ID, models.SessionStatusScheduled, models.SessionStatusCancelled)
+		if err != nil {
+			return fmt.Errorf("failed to move future series sessions: %w", err)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM session_series_exceptions WHERE series_id = $1 AND occurrence_start >= $2`,
+			series.ID, splitAt)
+		if err != nil {
+			return fmt.Errorf("failed to remove series exceptions: %w", err)
+		}
+		
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM language_sessions WHERE series_id = $1 AND occurrence_start >= $2 AND status IN ($3, $4)`,
+			series.ID, splitAt, models.SessionStatusScheduled, models.SessionStatusCancelled)
+		if err != nil {
+			return fmt.Errorf("failed to remove future series sessions: %w", err)
+		}
+	}
+	
+	return tx.Commit()
+}
+
+func (r *sessionRepository) GetSeriesExceptions(ctx context.Context, seriesID string) ([]*models.SessionSeriesException, error) {
+	query := `
+		SELECT id, series_id, occurrence_start, cancelled, name, description, starts_at, ends_at, created_at, updated_at
+		FROM session_series_exceptions
+		WHERE series_id = $1
+		ORDER BY occurrence_start ASC`
+	
+	rows, err := r.db.QueryContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series exceptions: %w", err)
+	}
+	defer rows.Close()
+	
+	var exceptions []*models.SessionSeriesException
+	for rows.Next() {
+		exception := &models.SessionSeriesException{}
+		err := rows.Scan(
+			&exception.ID, &exception.SeriesID, &exception.OccurrenceStart, &exception.Cancelled,
+			&exception.Name, &exception.Description, &exception.StartsAt, &exception.EndsAt,
+			&exception.CreatedAt, &exception.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan series exception: %w", err)
+		}
+		exceptions = append(exceptions, exception)
+	}
+	
+	return exceptions, rows.Err()
+}
+
+// SaveSeriesException creates or replaces the exception for an occurrence
+func (r *sessionRepository) SaveSeriesException(ctx context.Context, exception *models.SessionSeriesException) error {
+	query := `
+		INSERT INTO session_series_exceptions (id, series_id, occurrence_start, cancelled, name, description, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (series_id, occurrence_start) DO UPDATE
+		SET cancelled = EXCLUDED.cancelled, name = EXCLUDED.name, description = EXCLUDED.description,
+			starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at
+		RETURNING id, created_at, updated_at`
+	
+	err := r.db.QueryRowContext(ctx, query,
+		exception.ID, exception.SeriesID, exception.OccurrenceStart, exception.Cancelled,
+		exception.Name, exception.Description, exception.StartsAt, exception.EndsAt,
+	).Scan(&exception.ID, &exception.CreatedAt, &exception.UpdatedAt)
+	
+	if err != nil {
+		return fmt.Errorf("failed to save series exception: %w", err)
+	}
+	
+	return nil
+}
+
+// GetSeriesSessions returns the sessions created for a series' occurrences
+// from the given original start time on
+func (r *sessionRepository) GetSeriesSessions(ctx context.Context, seriesID string, from time.Time) ([]*models.LanguageSession, error) {
+	query := `
+		SELECT ` + scheduledSessionColumns + `
+		FROM language_sessions s
+		WHERE s.series_id = $1 AND s.occurrence_start >= $2
+		ORDER BY s.occurrence_start ASC`
+	
+	return r.queryScheduledSessions(ctx, query, seriesID, from)
+}
+
+// GetUpcomingSeriesSessions returns a series' sessions that have not started
+// by after, going by their scheduled start rather than the occurrence they
+// were created for, so rescheduled occurrences are included
+func (r *sessionRepository) GetUpcomingSeriesSessions(ctx context.Context, seriesID string, after time.Time) ([]*models.LanguageSession, error) {
+	query := `
+		SELECT ` + scheduledSessionColumns + `
+		FROM language_sessions s
+		WHERE s.series_id = $1 AND s.status = $2 AND s.scheduled_start_at >= $3
+		ORDER BY s.scheduled_start_at ASC`
+	
+	return r.queryScheduledSessions(ctx, query, seriesID, models.SessionStatusScheduled, after)
+}
+
+// UpdateSessionSchedule changes the name, description and times of a session
+// that has not started yet, so that its reminder is sent again
+func (r *sessionRepository) UpdateSessionSchedule(ctx context.Context, session *models.LanguageSession) error {
+	query := `
+		UPDATE language_sessions
+		SET name = $1, description = $2, scheduled_start_at = $3, scheduled_end_at = $4,
+			reminder_sent_at = NULL, updated_at = NOW()
+		WHERE id = $5 AND status = $6`
+	
+	_, err := r.db.ExecContext(ctx, query,
+		session.Name, session.Description, session.ScheduledStartAt, session.ScheduledEndAt,
+		session.ID, models.SessionStatusScheduled)
+	if err != nil {
+		return fmt.Errorf("failed to update session schedule: %w", err)
+	}
+	
 	return nil
 }
 
@@ -251,7 +837,7 @@ func (r *sessionRepository) AddParticipant(ctx context.Context, participant *mod
 		INSERT INTO session_participants (session_id, user_id, role, is_active)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (session_id, user_id) 
-		DO UPDATE SET is_active = true, joined_at = NOW()
+		DO UPDATE SET is_active = true, left_at = NULL, role = EXCLUDED.role
 		RETURNING id, joined_at`
 	
 	err := r.db.QueryRowContext(ctx, query,
@@ -374,6 +960,27 @@ func (r *sessionRepository) GetActiveParticipants(ctx context.Context, sessionID
 	return participants, rows.Err()
 }
 
+func (r *sessionRepository) GetParticipant(ctx context.Context, sessionID, userID string) (*models.SessionParticipant, error) {
+	query := `
+		SELECT id, session_id, user_id, joined_at, left_at, role, is_active
+		FROM session_participants
+		WHERE session_id = $1 AND user_id = $2`
+	
+	participant := &models.SessionParticipant{}
+	err := r.db.QueryRowContext(ctx, query, sessionID, userID).Scan(
+		&participant.ID, &participant.SessionID, &participant.UserID,
+		&participant.JoinedAt, &participant.LeftAt, &participant.Role, &participant.IsActive,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrParticipantNotFound
+		}
+		return nil, fmt.Errorf("failed to get participant: %w", err)
+	}
+	
+	return participant, nil
+}
+
 func (r *sessionRepository) IsUserInSession(ctx context.Context, sessionID, userID string) (bool, error) {
 	query := `
 		SELECT EXISTS(
@@ -414,28 +1021,103 @@ func (r *sessionRepository) UpdateParticipantStatus(ctx context.Context, session
 }
 
 // Canvas operations
-func (r *sessionRepository) SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation) error {
+
+// SaveCanvasOperation saves an operation as the next canvas version of its
+// session. It returns models.ErrCanvasVersionConflict if the session's canvas
+// is no longer at expectedVersion.
+func (r *sessionRepository) SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation, expectedVersion int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	versionQuery := `
+		UPDATE language_sessions
+		SET canvas_version = canvas_version + 1
+		WHERE id = $1 AND canvas_version = $2
+		RETURNING canvas_version`
+	
+	err = tx.QueryRowContext(ctx, versionQuery, operation.SessionID, expectedVersion).Scan(&operation.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrCanvasVersionConflict
+		}
+		return fmt.Errorf("failed to advance canvas version: %w", err)
+	}
+	
+	var previousElement interface{}
+	if len(operation.PreviousElement) > 0 {
+		previousElement = []byte(operation.PreviousElement)
+	}
+	
 	query := `
-		INSERT INTO canvas_operations (id, session_id, user_id, operation_type, operation_data)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO canvas_operations (id, session_id, user_id, operation_type, operation_data,
+			version, base_version, element_id, previous_element, undo_of, redo_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING sequence_number, timestamp`
 	
-	err := r.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		operation.ID, operation.SessionID, operation.UserID,
 		operation.OperationType, operation.OperationData,
+		operation.Version, operation.BaseVersion, operation.ElementID,
+		previousElement, operation.UndoOf, operation.RedoOf,
 	).Scan(&operation.SequenceNumber, &operation.Timestamp)
 	
 	if err != nil {
 		return fmt.Errorf("failed to save canvas operation: %w", err)
 	}
 	
-	return nil
+	return tx.Commit()
+}
+
+const canvasOperationColumns = `
+	co.id, co.session_id, co.user_id, co.operation_type, co.operation_data,
+	co.sequence_number, co.timestamp, co.version, co.base_version, co.element_id,
+	co.previous_element, co.undo_of, co.redo_of, u.name`
+
+func scanCanvasOperation(rows *sql.Rows) (*models.CanvasOperation, error) {
+	operation := &models.CanvasOperation{}
+	var userName, elementID, undoOf, redoOf sql.NullString
+	var previousElement []byte
+	
+	err := rows.Scan(
+		&operation.ID, &operation.SessionID, &operation.UserID,
+		&operation.OperationType, &operation.OperationData,
+		&operation.SequenceNumber, &operation.Timestamp,
+		&operation.Version, &operation.BaseVersion, &elementID,
+		&previousElement, &undoOf, &redoOf, &userName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan canvas operation: %w", err)
+	}
+	
+	if elementID.Valid {
+		operation.ElementID = &elementID.String
+	}
+	if undoOf.Valid {
+		operation.UndoOf = &undoOf.String
+	}
+	if redoOf.Valid {
+		operation.RedoOf = &redoOf.String
+	}
+	if len(previousElement) > 0 {
+		operation.PreviousElement = previousElement
+	}
+	
+	if userName.Valid {
+		operation.User = &models.User{
+			ID:   operation.UserID,
+			Name: userName.String,
+		}
+	}
+	
+	return operation, nil
 }
 
 func (r *sessionRepository) GetCanvasOperations(ctx context.Context, sessionID string, fromSequence int64) ([]*models.CanvasOperation, error) {
 	query := `
-		SELECT co.id, co.session_id, co.user_id, co.operation_type, co.operation_data,
-			   co.sequence_number, co.timestamp, u.name
+		SELECT ` + canvasOperationColumns + `
 		FROM canvas_operations co
 		LEFT JOIN users u ON co.user_id = u.id
 		WHERE co.session_id = $1 AND co.sequence_number > $2
@@ -449,23 +1131,9 @@ func (r *sessionRepository) GetCanvasOperations(ctx context.Context, sessionID s
 	
 	var operations []*models.CanvasOperation
 	for rows.Next() {
-		operation := &models.CanvasOperation{}
-		var userName sql.NullString
-		
-		err := rows.Scan(
-			&operation.ID, &operation.SessionID, &operation.UserID,
-			&operation.OperationType, &operation.OperationData,
-			&operation.SequenceNumber, &operation.Timestamp, &userName,
-		)
+		operation, err := scanCanvasOperation(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan canvas operation: %w", err)
-		}
-		
-		if userName.Valid {
-			operation.User = &models.User{
-				ID:   operation.UserID,
-				Name: userName.String,
-			}
+			return nil, err
 		}
 		
 		operations = append(operations, operation)
@@ -476,8 +1144,7 @@ func (r *sessionRepository) GetCanvasOperations(ctx context.Context, sessionID s
 
 func (r *sessionRepository) GetLatestCanvasOperations(ctx context.Context, sessionID string, limit int) ([]*models.CanvasOperation, error) {
 	query := `
-		SELECT co.id, co.session_id, co.user_id, co.operation_type, co.operation_data,
-			   co.sequence_number, co.timestamp, u.name
+		SELECT ` + canvasOperationColumns + `
 		FROM canvas_operations co
 		LEFT JOIN users u ON co.user_id = u.id
 		WHERE co.session_id = $1
@@ -492,23 +1159,9 @@ func (r *sessionRepository) GetLatestCanvasOperations(ctx context.Context, sessi
 	
 	var operations []*models.CanvasOperation
 	for rows.Next() {
-		operation := &models.CanvasOperation{}
-		var userName sql.NullString
-		
-		err := rows.Scan(
-			&operation.ID, &operation.SessionID, &operation.UserID,
-			&operation.OperationType, &operation.OperationData,
-			&operation.SequenceNumber, &operation.Timestamp, &userName,
-		)
+		operation, err := scanCanvasOperation(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan canvas operation: %w", err)
-		}
-		
-		if userName.Valid {
-			operation.User = &models.User{
-				ID:   operation.UserID,
-				Name: userName.String,
-			}
+			return nil, err
 		}
 		
 		operations = append(operations, operation)
@@ -533,16 +1186,109 @@ func (r *sessionRepository) ClearCanvasOperations(ctx context.Context, sessionID
 	return nil
 }
 
+func (r *sessionRepository) CountCanvasOperations(ctx context.Context, sessionID string, fromSequence int64) (int, error) {
+	query := `SELECT COUNT(*) FROM canvas_operations WHERE session_id = $1 AND sequence_number > $2`
+	
+	var count int
+	err := r.db.QueryRowContext(ctx, query, sessionID, fromSequence).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count canvas operations: %w", err)
+	}
+	
+	return count, nil
+}
+
+// Canvas snapshots
+
+// GetLatestCanvasSnapshot returns the session's newest snapshot, or nil if none exists
+func (r *sessionRepository) GetLatestCanvasSnapshot(ctx context.Context, sessionID string) (*models.CanvasSnapshot, error) {
+	query := `
+		SELECT id, session_id, sequence_number, state, operation_count, created_at
+		FROM canvas_snapshots
+		WHERE session_id = $1
+		ORDER BY sequence_number DESC
+		LIMIT 1`
+	
+	snapshot := &models.CanvasSnapshot{}
+	var state []byte
+	err := r.db.QueryRowContext(ctx, query, sessionID).Scan(
+		&snapshot.ID, &snapshot.SessionID, &snapshot.SequenceNumber,
+		&state, &snapshot.OperationCount, &snapshot.CreatedAt,
+	)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get canvas snapshot: %w", err)
+	}
+	
+	if err := json.Unmarshal(state, &snapshot.State); err != nil {
+		return nil, fmt.Errorf("failed to decode canvas snapshot: %w", err)
+	}
+	
+	return snapshot, nil
+}
+
+// SaveCanvasSnapshot stores a snapshot and compacts away the operations and
+// older snapshots it supersedes
+func (r *sessionRepository) SaveCanvasSnapshot(ctx context.Context, snapshot *models.CanvasSnapshot) error {
+	state, err := json.Marshal(snapshot.State)
+	if err != nil {
+		return fmt.Errorf("failed to encode canvas snapshot: %w", err)
+	}
+	
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	query := `
+		INSERT INTO canvas_snapshots (id, session_id, sequence_number, state, operation_count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (session_id, sequence_number) DO NOTHING
+		RETURNING created_at`
+	
+	err = tx.QueryRowContext(ctx, query,
+		snapshot.ID, snapshot.SessionID, snapshot.SequenceNumber, state, snapshot.OperationCount,
+	).Scan(&snapshot.CreatedAt)
+	
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Another request already snapshotted this sequence number
+			return nil
+		}
+		return fmt.Errorf("failed to save canvas snapshot: %w", err)
+	}
+	
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM canvas_operations WHERE session_id = $1 AND sequence_number <= $2`,
+		snapshot.SessionID, snapshot.SequenceNumber)
+	if err != nil {
+		return fmt.Errorf("failed to compact canvas operations: %w", err)
+	}
+	
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM canvas_snapshots WHERE session_id = $1 AND sequence_number < $2`,
+		snapshot.SessionID, snapshot.SequenceNumber)
+	if err != nil {
+		return fmt.Errorf("failed to remove old canvas snapshots: %w", err)
+	}
+	
+	return tx.Commit()
+}
+
 // Session messages
 func (r *sessionRepository) SaveMessage(ctx context.Context, message *models.SessionMessage) error {
 	query := `
-		INSERT INTO session_messages (id, session_id, user_id, message_text, message_type)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO session_messages (id, session_id, user_id, message_text, message_type, language)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING timestamp`
 	
 	err := r.db.QueryRowContext(ctx, query,
 		message.ID, message.SessionID, message.UserID,
-		message.Content, message.MessageType,
+		message.Content, message.MessageType, message.Language,
 	).Scan(&message.CreatedAt)
 	
 	if err != nil {
@@ -552,9 +1298,19 @@ func (r *sessionRepository) SaveMessage(ctx context.Context, message *models.Ses
 	return nil
 }
 
+// UpdateMessageLanguage records the language a chat message was detected to
+// be written in
+func (r *sessionRepository) UpdateMessageLanguage(ctx context.Context, messageID, language string) error {
+	query := `UPDATE session_messages SET language = $2 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, messageID, language); err != nil {
+		return fmt.Errorf("failed to update message language: %w", err)
+	}
+	return nil
+}
+
 func (r *sessionRepository) GetMessages(ctx context.Context, sessionID string, limit int, offset int) ([]*models.SessionMessage, error) {
 	query := `
-		SELECT sm.id, sm.session_id, sm.user_id, sm.message_text, sm.message_type, sm.timestamp,
+		SELECT sm.id, sm.session_id, sm.user_id, sm.message_text, sm.message_type, sm.language, sm.timestamp,
 			   u.name
 		FROM session_messages sm
 		LEFT JOIN users u ON sm.user_id = u.id
@@ -575,7 +1331,7 @@ func (r *sessionRepository) GetMessages(ctx context.Context, sessionID string, l
 		
 		err := rows.Scan(
 			&message.ID, &message.SessionID, &message.UserID,
-			&message.Content, &message.MessageType, &message.CreatedAt,
+			&message.Content, &message.MessageType, &message.Language, &message.CreatedAt,
 			&userName,
 		)
 		if err != nil {
@@ -602,4 +1358,182 @@ func (r *sessionRepository) GetMessages(ctx context.Context, sessionID string, l
 
 func (r *sessionRepository) GetLatestMessages(ctx context.Context, sessionID string, limit int) ([]*models.SessionMessage, error) {
 	return r.GetMessages(ctx, sessionID, limit, 0)
-}
\ No newline at end of file
+}
+
+// Session room connections
+// OpenSessionConnection records a connection and marks the participant, if
+// any, as present again
+func (r *sessionRepository) OpenSessionConnection(ctx context.Context, connection *models.SessionConnection) error {
+	if connection.Source == "" {
+		connection.Source = models.SessionConnectionSourceWebSocket
+	}
+	
+	query := `
+		INSERT INTO session_connections (id, session_id, user_id, source, node_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING connected_at, last_seen_at`
+	
+	err := r.db.QueryRowContext(ctx, query,
+		connection.ID, connection.SessionID, connection.UserID, connection.Source, connection.NodeID,
+	).Scan(&connection.ConnectedAt, &connection.LastSeenAt)
+	
+	if err != nil {
+		return fmt.Errorf("failed to open session connection: %w", err)
+	}
+	
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE session_participants SET left_at = NULL WHERE session_id = $1 AND user_id = $2`,
+		connection.SessionID, connection.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update participant: %w", err)
+	}
+	
+	return nil
+}
+
+// CloseSessionConnection records the end of a connection and the
+// participant's latest leave time
+func (r *sessionRepository) CloseSessionConnection(ctx context.Context, connectionID string) error {
+	query := `
+		UPDATE session_connections
+		SET disconnected_at = NOW(), last_seen_at = NOW()
+		WHERE id = $1 AND disconnected_at IS NULL
+		RETURNING session_id, user_id`
+	
+	var sessionID, userID string
+	err := r.db.QueryRowContext(ctx, query, connectionID).Scan(&sessionID, &userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close session connection: %w", err)
+	}
+	
+	return r.markParticipantLeft(ctx, sessionID, userID)
+}
+
+// CloseUserSessionConnections ends a user's open connections of one source
+func (r *sessionRepository) CloseUserSessionConnections(ctx context.Context, sessionID, userID, source string) error {
+	query := `
+		UPDATE session_connections
+		SET disconnected_at = NOW(), last_seen_at = NOW()
+		WHERE session_id = $1 AND user_id = $2 AND source = $3 AND disconnected_at IS NULL`
+	
+	if _, err := r.db.ExecContext(ctx, query, sessionID, userID, source); err != nil {
+		return fmt.Errorf("failed to close session connections: %w", err)
+	}
+	
+	return r.markParticipantLeft(ctx, sessionID, userID)
+}
+
+// markParticipantLeft sets the participant's leave time once none of their
+// connections are open
+func (r *sessionRepository) markParticipantLeft(ctx context.Context, sessionID, userID string) error {
+	query := `
+		UPDATE session_participants
+		SET left_at = NOW()
+		WHERE session_id = $1 AND user_id = $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM session_connections
+			WHERE session_id = $1 AND user_id = $2 AND disconnected_at IS NULL
+		  )`
+	
+	if _, err := r.db.ExecContext(ctx, query, sessionID, userID); err != nil {
+		return fmt.Errorf("failed to update participant: %w", err)
+	}
+	
+	return nil
+}
+
+// RefreshSessionConnections marks a node's open connections as still alive
+func (r *sessionRepository) RefreshSessionConnections(ctx context.Context, nodeID string) error {
+	query := `
+		UPDATE session_connections
+		SET last_seen_at = NOW()
+		WHERE node_id = $1 AND disconnected_at IS NULL`
+	
+	if _, err := r.db.ExecContext(ctx, query, nodeID); err != nil {
+		return fmt.Errorf("failed to refresh session connections: %w", err)
+	}
+	
+	return nil
+}
+
+func (r *sessionRepository) GetSessionConnections(ctx context.Context, sessionID string) ([]*models.SessionConnection, error) {
+	query := `
+		SELECT id, session_id, user_id, source, node_id, connected_at, last_seen_at, disconnected_at
+		FROM session_connections
+		WHERE session_id = $1
+		ORDER BY connected_at ASC`
+	
+	var connections []*models.SessionConnection
+	if err := r.db.SelectContext(ctx, &connections, query, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session connections: %w", err)
+	}
+	
+	return connections, nil
+}
+
+// GetIdleSessionIDs returns active sessions that have had no live WebSocket
+// connection since idleSince. Open connections last refreshed before
+// staleBefore belong to a node that went away and count as closed at their
+// last refresh. Open REST intervals do not keep a session alive.
+func (r *sessionRepository) GetIdleSessionIDs(ctx context.Context, idleSince, staleBefore time.Time) ([]string, error) {
+	query := `
+		SELECT s.id
+		FROM language_sessions s
+		WHERE s.status = $1
+		  AND COALESCE(s.started_at, s.created_at) < $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM session_connections c
+			WHERE c.session_id = s.id AND c.source = $4
+			  AND (c.disconnected_at IS NULL AND c.last_seen_at >= $3
+			       OR COALESCE(c.disconnected_at, c.last_seen_at) >= $2)
+		  )`
+	
+	var sessionIDs []string
+	if err := r.db.SelectContext(ctx, &sessionIDs, query, models.SessionStatusActive, idleSince, staleBefore, models.SessionConnectionSourceWebSocket); err != nil {
+		return nil, fmt.Errorf("failed to get idle sessions: %w", err)
+	}
+	
+	return sessionIDs, nil
+}
+
+// GetSessionMessageTotals counts each user's chat messages and characters in
+// a session, excluding system messages
+func (r *sessionRepository) GetSessionMessageTotals(ctx context.Context, sessionID string) ([]*models.SessionMessageTotals, error) {
+	query := `
+		SELECT user_id, COUNT(*) AS messages, COALESCE(SUM(LENGTH(message_text)), 0) AS characters
+		FROM session_messages
+		WHERE session_id = $1 AND message_type <> 'system'
+		GROUP BY user_id`
+	
+	var totals []*models.SessionMessageTotals
+	if err := r.db.SelectContext(ctx, &totals, query, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session message totals: %w", err)
+	}
+	
+	return totals, nil
+}
+
+// CountConnectedUsers counts the users other than excludeUserID with a live
+// WebSocket connection to the session, not counting observers
+func (r *sessionRepository) CountConnectedUsers(ctx context.Context, sessionID, excludeUserID string, staleBefore time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT c.user_id)
+		FROM session_connections c
+		JOIN session_participants sp ON sp.session_id = c.session_id AND sp.user_id = c.user_id
+		WHERE c.session_id = $1 AND c.user_id <> $2 AND c.source = $3
+		  AND c.disconnected_at IS NULL AND c.last_seen_at >= $4
+		  AND sp.role <> $5`
+	
+	var count int
+	err := r.db.QueryRowContext(ctx, query,
+		sessionID, excludeUserID, models.SessionConnectionSourceWebSocket, staleBefore, models.RoleObserver,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count connected users: %w", err)
+	}
+	
+	return count, nil
+}
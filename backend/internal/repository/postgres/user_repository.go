@@ -364,3 +364,43 @@ func (r *userRepository) GetTotalCount(ctx context.Context) (int, error) {
 	}
 	return count, nil
 }
+
+func (r *userRepository) GetCalendarFeedToken(ctx context.Context, userID string) (string, error) {
+	var token sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT calendar_feed_token FROM users WHERE id = $1`, userID).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", models.ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get calendar feed token: %w", err)
+	}
+	return token.String, nil
+}
+
+func (r *userRepository) EnsureCalendarFeedToken(ctx context.Context, userID, token string) (string, error) {
+	query := `
+		UPDATE users SET calendar_feed_token = COALESCE(calendar_feed_token, $2)
+		WHERE id = $1
+		RETURNING calendar_feed_token`
+
+	var stored string
+	err := r.db.QueryRowContext(ctx, query, userID, token).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return "", models.ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save calendar feed token: %w", err)
+	}
+	return stored, nil
+}
+
+func (r *userRepository) SetCalendarFeedToken(ctx context.Context, userID, token string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET calendar_feed_token = $2 WHERE id = $1`, userID, token)
+	if err != nil {
+		return fmt.Errorf("failed to save calendar feed token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type vocabularyRepository struct {
+	db *sqlx.DB
+}
+
+func NewVocabularyRepository(db *sqlx.DB) repository.VocabularyRepository {
+	return &vocabularyRepository{db: db}
+}
+
+const vocabularyColumns = `id, user_id, term, translation, source_language, target_language,
+	example_sentence, origin_type, origin_id, lookup_count, ease_factor, interval_days, repetitions,
+	lapses, due_at, last_reviewed_at, learned_at, created_at, updated_at`
+
+func (r *vocabularyRepository) Create(ctx context.Context, item *models.VocabularyItem) error {
+	query := `
+		INSERT INTO vocabulary_items (user_id, term, translation, source_language, target_language,
+			example_sentence, origin_type, origin_id, lookup_count, ease_factor, due_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (user_id, lower(term), source_language, target_language) DO NOTHING
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		item.UserID,
+		item.Term,
+		item.Translation,
+		item.SourceLanguage,
+		item.TargetLanguage,
+		item.ExampleSentence,
+		item.OriginType,
+		item.OriginID,
+		item.LookupCount,
+		item.EaseFactor,
+		item.DueAt,
+	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		// The word is already saved (due to ON CONFLICT)
+		return models.ErrDuplicateVocabulary
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create vocabulary item: %w", err)
+	}
+	return nil
+}
+
+// SaveLookup saves a translated word, or records another lookup of a word
+// that is already saved: the word gets harder and is due for review now.
+// Translations edited by the user are kept.
+func (r *vocabularyRepository) SaveLookup(ctx context.Context, item *models.VocabularyItem) error {
+	query := `
+		INSERT INTO vocabulary_items (user_id, term, translation, source_language, target_language,
+			example_sentence, origin_type, origin_id, ease_factor, due_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, lower(term), source_language, target_language) DO UPDATE SET
+			lookup_count = vocabulary_items.lookup_count + 1,
+			ease_factor = GREATEST(vocabulary_items.ease_factor - $11, $12),
+			due_at = LEAST(vocabulary_items.due_at, EXCLUDED.due_at),
+			example_sentence = COALESCE(vocabulary_items.example_sentence, EXCLUDED.example_sentence),
+			origin_type = COALESCE(vocabulary_items.origin_type, EXCLUDED.origin_type),
+			origin_id = CASE WHEN vocabulary_items.origin_type IS NULL THEN EXCLUDED.origin_id ELSE vocabulary_items.origin_id END,
+			updated_at = NOW()
+		RETURNING ` + vocabularyColumns
+
+	err := r.db.QueryRowxContext(ctx, query,
+		item.UserID,
+		item.Term,
+		item.Translation,
+		item.SourceLanguage,
+		item.TargetLanguage,
+		item.ExampleSentence,
+		item.OriginType,
+		item.OriginID,
+		item.EaseFactor,
+		item.DueAt,
+		models.VocabularyLookupPenalty,
+		models.VocabularyMinimumEase,
+	).StructScan(item)
+	if err != nil {
+		return fmt.Errorf("failed to save vocabulary lookup: %w", err)
+	}
+	return nil
+}
+
+func (r *vocabularyRepository) GetByID(ctx context.Context, userID, itemID string) (*models.VocabularyItem, error) {
+	var item models.VocabularyItem
+	query := `SELECT ` + vocabularyColumns + ` FROM vocabulary_items WHERE id = $1 AND user_id = $2`
+	err := r.db.GetContext(ctx, &item, query, itemID, userID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrVocabularyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vocabulary item: %w", err)
+	}
+	return &item, nil
+}
+
+func (r *vocabularyRepository) List(ctx context.Context, userID string, filters models.VocabularyFilters) ([]*models.VocabularyItem, error) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{userID}
+
+	if filters.SourceLanguage != "" {
+		args = append(args, filters.SourceLanguage)
+		conditions = append(conditions, fmt.Sprintf("source_language = $%d", len(args)))
+	}
+	if filters.TargetLanguage != "" {
+		args = append(args, filters.TargetLanguage)
+		conditions = append(conditions, fmt.Sprintf("target_language = $%d", len(args)))
+	}
+	if filters.Search != "" {
+		args = append(args, "%"+filters.Search+"%")
+		conditions = append(conditions, fmt.Sprintf("(term ILIKE $%d OR translation ILIKE $%d)", len(args), len(args)))
+	}
+
+	// The review queue is ordered by how overdue each word is
+	order := "created_at DESC"
+	if filters.DueOnly {
+		conditions = append(conditions, "due_at <= NOW()")
+		order = "due_at ASC"
+	}
+
+	args = append(args, filters.Limit, filters.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s FROM vocabulary_items
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`,
+		vocabularyColumns, strings.Join(conditions, " AND "), order, len(args)-1, len(args))
+
+	items := []*models.VocabularyItem{}
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list vocabulary: %w", err)
+	}
+	return items, nil
+}
+
+func (r *vocabularyRepository) CountDue(ctx context.Context, userID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM vocabulary_items WHERE user_id = $1 AND due_at <= NOW()`
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count due vocabulary: %w", err)
+	}
+	return count, nil
+}
+
+func (r *vocabularyRepository) Update(ctx context.Context, item *models.VocabularyItem) error {
+	query := `
+		UPDATE vocabulary_items
+		SET term = $3, translation = $4, example_sentence = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		item.ID, item.UserID, item.Term, item.Translation, item.ExampleSentence,
+	).Scan(&item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrVocabularyNotFound
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return models.ErrDuplicateVocabulary
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update vocabulary item: %w", err)
+	}
+	return nil
+}
+
+func (r *vocabularyRepository) Delete(ctx context.Context, userID, itemID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM vocabulary_items WHERE id = $1 AND user_id = $2`, itemID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete vocabulary item: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrVocabularyNotFound
+	}
+	return nil
+}
+
+// SaveReview stores the item's new schedule and logs the review. The update
+// only applies if the item was last reviewed at previousReview, so concurrent
+// reviews of the same card cannot both count. When the review learned the
+// item, its term is recorded as learned and SaveReview reports whether the
+// user had not learned the term before, e.g. in an item since deleted.
+func (r *vocabularyRepository) SaveReview(ctx context.Context, item *models.VocabularyItem, quality int, previousReview *time.Time, learned bool) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE vocabulary_items
+		SET ease_factor = $3, interval_days = $4, repetitions = $5, lapses = $6,
+			due_at = $7, last_reviewed_at = $8, learned_at = $9, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND last_reviewed_at IS NOT DISTINCT FROM $10
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		item.ID,
+		item.UserID,
+		item.EaseFactor,
+		item.IntervalDays,
+		item.Repetitions,
+		item.Lapses,
+		item.DueAt,
+		item.LastReviewedAt,
+		item.LearnedAt,
+		previousReview,
+	).Scan(&item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, models.ErrVocabularyReviewConflict
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save vocabulary review: %w", err)
+	}
+
+	reviewQuery := `
+		INSERT INTO vocabulary_reviews (item_id, user_id, quality, ease_factor, interval_days, reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.ExecContext(ctx, reviewQuery,
+		item.ID, item.UserID, quality, item.EaseFactor, item.IntervalDays, item.LastReviewedAt,
+	); err != nil {
+		return false, fmt.Errorf("failed to log vocabulary review: %w", err)
+	}
+
+	firstTime := false
+	if learned {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO vocabulary_learned_terms (user_id, term, source_language, target_language, learned_at)
+			VALUES ($1, lower($2), $3, $4, $5)
+			ON CONFLICT DO NOTHING`,
+			item.UserID, item.Term, item.SourceLanguage, item.TargetLanguage, item.LearnedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to record learned term: %w", err)
+		}
+		rows, _ := result.RowsAffected()
+		firstTime = rows > 0
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return firstTime, nil
+}
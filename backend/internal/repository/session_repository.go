@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"time"
+
 	"language-exchange/internal/models"
 )
 
@@ -13,23 +15,68 @@ type SessionRepository interface {
 	GetActiveSessions(ctx context.Context, limit int) ([]*models.LanguageSession, error)
 	UpdateSessionStatus(ctx context.Context, sessionID string, status string) error
 	EndSession(ctx context.Context, sessionID string) error
+	CancelSession(ctx context.Context, sessionID string) error
+	ReopenSession(ctx context.Context, sessionID string) error
+	
+	// Scheduling
+	StartSession(ctx context.Context, sessionID string) error
+	GetUpcomingSessionsByUser(ctx context.Context, userID string, from time.Time) ([]*models.LanguageSession, error)
+	GetSessionsDueForReminder(ctx context.Context, before time.Time) ([]*models.LanguageSession, error)
+	MarkReminderSent(ctx context.Context, sessionID string) (bool, error)
+	EndExpiredScheduledSessions(ctx context.Context, before time.Time) (int64, error)
+	
+	// Session series
+	CreateSeries(ctx context.Context, series *models.SessionSeries) error
+	GetSeriesByID(ctx context.Context, seriesID string) (*models.SessionSeries, error)
+	GetSeriesToMaterialize(ctx context.Context, until time.Time) ([]*models.SessionSeries, error)
+	UpdateSeries(ctx context.Context, series *models.SessionSeries) error
+	MarkSeriesMaterialized(ctx context.Context, seriesID string, until time.Time, ended bool) error
+	SplitSeries(ctx context.Context, series, next *models.SessionSeries, splitAt time.Time, keepSchedule bool) error
+	GetSeriesExceptions(ctx context.Context, seriesID string) ([]*models.SessionSeriesException, error)
+	SaveSeriesException(ctx context.Context, exception *models.SessionSeriesException) error
+	CreateSeriesOccurrence(ctx context.Context, session *models.LanguageSession) (bool, error)
+	GetSeriesSessions(ctx context.Context, seriesID string, from time.Time) ([]*models.LanguageSession, error)
+	GetUpcomingSeriesSessions(ctx context.Context, seriesID string, after time.Time) ([]*models.LanguageSession, error)
+	UpdateSessionSchedule(ctx context.Context, session *models.LanguageSession) error
+	
+	// Invitations
+	CreateInvitation(ctx context.Context, invitation *models.SessionInvitation) error
+	GetSessionInvitations(ctx context.Context, sessionID string) ([]*models.SessionInvitation, error)
+	UpdateInvitationStatus(ctx context.Context, sessionID, userID, status string) (*models.SessionInvitation, error)
 	
 	// Participant management
 	AddParticipant(ctx context.Context, participant *models.SessionParticipant) error
 	RemoveParticipant(ctx context.Context, sessionID, userID string) error
 	GetSessionParticipants(ctx context.Context, sessionID string) ([]*models.SessionParticipant, error)
 	GetActiveParticipants(ctx context.Context, sessionID string) ([]*models.SessionParticipant, error)
+	GetParticipant(ctx context.Context, sessionID, userID string) (*models.SessionParticipant, error)
 	IsUserInSession(ctx context.Context, sessionID, userID string) (bool, error)
 	UpdateParticipantStatus(ctx context.Context, sessionID, userID string, isActive bool) error
 	
+	// Session room connections
+	OpenSessionConnection(ctx context.Context, connection *models.SessionConnection) error
+	CloseSessionConnection(ctx context.Context, connectionID string) error
+	CloseUserSessionConnections(ctx context.Context, sessionID, userID, source string) error
+	RefreshSessionConnections(ctx context.Context, nodeID string) error
+	GetSessionConnections(ctx context.Context, sessionID string) ([]*models.SessionConnection, error)
+	GetIdleSessionIDs(ctx context.Context, idleSince, staleBefore time.Time) ([]string, error)
+	CountConnectedUsers(ctx context.Context, sessionID, excludeUserID string, staleBefore time.Time) (int, error)
+	
 	// Canvas operations
-	SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation) error
+	SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation, expectedVersion int64) error
 	GetCanvasOperations(ctx context.Context, sessionID string, fromSequence int64) ([]*models.CanvasOperation, error)
 	GetLatestCanvasOperations(ctx context.Context, sessionID string, limit int) ([]*models.CanvasOperation, error)
 	ClearCanvasOperations(ctx context.Context, sessionID string) error
+	CountCanvasOperations(ctx context.Context, sessionID string, fromSequence int64) (int, error)
+	
+	// Canvas snapshots
+	GetLatestCanvasSnapshot(ctx context.Context, sessionID string) (*models.CanvasSnapshot, error)
+	SaveCanvasSnapshot(ctx context.Context, snapshot *models.CanvasSnapshot) error
 	
 	// Session messages
 	SaveMessage(ctx context.Context, message *models.SessionMessage) error
+	UpdateMessageLanguage(ctx context.Context, messageID, language string) error
 	GetMessages(ctx context.Context, sessionID string, limit int, offset int) ([]*models.SessionMessage, error)
 	GetLatestMessages(ctx context.Context, sessionID string, limit int) ([]*models.SessionMessage, error)
+	GetSessionMessageTotals(ctx context.Context, sessionID string) ([]*models.SessionMessageTotals, error)
 }
\ No newline at end of file
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// ProviderOpenAICompatible is the name of the OpenAI-compatible LLM provider
+const ProviderOpenAICompatible = "openai-compatible"
+
+// LLMProvider generates chat completions with a large language model
+type LLMProvider interface {
+	Name() string
+	Model() string
+	Complete(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions) (string, error)
+	// Stream generates a completion, passing each piece of text to onToken as
+	// it arrives, and returns the whole text. It stops with onToken's error
+	// if onToken fails.
+	Stream(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions, onToken func(string) error) (string, error)
+}
+
+// openAICompatibleProvider talks to any server implementing OpenAI's chat
+// completions API, such as OpenAI itself, a llama.cpp server or Ollama
+type openAICompatibleProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+// NewOpenAICompatibleProvider creates an LLM provider for an OpenAI-compatible
+// API. baseURL includes the version prefix, for example
+// http://localhost:11434/v1 for Ollama. apiKey may be empty for local servers.
+// timeout bounds a whole completion, but only the wait for the response to
+// start when streaming, so long generations are not cut off mid-stream.
+func NewOpenAICompatibleProvider(baseURL, apiKey, model string, timeout time.Duration) LLMProvider {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	return &openAICompatibleProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		timeout:    timeout,
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+func (p *openAICompatibleProvider) Name() string {
+	return ProviderOpenAICompatible
+}
+
+func (p *openAICompatibleProvider) Model() string {
+	return p.model
+}
+
+func (p *openAICompatibleProvider) newRequest(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions, stream bool) (*http.Request, error) {
+	chatRequest := models.OpenAIChatRequest{
+		Model:       p.model,
+		Messages:    messages,
+		Temperature: options.Temperature,
+		Stream:      stream,
+	}
+	if options.JSON {
+		chatRequest.ResponseFormat = &models.OpenAIResponseFormat{Type: "json_object"}
+	}
+
+	requestBody, err := json.Marshal(chatRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	return req, nil
+}
+
+func (p *openAICompatibleProvider) Complete(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions) (string, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	req, err := p.newRequest(ctx, messages, options, false)
+	if err != nil {
+		return "", err
+	}
+
+	var chatResponse models.OpenAIChatResponse
+	if err := doProviderRequest(p.httpClient, req, &chatResponse); err != nil {
+		return "", err
+	}
+	if len(chatResponse.Choices) == 0 {
+		return "", fmt.Errorf("completion API returned no choices")
+	}
+
+	return chatResponse.Choices[0].Message.Content, nil
+}
+
+func (p *openAICompatibleProvider) Stream(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions, onToken func(string) error) (string, error) {
+	req, err := p.newRequest(ctx, messages, options, true)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("provider API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // blank separators, comments and other SSE fields
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return content.String(), nil
+		}
+
+		var chunk models.OpenAIChatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		token := chunk.Choices[0].Delta.Content
+		content.WriteString(token)
+		if err := onToken(token); err != nil {
+			return "", err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	// Some servers close the stream without a [DONE] event
+	if content.Len() == 0 {
+		return "", fmt.Errorf("completion stream ended without content")
+	}
+	return content.String(), nil
+}
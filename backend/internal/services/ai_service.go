@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"language-exchange/internal/cache"
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+)
+
+// defaultExplanationLanguage is used when the user has no native language set
+const defaultExplanationLanguage = "English"
+
+// improvementTemperature keeps corrections close to the user's wording
+const improvementTemperature = 0.2
+
+// improvementStreamMarker separates the improved message from the
+// corrections in a streamed improvement, so the message can be streamed to
+// the user as plain text
+const improvementStreamMarker = "<<<CORRECTIONS>>>"
+
+const (
+	improvementJSONFormat = `Respond with a JSON object only, in this form:
+{"improved": "<the improved message>", "corrections": [` + correctionJSONFormat + `]}`
+
+	improvementStreamFormat = `First write the improved message and nothing else. Then write a line containing only ` + improvementStreamMarker + `, followed by a JSON object in this form:
+{"corrections": [` + correctionJSONFormat + `]}`
+
+	correctionJSONFormat = `{"original": "<exact text from the message>", "replacement": "<what it became>", "category": "grammar|spelling|style|register", "cefr": "A1|A2|B1|B2|C1|C2", "explanation": "<short explanation>"}`
+)
+
+type aiService struct {
+	provider         LLMProvider
+	userRepo         repository.UserRepository
+	matchRepo        repository.MatchRepository
+	conversationRepo repository.ConversationRepository
+	cache            cache.Cache
+	keys             *cache.CacheKeyBuilder
+}
+
+// NewAIService creates an AI service backed by provider. A nil provider
+// leaves AI features unavailable, except for those with a non-AI fallback.
+// AI conversation starters are cached in startersCache when it is not nil.
+func NewAIService(
+	provider LLMProvider,
+	userRepo repository.UserRepository,
+	matchRepo repository.MatchRepository,
+	conversationRepo repository.ConversationRepository,
+	startersCache cache.Cache,
+) AIService {
+	return &aiService{
+		provider:         provider,
+		userRepo:         userRepo,
+		matchRepo:        matchRepo,
+		conversationRepo: conversationRepo,
+		cache:            startersCache,
+		keys:             cache.NewCacheKeyBuilder(),
+	}
+}
+
+// improvementResponse is the JSON object the model is asked to return
+type improvementResponse struct {
+	Improved    string            `json:"improved"`
+	Corrections []modelCorrection `json:"corrections"`
+}
+
+// modelCorrection is a correction as described by the model. It is only used
+// to label the changes of the server-side diff, never returned as is.
+type modelCorrection struct {
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+	Category    string `json:"category"`
+	Explanation string `json:"explanation"`
+	CEFR        string `json:"cefr"`
+	span        models.TextSpan
+}
+
+// ImproveMessage corrects a message written by a language learner and
+// explains each correction in the learner's own language. The corrections
+// are a token-level diff between the message and the improved text, labeled
+// with the model's explanations where it gave one.
+func (s *aiService) ImproveMessage(ctx context.Context, input models.ImproveMessageInput) (*models.MessageImprovement, error) {
+	text, err := s.improvementText(input)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.provider.Complete(ctx, improvementPrompt(text, input, improvementJSONFormat), models.CompletionOptions{
+		Temperature: improvementTemperature,
+		JSON:        true,
+	})
+	if err != nil {
+		log.Printf("AI improvement with %s failed: %v", s.provider.Name(), err)
+		return nil, models.ErrAIUnavailable
+	}
+
+	response, err := parseImprovement(content)
+	if err != nil {
+		log.Printf("AI improvement with %s returned an unusable response: %v", s.provider.Name(), err)
+		return nil, models.ErrAIInvalidResponse
+	}
+
+	return s.newImprovement(text, response), nil
+}
+
+// StreamImprovement works like ImproveMessage but passes the improved
+// message to onToken piece by piece while the model writes it. The
+// corrections are only known once the stream has finished.
+func (s *aiService) StreamImprovement(ctx context.Context, input models.ImproveMessageInput, onToken func(string) error) (*models.MessageImprovement, error) {
+	text, err := s.improvementText(input)
+	if err != nil {
+		return nil, err
+	}
+
+	splitter := &markerSplitter{marker: improvementStreamMarker, emit: onToken}
+	content, err := s.provider.Stream(ctx, improvementPrompt(text, input, improvementStreamFormat), models.CompletionOptions{
+		Temperature: improvementTemperature,
+	}, splitter.write)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("AI improvement stream with %s failed: %v", s.provider.Name(), err)
+		return nil, models.ErrAIUnavailable
+	}
+	// Text held back in case it began the marker is part of the message
+	// when the marker never came
+	if err := splitter.finish(); err != nil {
+		return nil, err
+	}
+
+	response, err := parseStreamedImprovement(content)
+	if err != nil {
+		log.Printf("AI improvement stream with %s returned an unusable response: %v", s.provider.Name(), err)
+		return nil, models.ErrAIInvalidResponse
+	}
+
+	return s.newImprovement(text, response), nil
+}
+
+// improvementText validates input and returns the text to improve
+func (s *aiService) improvementText(input models.ImproveMessageInput) (string, error) {
+	if s.provider == nil {
+		return "", models.ErrAIUnavailable
+	}
+
+	text := strings.TrimSpace(input.Text)
+	if text == "" {
+		return "", models.NewAppError("INVALID_INPUT", "Text is required", 400)
+	}
+	return text, nil
+}
+
+func (s *aiService) newImprovement(text string, response *improvementResponse) *models.MessageImprovement {
+	return &models.MessageImprovement{
+		Original:    text,
+		Improved:    response.Improved,
+		Corrections: buildCorrections(text, response),
+		Provider:    s.provider.Name(),
+		Model:       s.provider.Model(),
+	}
+}
+
+// markerSplitter passes streamed text to emit until marker appears. Text
+// that could be the start of the marker is held back until the next token
+// shows whether it is.
+type markerSplitter struct {
+	marker  string
+	emit    func(string) error
+	pending string
+	done    bool
+}
+
+func (m *markerSplitter) write(token string) error {
+	if m.done {
+		return nil
+	}
+
+	m.pending += token
+	if index := strings.Index(m.pending, m.marker); index >= 0 {
+		m.done = true
+		return m.flush(index)
+	}
+
+	// Hold back the longest suffix that is a prefix of the marker
+	keep := 0
+	for n := min(len(m.marker)-1, len(m.pending)); n > 0; n-- {
+		if strings.HasPrefix(m.marker, m.pending[len(m.pending)-n:]) {
+			keep = n
+			break
+		}
+	}
+	return m.flush(len(m.pending) - keep)
+}
+
+// finish emits the text still held back once the stream has ended
+func (m *markerSplitter) finish() error {
+	if m.done {
+		return nil
+	}
+	m.done = true
+	return m.flush(len(m.pending))
+}
+
+// flush emits the first n bytes of the pending text
+func (m *markerSplitter) flush(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	text := m.pending[:n]
+	m.pending = m.pending[n:]
+	return m.emit(text)
+}
+
+func improvementPrompt(text string, input models.ImproveMessageInput, format string) []models.ChatMessage {
+	explanationLanguage := input.ExplanationLanguage
+	if explanationLanguage == "" {
+		explanationLanguage = defaultExplanationLanguage
+	}
+
+	textLanguage := "the language it is written in"
+	if input.Language != "" {
+		textLanguage = input.Language
+	}
+
+	audience := "a learner"
+	if models.IsValidCEFRLevel(input.Level) {
+		audience = "a learner at CEFR level " + input.Level
+	}
+
+	system := fmt.Sprintf(`You are a friendly language tutor helping a learner write chat messages to a language exchange partner.
+Improve the learner's message so it is correct and natural in %s. Keep its meaning, tone and level of formality unless the register is wrong for a casual chat. Do not translate it.
+
+%s
+
+Rules for corrections:
+- "original" must be copied exactly from the learner's message. For a missing word or punctuation mark, include the neighbouring word.
+- List corrections in the order they appear in the message.
+- "cefr" is the CEFR level at which the rule behind the correction is usually taught.
+- Write every explanation in %s, in one or two short sentences %s can follow. Name the rule, not just the fix.
+- If the message needs no changes, return it unchanged with an empty corrections list.`, textLanguage, format, explanationLanguage, audience)
+
+	return []models.ChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: text},
+	}
+}
+
+// parseImprovement decodes the model's answer. Models that ignore the
+// requested format and answer with the rewritten text only are accepted, as
+// the corrections are computed from the diff anyway.
+func parseImprovement(content string) (*improvementResponse, error) {
+	// Models served without JSON mode sometimes wrap the object in prose or
+	// code fences
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+
+	var response improvementResponse
+	if start < 0 || end < start {
+		response.Improved = stripCodeFence(content)
+	} else if err := json.Unmarshal([]byte(content[start:end+1]), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	response.Improved = strings.TrimSpace(response.Improved)
+	if response.Improved == "" {
+		return nil, fmt.Errorf("response has no improved text: %q", content)
+	}
+
+	return &response, nil
+}
+
+// parseStreamedImprovement splits a streamed answer into the improved
+// message and the corrections that follow the marker. An answer without the
+// marker is taken as the improved message alone.
+func parseStreamedImprovement(content string) (*improvementResponse, error) {
+	improved, corrections, found := strings.Cut(content, improvementStreamMarker)
+
+	var response improvementResponse
+	if found {
+		start := strings.Index(corrections, "{")
+		end := strings.LastIndex(corrections, "}")
+		if start >= 0 && end > start {
+			// The corrections only label the diff, so a malformed list is
+			// not worth failing the improvement over
+			if err := json.Unmarshal([]byte(corrections[start:end+1]), &response); err != nil {
+				log.Printf("Ignoring unparsable streamed corrections: %v", err)
+			}
+		}
+	}
+
+	response.Improved = stripCodeFence(improved)
+	if response.Improved == "" {
+		return nil, fmt.Errorf("response has no improved text: %q", content)
+	}
+
+	return &response, nil
+}
+
+// stripCodeFence removes a Markdown code fence around text, including its
+// language tag
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+	if newline := strings.Index(text, "\n"); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(text)
+}
+
+// buildCorrections diffs text against the improved text and labels each
+// change with the model correction that covers it. Changes the model did not
+// describe get a category guessed from the change itself.
+func buildCorrections(text string, response *improvementResponse) []models.MessageCorrection {
+	// Locate the model's corrections in the original text
+	hints := make([]modelCorrection, 0, len(response.Corrections))
+	searchFrom := 0
+	for _, correction := range response.Corrections {
+		if correction.Original == "" || correction.Original == correction.Replacement {
+			continue
+		}
+		span, ok := findSpan(text, correction.Original, searchFrom)
+		if !ok {
+			continue
+		}
+		searchFrom = span.End
+		correction.span = span
+		hints = append(hints, correction)
+	}
+
+	changes := diffText(text, response.Improved)
+	corrections := make([]models.MessageCorrection, 0, len(changes))
+	for _, change := range changes {
+		correction := models.MessageCorrection{
+			Span:         change.originalSpan,
+			ImprovedSpan: change.replacementSpan,
+			Original:     change.original,
+			Replacement:  change.replacement,
+		}
+
+		if hint, ok := hintForChange(hints, change); ok {
+			correction.Category = strings.ToLower(strings.TrimSpace(hint.Category))
+			correction.Explanation = strings.TrimSpace(hint.Explanation)
+			if level := strings.ToUpper(strings.TrimSpace(hint.CEFR)); models.IsValidCEFRLevel(level) {
+				correction.CEFRLevel = level
+			}
+		}
+		if !models.IsValidCorrectionCategory(correction.Category) {
+			correction.Category = classifyChange(change)
+		}
+		if correction.Explanation == "" {
+			correction.Explanation = fallbackExplanation(change)
+		}
+
+		corrections = append(corrections, correction)
+	}
+
+	return corrections
+}
+
+// hintForChange finds the first model correction overlapping change. An
+// insertion matches a correction that contains or touches its position.
+func hintForChange(hints []modelCorrection, change textChange) (modelCorrection, bool) {
+	for _, hint := range hints {
+		if change.originalSpan.Start == change.originalSpan.End {
+			if hint.span.Start <= change.originalSpan.Start && change.originalSpan.Start <= hint.span.End {
+				return hint, true
+			}
+		} else if hint.span.Start < change.originalSpan.End && change.originalSpan.Start < hint.span.End {
+			return hint, true
+		}
+	}
+	return modelCorrection{}, false
+}
+
+// fallbackExplanation describes a change the model gave no explanation for
+func fallbackExplanation(change textChange) string {
+	original := strings.TrimSpace(change.original)
+	replacement := strings.TrimSpace(change.replacement)
+
+	switch {
+	case original == "" && replacement == "":
+		return "Adjusted spacing."
+	case original == "":
+		return fmt.Sprintf("Added %q.", replacement)
+	case replacement == "":
+		return fmt.Sprintf("Removed %q.", original)
+	default:
+		return fmt.Sprintf("Changed %q to %q.", original, replacement)
+	}
+}
+
+// findSpan locates substr in text, preferring the first match at or after
+// the character offset from so repeated words map to the right occurrence
+func findSpan(text, substr string, from int) (models.TextSpan, bool) {
+	runes := []rune(text)
+	if from > len(runes) {
+		from = len(runes)
+	}
+
+	index := strings.Index(string(runes[from:]), substr)
+	if index >= 0 {
+		index = from + len([]rune(string(runes[from:])[:index]))
+	} else if index = strings.Index(text, substr); index >= 0 {
+		index = len([]rune(text[:index]))
+	} else {
+		return models.TextSpan{}, false
+	}
+
+	return models.TextSpan{Start: index, End: index + len([]rune(substr))}, true
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// cannedLLM answers every request with content, streamed token by token
+type cannedLLM struct {
+	content string
+	tokens  []string
+}
+
+func (p *cannedLLM) Name() string  { return "canned" }
+func (p *cannedLLM) Model() string { return "canned-1" }
+
+func (p *cannedLLM) Complete(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions) (string, error) {
+	return p.content, nil
+}
+
+func (p *cannedLLM) Stream(ctx context.Context, messages []models.ChatMessage, options models.CompletionOptions, onToken func(string) error) (string, error) {
+	for _, token := range p.tokens {
+		if err := onToken(token); err != nil {
+			return "", err
+		}
+	}
+	return p.content, nil
+}
+
+func TestImproveMessageSpansReferToOriginal(t *testing.T) {
+	service := NewAIService(&cannedLLM{content: `{"improved": "I go home", "corrections": []}`}, nil, nil, nil, nil)
+
+	improvement, err := service.ImproveMessage(context.Background(), models.ImproveMessageInput{Text: "  I goes home\n"})
+	if err != nil {
+		t.Fatalf("ImproveMessage() error = %v", err)
+	}
+	if improvement.Original != "I goes home" {
+		t.Errorf("Original = %q, want the text without surrounding whitespace", improvement.Original)
+	}
+	if len(improvement.Corrections) != 1 {
+		t.Fatalf("got %d corrections, want 1", len(improvement.Corrections))
+	}
+	span := improvement.Corrections[0].Span
+	if got := string([]rune(improvement.Original)[span.Start:span.End]); got != "goes" {
+		t.Errorf("span %+v of %q covers %q, want %q", span, improvement.Original, got, "goes")
+	}
+}
+
+func TestStreamImprovementStreamsMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		want   string
+	}{
+		{"plain message", []string{"I go ", "home"}, "I go home"},
+		{"ends like the marker", []string{"I go home <", "<"}, "I go home <<"},
+		{"marker", []string{"I go home", "\n<<<CORR", "ECTIONS>>>", `{"corrections": []}`}, "I go home\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &cannedLLM{content: strings.Join(tt.tokens, ""), tokens: tt.tokens}
+			service := NewAIService(provider, nil, nil, nil, nil)
+
+			var streamed strings.Builder
+			_, err := service.StreamImprovement(context.Background(), models.ImproveMessageInput{Text: "I goes home"}, func(token string) error {
+				streamed.WriteString(token)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamImprovement() error = %v", err)
+			}
+			if streamed.String() != tt.want {
+				t.Errorf("streamed %q, want %q", streamed.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamOutlastsTimeout(t *testing.T) {
+	timeout := 100 * time.Millisecond
+	server := newProviderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range []string{"I ", "go ", "home"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", word)
+			w.(http.Flusher).Flush()
+			time.Sleep(timeout)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	provider := NewOpenAICompatibleProvider(server.URL, "", "model", timeout)
+
+	content, err := provider.Stream(context.Background(), nil, models.CompletionOptions{}, func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if content != "I go home" {
+		t.Errorf("Stream() = %q, want %q", content, "I go home")
+	}
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// Billing provider names
+const (
+	BillingProviderStripe = "stripe"
+	BillingProviderFake   = "fake"
+)
+
+// stripeSignatureTolerance is how old a signed webhook may be, limiting
+// replays of captured requests
+const stripeSignatureTolerance = 5 * time.Minute
+
+// BillingProvider takes payments for paid plans and reports what happens to
+// subscriptions through webhooks
+type BillingProvider interface {
+	Name() string
+	CreateCheckoutSession(ctx context.Context, input models.CheckoutInput) (*models.CheckoutSession, error)
+	// ParseWebhook verifies a webhook's signature and decodes its event. It
+	// returns ErrInvalidWebhookSignature or ErrInvalidWebhookPayload when the
+	// webhook cannot be trusted or read.
+	ParseWebhook(payload []byte, header http.Header) (*models.BillingEvent, error)
+}
+
+// stripeProvider uses the Stripe API, or any server compatible with its
+// checkout sessions and webhooks
+type stripeProvider struct {
+	apiURL        string
+	secretKey     string
+	webhookSecret string
+	priceIDs      map[string]string
+	httpClient    *http.Client
+}
+
+// NewStripeProvider creates a Stripe billing provider. priceIDs maps each
+// paid plan to the recurring price it is sold at.
+func NewStripeProvider(apiURL, secretKey, webhookSecret string, priceIDs map[string]string) BillingProvider {
+	if apiURL == "" {
+		apiURL = "https://api.stripe.com"
+	}
+	return &stripeProvider{
+		apiURL:        strings.TrimRight(apiURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		priceIDs:      priceIDs,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+func (p *stripeProvider) Name() string {
+	return BillingProviderStripe
+}
+
+func (p *stripeProvider) CreateCheckoutSession(ctx context.Context, input models.CheckoutInput) (*models.CheckoutSession, error) {
+	priceID, ok := p.priceIDs[input.Plan]
+	if !ok || priceID == "" {
+		return nil, models.ErrPlanNotPurchasable
+	}
+
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("line_items[0][price]", priceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", input.SuccessURL)
+	form.Set("cancel_url", input.CancelURL)
+	form.Set("client_reference_id", input.UserID)
+	form.Set("subscription_data[metadata][user_id]", input.UserID)
+	form.Set("subscription_data[metadata][plan]", input.Plan)
+	if input.CustomerID != "" {
+		form.Set("customer", input.CustomerID)
+	} else if input.Email != "" {
+		form.Set("customer_email", input.Email)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiURL+"/v1/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+p.secretKey)
+
+	var session models.CheckoutSession
+	if err := doProviderRequest(p.httpClient, req, &session); err != nil {
+		return nil, err
+	}
+	if session.URL == "" {
+		return nil, fmt.Errorf("checkout session %s has no URL", session.ID)
+	}
+	return &session, nil
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type stripeSubscription struct {
+	ID               string            `json:"id"`
+	Customer         string            `json:"customer"`
+	Status           string            `json:"status"`
+	CurrentPeriodEnd int64             `json:"current_period_end"`
+	Metadata         map[string]string `json:"metadata"`
+	Items            struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+type stripeSubscriptionDetails struct {
+	Subscription string            `json:"subscription"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+type stripeInvoice struct {
+	ID                  string                    `json:"id"`
+	Customer            string                    `json:"customer"`
+	Subscription        string                    `json:"subscription"`
+	SubscriptionDetails stripeSubscriptionDetails `json:"subscription_details"`
+	Parent              struct {
+		SubscriptionDetails stripeSubscriptionDetails `json:"subscription_details"`
+	} `json:"parent"`
+	Status            string `json:"status"`
+	Currency          string `json:"currency"`
+	AmountDue         int64  `json:"amount_due"`
+	AmountPaid        int64  `json:"amount_paid"`
+	HostedInvoiceURL  string `json:"hosted_invoice_url"`
+	StatusTransitions struct {
+		PaidAt int64 `json:"paid_at"`
+	} `json:"status_transitions"`
+	Lines struct {
+		Data []struct {
+			Period struct {
+				Start int64 `json:"start"`
+				End   int64 `json:"end"`
+			} `json:"period"`
+		} `json:"data"`
+	} `json:"lines"`
+}
+
+// ParseWebhook verifies the Stripe-Signature header and translates the
+// subscription and invoice events the app acts on
+func (p *stripeProvider) ParseWebhook(payload []byte, header http.Header) (*models.BillingEvent, error) {
+	if err := verifyStripeSignature(payload, header.Get("Stripe-Signature"), p.webhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var raw stripeEvent
+	if err := json.Unmarshal(payload, &raw); err != nil || raw.ID == "" {
+		return nil, models.ErrInvalidWebhookPayload
+	}
+	event := &models.BillingEvent{ID: raw.ID, ProviderType: raw.Type}
+
+	switch raw.Type {
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var subscription stripeSubscription
+		if err := json.Unmarshal(raw.Data.Object, &subscription); err != nil {
+			return nil, models.ErrInvalidWebhookPayload
+		}
+		p.fillSubscription(event, &subscription)
+		event.Type = subscriptionEventType(raw.Type, subscription.Status)
+
+	case "invoice.paid", "invoice.payment_failed":
+		var invoice stripeInvoice
+		if err := json.Unmarshal(raw.Data.Object, &invoice); err != nil {
+			return nil, models.ErrInvalidWebhookPayload
+		}
+		details := invoice.Parent.SubscriptionDetails
+		if details.Subscription == "" {
+			details = invoice.SubscriptionDetails
+			details.Subscription = invoice.Subscription
+		}
+		if details.Subscription == "" {
+			return event, nil // one-off invoice, not for a plan
+		}
+
+		event.CustomerID = invoice.Customer
+		event.SubscriptionID = details.Subscription
+		event.UserID = details.Metadata["user_id"]
+		event.PlanType = details.Metadata["plan"]
+		event.Invoice = &models.Invoice{
+			ProviderInvoiceID: invoice.ID,
+			AmountCents:       invoice.AmountDue,
+			Currency:          invoice.Currency,
+			Status:            invoice.Status,
+			PaidAt:            unixTime(invoice.StatusTransitions.PaidAt),
+		}
+		if invoice.HostedInvoiceURL != "" {
+			event.Invoice.HostedURL = &invoice.HostedInvoiceURL
+		}
+		if len(invoice.Lines.Data) > 0 {
+			period := invoice.Lines.Data[0].Period
+			event.Invoice.PeriodStart = unixTime(period.Start)
+			event.Invoice.PeriodEnd = unixTime(period.End)
+			event.PeriodEnd = event.Invoice.PeriodEnd
+		}
+
+		if raw.Type == "invoice.paid" {
+			event.Type = models.BillingEventSubscriptionRenewed
+			event.Invoice.AmountCents = invoice.AmountPaid
+		} else {
+			event.Type = models.BillingEventSubscriptionPastDue
+		}
+	}
+
+	return event, nil
+}
+
+func (p *stripeProvider) fillSubscription(event *models.BillingEvent, subscription *stripeSubscription) {
+	event.CustomerID = subscription.Customer
+	event.SubscriptionID = subscription.ID
+	event.UserID = subscription.Metadata["user_id"]
+	event.PlanType = subscription.Metadata["plan"]
+
+	// Newer API versions report the period per subscription item
+	periodEnd := subscription.CurrentPeriodEnd
+	if len(subscription.Items.Data) > 0 {
+		item := subscription.Items.Data[0]
+		if periodEnd == 0 {
+			periodEnd = item.CurrentPeriodEnd
+		}
+		if event.PlanType == "" {
+			for plan, priceID := range p.priceIDs {
+				if priceID == item.Price.ID {
+					event.PlanType = plan
+				}
+			}
+		}
+	}
+	event.PeriodEnd = unixTime(periodEnd)
+}
+
+// subscriptionEventType translates a Stripe subscription event by the status
+// the subscription is now in. Incomplete subscriptions, whose first payment
+// has not gone through, are left alone.
+func subscriptionEventType(stripeType, status string) string {
+	if stripeType == "customer.subscription.deleted" {
+		return models.BillingEventSubscriptionCancelled
+	}
+
+	switch status {
+	case "active", "trialing":
+		if stripeType == "customer.subscription.created" {
+			return models.BillingEventSubscriptionCreated
+		}
+		return models.BillingEventSubscriptionRenewed
+	case "past_due", "unpaid":
+		return models.BillingEventSubscriptionPastDue
+	case "canceled", "incomplete_expired":
+		return models.BillingEventSubscriptionCancelled
+	default:
+		return ""
+	}
+}
+
+// verifyStripeSignature checks a Stripe-Signature header of the form
+// t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<payload>">. Any of several v1
+// signatures may match, which happens while the signing secret is rolled.
+func verifyStripeSignature(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return models.ErrInvalidWebhookSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return models.ErrInvalidWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := []byte(hex.EncodeToString(mac.Sum(nil)))
+
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+	return models.ErrInvalidWebhookSignature
+}
+
+func unixTime(seconds int64) *time.Time {
+	if seconds == 0 {
+		return nil
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t
+}
+
+// fakeBillingProvider takes no payments. Checkouts go straight to the
+// success URL, and webhooks carry a models.BillingEvent as JSON signed with
+// SignFakeWebhook. It is meant for development and tests.
+type fakeBillingProvider struct {
+	webhookSecret string
+}
+
+// NewFakeBillingProvider creates a billing provider that needs no account
+func NewFakeBillingProvider(webhookSecret string) BillingProvider {
+	return &fakeBillingProvider{webhookSecret: webhookSecret}
+}
+
+func (p *fakeBillingProvider) Name() string {
+	return BillingProviderFake
+}
+
+func (p *fakeBillingProvider) CreateCheckoutSession(ctx context.Context, input models.CheckoutInput) (*models.CheckoutSession, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate checkout session ID: %w", err)
+	}
+
+	sessionID := "cs_fake_" + hex.EncodeToString(id)
+	successURL, err := url.Parse(input.SuccessURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid success URL: %w", err)
+	}
+	query := successURL.Query()
+	query.Set("session_id", sessionID)
+	successURL.RawQuery = query.Encode()
+
+	return &models.CheckoutSession{ID: sessionID, URL: successURL.String()}, nil
+}
+
+func (p *fakeBillingProvider) ParseWebhook(payload []byte, header http.Header) (*models.BillingEvent, error) {
+	expected := SignFakeWebhook(p.webhookSecret, payload)
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Fake-Signature"))) {
+		return nil, models.ErrInvalidWebhookSignature
+	}
+
+	var event models.BillingEvent
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" {
+		return nil, models.ErrInvalidWebhookPayload
+	}
+	event.ProviderType = event.Type
+	return &event, nil
+}
+
+// SignFakeWebhook returns the X-Fake-Signature header the fake billing
+// provider expects for payload: its hex HMAC-SHA256 under secret
+func SignFakeWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+)
+
+// billingGracePeriod keeps a paid plan a little past the end of the period
+// it was paid for, so a renewal that is charged or delivered late does not
+// downgrade the user in between
+const billingGracePeriod = 24 * time.Hour
+
+// pastDueGracePeriod is how long a user whose payment failed keeps their
+// plan while the provider retries the charge
+const pastDueGracePeriod = 3 * 24 * time.Hour
+
+type billingService struct {
+	provider    BillingProvider
+	billingRepo repository.BillingRepository
+	userRepo    repository.UserRepository
+	successURL  string
+	cancelURL   string
+}
+
+// NewBillingService creates the billing service. provider may be nil when
+// billing is not configured, in which case checkouts and webhooks are
+// unavailable but past invoices can still be listed.
+func NewBillingService(provider BillingProvider, billingRepo repository.BillingRepository, userRepo repository.UserRepository, successURL, cancelURL string) BillingService {
+	return &billingService{
+		provider:    provider,
+		billingRepo: billingRepo,
+		userRepo:    userRepo,
+		successURL:  successURL,
+		cancelURL:   cancelURL,
+	}
+}
+
+// CreateCheckout starts paying for plan and returns the provider's payment
+// page. The plan is only applied once the provider's webhook confirms the
+// subscription.
+func (s *billingService) CreateCheckout(ctx context.Context, userID, plan string) (*models.CheckoutSession, error) {
+	if s.provider == nil {
+		return nil, models.ErrBillingUnavailable
+	}
+	if purchasablePlan(plan) == "" {
+		return nil, models.ErrPlanNotPurchasable
+	}
+
+	existing, err := s.billingRepo.GetLatestSubscription(ctx, userID)
+	if err != nil && err != models.ErrSubscriptionNotFound {
+		return nil, err
+	}
+	if existing != nil && existing.Status != models.SubscriptionCancelled {
+		return nil, models.ErrAlreadySubscribed
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	input := models.CheckoutInput{
+		UserID:     userID,
+		Email:      user.Email,
+		Plan:       plan,
+		SuccessURL: s.successURL,
+		CancelURL:  s.cancelURL,
+	}
+	// Bill returning subscribers to the customer they had before
+	if existing != nil && existing.Provider == s.provider.Name() {
+		input.CustomerID = existing.ProviderCustomerID
+	}
+
+	session, err := s.provider.CreateCheckoutSession(ctx, input)
+	if err != nil {
+		if _, ok := err.(*models.AppError); ok {
+			return nil, err
+		}
+		log.Printf("Failed to create %s checkout session for user %s: %v", s.provider.Name(), userID, err)
+		return nil, models.ErrBillingUnavailable
+	}
+	return session, nil
+}
+
+// GetSubscription returns the user's current or most recent subscription
+func (s *billingService) GetSubscription(ctx context.Context, userID string) (*models.Subscription, error) {
+	return s.billingRepo.GetLatestSubscription(ctx, userID)
+}
+
+// GetInvoices returns the user's invoices, newest first
+func (s *billingService) GetInvoices(ctx context.Context, userID string, limit, offset int) ([]*models.Invoice, error) {
+	return s.billingRepo.GetInvoices(ctx, userID, limit, offset)
+}
+
+// HandleWebhook verifies and applies a billing provider webhook. Events are
+// applied at most once: a redelivered event is acknowledged without changing
+// anything, and an event that fails is left for the provider to retry.
+func (s *billingService) HandleWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	if s.provider == nil {
+		return models.ErrBillingUnavailable
+	}
+
+	event, err := s.provider.ParseWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+
+	var change *models.SubscriptionChange
+	processed, err := s.billingRepo.ProcessEvent(ctx, s.provider.Name(), event.ID, event.ProviderType, event.SubscriptionID,
+		func(existing *models.Subscription) (*models.SubscriptionChange, error) {
+			decided, err := s.subscriptionChange(event, existing, time.Now())
+			change = decided
+			return decided, err
+		})
+	if err != nil {
+		log.Printf("Failed to process billing event %s: %v", event.ID, err)
+		return err
+	}
+	if !processed {
+		log.Printf("Skipped billing event %s, already processed", event.ID)
+	} else if change != nil && change.PlanType != "" {
+		log.Printf("Billing event %s (%s) moved user %s to the %s plan", event.ID, event.Type, change.Subscription.UserID, change.PlanType)
+	}
+	return nil
+}
+
+// subscriptionChange works out what event changes about existing, the stored
+// subscription or nil if there is none yet. It returns nil for events that
+// need no action.
+func (s *billingService) subscriptionChange(event *models.BillingEvent, existing *models.Subscription, now time.Time) (*models.SubscriptionChange, error) {
+	if event.Type == "" || event.SubscriptionID == "" {
+		return nil, nil
+	}
+
+	subscription := &models.Subscription{
+		UserID:                 event.UserID,
+		Provider:               s.provider.Name(),
+		ProviderSubscriptionID: event.SubscriptionID,
+	}
+	if existing != nil {
+		*subscription = *existing
+	}
+	if subscription.UserID == "" {
+		// Retried by the provider, by which time the subscription may be known
+		return nil, fmt.Errorf("billing event %s is for unknown subscription %s", event.ID, event.SubscriptionID)
+	}
+	if event.CustomerID != "" {
+		subscription.ProviderCustomerID = event.CustomerID
+	}
+	if plan := purchasablePlan(event.PlanType); plan != "" {
+		subscription.PlanType = plan
+	} else if subscription.PlanType == "" {
+		subscription.PlanType = models.PlanPro
+	}
+	// Events can arrive out of order, so never move the period back
+	if event.PeriodEnd != nil && (subscription.CurrentPeriodEnd == nil || event.PeriodEnd.After(*subscription.CurrentPeriodEnd)) {
+		subscription.CurrentPeriodEnd = event.PeriodEnd
+	}
+
+	change := &models.SubscriptionChange{Subscription: subscription}
+	if event.Invoice != nil {
+		invoice := *event.Invoice
+		invoice.UserID = subscription.UserID
+		invoice.Provider = s.provider.Name()
+		change.Invoice = &invoice
+	}
+
+	// A cancelled subscription stays cancelled. Late events for it only
+	// record their invoice.
+	if existing != nil && existing.Status == models.SubscriptionCancelled && event.Type != models.BillingEventSubscriptionCreated {
+		return change, nil
+	}
+
+	switch event.Type {
+	case models.BillingEventSubscriptionCreated, models.BillingEventSubscriptionRenewed:
+		subscription.Status = models.SubscriptionActive
+		subscription.CancelledAt = nil
+		change.PlanType = subscription.PlanType
+		change.PlanExpiresAt = paidUntil(subscription, now)
+
+	case models.BillingEventSubscriptionPastDue:
+		// The grace period starts at the first failed payment, not at
+		// each retry
+		wasPastDue := existing != nil && existing.Status == models.SubscriptionPastDue
+		subscription.Status = models.SubscriptionPastDue
+		if !wasPastDue {
+			expiresAt := now.Add(pastDueGracePeriod)
+			change.PlanType = subscription.PlanType
+			change.PlanExpiresAt = &expiresAt
+		}
+
+	case models.BillingEventSubscriptionCancelled:
+		subscription.Status = models.SubscriptionCancelled
+		subscription.CancelledAt = &now
+		change.PlanType = models.PlanFree
+		change.PlanExpiresAt = nil
+	}
+
+	return change, nil
+}
+
+// paidUntil returns when the plan of an active subscription expires: the end
+// of its current period plus billingGracePeriod, or a month from now if the
+// provider did not say when the period ends
+func paidUntil(subscription *models.Subscription, now time.Time) *time.Time {
+	periodEnd := now.AddDate(0, 1, 0)
+	if subscription.CurrentPeriodEnd != nil {
+		periodEnd = *subscription.CurrentPeriodEnd
+	}
+	expiresAt := periodEnd.Add(billingGracePeriod)
+	return &expiresAt
+}
+
+// purchasablePlan returns name if it is a paid plan in the catalogue, or ""
+func purchasablePlan(name string) string {
+	if _, ok := plans[name]; !ok || name == models.PlanFree {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// memoryBillingRepository keeps subscriptions and user plans in memory and
+// applies events the way the postgres repository does
+type memoryBillingRepository struct {
+	events        map[string]bool
+	subscriptions map[string]*models.Subscription
+	plans         map[string]*models.SubscriptionChange
+	applied       int
+}
+
+func newMemoryBillingRepository() *memoryBillingRepository {
+	return &memoryBillingRepository{
+		events:        make(map[string]bool),
+		subscriptions: make(map[string]*models.Subscription),
+		plans:         make(map[string]*models.SubscriptionChange),
+	}
+}
+
+func (r *memoryBillingRepository) GetLatestSubscription(ctx context.Context, userID string) (*models.Subscription, error) {
+	for _, subscription := range r.subscriptions {
+		if subscription.UserID == userID {
+			stored := *subscription
+			return &stored, nil
+		}
+	}
+	return nil, models.ErrSubscriptionNotFound
+}
+
+func (r *memoryBillingRepository) ProcessEvent(ctx context.Context, provider, eventID, eventType, providerSubscriptionID string, decide func(existing *models.Subscription) (*models.SubscriptionChange, error)) (bool, error) {
+	if r.events[eventID] {
+		return false, nil
+	}
+
+	var existing *models.Subscription
+	if stored, ok := r.subscriptions[providerSubscriptionID]; ok {
+		copied := *stored
+		existing = &copied
+	}
+
+	change, err := decide(existing)
+	if err != nil {
+		return false, err
+	}
+	if change != nil {
+		stored := *change.Subscription
+		r.subscriptions[stored.ProviderSubscriptionID] = &stored
+		if change.PlanType != "" {
+			r.plans[stored.UserID] = change
+		}
+		r.applied++
+	}
+	r.events[eventID] = true
+	return true, nil
+}
+
+func (r *memoryBillingRepository) GetInvoices(ctx context.Context, userID string, limit, offset int) ([]*models.Invoice, error) {
+	return []*models.Invoice{}, nil
+}
+
+func signStripe(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":"evt_1"}`)
+	signature := signStripe(payload, testWebhookSecret, now)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		wantErr bool
+	}{
+		{"valid", payload, fmt.Sprintf("t=%d,v1=%s", now.Unix(), signature), false},
+		{"one of several v1 signatures", payload, fmt.Sprintf("t=%d,v1=%s,v1=%s", now.Unix(), signStripe(payload, "old_secret", now), signature), false},
+		{"tampered payload", []byte(`{"id":"evt_2"}`), fmt.Sprintf("t=%d,v1=%s", now.Unix(), signature), true},
+		{"wrong secret", payload, fmt.Sprintf("t=%d,v1=%s", now.Unix(), signStripe(payload, "other", now)), true},
+		{"missing timestamp", payload, "v1=" + signature, true},
+		{"missing signature", payload, fmt.Sprintf("t=%d", now.Unix()), true},
+		{"empty header", payload, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyStripeSignature(tt.payload, tt.header, testWebhookSecret, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyStripeSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyStripeSignatureTolerance(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`{"id":"evt_1"}`)
+
+	tests := []struct {
+		name     string
+		signedAt time.Time
+		wantErr  bool
+	}{
+		{"just signed", now, false},
+		{"at the tolerance", now.Add(-stripeSignatureTolerance), false},
+		{"too old", now.Add(-stripeSignatureTolerance - time.Second), true},
+		{"too far in the future", now.Add(stripeSignatureTolerance + time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := fmt.Sprintf("t=%d,v1=%s", tt.signedAt.Unix(), signStripe(payload, testWebhookSecret, tt.signedAt))
+			err := verifyStripeSignature(payload, header, testWebhookSecret, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyStripeSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newTestBillingService() (BillingService, *memoryBillingRepository) {
+	repo := newMemoryBillingRepository()
+	return NewBillingService(NewFakeBillingProvider(testWebhookSecret), repo, nil, "", ""), repo
+}
+
+func deliver(t *testing.T, service BillingService, event models.BillingEvent) error {
+	t.Helper()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	header := http.Header{}
+	header.Set("X-Fake-Signature", SignFakeWebhook(testWebhookSecret, payload))
+	return service.HandleWebhook(context.Background(), payload, header)
+}
+
+func TestHandleWebhookRejectsBadSignature(t *testing.T) {
+	service, repo := newTestBillingService()
+
+	payload := []byte(`{"id":"evt_1","type":"subscription.created","userId":"user-1","subscriptionId":"sub_1"}`)
+	header := http.Header{}
+	header.Set("X-Fake-Signature", SignFakeWebhook("other", payload))
+
+	if err := service.HandleWebhook(context.Background(), payload, header); err != models.ErrInvalidWebhookSignature {
+		t.Errorf("HandleWebhook() error = %v, want ErrInvalidWebhookSignature", err)
+	}
+	if repo.applied != 0 {
+		t.Errorf("applied %d changes, want none", repo.applied)
+	}
+}
+
+func TestHandleWebhookIgnoresRedelivery(t *testing.T) {
+	service, repo := newTestBillingService()
+	event := models.BillingEvent{
+		ID:             "evt_1",
+		Type:           models.BillingEventSubscriptionCreated,
+		UserID:         "user-1",
+		PlanType:       models.PlanPro,
+		SubscriptionID: "sub_1",
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := deliver(t, service, event); err != nil {
+			t.Fatalf("delivery %d: HandleWebhook() error = %v", i+1, err)
+		}
+	}
+	if repo.applied != 1 {
+		t.Errorf("applied %d changes for one event, want 1", repo.applied)
+	}
+}
+
+func TestHandleWebhookRetriesUnknownSubscription(t *testing.T) {
+	service, repo := newTestBillingService()
+	event := models.BillingEvent{
+		ID:             "evt_1",
+		Type:           models.BillingEventSubscriptionRenewed,
+		SubscriptionID: "sub_unknown",
+	}
+
+	if err := deliver(t, service, event); err == nil {
+		t.Fatal("HandleWebhook() succeeded for an unknown subscription, want an error so the provider retries")
+	}
+	if repo.events[event.ID] {
+		t.Error("failed event was recorded as processed")
+	}
+}
+
+func TestSubscriptionTransitions(t *testing.T) {
+	service, repo := newTestBillingService()
+	periodEnd := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	renewedEnd := periodEnd.Add(30 * 24 * time.Hour)
+
+	steps := []struct {
+		name       string
+		event      models.BillingEvent
+		wantStatus string
+		wantPlan   string
+	}{
+		{
+			name: "created",
+			event: models.BillingEvent{Type: models.BillingEventSubscriptionCreated, UserID: "user-1",
+				PlanType: models.PlanPro, PeriodEnd: &periodEnd},
+			wantStatus: models.SubscriptionActive,
+			wantPlan:   models.PlanPro,
+		},
+		{
+			name:       "renewed",
+			event:      models.BillingEvent{Type: models.BillingEventSubscriptionRenewed, PeriodEnd: &renewedEnd},
+			wantStatus: models.SubscriptionActive,
+			wantPlan:   models.PlanPro,
+		},
+		{
+			name:       "past due",
+			event:      models.BillingEvent{Type: models.BillingEventSubscriptionPastDue},
+			wantStatus: models.SubscriptionPastDue,
+			wantPlan:   models.PlanPro,
+		},
+		{
+			name:       "past due again",
+			event:      models.BillingEvent{Type: models.BillingEventSubscriptionPastDue},
+			wantStatus: models.SubscriptionPastDue,
+			wantPlan:   models.PlanPro,
+		},
+		{
+			name:       "cancelled",
+			event:      models.BillingEvent{Type: models.BillingEventSubscriptionCancelled},
+			wantStatus: models.SubscriptionCancelled,
+			wantPlan:   models.PlanFree,
+		},
+		{
+			name:       "renewed after cancelling",
+			event:      models.BillingEvent{Type: models.BillingEventSubscriptionRenewed, PeriodEnd: &renewedEnd},
+			wantStatus: models.SubscriptionCancelled,
+			wantPlan:   models.PlanFree,
+		},
+	}
+
+	var pastDueExpiry *time.Time
+	for i, step := range steps {
+		step.event.ID = fmt.Sprintf("evt_%d", i+1)
+		step.event.SubscriptionID = "sub_1"
+		if err := deliver(t, service, step.event); err != nil {
+			t.Fatalf("%s: HandleWebhook() error = %v", step.name, err)
+		}
+
+		subscription := repo.subscriptions["sub_1"]
+		if subscription.Status != step.wantStatus {
+			t.Errorf("%s: status = %s, want %s", step.name, subscription.Status, step.wantStatus)
+		}
+		plan := repo.plans["user-1"]
+		if plan.PlanType != step.wantPlan {
+			t.Errorf("%s: plan = %s, want %s", step.name, plan.PlanType, step.wantPlan)
+		}
+
+		switch step.name {
+		case "created":
+			if want := periodEnd.Add(billingGracePeriod); plan.PlanExpiresAt == nil || !plan.PlanExpiresAt.Equal(want) {
+				t.Errorf("%s: plan expires at %v, want %v", step.name, plan.PlanExpiresAt, want)
+			}
+		case "renewed":
+			if want := renewedEnd.Add(billingGracePeriod); plan.PlanExpiresAt == nil || !plan.PlanExpiresAt.Equal(want) {
+				t.Errorf("%s: plan expires at %v, want %v", step.name, plan.PlanExpiresAt, want)
+			}
+		case "past due":
+			pastDueExpiry = plan.PlanExpiresAt
+			if pastDueExpiry == nil || time.Until(*pastDueExpiry) > pastDueGracePeriod {
+				t.Errorf("%s: plan expires at %v, want within the grace period", step.name, pastDueExpiry)
+			}
+		case "past due again":
+			if plan.PlanExpiresAt != pastDueExpiry {
+				t.Errorf("%s: retried payment moved the grace period to %v", step.name, plan.PlanExpiresAt)
+			}
+		case "cancelled":
+			if plan.PlanExpiresAt != nil || subscription.CancelledAt == nil {
+				t.Errorf("%s: plan expires at %v, cancelled at %v", step.name, plan.PlanExpiresAt, subscription.CancelledAt)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+	"language-exchange/pkg/ics"
+)
+
+const calendarProdID = "-//Language Exchange//Sessions//EN"
+
+// Map invitation statuses to iCalendar participation statuses
+var invitationPartStats = map[string]string{
+	models.InvitationStatusPending:   "NEEDS-ACTION",
+	models.InvitationStatusAccepted:  "ACCEPTED",
+	models.InvitationStatusDeclined:  "DECLINED",
+	models.InvitationStatusTentative: "TENTATIVE",
+}
+
+// calendarFeedTokenBytes is the amount of randomness in a feed token
+const calendarFeedTokenBytes = 32
+
+// calendarService implements CalendarService
+type calendarService struct {
+	sessionService SessionService
+	userRepo       repository.UserRepository
+}
+
+// NewCalendarService creates a new calendar service
+func NewCalendarService(sessionService SessionService, userRepo repository.UserRepository) CalendarService {
+	return &calendarService{
+		sessionService: sessionService,
+		userRepo:       userRepo,
+	}
+}
+
+// GetUserCalendar renders the user's upcoming sessions as an iCalendar feed
+func (s *calendarService) GetUserCalendar(ctx context.Context, userID string) (string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	sessions, err := s.sessionService.GetUpcomingSessions(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	calendar := &ics.Calendar{
+		ProdID: calendarProdID,
+		Name:   "Language exchange sessions",
+	}
+	if user.Timezone != nil {
+		calendar.Timezone = *user.Timezone
+	}
+
+	organizers := map[string]*models.User{userID: user}
+	for _, session := range sessions {
+		organizer, ok := organizers[session.CreatedBy]
+		if !ok {
+			organizer, err = s.userRepo.GetByID(ctx, session.CreatedBy)
+			if err != nil {
+				return "", fmt.Errorf("failed to get session creator: %w", err)
+			}
+			organizers[session.CreatedBy] = organizer
+		}
+
+		calendar.Events = append(calendar.Events, sessionEvent(session, organizer))
+	}
+
+	return calendar.String(), nil
+}
+
+// FeedToken returns the token that authorizes the user's calendar feed URL,
+// creating it the first time the user asks for their feed
+func (s *calendarService) FeedToken(ctx context.Context, userID string) (string, error) {
+	token, err := s.userRepo.GetCalendarFeedToken(ctx, userID)
+	if err != nil || token != "" {
+		return token, err
+	}
+
+	token, err = newCalendarFeedToken()
+	if err != nil {
+		return "", err
+	}
+	return s.userRepo.EnsureCalendarFeedToken(ctx, userID, token)
+}
+
+// RotateFeedToken replaces the user's calendar feed token, so calendar apps
+// subscribed with the old URL lose access
+func (s *calendarService) RotateFeedToken(ctx context.Context, userID string) (string, error) {
+	token, err := newCalendarFeedToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.userRepo.SetCalendarFeedToken(ctx, userID, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ValidateFeedToken checks a calendar feed token in constant time. Users
+// who never asked for their feed have no token and nothing validates.
+func (s *calendarService) ValidateFeedToken(ctx context.Context, userID, token string) bool {
+	expected, err := s.userRepo.GetCalendarFeedToken(ctx, userID)
+	if err != nil || expected == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+func newCalendarFeedToken() (string, error) {
+	token := make([]byte, calendarFeedTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate calendar feed token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func sessionEvent(session *models.LanguageSession, organizer *models.User) ics.Event {
+	event := ics.Event{
+		UID:     session.ID + "@language-exchange",
+		Summary: session.Name,
+		Start:   *session.ScheduledStartAt,
+		End:     session.ScheduledStartAt.Add(defaultSessionDuration),
+		Created: session.CreatedAt,
+		Updated: session.UpdatedAt,
+		Organizer: &ics.Attendee{
+			Name:  organizer.Name,
+			Email: organizer.Email,
+		},
+	}
+	if session.ScheduledEndAt != nil {
+		event.End = *session.ScheduledEndAt
+	}
+	if session.Description != nil {
+		event.Description = *session.Description
+	}
+
+	for _, invitation := range session.Invitations {
+		if invitation.User == nil {
+			continue
+		}
+		event.Attendees = append(event.Attendees, ics.Attendee{
+			Name:     invitation.User.Name,
+			Email:    invitation.User.Email,
+			PartStat: invitationPartStats[invitation.Status],
+		})
+	}
+
+	return event
+}
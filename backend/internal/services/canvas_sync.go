@@ -0,0 +1,263 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"language-exchange/internal/models"
+)
+
+// prepareCanvasOperation resolves an incoming operation against the current
+// canvas state: undo and redo requests are expanded into the operation they
+// produce, the target element is recorded, and stale edits are transformed
+// or rejected.
+func prepareCanvasOperation(state *models.CanvasState, history []*models.CanvasOperation, operation *models.CanvasOperation) error {
+	switch operation.OperationType {
+	case models.OperationTypeUndo:
+		if err := expandCanvasUndo(state, history, operation); err != nil {
+			return err
+		}
+	case models.OperationTypeRedo:
+		if err := expandCanvasRedo(state, history, operation); err != nil {
+			return err
+		}
+	case models.OperationTypeClear:
+		if len(operation.OperationData) == 0 {
+			operation.OperationData = json.RawMessage(`{"type":"clear"}`)
+		}
+		return nil
+	default:
+		if len(operation.OperationData) == 0 || string(operation.OperationData) == "null" {
+			return models.NewAppError("INVALID_INPUT", "operation_data is required", 400)
+		}
+		if err := resolveCanvasConflict(state, operation); err != nil {
+			return err
+		}
+	}
+
+	// A whole Excalidraw scene does not apply to a single element
+	if operation.OperationType == models.OperationTypeExcalidrawUpdate {
+		return nil
+	}
+
+	elementID := models.CanvasOperationElementID(operation)
+	operation.ElementID = &elementID
+	if current := state.Element(elementID); current != nil {
+		previous, err := json.Marshal(current)
+		if err != nil {
+			return fmt.Errorf("failed to encode canvas element: %w", err)
+		}
+		operation.PreviousElement = previous
+	}
+
+	return nil
+}
+
+// resolveCanvasConflict checks an operation created at BaseVersion against
+// changes other participants made since. Concurrent changes to the same
+// element are rejected, except that an edit of an element that was only
+// moved keeps the element at its new position.
+func resolveCanvasConflict(state *models.CanvasState, operation *models.CanvasOperation) error {
+	base := operation.BaseVersion
+	if base <= 0 || base >= state.Version {
+		// Clients that do not track versions keep last-writer-wins behaviour
+		return nil
+	}
+	userID := operation.UserID
+
+	switch operation.OperationType {
+	case models.OperationTypeClear:
+		return nil
+
+	case models.OperationTypeExcalidrawUpdate:
+		if state.SceneVersion > base && state.SceneUserID != userID {
+			return models.NewCanvasConflictError("", state.SceneVersion)
+		}
+		return nil
+	}
+
+	elementID := models.CanvasOperationElementID(operation)
+	element := state.Element(elementID)
+
+	switch operation.OperationType {
+	case models.OperationTypeMove:
+		if element == nil {
+			return models.ErrCanvasElementNotFound
+		}
+		if element.PositionVersion > base && element.PositionUserID != userID {
+			return models.NewCanvasConflictError(elementID, element.PositionVersion)
+		}
+		return nil
+
+	case models.OperationTypeDelete:
+		if element == nil {
+			return nil
+		}
+		if element.ContentVersion > base && element.UserID != userID {
+			return models.NewCanvasConflictError(elementID, element.ContentVersion)
+		}
+		if element.PositionVersion > base && element.PositionUserID != userID {
+			return models.NewCanvasConflictError(elementID, element.PositionVersion)
+		}
+		return nil
+	}
+
+	// text, text_update, draw and erase replace the element
+	if tombstone, ok := state.Deleted[elementID]; ok && tombstone.Version > base && tombstone.UserID != userID {
+		return models.NewCanvasConflictError(elementID, tombstone.Version)
+	}
+	if element == nil {
+		return nil
+	}
+	if element.ContentVersion > base && element.UserID != userID {
+		return models.NewCanvasConflictError(elementID, element.ContentVersion)
+	}
+	if element.PositionVersion > base && element.PositionUserID != userID {
+		// Only the position changed, so keep the edit at the new position
+		if x, y, ok := models.ElementPosition(element.Data); ok {
+			if data, ok := models.WithElementPosition(operation.OperationData, x, y); ok {
+				operation.OperationData = data
+			}
+		}
+	}
+	return nil
+}
+
+// expandCanvasUndo turns an undo request into the operation that restores the
+// element touched by the user's most recent undoable operation
+func expandCanvasUndo(state *models.CanvasState, history []*models.CanvasOperation, operation *models.CanvasOperation) error {
+	undoStack, _ := canvasUndoStacks(history, operation.UserID)
+	if len(undoStack) == 0 {
+		return models.ErrNothingToUndo
+	}
+	target := undoStack[len(undoStack)-1]
+	elementID := *target.ElementID
+
+	if err := checkCanvasUndoConflict(state, elementID, target.Version, operation.UserID); err != nil {
+		return err
+	}
+
+	if len(target.PreviousElement) == 0 {
+		// The target created the element, so undoing it deletes the element
+		data, err := json.Marshal(models.DeleteOperation{Type: models.OperationTypeDelete, ElementID: elementID})
+		if err != nil {
+			return fmt.Errorf("failed to encode undo operation: %w", err)
+		}
+		operation.OperationType = models.OperationTypeDelete
+		operation.OperationData = data
+	} else {
+		var previous models.CanvasElement
+		if err := json.Unmarshal(target.PreviousElement, &previous); err != nil {
+			return fmt.Errorf("failed to decode previous canvas element: %w", err)
+		}
+		operation.OperationType = previous.OperationType
+		operation.OperationData = previous.Data
+	}
+
+	operation.ElementID = &elementID
+	operation.UndoOf = &target.ID
+	return nil
+}
+
+// expandCanvasRedo turns a redo request into a repeat of the user's most
+// recently undone operation
+func expandCanvasRedo(state *models.CanvasState, history []*models.CanvasOperation, operation *models.CanvasOperation) error {
+	_, redoStack := canvasUndoStacks(history, operation.UserID)
+	if len(redoStack) == 0 {
+		return models.ErrNothingToRedo
+	}
+	target := redoStack[len(redoStack)-1]
+	elementID := *target.ElementID
+
+	// The undo of the target is the user's latest change to the element
+	var undoVersion int64
+	for _, op := range history {
+		if op.UndoOf != nil && *op.UndoOf == target.ID {
+			undoVersion = op.Version
+		}
+	}
+	if err := checkCanvasUndoConflict(state, elementID, undoVersion, operation.UserID); err != nil {
+		return err
+	}
+
+	operation.OperationType = target.OperationType
+	operation.OperationData = target.OperationData
+	operation.ElementID = &elementID
+	operation.RedoOf = &target.ID
+	return nil
+}
+
+// checkCanvasUndoConflict rejects undoing or redoing a change to an element
+// that another participant has changed since
+func checkCanvasUndoConflict(state *models.CanvasState, elementID string, since int64, userID string) error {
+	if tombstone, ok := state.Deleted[elementID]; ok && tombstone.Version > since && tombstone.UserID != userID {
+		return models.NewCanvasConflictError(elementID, tombstone.Version)
+	}
+
+	element := state.Element(elementID)
+	if element == nil {
+		return nil
+	}
+	if element.ContentVersion > since && element.UserID != userID {
+		return models.NewCanvasConflictError(elementID, element.ContentVersion)
+	}
+	if element.PositionVersion > since && element.PositionUserID != userID {
+		return models.NewCanvasConflictError(elementID, element.PositionVersion)
+	}
+	return nil
+}
+
+// canvasUndoStacks replays the operation log to find a user's undo and redo
+// stacks. Each user only undoes their own operations, and a clear by anyone
+// resets every stack.
+func canvasUndoStacks(history []*models.CanvasOperation, userID string) (undoStack, redoStack []*models.CanvasOperation) {
+	byID := make(map[string]*models.CanvasOperation, len(history))
+
+	for _, operation := range history {
+		byID[operation.ID] = operation
+
+		if operation.OperationType == models.OperationTypeClear {
+			undoStack, redoStack = nil, nil
+			continue
+		}
+		if operation.UserID != userID {
+			continue
+		}
+
+		switch {
+		case operation.UndoOf != nil:
+			var target *models.CanvasOperation
+			undoStack, target = removeCanvasOperation(undoStack, *operation.UndoOf)
+			if target == nil {
+				target = byID[*operation.UndoOf]
+			}
+			if target != nil {
+				redoStack = append(redoStack, target)
+			}
+
+		case operation.RedoOf != nil:
+			var target *models.CanvasOperation
+			redoStack, target = removeCanvasOperation(redoStack, *operation.RedoOf)
+			if target != nil {
+				undoStack = append(undoStack, target)
+			}
+
+		case operation.ElementID != nil:
+			undoStack = append(undoStack, operation)
+			redoStack = nil
+		}
+	}
+
+	return undoStack, redoStack
+}
+
+// removeCanvasOperation removes the most recent entry with the given ID
+func removeCanvasOperation(stack []*models.CanvasOperation, operationID string) ([]*models.CanvasOperation, *models.CanvasOperation) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].ID == operationID {
+			target := stack[i]
+			return append(stack[:i:i], stack[i+1:]...), target
+		}
+	}
+	return stack, nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"language-exchange/internal/models"
+)
+
+const (
+	textA       = `{"type":"text","id":"a","x":0,"y":0,"text":"hi"}`
+	textAEdited = `{"type":"text","id":"a","x":0,"y":0,"text":"hello"}`
+	textAOther  = `{"type":"text","id":"a","x":0,"y":0,"text":"hola"}`
+	textB       = `{"type":"text","id":"b","x":5,"y":5,"text":"bye"}`
+	moveA       = `{"type":"move","elementId":"a","x":50,"y":60}`
+	deleteA     = `{"type":"delete","elementId":"a"}`
+)
+
+// canvasStep is an operation a user sends, based on the latest version
+// unless base is set
+type canvasStep struct {
+	userID        string
+	operationType string
+	data          string
+	base          int64
+}
+
+// replayCanvas saves each step the way SaveCanvasOperation does and returns
+// the resulting canvas and operation log
+func replayCanvas(t *testing.T, steps []canvasStep) (*models.CanvasState, []*models.CanvasOperation) {
+	t.Helper()
+
+	state := &models.CanvasState{}
+	var history []*models.CanvasOperation
+	for i, step := range steps {
+		operation := newCanvasOperation(fmt.Sprintf("op-%d", i+1), step, state)
+		if err := prepareCanvasOperation(state, history, operation); err != nil {
+			t.Fatalf("step %d (%s by %s) error = %v", i+1, step.operationType, step.userID, err)
+		}
+		operation.Version = state.Version + 1
+		operation.SequenceNumber = operation.Version
+		state.Apply(operation)
+		history = append(history, operation)
+	}
+	return state, history
+}
+
+func newCanvasOperation(id string, step canvasStep, state *models.CanvasState) *models.CanvasOperation {
+	base := step.base
+	if base == 0 {
+		base = state.Version
+	}
+	operation := &models.CanvasOperation{
+		ID:            id,
+		UserID:        step.userID,
+		OperationType: step.operationType,
+		BaseVersion:   base,
+	}
+	if step.data != "" {
+		operation.OperationData = json.RawMessage(step.data)
+	}
+	return operation
+}
+
+// appErrorCode returns the code of an AppError, or the message of any other error
+func appErrorCode(err error) string {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func TestResolveCanvasConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []canvasStep
+		incoming canvasStep
+		wantErr  string
+		wantX    float64
+		wantY    float64
+	}{
+		{
+			name:     "stale edit of a moved element keeps the new position",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "move", moveA, 0}},
+			incoming: canvasStep{"alice", "text_update", textAEdited, 1},
+			wantX:    50,
+			wantY:    60,
+		},
+		{
+			name:     "stale move of a moved element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "move", moveA, 0}},
+			incoming: canvasStep{"alice", "move", moveA, 1},
+			wantErr:  "CANVAS_CONFLICT",
+		},
+		{
+			name:     "stale edit after the user's own move",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"alice", "move", moveA, 0}},
+			incoming: canvasStep{"alice", "text_update", textAEdited, 1},
+		},
+		{
+			name:     "stale edit of an edited element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "text_update", textAOther, 0}},
+			incoming: canvasStep{"alice", "text_update", textAEdited, 1},
+			wantErr:  "CANVAS_CONFLICT",
+		},
+		{
+			name:     "stale delete of an edited element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "text_update", textAOther, 0}},
+			incoming: canvasStep{"alice", "delete", deleteA, 1},
+			wantErr:  "CANVAS_CONFLICT",
+		},
+		{
+			name:     "stale delete of a moved element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "move", moveA, 0}},
+			incoming: canvasStep{"alice", "delete", deleteA, 1},
+			wantErr:  "CANVAS_CONFLICT",
+		},
+		{
+			name:     "stale edit of a deleted element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "delete", deleteA, 0}},
+			incoming: canvasStep{"alice", "text_update", textAEdited, 1},
+			wantErr:  "CANVAS_CONFLICT",
+		},
+		{
+			name:     "stale delete of a deleted element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "delete", deleteA, 0}},
+			incoming: canvasStep{"alice", "delete", deleteA, 1},
+		},
+		{
+			name:     "stale move of a deleted element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "delete", deleteA, 0}},
+			incoming: canvasStep{"alice", "move", moveA, 1},
+			wantErr:  "CANVAS_ELEMENT_NOT_FOUND",
+		},
+		{
+			name:     "edit of another element",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "text", textB, 0}},
+			incoming: canvasStep{"alice", "text_update", textAEdited, 1},
+		},
+		{
+			name:     "up to date edit",
+			steps:    []canvasStep{{"alice", "text", textA, 0}, {"bob", "text_update", textAOther, 0}},
+			incoming: canvasStep{"alice", "text_update", textAEdited, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, _ := replayCanvas(t, tt.steps)
+			operation := newCanvasOperation("incoming", tt.incoming, state)
+
+			err := resolveCanvasConflict(state, operation)
+			if got := appErrorCode(err); got != tt.wantErr {
+				t.Fatalf("resolveCanvasConflict() error = %q, want %q", got, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if x, y, ok := models.ElementPosition(operation.OperationData); ok && (x != tt.wantX || y != tt.wantY) {
+				t.Errorf("operation position = (%v, %v), want (%v, %v)", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestCanvasUndoRedo(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []canvasStep
+		request    canvasStep
+		wantErr    string
+		wantType   string
+		wantData   string
+		wantUndoOf string
+		wantRedoOf string
+	}{
+		{
+			name:       "undo restores the previous content",
+			steps:      []canvasStep{{"alice", "text", textA, 0}, {"alice", "text_update", textAEdited, 0}},
+			request:    canvasStep{"alice", "undo", "", 0},
+			wantType:   "text",
+			wantData:   textA,
+			wantUndoOf: "op-2",
+		},
+		{
+			name:       "undo of a new element deletes it",
+			steps:      []canvasStep{{"alice", "text", textA, 0}},
+			request:    canvasStep{"alice", "undo", "", 0},
+			wantType:   "delete",
+			wantData:   deleteA,
+			wantUndoOf: "op-1",
+		},
+		{
+			name:       "undo skips other users' operations",
+			steps:      []canvasStep{{"alice", "text", textA, 0}, {"bob", "text", textB, 0}},
+			request:    canvasStep{"alice", "undo", "", 0},
+			wantType:   "delete",
+			wantData:   deleteA,
+			wantUndoOf: "op-1",
+		},
+		{
+			name:    "undo after another user's edit",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"bob", "text_update", textAOther, 0}},
+			request: canvasStep{"alice", "undo", "", 0},
+			wantErr: "CANVAS_CONFLICT",
+		},
+		{
+			name:    "undo after another user's move",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"alice", "text_update", textAEdited, 0}, {"bob", "move", moveA, 0}},
+			request: canvasStep{"alice", "undo", "", 0},
+			wantErr: "CANVAS_CONFLICT",
+		},
+		{
+			name:    "undo after another user's delete",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"alice", "text_update", textAEdited, 0}, {"bob", "delete", deleteA, 0}},
+			request: canvasStep{"alice", "undo", "", 0},
+			wantErr: "CANVAS_CONFLICT",
+		},
+		{
+			name:    "undo after a clear",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"bob", "clear", "", 0}},
+			request: canvasStep{"alice", "undo", "", 0},
+			wantErr: "NOTHING_TO_UNDO",
+		},
+		{
+			name:       "redo repeats the undone operation",
+			steps:      []canvasStep{{"alice", "text", textA, 0}, {"alice", "text_update", textAEdited, 0}, {"alice", "undo", "", 0}},
+			request:    canvasStep{"alice", "redo", "", 0},
+			wantType:   "text_update",
+			wantData:   textAEdited,
+			wantRedoOf: "op-2",
+		},
+		{
+			name:    "redo after another user's edit",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"alice", "text_update", textAEdited, 0}, {"alice", "undo", "", 0}, {"bob", "text_update", textAOther, 0}},
+			request: canvasStep{"alice", "redo", "", 0},
+			wantErr: "CANVAS_CONFLICT",
+		},
+		{
+			name:    "redo after a clear",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"alice", "undo", "", 0}, {"bob", "clear", "", 0}},
+			request: canvasStep{"alice", "redo", "", 0},
+			wantErr: "NOTHING_TO_REDO",
+		},
+		{
+			name:    "redo after a new change",
+			steps:   []canvasStep{{"alice", "text", textA, 0}, {"alice", "undo", "", 0}, {"alice", "text", textB, 0}},
+			request: canvasStep{"alice", "redo", "", 0},
+			wantErr: "NOTHING_TO_REDO",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, history := replayCanvas(t, tt.steps)
+			operation := newCanvasOperation("request", tt.request, state)
+
+			err := prepareCanvasOperation(state, history, operation)
+			if got := appErrorCode(err); got != tt.wantErr {
+				t.Fatalf("prepareCanvasOperation() error = %q, want %q", got, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if operation.OperationType != tt.wantType {
+				t.Errorf("operation type = %q, want %q", operation.OperationType, tt.wantType)
+			}
+			if string(operation.OperationData) != tt.wantData {
+				t.Errorf("operation data = %s, want %s", operation.OperationData, tt.wantData)
+			}
+			if got := stringValue(operation.UndoOf); got != tt.wantUndoOf {
+				t.Errorf("undo_of = %q, want %q", got, tt.wantUndoOf)
+			}
+			if got := stringValue(operation.RedoOf); got != tt.wantRedoOf {
+				t.Errorf("redo_of = %q, want %q", got, tt.wantRedoOf)
+			}
+		})
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
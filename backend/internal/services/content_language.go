@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Detection of the language user content is written in. Detection runs in
+// the background once the content is saved, at most
+// contentLanguageConcurrency at a time, and results that are too uncertain
+// are not stored.
+const (
+	contentLanguageTimeout       = 10 * time.Second
+	contentLanguageConcurrency   = 8
+	contentLanguageMinLength     = 10 // characters; shorter texts detect poorly
+	contentLanguageMinConfidence = 0.5
+	contentLanguageSampleLength  = 1000 // characters sent for detection
+)
+
+// contentLanguageSlots bounds how many detections run at once
+var contentLanguageSlots = make(chan struct{}, contentLanguageConcurrency)
+
+// detectContentLanguageLater detects the language of content that was just
+// saved and stores it with save. Writes never wait for a provider; content
+// whose language cannot be told, or that arrives while every slot is busy,
+// keeps no language.
+func detectContentLanguageLater(detector LanguageDetector, text string, save func(ctx context.Context, language string) error) {
+	text = strings.TrimSpace(text)
+	if detector == nil || utf8.RuneCountInString(text) < contentLanguageMinLength {
+		return
+	}
+
+	select {
+	case contentLanguageSlots <- struct{}{}:
+	default:
+		return
+	}
+
+	go func() {
+		defer func() { <-contentLanguageSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), contentLanguageTimeout)
+		defer cancel()
+
+		language := detectContentLanguage(ctx, detector, text)
+		if language == nil {
+			return
+		}
+		if err := save(ctx, *language); err != nil {
+			log.Printf("Failed to save content language: %v", err)
+		}
+	}()
+}
+
+// detectContentLanguage returns the language code of text, or nil when it
+// cannot be told reliably. Failures are logged.
+func detectContentLanguage(ctx context.Context, detector LanguageDetector, text string) *string {
+	if runes := []rune(text); len(runes) > contentLanguageSampleLength {
+		text = string(runes[:contentLanguageSampleLength])
+	}
+
+	detection, err := detector.DetectContentLanguage(ctx, text)
+	if err != nil {
+		log.Printf("Failed to detect content language: %v", err)
+		return nil
+	}
+
+	// Providers that do not report confidence return zero, which is treated
+	// as uncertain
+	if detection.Confidence < contentLanguageMinConfidence {
+		return nil
+	}
+
+	language := strings.ToLower(detection.Language)
+	return &language
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"strings"
+
+	"language-exchange/internal/models"
+)
+
+// Conversation starter topics
+const (
+	starterTopicGeneral = "general"
+	starterTopicTravel  = "travel"
+	starterTopicFood    = "food"
+	starterTopicMusic   = "music"
+	starterTopicMovies  = "movies"
+	starterTopicSports  = "sports"
+	starterTopicBooks   = "books"
+)
+
+// starterTopicOrder is the order topics are offered in when the partners'
+// interests don't point to any
+var starterTopicOrder = []string{
+	starterTopicTravel,
+	starterTopicFood,
+	starterTopicMusic,
+	starterTopicMovies,
+	starterTopicSports,
+	starterTopicBooks,
+}
+
+// starterTopicKeywords maps each topic to words that identify it in a
+// user's interests
+var starterTopicKeywords = map[string][]string{
+	starterTopicTravel: {"travel", "trip", "backpack", "culture", "explor"},
+	starterTopicFood:   {"cook", "food", "bak", "cuisine", "restaurant", "wine", "coffee"},
+	starterTopicMusic:  {"music", "sing", "guitar", "piano", "concert", "danc"},
+	starterTopicMovies: {"movie", "film", "cinema", "tv", "series", "anime", "netflix"},
+	starterTopicSports: {"sport", "football", "soccer", "basketball", "tennis", "running", "gym", "fitness", "yoga", "hik", "swim", "cycl"},
+	starterTopicBooks:  {"book", "reading", "literature", "writ", "poetry"},
+}
+
+type starterTemplate struct {
+	level string
+	text  string
+}
+
+// starterTemplates is a curated library of conversation starters by
+// language name and topic, used when no language model is configured. Each
+// topic has a simple version and a B1 version.
+var starterTemplates = map[string]map[string][]starterTemplate{
+	"english": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "Hi! Where are you from?"},
+			{models.CEFRA2, "Hi! What do you like to do on the weekend?"},
+			{models.CEFRB1, "Hi! What made you want to learn a new language, and how is it going so far?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "Do you like to travel? Where did you go last?"},
+			{models.CEFRB1, "If you could visit any country next year, where would you go and why?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "What is your favorite food?"},
+			{models.CEFRB1, "Is there a dish from your country that I should try? How is it made?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "What kind of music do you like?"},
+			{models.CEFRB1, "Which song have you been listening to a lot lately, and what do you like about it?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "Do you like movies? What is your favorite film?"},
+			{models.CEFRB1, "Have you seen a good film or series recently? What was it about?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "Do you play any sports?"},
+			{models.CEFRB1, "How did you get into your favorite sport, and how often do you practice it?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "Do you like reading? What are you reading now?"},
+			{models.CEFRB1, "What book would you recommend to someone learning English?"},
+		},
+	},
+	"spanish": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "¡Hola! ¿De dónde eres?"},
+			{models.CEFRA2, "¡Hola! ¿Qué te gusta hacer los fines de semana?"},
+			{models.CEFRB1, "¡Hola! ¿Por qué decidiste aprender un nuevo idioma y cómo te va hasta ahora?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "¿Te gusta viajar? ¿Adónde fuiste la última vez?"},
+			{models.CEFRB1, "Si pudieras visitar cualquier país el año que viene, ¿adónde irías y por qué?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "¿Cuál es tu comida favorita?"},
+			{models.CEFRB1, "¿Hay algún plato de tu país que debería probar? ¿Cómo se prepara?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "¿Qué tipo de música te gusta?"},
+			{models.CEFRB1, "¿Qué canción has estado escuchando mucho últimamente y qué te gusta de ella?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "¿Te gusta el cine? ¿Cuál es tu película favorita?"},
+			{models.CEFRB1, "¿Has visto alguna buena película o serie recientemente? ¿De qué trataba?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "¿Practicas algún deporte?"},
+			{models.CEFRB1, "¿Cómo empezaste con tu deporte favorito y con qué frecuencia lo practicas?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "¿Te gusta leer? ¿Qué estás leyendo ahora?"},
+			{models.CEFRB1, "¿Qué libro le recomendarías a alguien que está aprendiendo español?"},
+		},
+	},
+	"french": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "Salut ! Tu viens d'où ?"},
+			{models.CEFRA2, "Salut ! Qu'est-ce que tu aimes faire le week-end ?"},
+			{models.CEFRB1, "Salut ! Pourquoi as-tu décidé d'apprendre une nouvelle langue, et comment ça se passe jusqu'à présent ?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "Tu aimes voyager ? Quel est le dernier endroit que tu as visité ?"},
+			{models.CEFRB1, "Si tu pouvais visiter n'importe quel pays l'année prochaine, où irais-tu et pourquoi ?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "Quel est ton plat préféré ?"},
+			{models.CEFRB1, "Y a-t-il un plat de ton pays que je devrais goûter ? Comment est-ce qu'on le prépare ?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "Quel genre de musique aimes-tu ?"},
+			{models.CEFRB1, "Quelle chanson écoutes-tu beaucoup en ce moment, et qu'est-ce qui te plaît dedans ?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "Tu aimes le cinéma ? Quel est ton film préféré ?"},
+			{models.CEFRB1, "Tu as vu un bon film ou une bonne série récemment ? Ça parlait de quoi ?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "Tu fais du sport ?"},
+			{models.CEFRB1, "Comment as-tu commencé ton sport préféré, et tu le pratiques combien de fois par semaine ?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "Tu aimes lire ? Qu'est-ce que tu lis en ce moment ?"},
+			{models.CEFRB1, "Quel livre conseillerais-tu à quelqu'un qui apprend le français ?"},
+		},
+	},
+	"german": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "Hallo! Woher kommst du?"},
+			{models.CEFRA2, "Hallo! Was machst du gern am Wochenende?"},
+			{models.CEFRB1, "Hallo! Warum hast du angefangen, eine neue Sprache zu lernen, und wie läuft es bisher?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "Reist du gern? Wo warst du zuletzt?"},
+			{models.CEFRB1, "Wenn du nächstes Jahr jedes Land besuchen könntest, wohin würdest du fahren und warum?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "Was ist dein Lieblingsessen?"},
+			{models.CEFRB1, "Gibt es ein Gericht aus deinem Land, das ich probieren sollte? Wie wird es zubereitet?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "Welche Musik hörst du gern?"},
+			{models.CEFRB1, "Welches Lied hörst du in letzter Zeit oft, und was gefällt dir daran?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "Siehst du gern Filme? Was ist dein Lieblingsfilm?"},
+			{models.CEFRB1, "Hast du in letzter Zeit einen guten Film oder eine gute Serie gesehen? Worum ging es?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "Machst du Sport?"},
+			{models.CEFRB1, "Wie bist du zu deinem Lieblingssport gekommen, und wie oft trainierst du?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "Liest du gern? Was liest du gerade?"},
+			{models.CEFRB1, "Welches Buch würdest du jemandem empfehlen, der Deutsch lernt?"},
+		},
+	},
+	"italian": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "Ciao! Di dove sei?"},
+			{models.CEFRA2, "Ciao! Cosa ti piace fare nel fine settimana?"},
+			{models.CEFRB1, "Ciao! Perché hai deciso di imparare una nuova lingua, e come sta andando finora?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "Ti piace viaggiare? Qual è l'ultimo posto che hai visitato?"},
+			{models.CEFRB1, "Se potessi visitare qualsiasi paese l'anno prossimo, dove andresti e perché?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "Qual è il tuo piatto preferito?"},
+			{models.CEFRB1, "C'è un piatto del tuo paese che dovrei assaggiare? Come si prepara?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "Che tipo di musica ti piace?"},
+			{models.CEFRB1, "Quale canzone stai ascoltando molto in questo periodo? Cosa ti piace di questa canzone?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "Ti piace il cinema? Qual è il tuo film preferito?"},
+			{models.CEFRB1, "Hai visto un bel film o una bella serie di recente? Di cosa parlava?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "Fai sport?"},
+			{models.CEFRB1, "Come hai iniziato a praticare il tuo sport preferito, e quanto spesso ti alleni?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "Ti piace leggere? Cosa stai leggendo adesso?"},
+			{models.CEFRB1, "Quale libro consiglieresti a qualcuno che sta imparando l'italiano?"},
+		},
+	},
+	"portuguese": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "Oi! De onde você é?"},
+			{models.CEFRA2, "Oi! O que você gosta de fazer no fim de semana?"},
+			{models.CEFRB1, "Oi! Por que você decidiu aprender um novo idioma, e como está indo até agora?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "Você gosta de viajar? Qual foi o último lugar que você visitou?"},
+			{models.CEFRB1, "Se você pudesse visitar qualquer país no ano que vem, para onde iria e por quê?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "Qual é a sua comida favorita?"},
+			{models.CEFRB1, "Tem algum prato do seu país que eu deveria experimentar? Como ele é preparado?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "De que tipo de música você gosta?"},
+			{models.CEFRB1, "Que música você tem ouvido muito ultimamente, e do que você gosta nela?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "Você gosta de filmes? Qual é o seu filme favorito?"},
+			{models.CEFRB1, "Você viu algum filme ou série bom recentemente? Sobre o que era?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "Você pratica algum esporte?"},
+			{models.CEFRB1, "Como você começou a praticar o seu esporte favorito, e com que frequência você treina?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "Você gosta de ler? O que você está lendo agora?"},
+			{models.CEFRB1, "Que livro você recomendaria para alguém que está aprendendo português?"},
+		},
+	},
+	"japanese": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "はじめまして！どこの出身ですか？"},
+			{models.CEFRA2, "こんにちは！週末は何をするのが好きですか？"},
+			{models.CEFRB1, "こんにちは！どうして新しい言語を勉強しようと思ったんですか？今のところどうですか？"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "旅行は好きですか？最後にどこに行きましたか？"},
+			{models.CEFRB1, "来年どの国にでも行けるとしたら、どこに行きたいですか？それはなぜですか？"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "好きな食べ物は何ですか？"},
+			{models.CEFRB1, "あなたの国の料理で、私が食べてみるべきものはありますか？どうやって作るんですか？"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "どんな音楽が好きですか？"},
+			{models.CEFRB1, "最近よく聴いている曲は何ですか？どんなところが好きですか？"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "映画は好きですか？好きな映画は何ですか？"},
+			{models.CEFRB1, "最近、面白い映画やドラマを見ましたか？どんな話でしたか？"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "何かスポーツをしていますか？"},
+			{models.CEFRB1, "好きなスポーツを始めたきっかけは何ですか？どのくらいの頻度で練習していますか？"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "読書は好きですか？今、何を読んでいますか？"},
+			{models.CEFRB1, "日本語を勉強している人に、どんな本をおすすめしますか？"},
+		},
+	},
+	"korean": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "안녕하세요! 어디에서 왔어요?"},
+			{models.CEFRA2, "안녕하세요! 주말에 뭐 하는 걸 좋아해요?"},
+			{models.CEFRB1, "안녕하세요! 왜 새로운 언어를 배우기로 했어요? 지금까지 어때요?"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "여행을 좋아해요? 마지막으로 어디에 갔어요?"},
+			{models.CEFRB1, "내년에 어느 나라든 갈 수 있다면 어디에 가고 싶어요? 왜요?"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "제일 좋아하는 음식이 뭐예요?"},
+			{models.CEFRB1, "고향 음식 중에 제가 꼭 먹어 봐야 할 음식이 있어요? 어떻게 만들어요?"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "어떤 음악을 좋아해요?"},
+			{models.CEFRB1, "요즘 자주 듣는 노래가 뭐예요? 그 노래의 어떤 점이 좋아요?"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "영화 좋아해요? 제일 좋아하는 영화가 뭐예요?"},
+			{models.CEFRB1, "최근에 재미있는 영화나 드라마를 봤어요? 무슨 내용이었어요?"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "운동하는 거 있어요?"},
+			{models.CEFRB1, "좋아하는 운동을 어떻게 시작하게 됐어요? 얼마나 자주 해요?"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "책 읽는 거 좋아해요? 요즘 무슨 책을 읽고 있어요?"},
+			{models.CEFRB1, "한국어를 배우는 사람에게 어떤 책을 추천해 주고 싶어요?"},
+		},
+	},
+	"chinese": {
+		starterTopicGeneral: {
+			{models.CEFRA1, "你好！你是哪里人？"},
+			{models.CEFRA2, "你好！你周末喜欢做什么？"},
+			{models.CEFRB1, "你好！你为什么决定学习一门新的语言？到目前为止学得怎么样？"},
+		},
+		starterTopicTravel: {
+			{models.CEFRA2, "你喜欢旅行吗？你上次去了哪里？"},
+			{models.CEFRB1, "如果明年你可以去任何一个国家，你会去哪里？为什么？"},
+		},
+		starterTopicFood: {
+			{models.CEFRA2, "你最喜欢吃什么？"},
+			{models.CEFRB1, "你们国家有什么菜是我一定要尝尝的吗？是怎么做的？"},
+		},
+		starterTopicMusic: {
+			{models.CEFRA2, "你喜欢什么样的音乐？"},
+			{models.CEFRB1, "你最近常听哪首歌？你喜欢它的什么地方？"},
+		},
+		starterTopicMovies: {
+			{models.CEFRA2, "你喜欢看电影吗？你最喜欢的电影是什么？"},
+			{models.CEFRB1, "你最近看过什么好看的电影或电视剧吗？讲的是什么？"},
+		},
+		starterTopicSports: {
+			{models.CEFRA2, "你平时做运动吗？"},
+			{models.CEFRB1, "你是怎么开始喜欢上你最喜欢的运动的？你多久练习一次？"},
+		},
+		starterTopicBooks: {
+			{models.CEFRA2, "你喜欢看书吗？你最近在看什么书？"},
+			{models.CEFRB1, "你会推荐什么书给正在学中文的人？"},
+		},
+	},
+}
+
+// starterLanguageAliases maps other names of a language to its key in
+// starterTemplates
+var starterLanguageAliases = map[string]string{
+	"mandarin":             "chinese",
+	"mandarin chinese":     "chinese",
+	"brazilian portuguese": "portuguese",
+}
+
+// cefrRank orders CEFR levels, with unknown levels ranked as A2
+func cefrRank(level string) int {
+	switch level {
+	case models.CEFRA1:
+		return 1
+	case models.CEFRB1:
+		return 3
+	case models.CEFRB2:
+		return 4
+	case models.CEFRC1:
+		return 5
+	case models.CEFRC2:
+		return 6
+	default:
+		return 2
+	}
+}
+
+// interestTopics returns the starter topics matching interests, in the
+// order of the interests
+func interestTopics(interests []string) []string {
+	var topics []string
+	seen := make(map[string]bool)
+	for _, interest := range interests {
+		interest = strings.ToLower(interest)
+		for _, topic := range starterTopicOrder {
+			if seen[topic] {
+				continue
+			}
+			for _, keyword := range starterTopicKeywords[topic] {
+				if strings.Contains(interest, keyword) {
+					topics = append(topics, topic)
+					seen[topic] = true
+					break
+				}
+			}
+		}
+	}
+	return topics
+}
+
+// templateStarters picks up to count starters in language from the template
+// library: a general greeting first, then topics from interests, then the
+// remaining topics. For each topic the hardest template not above level is
+// used. It returns nil when the library has no templates for language.
+func templateStarters(language, level string, interests []string, count int) []models.ConversationStarter {
+	key := strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := starterLanguageAliases[key]; ok {
+		key = alias
+	}
+	templates, ok := starterTemplates[key]
+	if !ok {
+		return nil
+	}
+
+	topics := append([]string{starterTopicGeneral}, interestTopics(interests)...)
+	topics = append(topics, starterTopicOrder...)
+
+	starters := make([]models.ConversationStarter, 0, count)
+	used := make(map[string]bool)
+	for _, topic := range topics {
+		if len(starters) == count {
+			break
+		}
+		if used[topic] {
+			continue
+		}
+		used[topic] = true
+
+		if template, ok := pickTemplate(templates[topic], level); ok {
+			starters = append(starters, models.ConversationStarter{
+				Text:  template.text,
+				Topic: topic,
+			})
+		}
+	}
+
+	return starters
+}
+
+// pickTemplate returns the hardest template at or below level, or the
+// easiest one when all are above it
+func pickTemplate(templates []starterTemplate, level string) (starterTemplate, bool) {
+	if len(templates) == 0 {
+		return starterTemplate{}, false
+	}
+
+	best := -1
+	easiest := 0
+	for i, template := range templates {
+		rank := cefrRank(template.level)
+		if rank <= cefrRank(level) && (best < 0 || rank > cefrRank(templates[best].level)) {
+			best = i
+		}
+		if rank < cefrRank(templates[easiest].level) {
+			easiest = i
+		}
+	}
+	if best < 0 {
+		best = easiest
+	}
+	return templates[best], true
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"language-exchange/internal/cache"
+	"language-exchange/internal/models"
+)
+
+// startersPerSet is the number of conversation starters suggested to each
+// partner
+const startersPerSet = 5
+
+// defaultStarterLevel is used for partners whose level is unknown
+const defaultStarterLevel = models.CEFRA2
+
+// startersTemperature gives varied suggestions for the same pair of users
+const startersTemperature = 0.8
+
+// maxStarterBioLength limits how much of a bio is sent to the model
+const maxStarterBioLength = 500
+
+// startersCacheTTL is how long AI starters are reused for the same partners
+// and levels, so asking again does not cost another model request
+const startersCacheTTL = cache.DayDuration
+
+// startersResponse is the JSON object the model is asked to return, keyed
+// by the partner who will send the starters
+type startersResponse struct {
+	A []models.ConversationStarter `json:"A"`
+	B []models.ConversationStarter `json:"B"`
+}
+
+// SuggestConversationStarters suggests opening messages for both partners of
+// a match or conversation, each written in the language that partner is
+// practicing. Starters come from the language model when one is configured,
+// and from the template library when it is not or when it fails. A partner
+// whose level is not given gets starters at defaultStarterLevel.
+func (s *aiService) SuggestConversationStarters(ctx context.Context, input models.ConversationStarterInput) (*models.ConversationStarters, error) {
+	partnerID, err := s.resolvePartner(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	partner, err := s.userRepo.GetByID(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	userLevel := starterLevel(input.Level)
+	partnerLevel := starterLevel(input.PartnerLevel)
+
+	key := s.keys.ConversationStartersKey(user.ID, partner.ID, userLevel, partnerLevel)
+	if s.provider != nil && s.cache != nil {
+		var cached models.ConversationStarters
+		if err := s.cache.Get(ctx, key, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	sets := []models.ConversationStarterSet{
+		{UserID: user.ID, Language: practiceLanguage(user, partner), Level: userLevel},
+		{UserID: partner.ID, Language: practiceLanguage(partner, user), Level: partnerLevel},
+	}
+
+	starters := &models.ConversationStarters{
+		PartnerID: partner.ID,
+		Source:    models.StarterSourceTemplate,
+	}
+
+	if s.provider != nil {
+		if err := s.aiStarters(ctx, user, partner, sets); err != nil {
+			log.Printf("AI conversation starters with %s failed, using templates: %v", s.provider.Name(), err)
+		} else {
+			starters.Source = models.StarterSourceAI
+		}
+	}
+
+	if starters.Source == models.StarterSourceTemplate {
+		interests := sharedInterestsFirst(user.Interests, partner.Interests)
+		for i := range sets {
+			sets[i].Starters = templateStarters(sets[i].Language, sets[i].Level, interests, startersPerSet)
+		}
+	}
+
+	// Drop partners with nothing to practice or no starters in their language
+	for _, set := range sets {
+		if set.Language != "" && len(set.Starters) > 0 {
+			starters.Sets = append(starters.Sets, set)
+		}
+	}
+	if starters.Sets == nil {
+		starters.Sets = []models.ConversationStarterSet{}
+	}
+
+	if starters.Source == models.StarterSourceAI && s.cache != nil {
+		if err := s.cache.Set(ctx, key, starters, startersCacheTTL); err != nil {
+			log.Printf("Conversation starters cache write failed: %v", err)
+		}
+	}
+
+	return starters, nil
+}
+
+// starterLevel returns level if it is a CEFR level, or defaultStarterLevel
+func starterLevel(level string) string {
+	if models.IsValidCEFRLevel(level) {
+		return level
+	}
+	return defaultStarterLevel
+}
+
+// resolvePartner returns the ID of the other user in the requested match or
+// conversation, checking the requester is part of it
+func (s *aiService) resolvePartner(ctx context.Context, input models.ConversationStarterInput) (string, error) {
+	switch {
+	case input.MatchID != "":
+		match, err := s.matchRepo.GetByID(ctx, input.MatchID)
+		if err != nil {
+			return "", models.ErrMatchNotFound
+		}
+		if match.User1ID != input.UserID && match.User2ID != input.UserID {
+			return "", models.ErrNotPartner
+		}
+		return match.GetOtherUserID(input.UserID), nil
+
+	case input.ConversationID != "":
+		conversation, err := s.conversationRepo.GetByID(ctx, input.ConversationID)
+		if err != nil {
+			return "", models.ErrConversationNotFound
+		}
+		if !conversation.IsParticipant(input.UserID) {
+			return "", models.ErrNotPartner
+		}
+		return conversation.GetOtherUserID(input.UserID), nil
+
+	default:
+		return "", models.NewAppError("INVALID_INPUT", "match_id or conversation_id is required", 400)
+	}
+}
+
+// practiceLanguage picks the language learner practices with partner: the
+// first of the learner's target languages the partner speaks natively, or
+// else their first target language
+func practiceLanguage(learner, partner *models.User) string {
+	for _, target := range learner.TargetLanguages {
+		for _, native := range partner.NativeLanguages {
+			if strings.EqualFold(target, native) {
+				return target
+			}
+		}
+	}
+	if len(learner.TargetLanguages) > 0 {
+		return learner.TargetLanguages[0]
+	}
+	return ""
+}
+
+// sharedInterestsFirst lists the interests of both users once each, those
+// they have in common first
+func sharedInterestsFirst(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, interest := range b {
+		inB[strings.ToLower(interest)] = true
+	}
+
+	var shared, rest []string
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, interest := range a {
+		key := strings.ToLower(interest)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if inB[key] {
+			shared = append(shared, interest)
+		} else {
+			rest = append(rest, interest)
+		}
+	}
+	for _, interest := range b {
+		key := strings.ToLower(interest)
+		if !seen[key] {
+			seen[key] = true
+			rest = append(rest, interest)
+		}
+	}
+	return append(shared, rest...)
+}
+
+// aiStarters fills the starters of both sets with one model request. sets[0]
+// belongs to user and sets[1] to partner.
+func (s *aiService) aiStarters(ctx context.Context, user, partner *models.User, sets []models.ConversationStarterSet) error {
+	content, err := s.provider.Complete(ctx, startersPrompt(user, partner, sets), models.CompletionOptions{
+		Temperature: startersTemperature,
+		JSON:        true,
+	})
+	if err != nil {
+		return err
+	}
+
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("no JSON object in response: %q", content)
+	}
+
+	var response startersResponse
+	if err := json.Unmarshal([]byte(content[start:end+1]), &response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	for i, starters := range [][]models.ConversationStarter{response.A, response.B} {
+		if sets[i].Language == "" {
+			continue
+		}
+		sets[i].Starters = cleanStarters(starters)
+		if len(sets[i].Starters) == 0 {
+			return fmt.Errorf("response has no starters for partner %d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// cleanStarters drops empty suggestions and keeps at most startersPerSet
+func cleanStarters(starters []models.ConversationStarter) []models.ConversationStarter {
+	cleaned := make([]models.ConversationStarter, 0, startersPerSet)
+	for _, starter := range starters {
+		starter.Text = strings.TrimSpace(starter.Text)
+		starter.Topic = strings.ToLower(strings.TrimSpace(starter.Topic))
+		starter.Translation = strings.TrimSpace(starter.Translation)
+		if starter.Text == "" {
+			continue
+		}
+		cleaned = append(cleaned, starter)
+		if len(cleaned) == startersPerSet {
+			break
+		}
+	}
+	return cleaned
+}
+
+func startersPrompt(user, partner *models.User, sets []models.ConversationStarterSet) []models.ChatMessage {
+	system := fmt.Sprintf(`You help two language exchange partners who just matched start their first conversation.
+Suggest %d conversation starters for each partner: short, friendly opening messages they could send to the other, based on what they have in common and on the other's interests and bio. Prefer shared interests. Avoid personal or sensitive topics such as appearance, religion, politics and relationships.
+
+Each partner writes in the language they are practicing, at the CEFR level given, using vocabulary and grammar a learner at that level knows. Give a translation of each starter into the writer's first native language.
+
+Respond with a JSON object only, in this form:
+{"A": [{"text": "<starter for A to send>", "topic": "<one or two words>", "translation": "<translation>"}], "B": [...]}
+Return an empty list for a partner with no practice language.`, startersPerSet)
+
+	var profiles strings.Builder
+	for i, person := range []*models.User{user, partner} {
+		label := string(rune('A' + i))
+		fmt.Fprintf(&profiles, "Partner %s\n", label)
+		fmt.Fprintf(&profiles, "Native languages: %s\n", strings.Join(person.NativeLanguages, ", "))
+		fmt.Fprintf(&profiles, "Learning: %s\n", strings.Join(person.TargetLanguages, ", "))
+		if sets[i].Language != "" {
+			fmt.Fprintf(&profiles, "Practice language: %s at CEFR level %s\n", sets[i].Language, sets[i].Level)
+		} else {
+			fmt.Fprintf(&profiles, "Practice language: none\n")
+		}
+		if len(person.Interests) > 0 {
+			fmt.Fprintf(&profiles, "Interests: %s\n", strings.Join(person.Interests, ", "))
+		}
+		if person.Bio != nil && strings.TrimSpace(*person.Bio) != "" {
+			bio := []rune(strings.TrimSpace(*person.Bio))
+			if len(bio) > maxStarterBioLength {
+				bio = bio[:maxStarterBioLength]
+			}
+			fmt.Fprintf(&profiles, "Bio: %s\n", string(bio))
+		}
+		profiles.WriteString("\n")
+	}
+
+	return []models.ChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: strings.TrimSpace(profiles.String())},
+	}
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+)
+
+// planExpiryInterval is how often expired plans are downgraded
+const planExpiryInterval = 10 * time.Minute
+
+// meters lists the metered limits, in the order usage is reported
+var meters = []models.Meter{
+	{Name: models.MeterAIImprovements, Period: models.PeriodMonth, Unit: models.UnitCount, Recorded: true},
+	{Name: models.MeterTranslations, Period: models.PeriodDay, Unit: models.UnitCount, Recorded: true},
+	{Name: models.MeterSessions, Period: models.PeriodWeek, Unit: models.UnitCount, Recorded: true},
+	{Name: models.MeterUploadStorage, Period: models.PeriodNone, Unit: models.UnitBytes},
+}
+
+// plans is the plan catalogue. Users on an unknown plan get the free plan.
+var plans = map[string]models.Plan{
+	models.PlanFree: {
+		Name: models.PlanFree,
+		Features: map[string]bool{
+			models.FeatureAIImprovement:        true,
+			models.FeatureAIStreaming:          false,
+			models.FeatureConversationStarters: true,
+			models.FeatureSessionExport:        true,
+		},
+		Limits: map[string]int64{
+			models.MeterAIImprovements: 50,
+			models.MeterTranslations:   200,
+			models.MeterSessions:       5,
+			models.MeterUploadStorage:  100 << 20, // 100 MB
+		},
+	},
+	models.PlanPro: {
+		Name: models.PlanPro,
+		Features: map[string]bool{
+			models.FeatureAIImprovement:        true,
+			models.FeatureAIStreaming:          true,
+			models.FeatureConversationStarters: true,
+			models.FeatureSessionExport:        true,
+		},
+		Limits: map[string]int64{
+			models.MeterAIImprovements: models.UnlimitedQuota,
+			models.MeterTranslations:   5000,
+			models.MeterSessions:       models.UnlimitedQuota,
+			models.MeterUploadStorage:  5 << 30, // 5 GB
+		},
+	},
+}
+
+// StorageMeter measures the bytes of uploaded files a user stores
+type StorageMeter interface {
+	StorageUsed(userID string) (int64, error)
+}
+
+type entitlementsService struct {
+	entitlementRepo repository.EntitlementRepository
+	storage         StorageMeter
+}
+
+func NewEntitlementsService(entitlementRepo repository.EntitlementRepository, storage StorageMeter) EntitlementsService {
+	return &entitlementsService{
+		entitlementRepo: entitlementRepo,
+		storage:         storage,
+	}
+}
+
+// GetPlans returns the plan catalogue, free plan first
+func (s *entitlementsService) GetPlans() []models.Plan {
+	return []models.Plan{plans[models.PlanFree], plans[models.PlanPro]}
+}
+
+// GetPlan returns the plan the user is on. A plan that has expired is
+// downgraded to the free plan on the spot.
+func (s *entitlementsService) GetPlan(ctx context.Context, userID string) (*models.Plan, *models.UserPlan, error) {
+	userPlan, err := s.entitlementRepo.GetUserPlan(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if now := time.Now(); userPlan.PlanExpiresAt != nil && !userPlan.PlanExpiresAt.After(now) {
+		// The plan may be renewed between the read and the downgrade, so
+		// only downgrade if it is still expired and read it again
+		downgraded, err := s.entitlementRepo.DowngradeExpiredPlan(ctx, userID, now, models.PlanFree)
+		if err != nil {
+			return nil, nil, err
+		}
+		if downgraded {
+			log.Printf("Downgraded user %s from expired %s plan", userID, userPlan.PlanType)
+		}
+		if userPlan, err = s.entitlementRepo.GetUserPlan(ctx, userID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	plan, ok := plans[userPlan.PlanType]
+	if !ok {
+		plan = plans[models.PlanFree]
+	}
+	return &plan, userPlan, nil
+}
+
+// HasFeature reports whether the user's plan includes feature
+func (s *entitlementsService) HasFeature(ctx context.Context, userID, feature string) (bool, error) {
+	plan, _, err := s.GetPlan(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return plan.Features[feature], nil
+}
+
+// GetMeterUsage returns the user's usage of meter in the current period
+func (s *entitlementsService) GetMeterUsage(ctx context.Context, userID, meter string) (*models.MeterUsage, error) {
+	plan, _, err := s.GetPlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, ok := findMeter(meter)
+	if !ok {
+		return nil, models.NewAppError("UNKNOWN_METER", "Unknown usage meter: "+meter, http.StatusInternalServerError)
+	}
+	return s.meterUsage(ctx, userID, plan, definition, time.Now())
+}
+
+// CheckQuota returns the user's usage of meter, or a quota exceeded error
+// when amount more would go over the plan's limit
+func (s *entitlementsService) CheckQuota(ctx context.Context, userID, meter string, amount int64) (*models.MeterUsage, error) {
+	usage, err := s.GetMeterUsage(ctx, userID, meter)
+	if err != nil {
+		return nil, err
+	}
+	if !usage.Allows(amount) {
+		return usage, models.NewQuotaExceededError(usage)
+	}
+	return usage, nil
+}
+
+// RecordUsage counts amount against meter. Meters that are measured rather
+// than recorded, such as storage, are left alone.
+func (s *entitlementsService) RecordUsage(ctx context.Context, userID, meter string, amount int64) error {
+	definition, ok := findMeter(meter)
+	if !ok || !definition.Recorded {
+		return nil
+	}
+
+	return s.entitlementRepo.RecordUsage(ctx, &models.UsageRecord{
+		UserID: userID,
+		Meter:  meter,
+		Amount: amount,
+	})
+}
+
+// ReserveUsage counts amount against a recorded meter before the work it
+// pays for is done. If that would go over the plan's limit nothing is
+// counted and a quota exceeded error is returned along with the usage.
+func (s *entitlementsService) ReserveUsage(ctx context.Context, userID, meter string, amount int64) (*models.UsageReservation, error) {
+	plan, _, err := s.GetPlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, ok := findMeter(meter)
+	if !ok || !definition.Recorded {
+		return nil, models.NewAppError("UNKNOWN_METER", "Unknown recorded usage meter: "+meter, http.StatusInternalServerError)
+	}
+
+	limit := models.UnlimitedQuota
+	if planLimit, ok := plan.Limits[meter]; ok {
+		limit = planLimit
+	}
+	start, resetsAt := meterPeriod(definition.Period, time.Now())
+
+	record := &models.UsageRecord{UserID: userID, Meter: meter, Amount: amount}
+	used, reserved, err := s.entitlementRepo.ReserveUsage(ctx, record, start, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &models.UsageReservation{
+		RecordID: record.ID,
+		Usage: models.MeterUsage{
+			Meter:     meter,
+			Unit:      definition.Unit,
+			Period:    definition.Period,
+			Used:      used,
+			Limit:     limit,
+			Remaining: models.UnlimitedQuota,
+		},
+	}
+	if definition.Period != models.PeriodNone {
+		reservation.Usage.ResetsAt = &resetsAt
+	}
+	if limit != models.UnlimitedQuota {
+		reservation.Usage.Remaining = max(limit-used, 0)
+	}
+
+	if !reserved {
+		return reservation, models.NewQuotaExceededError(&reservation.Usage)
+	}
+	return reservation, nil
+}
+
+// ReleaseUsage gives back a reservation for work that failed
+func (s *entitlementsService) ReleaseUsage(ctx context.Context, reservation *models.UsageReservation) error {
+	if reservation == nil || reservation.RecordID == "" {
+		return nil
+	}
+	return s.entitlementRepo.DeleteUsage(ctx, reservation.RecordID)
+}
+
+// GetUsage returns the user's plan, features and usage of every meter
+func (s *entitlementsService) GetUsage(ctx context.Context, userID string) (*models.EntitlementUsage, error) {
+	plan, userPlan, err := s.GetPlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.EntitlementUsage{
+		Plan:          plan.Name,
+		PlanExpiresAt: userPlan.PlanExpiresAt,
+		Features:      plan.Features,
+		Meters:        make([]models.MeterUsage, 0, len(meters)),
+	}
+
+	now := time.Now()
+	for _, meter := range meters {
+		meterUsage, err := s.meterUsage(ctx, userID, plan, meter, now)
+		if err != nil {
+			return nil, err
+		}
+		usage.Meters = append(usage.Meters, *meterUsage)
+	}
+
+	return usage, nil
+}
+
+// RunPlanExpiry downgrades expired plans every planExpiryInterval until ctx
+// is cancelled. Plans are also downgraded as soon as they are looked up, so
+// this only keeps stored plans accurate for users who are away.
+func (s *entitlementsService) RunPlanExpiry(ctx context.Context) {
+	ticker := time.NewTicker(planExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		downgraded, err := s.entitlementRepo.DowngradeExpiredPlans(ctx, time.Now(), models.PlanFree)
+		if err != nil {
+			log.Printf("Failed to downgrade expired plans: %v", err)
+		} else if downgraded > 0 {
+			log.Printf("Downgraded %d users whose plan expired", downgraded)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *entitlementsService) meterUsage(ctx context.Context, userID string, plan *models.Plan, meter models.Meter, now time.Time) (*models.MeterUsage, error) {
+	usage := &models.MeterUsage{
+		Meter:  meter.Name,
+		Unit:   meter.Unit,
+		Period: meter.Period,
+		Limit:  models.UnlimitedQuota,
+	}
+	if limit, ok := plan.Limits[meter.Name]; ok {
+		usage.Limit = limit
+	}
+
+	var err error
+	if meter.Recorded {
+		start, resetsAt := meterPeriod(meter.Period, now)
+		if meter.Period != models.PeriodNone {
+			usage.ResetsAt = &resetsAt
+		}
+		usage.Used, err = s.entitlementRepo.SumUsage(ctx, userID, meter.Name, start)
+	} else if meter.Name == models.MeterUploadStorage && s.storage != nil {
+		usage.Used, err = s.storage.StorageUsed(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Remaining = models.UnlimitedQuota
+	if usage.Limit != models.UnlimitedQuota {
+		usage.Remaining = max(usage.Limit-usage.Used, 0)
+	}
+	return usage, nil
+}
+
+func findMeter(name string) (models.Meter, bool) {
+	for _, meter := range meters {
+		if meter.Name == name {
+			return meter, true
+		}
+	}
+	return models.Meter{}, false
+}
+
+// meterPeriod returns the start of the period containing now and the time
+// it resets. Periods follow UTC calendar days, weeks starting on Monday and
+// months. PeriodNone covers all time and never resets.
+func meterPeriod(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case models.PeriodDay:
+		return today, today.AddDate(0, 0, 1)
+	case models.PeriodWeek:
+		start := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case models.PeriodMonth:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return time.Time{}, time.Time{}
+	}
+}
@@ -383,4 +383,37 @@ func (s *gamificationService) OnProfileComplete(ctx context.Context, userID stri
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+func (s *gamificationService) OnVocabularyReviewed(ctx context.Context, userID string, itemID string, learned bool) error {
+	// Update challenge progress
+	if err := s.UpdateChallengeProgress(ctx, userID, "flashcards_reviewed", 1); err != nil {
+		return err
+	}
+	
+	if !learned {
+		return nil
+	}
+	
+	// Award XP
+	if err := s.AwardXP(ctx, userID, models.XPRewardWordLearned, models.XPActionWordLearned, &itemID, "Learned a word"); err != nil {
+		return err
+	}
+	
+	// Increment words learned stat
+	if err := s.IncrementStat(ctx, userID, "words_learned", 1); err != nil {
+		return err
+	}
+	
+	// Update challenge progress
+	if err := s.UpdateChallengeProgress(ctx, userID, "words_learned", 1); err != nil {
+		return err
+	}
+	
+	// Check for vocabulary badges
+	if err := s.CheckAndAwardBadges(ctx, userID); err != nil {
+		return err
+	}
+	
+	return nil
+}
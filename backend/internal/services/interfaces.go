@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"net/http"
+
 	"language-exchange/internal/models"
 )
 
@@ -53,9 +55,11 @@ type ConversationService interface {
 type MessageService interface {
 	SendMessage(ctx context.Context, conversationID, senderID string, request models.SendMessageRequest) (*models.Message, error)
 	GetMessages(ctx context.Context, conversationID, userID string, limit, offset int) ([]*models.Message, error)
+	GetTranslatedMessages(ctx context.Context, conversationID, userID, targetLang string, limit, offset int) ([]*models.Message, error)
 	MarkAsRead(ctx context.Context, conversationID, userID string) error
 	UpdateMessageStatus(ctx context.Context, messageID, userID string, status models.MessageStatus) error
 	DeleteMessage(ctx context.Context, messageID, userID string) error
+	SendTypingIndicator(ctx context.Context, conversationID, userID string, isTyping bool) error
 }
 
 type SessionService interface {
@@ -66,15 +70,33 @@ type SessionService interface {
 	GetActiveSessions(ctx context.Context, limit int) ([]*models.LanguageSession, error)
 	EndSession(ctx context.Context, sessionID, userID string) error
 	
+	// Scheduling
+	RespondToInvitation(ctx context.Context, sessionID, userID, status string) (*models.SessionInvitation, error)
+	GetUpcomingSessions(ctx context.Context, userID string) ([]*models.LanguageSession, error)
+	RunScheduler(ctx context.Context)
+	
+	// Recurring session series
+	GetSeries(ctx context.Context, seriesID, userID string) (*models.SessionSeries, error)
+	UpdateSeries(ctx context.Context, seriesID, userID string, input models.UpdateSeriesInput) (*models.SessionSeries, error)
+	EndSeries(ctx context.Context, seriesID, userID string) error
+	
 	// Participant management
-	JoinSession(ctx context.Context, sessionID, userID string) (*models.SessionParticipant, error)
+	JoinSession(ctx context.Context, sessionID, userID, role string) (*models.SessionParticipant, error)
 	LeaveSession(ctx context.Context, sessionID, userID string) error
 	GetSessionParticipants(ctx context.Context, sessionID string) ([]*models.SessionParticipant, error)
+	GetSessionAttendance(ctx context.Context, sessionID, userID string) (*models.SessionAttendance, error)
+	AuthorizeSessionConnection(ctx context.Context, sessionID, userID string) (*models.SessionParticipant, error)
+	
+	// Session room connections, recorded by the WebSocket hub
+	OpenSessionConnection(ctx context.Context, connection *models.SessionConnection) error
+	CloseSessionConnection(ctx context.Context, connectionID string) error
+	RefreshSessionConnections(ctx context.Context, nodeID string) error
 	IsUserInSession(ctx context.Context, sessionID, userID string) (bool, error)
 	
 	// Canvas operations
 	SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation) error
 	GetCanvasOperations(ctx context.Context, sessionID string, limit, offset int) ([]*models.CanvasOperation, error)
+	GetCanvasState(ctx context.Context, sessionID string) (*models.CanvasStateResponse, error)
 	ClearCanvas(ctx context.Context, sessionID, userID string) error
 	
 	// Session messages
@@ -83,10 +105,83 @@ type SessionService interface {
 	GetSessionMessages(ctx context.Context, sessionID string, limit, offset int) ([]*models.SessionMessage, error)
 }
 
+type CalendarService interface {
+	GetUserCalendar(ctx context.Context, userID string) (string, error)
+	FeedToken(ctx context.Context, userID string) (string, error)
+	RotateFeedToken(ctx context.Context, userID string) (string, error)
+	ValidateFeedToken(ctx context.Context, userID, token string) bool
+}
+
+type VocabularyService interface {
+	SaveVocabulary(ctx context.Context, userID string, input models.CreateVocabularyInput) (*models.VocabularyItem, error)
+	SaveLookup(ctx context.Context, userID string, input models.CreateVocabularyInput) (*models.VocabularyItem, error)
+	GetVocabulary(ctx context.Context, userID string, filters models.VocabularyFilters) ([]*models.VocabularyItem, error)
+	GetVocabularyItem(ctx context.Context, userID, itemID string) (*models.VocabularyItem, error)
+	GetReviewQueue(ctx context.Context, userID string, limit int) ([]*models.VocabularyItem, int, error)
+	UpdateVocabularyItem(ctx context.Context, userID, itemID string, input models.UpdateVocabularyInput) (*models.VocabularyItem, error)
+	DeleteVocabularyItem(ctx context.Context, userID, itemID string) error
+	ReviewVocabularyItem(ctx context.Context, userID, itemID string, quality int) (*models.VocabularyReviewResult, error)
+}
+
+type SessionExportService interface {
+	ExportSession(ctx context.Context, sessionID, userID string) (*models.SessionExport, error)
+	RenderSessionExport(export *models.SessionExport, format string) ([]byte, error)
+}
+
+type WebRTCService interface {
+	IssueTURNCredentials(ctx context.Context, sessionID, userID string) (*models.TURNCredentials, error)
+}
+
+type AIService interface {
+	ImproveMessage(ctx context.Context, input models.ImproveMessageInput) (*models.MessageImprovement, error)
+	StreamImprovement(ctx context.Context, input models.ImproveMessageInput, onToken func(string) error) (*models.MessageImprovement, error)
+	SuggestConversationStarters(ctx context.Context, input models.ConversationStarterInput) (*models.ConversationStarters, error)
+}
+
+type EntitlementsService interface {
+	GetPlans() []models.Plan
+	GetPlan(ctx context.Context, userID string) (*models.Plan, *models.UserPlan, error)
+	HasFeature(ctx context.Context, userID, feature string) (bool, error)
+	GetMeterUsage(ctx context.Context, userID, meter string) (*models.MeterUsage, error)
+	CheckQuota(ctx context.Context, userID, meter string, amount int64) (*models.MeterUsage, error)
+	RecordUsage(ctx context.Context, userID, meter string, amount int64) error
+	ReserveUsage(ctx context.Context, userID, meter string, amount int64) (*models.UsageReservation, error)
+	ReleaseUsage(ctx context.Context, reservation *models.UsageReservation) error
+	GetUsage(ctx context.Context, userID string) (*models.EntitlementUsage, error)
+	RunPlanExpiry(ctx context.Context)
+}
+
+type BillingService interface {
+	CreateCheckout(ctx context.Context, userID, plan string) (*models.CheckoutSession, error)
+	GetSubscription(ctx context.Context, userID string) (*models.Subscription, error)
+	GetInvoices(ctx context.Context, userID string, limit, offset int) ([]*models.Invoice, error)
+	HandleWebhook(ctx context.Context, payload []byte, header http.Header) error
+}
+
+// UsageMeter counts metered usage against a user's plan. It is satisfied by
+// EntitlementsService.
+type UsageMeter interface {
+	ReserveUsage(ctx context.Context, userID, meter string, amount int64) (*models.UsageReservation, error)
+	ReleaseUsage(ctx context.Context, reservation *models.UsageReservation) error
+	RecordUsage(ctx context.Context, userID, meter string, amount int64) error
+}
+
+// LanguageDetector detects the language user content is written in. It is
+// satisfied by TranslationService.
+type LanguageDetector interface {
+	DetectContentLanguage(ctx context.Context, text string) (*models.DetectLanguageResponse, error)
+}
+
 type TranslationService interface {
 	Translate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error)
+	TranslateBatch(ctx context.Context, request models.BatchTranslateRequest) (*models.BatchTranslateResponse, error)
+	DetectLanguage(ctx context.Context, text string) (*models.DetectLanguageResponse, error)
+	DetectContentLanguage(ctx context.Context, text string) (*models.DetectLanguageResponse, error)
+	ForgetTranslations(ctx context.Context, text string) error
 	GetSupportedLanguages(ctx context.Context) (*models.LanguagesResponse, error)
 	IsLanguageSupported(languageCode string) bool
+	GetProviderNames() []string
+	GetProviderStatus() []models.TranslationProviderStatus
 }
 
 type ProfileVisitService interface {
@@ -127,4 +222,5 @@ type GamificationService interface {
 	OnPostCreated(ctx context.Context, userID string, postID string) error
 	OnHelpfulReply(ctx context.Context, userID string, replyID string) error
 	OnProfileComplete(ctx context.Context, userID string) error
+	OnVocabularyReviewed(ctx context.Context, userID string, itemID string, learned bool) error
 }
\ No newline at end of file
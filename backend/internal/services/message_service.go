@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"language-exchange/internal/models"
 	"language-exchange/internal/repository"
 	"language-exchange/internal/websocket"
@@ -16,7 +17,9 @@ type MessageServiceImpl struct {
 	messageRepo      repository.MessageRepository
 	conversationRepo repository.ConversationRepository
 	userRepo         repository.UserRepository
-	wsHub            *websocket.Hub
+	wsHub              *websocket.Hub
+	translationService TranslationService
+	usageMeter         UsageMeter
 }
 
 func NewMessageService(
@@ -24,12 +27,16 @@ func NewMessageService(
 	conversationRepo repository.ConversationRepository,
 	userRepo repository.UserRepository,
 	wsHub *websocket.Hub,
+	translationService TranslationService,
+	usageMeter UsageMeter,
 ) MessageService {
 	return &MessageServiceImpl{
-		messageRepo:      messageRepo,
-		conversationRepo: conversationRepo,
-		userRepo:         userRepo,
-		wsHub:            wsHub,
+		messageRepo:        messageRepo,
+		conversationRepo:   conversationRepo,
+		userRepo:           userRepo,
+		wsHub:              wsHub,
+		translationService: translationService,
+		usageMeter:         usageMeter,
 	}
 }
 
@@ -88,9 +95,24 @@ func (s *MessageServiceImpl) SendMessage(ctx context.Context, conversationID, se
 	if err != nil {
 		return nil, fmt.Errorf("failed to save message: %w", err)
 	}
+	detectContentLanguageLater(s.translationService, content, func(ctx context.Context, language string) error {
+		return s.messageRepo.UpdateLanguage(ctx, message.ID, language)
+	})
 	
 	// Update conversation's last message timestamp (handled by database trigger)
 	
+	// Mark as delivered right away if the recipient has a live client
+	recipientID := conversation.GetOtherUserID(senderID)
+	if s.wsHub != nil && s.wsHub.IsUserOnline(recipientID) && message.CanUpdateStatus(models.MessageStatusDelivered) {
+		if err := s.messageRepo.UpdateStatus(ctx, message.ID, models.MessageStatusDelivered); err != nil {
+			log.Printf("Failed to mark message %s as delivered: %v", message.ID, err)
+		} else {
+			message.Status = models.MessageStatusDelivered
+		}
+	}
+	
+	s.notifyParticipants(conversation, models.WSMessageTypeNewMessage, message)
+	
 	return message, nil
 }
 
@@ -124,7 +146,15 @@ func (s *MessageServiceImpl) GetMessages(ctx context.Context, conversationID, us
 	go func() {
 		for _, msg := range messages {
 			if msg.SenderID != userID && msg.Status == models.MessageStatusSent {
-				s.messageRepo.UpdateStatus(context.Background(), msg.ID, models.MessageStatusDelivered)
+				if err := s.messageRepo.UpdateStatus(context.Background(), msg.ID, models.MessageStatusDelivered); err != nil {
+					continue
+				}
+				s.notifyParticipants(conversation, models.WSMessageTypeMessageStatus, models.MessageStatusEvent{
+					MessageID:      msg.ID,
+					ConversationID: conversationID,
+					Status:         models.MessageStatusDelivered,
+					UpdatedBy:      userID,
+				})
 			}
 		}
 	}()
@@ -132,6 +162,144 @@ func (s *MessageServiceImpl) GetMessages(ctx context.Context, conversationID, us
 	return messages, nil
 }
 
+// GetTranslatedMessages returns a page of messages with the ones the user
+// received translated into targetLang. Translations are stored on the
+// message, so each message is translated at most once per language. If the
+// translation service is unavailable the messages are returned untranslated.
+func (s *MessageServiceImpl) GetTranslatedMessages(ctx context.Context, conversationID, userID, targetLang string, limit, offset int) ([]*models.Message, error) {
+	targetLang = strings.ToLower(targetLang)
+	if s.translationService == nil {
+		return nil, models.ErrTranslationServiceUnavailable
+	}
+	if !s.translationService.IsLanguageSupported(targetLang) || targetLang == "auto" {
+		return nil, models.ErrUnsupportedLanguage
+	}
+
+	messages, err := s.GetMessages(ctx, conversationID, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only text the user received needs translating, and not when it is
+	// already in the target language
+	var pending []*models.Message
+	for _, message := range messages {
+		if message.SenderID == userID || message.MessageType != models.MessageTypeText {
+			continue
+		}
+		if message.Language != nil && *message.Language == targetLang {
+			continue
+		}
+		pending = append(pending, message)
+	}
+	if len(pending) == 0 {
+		return messages, nil
+	}
+
+	messageIDs := make([]string, len(pending))
+	for i, message := range pending {
+		messageIDs[i] = message.ID
+	}
+
+	stored, err := s.messageRepo.GetTranslations(ctx, messageIDs, targetLang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message translations: %w", err)
+	}
+
+	var missing []*models.Message
+	for _, message := range pending {
+		if translation, ok := stored[message.ID]; ok {
+			message.Translation = translation
+		} else {
+			missing = append(missing, message)
+		}
+	}
+
+	if len(missing) == 0 {
+		return messages, nil
+	}
+
+	// Each message translated counts against the user's translation quota
+	reservation, err := s.usageMeter.ReserveUsage(ctx, userID, models.MeterTranslations, int64(len(missing)))
+	if err != nil {
+		return nil, err
+	}
+
+	translated := s.translateMessages(ctx, missing, targetLang)
+
+	// Give back what could not be translated
+	if translated < len(missing) {
+		if err := s.usageMeter.ReleaseUsage(ctx, reservation); err != nil {
+			log.Printf("Failed to release translation usage for user %s: %v", userID, err)
+		} else if translated > 0 {
+			if err := s.usageMeter.RecordUsage(ctx, userID, models.MeterTranslations, int64(translated)); err != nil {
+				log.Printf("Failed to record translation usage for user %s: %v", userID, err)
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// translateMessages translates messages that have no stored translation yet
+// and stores the results. Messages are batched by their detected language,
+// falling back to automatic detection. It returns how many were translated.
+func (s *MessageServiceImpl) translateMessages(ctx context.Context, messages []*models.Message, targetLang string) int {
+	translated := 0
+	bySource := make(map[string][]*models.Message)
+	for _, message := range messages {
+		source := "auto"
+		if message.Language != nil {
+			source = *message.Language
+		}
+		bySource[source] = append(bySource[source], message)
+	}
+
+	for source, group := range bySource {
+		for start := 0; start < len(group); start += models.MaxBatchSegments {
+			end := start + models.MaxBatchSegments
+			if end > len(group) {
+				end = len(group)
+			}
+			chunk := group[start:end]
+
+			request := models.BatchTranslateRequest{
+				SourceLang: source,
+				TargetLang: targetLang,
+				Segments:   make([]models.BatchTranslateSegment, len(chunk)),
+			}
+			for i, message := range chunk {
+				request.Segments[i] = models.BatchTranslateSegment{ID: message.ID, Text: message.Content}
+			}
+
+			response, err := s.translationService.TranslateBatch(ctx, request)
+			if err != nil {
+				log.Printf("Failed to translate messages from %s to %s: %v", source, targetLang, err)
+				continue
+			}
+
+			for _, result := range response.Results {
+				if result.Error != nil {
+					continue
+				}
+
+				translation := &models.MessageTranslation{
+					Language:     targetLang,
+					Text:         result.TranslatedText,
+					Provider:     result.Provider,
+					TranslatedAt: time.Now(),
+				}
+				if err := s.messageRepo.SaveTranslation(ctx, chunk[result.Index].ID, translation); err != nil {
+					log.Printf("Failed to store translation of message %s: %v", chunk[result.Index].ID, err)
+				}
+				chunk[result.Index].Translation = translation
+				translated++
+			}
+		}
+	}
+	return translated
+}
+
 func (s *MessageServiceImpl) MarkAsRead(ctx context.Context, conversationID, userID string) error {
 	// Validate conversation exists and user is a participant
 	conversation, err := s.conversationRepo.GetByID(ctx, conversationID)
@@ -149,6 +317,12 @@ func (s *MessageServiceImpl) MarkAsRead(ctx context.Context, conversationID, use
 		return fmt.Errorf("failed to mark messages as read: %w", err)
 	}
 	
+	s.notifyParticipants(conversation, models.WSMessageTypeMessageRead, models.MessageReadEvent{
+		ConversationID: conversationID,
+		ReaderID:       userID,
+		ReadAt:         time.Now(),
+	})
+	
 	return nil
 }
 
@@ -190,6 +364,13 @@ func (s *MessageServiceImpl) UpdateMessageStatus(ctx context.Context, messageID,
 		return fmt.Errorf("failed to update message status: %w", err)
 	}
 	
+	s.notifyParticipants(conversation, models.WSMessageTypeMessageStatus, models.MessageStatusEvent{
+		MessageID:      messageID,
+		ConversationID: message.ConversationID,
+		Status:         status,
+		UpdatedBy:      userID,
+	})
+	
 	return nil
 }
 
@@ -211,11 +392,75 @@ func (s *MessageServiceImpl) DeleteMessage(ctx context.Context, messageID, userI
 		return fmt.Errorf("message too old to delete")
 	}
 	
-	// Delete the message
+	// Delete the message, and with it the translations stored on its row
 	err = s.messageRepo.Delete(ctx, messageID)
 	if err != nil {
 		return fmt.Errorf("failed to delete message: %w", err)
 	}
 	
+	// Deleted text should not outlive the message in the translation cache
+	if s.translationService != nil {
+		if err := s.translationService.ForgetTranslations(ctx, message.Content); err != nil {
+			log.Printf("Failed to evict translations of message %s: %v", messageID, err)
+		}
+	}
+	
+	// Let both participants remove the message from their view
+	conversation, err := s.conversationRepo.GetByID(ctx, message.ConversationID)
+	if err != nil {
+		log.Printf("Failed to load conversation %s for delete event: %v", message.ConversationID, err)
+		return nil
+	}
+	s.notifyParticipants(conversation, models.WSMessageTypeMessageDeleted, models.MessageDeletedEvent{
+		MessageID:      messageID,
+		ConversationID: message.ConversationID,
+		DeletedBy:      userID,
+	})
+	
+	return nil
+}
+
+func (s *MessageServiceImpl) SendTypingIndicator(ctx context.Context, conversationID, userID string, isTyping bool) error {
+	// Validate conversation exists and user is a participant
+	conversation, err := s.conversationRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("conversation not found: %w", err)
+	}
+	
+	if !conversation.IsParticipant(userID) {
+		return fmt.Errorf("access denied: user is not a participant in this conversation")
+	}
+	
+	if s.wsHub == nil {
+		return nil
+	}
+	
+	messageType := models.WSMessageTypeStopTyping
+	if isTyping {
+		messageType = models.WSMessageTypeTyping
+	}
+	
+	// Typing indicators are only relevant to the other participant
+	s.wsHub.SendEphemeralToUser(conversation.GetOtherUserID(userID), models.WebSocketMessage{
+		Type: messageType,
+		Data: models.TypingIndicator{
+			ConversationID: conversationID,
+			UserID:         userID,
+			IsTyping:       isTyping,
+		},
+	})
+	
 	return nil
-}
\ No newline at end of file
+}
+
+// notifyParticipants pushes a WebSocket event to both conversation participants
+func (s *MessageServiceImpl) notifyParticipants(conversation *models.Conversation, eventType string, data interface{}) {
+	if s.wsHub == nil {
+		return
+	}
+	
+	s.wsHub.SendToUsers([]string{conversation.User1ID, conversation.User2ID}, models.WebSocketMessage{
+		Type: eventType,
+		Data: data,
+	})
+}
@@ -32,6 +32,7 @@ type postService struct {
 	reactionRepo        repository.ReactionRepository
 	userRepo            repository.UserRepository
 	gamificationService GamificationService
+	languageDetector    LanguageDetector
 	
 	// Simple in-memory cache (replace with Redis in production)
 	cache      *postCache
@@ -55,6 +56,7 @@ func NewPostService(
 	reactionRepo repository.ReactionRepository,
 	userRepo repository.UserRepository,
 	gamificationService GamificationService,
+	languageDetector LanguageDetector,
 ) PostService {
 	return &postService{
 		postRepo:            postRepo,
@@ -62,6 +64,7 @@ func NewPostService(
 		reactionRepo:        reactionRepo,
 		userRepo:            userRepo,
 		gamificationService: gamificationService,
+		languageDetector:    languageDetector,
 		cache: &postCache{
 			posts:      make(map[string]*cacheEntry),
 			categories: make(map[string]*cacheEntry),
@@ -88,6 +91,7 @@ func (s *postService) CreatePost(ctx context.Context, userID string, input model
 	if err := s.postRepo.Create(ctx, post); err != nil {
 		return nil, err
 	}
+	s.detectPostLanguage(post)
 
 	// Get user info
 	user, err := s.userRepo.GetByID(ctx, userID)
@@ -166,10 +170,17 @@ func (s *postService) UpdatePost(ctx context.Context, userID, postID string, inp
 	if input.AskingFor != nil {
 		post.AskingFor = *input.AskingFor
 	}
+	if input.Title != nil || input.Content != nil {
+		// Detected again once the edit is saved
+		post.Language = nil
+	}
 
 	if err := s.postRepo.Update(ctx, post); err != nil {
 		return nil, err
 	}
+	if post.Language == nil {
+		s.detectPostLanguage(post)
+	}
 
 	// Invalidate caches
 	s.invalidateCache("post:" + postID)
@@ -204,7 +215,7 @@ func (s *postService) DeletePost(ctx context.Context, userID, postID string) err
 
 func (s *postService) ListPosts(ctx context.Context, filters models.PostFilters) (*models.PostListResponse, error) {
 	// For trending posts, check cache
-	if filters.SortBy == "trending" && filters.CursorID == 0 {
+	if filters.SortBy == "trending" && filters.CursorID == 0 && filters.Language == "" {
 		if cached := s.getFromCache("trending"); cached != nil {
 			if response, ok := cached.(*models.PostListResponse); ok {
 				return response, nil
@@ -230,7 +241,7 @@ func (s *postService) ListPosts(ctx context.Context, filters models.PostFilters)
 	}
 
 	// Cache trending posts for 2 minutes
-	if filters.SortBy == "trending" && filters.CursorID == 0 {
+	if filters.SortBy == "trending" && filters.CursorID == 0 && filters.Language == "" {
 		s.setCache("trending", response, 2*time.Minute)
 	}
 
@@ -260,6 +271,13 @@ func (s *postService) AddComment(ctx context.Context, userID string, input model
 	if err := s.commentRepo.Create(ctx, comment); err != nil {
 		return nil, err
 	}
+	detectContentLanguageLater(s.languageDetector, comment.Content, func(ctx context.Context, language string) error {
+		if err := s.commentRepo.UpdateLanguage(ctx, comment.ID, language); err != nil {
+			return err
+		}
+		s.invalidateCache("comments:" + comment.PostID)
+		return nil
+	})
 
 	// Get user info
 	user, err := s.userRepo.GetByID(ctx, userID)
@@ -433,6 +451,18 @@ func (s *postService) setCache(key string, data interface{}, duration time.Durat
 	}
 }
 
+// detectPostLanguage detects the language of a saved post in the background
+func (s *postService) detectPostLanguage(post *models.Post) {
+	saved := *post
+	detectContentLanguageLater(s.languageDetector, saved.Title+"\n"+saved.Content, func(ctx context.Context, language string) error {
+		if err := s.postRepo.UpdateLanguage(ctx, &saved, language); err != nil {
+			return err
+		}
+		s.invalidateCache("post:" + saved.ID)
+		return nil
+	})
+}
+
 func (s *postService) invalidateCache(key string) {
 	s.cacheMutex.Lock()
 	defer s.cacheMutex.Unlock()
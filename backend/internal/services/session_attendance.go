@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"language-exchange/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// GetSessionAttendance reports each participant's connected time, reconnects
+// and share of the conversation. It is visible to the creator, invitees and
+// anyone who joined.
+func (s *sessionService) GetSessionAttendance(ctx context.Context, sessionID, userID string) (*models.SessionAttendance, error) {
+	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	// A cancelled session was never held, so it has no attendance
+	if session.Status == models.SessionStatusCancelled {
+		return nil, models.ErrSessionCancelled
+	}
+
+	participants, err := s.sessionRepo.GetSessionParticipants(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session participants: %w", err)
+	}
+
+	if err := s.loadInvitations(ctx, session); err != nil {
+		return nil, err
+	}
+	allowed := session.CreatedBy == userID || session.IsInvited(userID)
+	for _, participant := range participants {
+		allowed = allowed || participant.UserID == userID
+	}
+	if !allowed {
+		return nil, models.NewAppError("NOT_INVITED", "You are not a participant of this session", 403)
+	}
+
+	connections, err := s.sessionRepo.GetSessionConnections(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.sessionRepo.GetSessionMessageTotals(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	until := time.Now()
+	if session.EndedAt != nil {
+		until = *session.EndedAt
+	}
+	intervals := presenceIntervals(connections, session.CreatedAt, until)
+
+	attendance := make(map[string]*models.ParticipantAttendance)
+	get := func(userID string) *models.ParticipantAttendance {
+		if _, ok := attendance[userID]; !ok {
+			attendance[userID] = &models.ParticipantAttendance{
+				UserID:    userID,
+				Intervals: []models.AttendanceInterval{},
+			}
+		}
+		return attendance[userID]
+	}
+
+	for _, participant := range participants {
+		entry := get(participant.UserID)
+		entry.User = participant.User
+		entry.Role = participant.Role
+	}
+
+	for userID, userIntervals := range intervals {
+		entry := get(userID)
+		entry.Reconnects = len(userIntervals) - 1
+
+		var connected time.Duration
+		for _, interval := range userIntervals {
+			connected += interval.end.Sub(interval.start)
+
+			attended := models.AttendanceInterval{JoinedAt: interval.start}
+			if interval.open {
+				entry.IsPresent = true
+			} else {
+				leftAt := interval.end
+				attended.LeftAt = &leftAt
+			}
+			entry.Intervals = append(entry.Intervals, attended)
+		}
+		entry.ConnectedSeconds = int64(connected.Seconds())
+	}
+
+	var totalCharacters int
+	for _, total := range totals {
+		totalCharacters += total.Characters
+	}
+	for _, total := range totals {
+		entry := get(total.UserID)
+		entry.MessageCount = total.Messages
+		if totalCharacters > 0 {
+			entry.ChatShare = float64(total.Characters) / float64(totalCharacters)
+		}
+	}
+
+	report := &models.SessionAttendance{
+		SessionID:    sessionID,
+		Participants: make([]models.ParticipantAttendance, 0, len(attendance)),
+	}
+	for _, entry := range attendance {
+		report.Participants = append(report.Participants, *entry)
+	}
+	sort.Slice(report.Participants, func(i, j int) bool {
+		return report.Participants[i].ConnectedSeconds > report.Participants[j].ConnectedSeconds
+	})
+
+	return report, nil
+}
+
+// recordRESTPresence opens or closes the user's REST attendance interval.
+// Attendance is best effort and never fails a join or leave.
+func (s *sessionService) recordRESTPresence(ctx context.Context, sessionID, userID string, present bool) {
+	// A repeated join replaces the open interval instead of overlapping it
+	if err := s.sessionRepo.CloseUserSessionConnections(ctx, sessionID, userID, models.SessionConnectionSourceREST); err != nil {
+		log.Printf("Failed to record user %s leaving session %s: %v", userID, sessionID, err)
+		return
+	}
+	if !present {
+		return
+	}
+
+	connection := &models.SessionConnection{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		UserID:    userID,
+		Source:    models.SessionConnectionSourceREST,
+	}
+	if err := s.sessionRepo.OpenSessionConnection(ctx, connection); err != nil {
+		log.Printf("Failed to record user %s joining session %s: %v", userID, sessionID, err)
+	}
+}
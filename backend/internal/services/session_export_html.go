@@ -0,0 +1,381 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// replayData is embedded in the HTML export and drives the replay script
+type replayData struct {
+	Initial    *models.CanvasState       `json:"initial"`
+	Operations []*models.CanvasOperation `json:"operations"`
+	Messages   []replayMessage           `json:"messages"`
+	StartedAt  time.Time                 `json:"started_at"`
+	EndedAt    time.Time                 `json:"ended_at"`
+}
+
+type replayMessage struct {
+	UserName    string    `json:"user_name"`
+	Content     string    `json:"content"`
+	MessageType string    `json:"message_type"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type replayAttendee struct {
+	Name       string
+	Role       string
+	Connected  string
+	Reconnects int
+	Messages   int
+	ChatShare  string
+}
+
+// renderSessionHTML writes a self-contained page that replays the session's
+// canvas operations and chat by timestamp
+func renderSessionHTML(export *models.SessionExport) ([]byte, error) {
+	session := export.Session
+	names := exportUserNames(export)
+
+	data := replayData{
+		Initial:    export.Canvas.Initial,
+		Operations: sortedOperations(export.Canvas.Operations),
+		Messages:   make([]replayMessage, 0, len(export.Messages)),
+		StartedAt:  session.CreatedAt,
+		EndedAt:    export.ExportedAt,
+	}
+	if session.StartedAt != nil {
+		data.StartedAt = *session.StartedAt
+	}
+	if session.EndedAt != nil {
+		data.EndedAt = *session.EndedAt
+	}
+	for _, message := range export.Messages {
+		data.Messages = append(data.Messages, replayMessage{
+			UserName:    names[message.UserID],
+			Content:     message.Content,
+			MessageType: message.MessageType,
+			CreatedAt:   message.CreatedAt,
+		})
+	}
+
+	// The replay starts at the first recorded event if it predates the start
+	if len(data.Operations) > 0 && data.Operations[0].Timestamp.Before(data.StartedAt) {
+		data.StartedAt = data.Operations[0].Timestamp
+	}
+	if len(data.Messages) > 0 && data.Messages[0].CreatedAt.Before(data.StartedAt) {
+		data.StartedAt = data.Messages[0].CreatedAt
+	}
+
+	replay, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session replay: %w", err)
+	}
+
+	attendees := make([]replayAttendee, 0, len(export.Attendance.Participants))
+	for _, participant := range export.Attendance.Participants {
+		attendees = append(attendees, replayAttendee{
+			Name:       names[participant.UserID],
+			Role:       participant.Role,
+			Connected:  formatExportDuration(time.Duration(participant.ConnectedSeconds) * time.Second),
+			Reconnects: participant.Reconnects,
+			Messages:   participant.MessageCount,
+			ChatShare:  fmt.Sprintf("%.0f%%", participant.ChatShare*100),
+		})
+	}
+
+	location := exportLocation(session)
+	var buf bytes.Buffer
+	err = sessionReplayTemplate.Execute(&buf, map[string]interface{}{
+		"Name":       session.Name,
+		"StartedAt":  data.StartedAt.In(location).Format("2006-01-02 15:04 MST"),
+		"Duration":   formatExportDuration(data.EndedAt.Sub(data.StartedAt)),
+		"ExportedAt": export.ExportedAt.In(location).Format("2006-01-02 15:04 MST"),
+		"Attendees":  attendees,
+		"Compacted":  export.Canvas.CompactedOperations,
+		// json.Marshal escapes <, > and &, so the data cannot close the script
+		"Replay": template.JS(replay),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render session replay: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var sessionReplayTemplate = template.Must(template.New("replay").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} - session replay</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #1f2937; background: #f9fafb; }
+header { padding: 16px 24px; background: #fff; border-bottom: 1px solid #e5e7eb; }
+header h1 { margin: 0 0 4px; font-size: 20px; }
+header p { margin: 0; color: #6b7280; font-size: 14px; }
+main { display: flex; gap: 16px; padding: 16px 24px; }
+#stage { flex: 1; min-width: 0; }
+#canvas { width: 100%; height: 540px; background: #fff; border: 1px solid #e5e7eb; border-radius: 8px; }
+#controls { display: flex; align-items: center; gap: 8px; margin-top: 8px; }
+#controls input[type=range] { flex: 1; }
+#clock { font-variant-numeric: tabular-nums; min-width: 64px; text-align: right; }
+.note { margin: 8px 0 0; color: #6b7280; font-size: 13px; }
+aside { width: 340px; display: flex; flex-direction: column; gap: 16px; }
+section { background: #fff; border: 1px solid #e5e7eb; border-radius: 8px; padding: 12px; }
+section h2 { margin: 0 0 8px; font-size: 15px; }
+#chat { max-height: 360px; overflow-y: auto; font-size: 14px; }
+#chat p { margin: 0 0 6px; }
+#chat .time { color: #9ca3af; font-size: 12px; margin-right: 4px; }
+#chat .system { color: #6b7280; font-style: italic; }
+table { width: 100%; border-collapse: collapse; font-size: 13px; }
+th, td { text-align: left; padding: 4px; border-bottom: 1px solid #f3f4f6; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Name}}</h1>
+<p>Started {{.StartedAt}} &middot; {{.Duration}} &middot; exported {{.ExportedAt}}</p>
+</header>
+<main>
+<div id="stage">
+<svg id="canvas" xmlns="http://www.w3.org/2000/svg"></svg>
+<div id="controls">
+<button id="play" type="button">Play</button>
+<button id="prev" type="button">&larr;</button>
+<button id="next" type="button">&rarr;</button>
+<input id="scrub" type="range" min="0" value="0">
+<span id="clock">0:00</span>
+</div>
+{{if .Compacted}}<p class="note">The replay starts from the canvas after its first {{.Compacted}} operations, which were compacted during the session.</p>
+{{end}}</div>
+<aside>
+<section>
+<h2>Attendance</h2>
+<table>
+<tr><th>Participant</th><th>Role</th><th>Connected</th><th>Msgs</th><th>Chat</th></tr>
+{{range .Attendees}}<tr><td>{{.Name}}</td><td>{{.Role}}</td><td>{{.Connected}}</td><td>{{.Messages}}</td><td>{{.ChatShare}}</td></tr>
+{{end}}</table>
+</section>
+<section>
+<h2>Chat</h2>
+<div id="chat"></div>
+</section>
+</aside>
+</main>
+<script>
+(function () {
+  var data = {{.Replay}};
+  var svgNS = "http://www.w3.org/2000/svg";
+  var canvas = document.getElementById("canvas");
+  var chat = document.getElementById("chat");
+  var scrub = document.getElementById("scrub");
+  var clock = document.getElementById("clock");
+  var playButton = document.getElementById("play");
+  var start = Date.parse(data.started_at);
+
+  // Canvas operations and chat messages form one timeline
+  var events = [];
+  (data.operations || []).forEach(function (op) {
+    events.push({ at: Date.parse(op.timestamp), op: op });
+  });
+  (data.messages || []).forEach(function (message) {
+    events.push({ at: Date.parse(message.created_at), message: message });
+  });
+  events.sort(function (a, b) { return a.at - b.at; });
+  scrub.max = events.length;
+
+  function elementID(op) {
+    var d = op.operation_data || {};
+    if (op.element_id) return op.element_id;
+    return d.elementId || d.id || (d.element && d.element.id) || op.id;
+  }
+
+  function initialState() {
+    var state = { elements: [], scene: null };
+    var initial = data.initial || {};
+    (initial.elements || []).forEach(function (element) {
+      state.elements.push({ id: element.id, type: element.operation_type, data: element.data });
+    });
+    state.scene = initial.scene || null;
+    return state;
+  }
+
+  // apply mirrors CanvasState.Apply on the server
+  function apply(state, op) {
+    var id = elementID(op);
+    var i = state.elements.findIndex(function (e) { return e.id === id; });
+    switch (op.operation_type) {
+      case "clear":
+        state.elements = [];
+        state.scene = null;
+        break;
+      case "excalidraw_update":
+        state.scene = op.operation_data;
+        break;
+      case "delete":
+        if (i >= 0) state.elements.splice(i, 1);
+        break;
+      case "move":
+        if (i >= 0) {
+          var moved = JSON.parse(JSON.stringify(state.elements[i].data));
+          var target = moved.element || moved;
+          target.x = op.operation_data.x;
+          target.y = op.operation_data.y;
+          state.elements[i].data = moved;
+        }
+        break;
+      default:
+        var element = { id: id, type: op.operation_type, data: op.operation_data };
+        if (i >= 0) state.elements[i] = element; else state.elements.push(element);
+    }
+  }
+
+  function node(name, attrs, text) {
+    var el = document.createElementNS(svgNS, name);
+    Object.keys(attrs).forEach(function (key) {
+      if (attrs[key] !== undefined && attrs[key] !== null) el.setAttribute(key, attrs[key]);
+    });
+    if (text !== undefined) el.textContent = text;
+    return el;
+  }
+
+  function drawText(d) {
+    var style = d.style || {};
+    canvas.appendChild(node("text", {
+      x: d.x || 0, y: d.y || 0,
+      fill: style.color || "#111827",
+      "font-size": style.fontSize || 16,
+      "font-family": style.fontFamily || "sans-serif",
+      "font-weight": style.bold ? "bold" : null,
+      "font-style": style.italic ? "italic" : null,
+      "dominant-baseline": "hanging"
+    }, d.text || ""));
+  }
+
+  function drawPath(d) {
+    var path = d.path || [];
+    if (!path.length) return;
+    var style = d.style || {};
+    canvas.appendChild(node("polyline", {
+      points: path.map(function (p) { return p.x + "," + p.y; }).join(" "),
+      fill: "none",
+      stroke: style.color || "#111827",
+      "stroke-width": style.width || 2,
+      "stroke-opacity": style.opacity || 1,
+      "stroke-linecap": "round",
+      "stroke-linejoin": "round"
+    }));
+  }
+
+  function drawSceneElement(e) {
+    if (e.isDeleted) return;
+    var stroke = e.strokeColor || "#111827";
+    var fill = e.backgroundColor && e.backgroundColor !== "transparent" ? e.backgroundColor : "none";
+    var common = { stroke: stroke, fill: fill, "stroke-width": e.strokeWidth || 1 };
+    if (e.type === "rectangle") {
+      canvas.appendChild(node("rect", Object.assign({ x: e.x, y: e.y, width: e.width, height: e.height }, common)));
+    } else if (e.type === "ellipse") {
+      canvas.appendChild(node("ellipse", Object.assign({
+        cx: e.x + e.width / 2, cy: e.y + e.height / 2, rx: Math.abs(e.width / 2), ry: Math.abs(e.height / 2)
+      }, common)));
+    } else if (e.type === "diamond") {
+      var points = [
+        [e.x + e.width / 2, e.y], [e.x + e.width, e.y + e.height / 2],
+        [e.x + e.width / 2, e.y + e.height], [e.x, e.y + e.height / 2]
+      ];
+      canvas.appendChild(node("polygon", Object.assign({ points: points.join(" ") }, common)));
+    } else if (e.type === "text") {
+      drawText({ x: e.x, y: e.y, text: e.text, style: { color: stroke, fontSize: e.fontSize } });
+    } else if (e.points) {
+      canvas.appendChild(node("polyline", {
+        points: e.points.map(function (p) { return (e.x + p[0]) + "," + (e.y + p[1]); }).join(" "),
+        fill: "none", stroke: stroke, "stroke-width": e.strokeWidth || 1
+      }));
+    }
+  }
+
+  function draw(state) {
+    while (canvas.firstChild) canvas.removeChild(canvas.firstChild);
+    if (state.scene && state.scene.elements) state.scene.elements.forEach(drawSceneElement);
+    state.elements.forEach(function (element) {
+      var d = element.data || {};
+      if (d.element) d = d.element;
+      if (d.path) drawPath(d); else if (d.text !== undefined) drawText(d);
+    });
+  }
+
+  function formatClock(ms) {
+    var seconds = Math.max(0, Math.floor(ms / 1000));
+    var minutes = Math.floor(seconds / 60);
+    seconds = seconds % 60;
+    return minutes + ":" + (seconds < 10 ? "0" : "") + seconds;
+  }
+
+  function addMessage(message) {
+    var p = document.createElement("p");
+    var time = document.createElement("span");
+    time.className = "time";
+    time.textContent = formatClock(Date.parse(message.created_at) - start);
+    p.appendChild(time);
+    if (message.message_type === "system") {
+      p.className = "system";
+      p.appendChild(document.createTextNode(message.content));
+    } else {
+      var name = document.createElement("strong");
+      name.textContent = message.user_name + ": ";
+      p.appendChild(name);
+      p.appendChild(document.createTextNode(message.content));
+    }
+    chat.appendChild(p);
+  }
+
+  // show replays the first n events from the initial state
+  var position = 0;
+  function show(n) {
+    position = Math.max(0, Math.min(events.length, n));
+    var state = initialState();
+    chat.textContent = "";
+    for (var i = 0; i < position; i++) {
+      if (events[i].op) apply(state, events[i].op); else addMessage(events[i].message);
+    }
+    draw(state);
+    chat.scrollTop = chat.scrollHeight;
+    scrub.value = position;
+    clock.textContent = formatClock((position > 0 ? events[position - 1].at : start) - start);
+  }
+
+  // Playback waits for the real gap between events, capped at two seconds
+  var timer = null;
+  function stop() {
+    clearTimeout(timer);
+    timer = null;
+    playButton.textContent = "Play";
+  }
+  function tick() {
+    if (position >= events.length) { stop(); return; }
+    show(position + 1);
+    var next = events[position];
+    var wait = next ? Math.min(2000, Math.max(50, next.at - events[position - 1].at)) : 0;
+    timer = setTimeout(tick, wait);
+  }
+
+  playButton.addEventListener("click", function () {
+    if (timer) { stop(); return; }
+    if (position >= events.length) show(0);
+    playButton.textContent = "Pause";
+    tick();
+  });
+  document.getElementById("prev").addEventListener("click", function () { stop(); show(position - 1); });
+  document.getElementById("next").addEventListener("click", function () { stop(); show(position + 1); });
+  scrub.addEventListener("input", function () { stop(); show(parseInt(scrub.value, 10)); });
+
+  show(0);
+})();
+</script>
+</body>
+</html>
+`))
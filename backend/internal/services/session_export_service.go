@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// Number of messages fetched per page while exporting a transcript
+const exportMessagePageSize = 100
+
+// sessionExportService implements SessionExportService
+type sessionExportService struct {
+	sessionService SessionService
+}
+
+// NewSessionExportService creates a new session export service
+func NewSessionExportService(sessionService SessionService) SessionExportService {
+	return &sessionExportService{
+		sessionService: sessionService,
+	}
+}
+
+// ExportSession bundles an ended session's transcript, attendance and canvas
+// history. Anyone who may see the session's attendance may export it.
+func (s *sessionExportService) ExportSession(ctx context.Context, sessionID, userID string) (*models.SessionExport, error) {
+	session, err := s.sessionService.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attendance also checks that the user may see the session
+	attendance, err := s.sessionService.GetSessionAttendance(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status != models.SessionStatusEnded {
+		return nil, models.NewAppError("SESSION_NOT_ENDED", "Sessions can be exported once they have ended", 409)
+	}
+
+	messages, err := s.allMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas, err := s.canvasHistory(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SessionExport{
+		Session:    session,
+		ExportedAt: time.Now().UTC(),
+		Messages:   messages,
+		Attendance: attendance,
+		Canvas:     *canvas,
+	}, nil
+}
+
+// RenderSessionExport formats an export as JSON, Markdown or an HTML replay
+func (s *sessionExportService) RenderSessionExport(export *models.SessionExport, format string) ([]byte, error) {
+	switch format {
+	case models.ExportFormatJSON:
+		return json.MarshalIndent(export, "", "  ")
+	case models.ExportFormatMarkdown:
+		return renderSessionMarkdown(export), nil
+	case models.ExportFormatHTML:
+		return renderSessionHTML(export)
+	default:
+		return nil, models.NewAppError("UNSUPPORTED_FORMAT", "Export format must be json, markdown or html", 406)
+	}
+}
+
+// allMessages pages through the session's messages, oldest first
+func (s *sessionExportService) allMessages(ctx context.Context, sessionID string) ([]*models.SessionMessage, error) {
+	messages := []*models.SessionMessage{}
+	for offset := 0; ; offset += exportMessagePageSize {
+		// Pages come newest first, each in chronological order
+		page, err := s.sessionService.GetSessionMessages(ctx, sessionID, exportMessagePageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(page, messages...)
+
+		if len(page) < exportMessagePageSize {
+			return messages, nil
+		}
+	}
+}
+
+// canvasHistory returns the canvas state the retained operations start from,
+// the operations and the resulting final state
+func (s *sessionExportService) canvasHistory(ctx context.Context, sessionID string) (*models.SessionCanvasExport, error) {
+	canvas, err := s.sessionService.GetCanvasState(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	initial := &models.CanvasState{}
+	final := &models.CanvasState{}
+	compacted := 0
+	if canvas.Snapshot != nil {
+		*initial = canvas.Snapshot.State
+		compacted = canvas.Snapshot.OperationCount
+
+		// Apply replaces elements in place, so give the final state its own copy
+		data, err := json.Marshal(canvas.Snapshot.State)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy canvas snapshot: %w", err)
+		}
+		if err := json.Unmarshal(data, final); err != nil {
+			return nil, fmt.Errorf("failed to copy canvas snapshot: %w", err)
+		}
+	}
+
+	for _, operation := range canvas.Operations {
+		final.Apply(operation)
+	}
+
+	return &models.SessionCanvasExport{
+		Initial:             initial,
+		CompactedOperations: compacted,
+		Operations:          canvas.Operations,
+		Final:               final,
+	}, nil
+}
+
+// renderSessionMarkdown writes the export as a readable transcript
+func renderSessionMarkdown(export *models.SessionExport) []byte {
+	session := export.Session
+	location := exportLocation(session)
+	names := exportUserNames(export)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscape(session.Name))
+	if session.Description != nil && *session.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", markdownEscape(*session.Description))
+	}
+
+	started := session.CreatedAt
+	if session.StartedAt != nil {
+		started = *session.StartedAt
+	}
+	fmt.Fprintf(&b, "- **Started:** %s\n", started.In(location).Format("2006-01-02 15:04 MST"))
+	if session.EndedAt != nil {
+		fmt.Fprintf(&b, "- **Ended:** %s\n", session.EndedAt.In(location).Format("2006-01-02 15:04 MST"))
+		fmt.Fprintf(&b, "- **Duration:** %s\n", formatExportDuration(session.EndedAt.Sub(started)))
+	}
+	if session.TargetLanguage != nil {
+		fmt.Fprintf(&b, "- **Language:** %s\n", markdownEscape(*session.TargetLanguage))
+	}
+
+	b.WriteString("\n## Attendance\n\n")
+	b.WriteString("| Participant | Role | Connected | Reconnects | Messages | Chat share |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, participant := range export.Attendance.Participants {
+		role := participant.Role
+		if role == "" {
+			role = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %d | %.0f%% |\n",
+			markdownEscape(names[participant.UserID]), role,
+			formatExportDuration(time.Duration(participant.ConnectedSeconds)*time.Second),
+			participant.Reconnects, participant.MessageCount, participant.ChatShare*100)
+	}
+
+	b.WriteString("\n## Transcript\n\n")
+	if len(export.Messages) == 0 {
+		b.WriteString("_No messages were sent._\n")
+	}
+	for _, message := range export.Messages {
+		timestamp := message.CreatedAt.In(location).Format("15:04:05")
+		content := markdownEscape(message.Content)
+		if message.MessageType == "system" {
+			fmt.Fprintf(&b, "- `%s` _%s_\n", timestamp, content)
+			continue
+		}
+		if message.MessageType != "text" {
+			content = fmt.Sprintf("[%s] %s", message.MessageType, content)
+		}
+		fmt.Fprintf(&b, "- `%s` **%s:** %s\n", timestamp, markdownEscape(names[message.UserID]), content)
+	}
+
+	b.WriteString("\n## Canvas\n\n")
+	final := export.Canvas.Final
+	texts := canvasTexts(final)
+	fmt.Fprintf(&b, "%d operations recorded, %d elements on the final canvas.\n", export.Canvas.CompactedOperations+len(export.Canvas.Operations), len(final.Elements))
+	if len(texts) > 0 {
+		b.WriteString("\nText on the final canvas:\n\n")
+		for _, text := range texts {
+			fmt.Fprintf(&b, "- %s\n", markdownEscape(text))
+		}
+	}
+
+	return b.Bytes()
+}
+
+// exportUserNames maps user IDs in the export to display names
+func exportUserNames(export *models.SessionExport) map[string]string {
+	names := make(map[string]string)
+	if export.Session.Creator != nil {
+		names[export.Session.CreatedBy] = export.Session.Creator.Name
+	}
+	for _, participant := range export.Attendance.Participants {
+		if participant.User != nil {
+			names[participant.UserID] = participant.User.Name
+		}
+	}
+	for _, message := range export.Messages {
+		if message.User != nil {
+			names[message.UserID] = message.User.Name
+		}
+	}
+	for _, participant := range export.Attendance.Participants {
+		if names[participant.UserID] == "" {
+			names[participant.UserID] = "Unknown user"
+		}
+	}
+	for _, message := range export.Messages {
+		if names[message.UserID] == "" {
+			names[message.UserID] = "Unknown user"
+		}
+	}
+	return names
+}
+
+// exportLocation is the session's timezone, falling back to UTC
+func exportLocation(session *models.LanguageSession) *time.Location {
+	if session.Timezone != nil {
+		if location, err := time.LoadLocation(*session.Timezone); err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// canvasTexts returns the text of the canvas' text elements in drawing order
+func canvasTexts(state *models.CanvasState) []string {
+	var texts []string
+	for _, element := range state.Elements {
+		var data struct {
+			Text    string `json:"text"`
+			Element *struct {
+				Text string `json:"text"`
+			} `json:"element"`
+		}
+		if err := json.Unmarshal(element.Data, &data); err != nil {
+			continue
+		}
+		text := data.Text
+		if data.Element != nil && data.Element.Text != "" {
+			text = data.Element.Text
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+func formatExportDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// markdownEscape keeps user text from being read as Markdown or table syntax
+func markdownEscape(text string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+		"<", "&lt;", ">", "&gt;", "|", `\|`, "#", `\#`,
+		"\r\n", " ", "\n", " ",
+	)
+	return replacer.Replace(text)
+}
+
+// sortedOperations returns the operations ordered by time, then sequence
+func sortedOperations(operations []*models.CanvasOperation) []*models.CanvasOperation {
+	sorted := make([]*models.CanvasOperation, len(operations))
+	copy(sorted, operations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Timestamp.Equal(sorted[j].Timestamp) {
+			return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+		}
+		return sorted[i].SequenceNumber < sorted[j].SequenceNumber
+	})
+	return sorted
+}
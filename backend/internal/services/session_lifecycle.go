@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// OpenSessionConnection records a WebSocket connection joining a session room
+func (s *sessionService) OpenSessionConnection(ctx context.Context, connection *models.SessionConnection) error {
+	return s.sessionRepo.OpenSessionConnection(ctx, connection)
+}
+
+// CloseSessionConnection records a WebSocket connection leaving a session room
+func (s *sessionService) CloseSessionConnection(ctx context.Context, connectionID string) error {
+	return s.sessionRepo.CloseSessionConnection(ctx, connectionID)
+}
+
+// RefreshSessionConnections keeps a hub node's open connections from going stale
+func (s *sessionService) RefreshSessionConnections(ctx context.Context, nodeID string) error {
+	return s.sessionRepo.RefreshSessionConnections(ctx, nodeID)
+}
+
+// reapIdleSessions ends active sessions that nobody has been connected to for
+// the idle timeout and settles their XP
+func (s *sessionService) reapIdleSessions(ctx context.Context, now time.Time) {
+	sessionIDs, err := s.sessionRepo.GetIdleSessionIDs(ctx, now.Add(-s.idleTimeout), now.Add(-models.SessionConnectionStaleAfter))
+	if err != nil {
+		log.Printf("Failed to get idle sessions: %v", err)
+		return
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := s.sessionRepo.EndSession(ctx, sessionID); err != nil {
+			// The creator may have ended it in the meantime
+			if err != models.ErrSessionEnded {
+				log.Printf("Failed to end idle session %s: %v", sessionID, err)
+			}
+			continue
+		}
+
+		log.Printf("Ended session %s after %s without connected participants", sessionID, s.idleTimeout)
+		s.settleSession(ctx, sessionID)
+		s.notifySessionEnded(sessionID, models.SessionEndReasonIdle)
+	}
+}
+
+// notifySessionEnded tells clients still in the session room that it ended
+func (s *sessionService) notifySessionEnded(sessionID, reason string) {
+	if s.wsHub == nil {
+		return
+	}
+
+	s.wsHub.SendToSession(sessionID, models.WebSocketMessage{
+		Type: models.WSMessageTypeSessionEnded,
+		Data: models.SessionEndedEvent{
+			SessionID: sessionID,
+			Reason:    reason,
+		},
+	}, nil)
+}
+
+// settleSession awards session XP to each participant for the minutes they
+// were actually connected between the session's start and end. Only WebSocket
+// connections count: they are kept alive by heartbeats, while a REST join
+// stays open until the user leaves whether or not they are still there.
+func (s *sessionService) settleSession(ctx context.Context, sessionID string) {
+	if s.gamificationService == nil {
+		return
+	}
+
+	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil || session.EndedAt == nil {
+		log.Printf("Failed to get ended session %s: %v", sessionID, err)
+		return
+	}
+
+	connections, err := s.sessionRepo.GetSessionConnections(ctx, sessionID)
+	if err != nil {
+		log.Printf("Failed to get connections for session %s: %v", sessionID, err)
+		return
+	}
+
+	startedAt := session.CreatedAt
+	if session.StartedAt != nil {
+		startedAt = *session.StartedAt
+	}
+
+	for userID, connected := range connectedDurations(connectionsFrom(connections, models.SessionConnectionSourceWebSocket), startedAt, *session.EndedAt) {
+		sessionMinutes := int(connected.Minutes())
+		if sessionMinutes == 0 {
+			continue
+		}
+
+		go func(userID string, sessionMinutes int) {
+			if err := s.gamificationService.OnSessionComplete(context.Background(), userID, sessionMinutes); err != nil {
+				log.Printf("Failed to award session XP to user %s: %v", userID, err)
+			}
+		}(userID, sessionMinutes)
+	}
+}
+
+// connectionsFrom returns the connections recorded from source
+func connectionsFrom(connections []*models.SessionConnection, source string) []*models.SessionConnection {
+	filtered := make([]*models.SessionConnection, 0, len(connections))
+	for _, connection := range connections {
+		if connection.Source == source {
+			filtered = append(filtered, connection)
+		}
+	}
+	return filtered
+}
+
+// connectedDurations returns how long each user was connected within
+// [from, to]. Overlapping connections, e.g. from two tabs, count once.
+func connectedDurations(connections []*models.SessionConnection, from, to time.Time) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for userID, intervals := range presenceIntervals(connections, from, to) {
+		for _, interval := range intervals {
+			durations[userID] += interval.end.Sub(interval.start)
+		}
+	}
+	return durations
+}
+
+// presenceInterval is a period a user was connected; open is set while any
+// of its connections still is
+type presenceInterval struct {
+	start, end time.Time
+	open       bool
+}
+
+// presenceIntervals clips each user's connections to [from, to] and merges
+// overlapping ones, ordered by start
+func presenceIntervals(connections []*models.SessionConnection, from, to time.Time) map[string][]presenceInterval {
+	byUser := make(map[string][]presenceInterval)
+	for _, connection := range connections {
+		start, end := connection.ConnectedAt, connection.Until(to)
+		open := connection.DisconnectedAt == nil && !end.Before(to)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) || open {
+			byUser[connection.UserID] = append(byUser[connection.UserID], presenceInterval{start, end, open})
+		}
+	}
+
+	merged := make(map[string][]presenceInterval, len(byUser))
+	for userID, intervals := range byUser {
+		sort.Slice(intervals, func(i, j int) bool {
+			return intervals[i].start.Before(intervals[j].start)
+		})
+
+		current := intervals[0]
+		for _, next := range intervals[1:] {
+			if !next.start.After(current.end) {
+				if next.end.After(current.end) {
+					current.end = next.end
+				}
+				current.open = current.open || next.open
+				continue
+			}
+			merged[userID] = append(merged[userID], current)
+			current = next
+		}
+		merged[userID] = append(merged[userID], current)
+	}
+
+	return merged
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+func TestPresenceIntervals(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC)
+	}
+	closed := func(userID string, start, end time.Time) *models.SessionConnection {
+		return &models.SessionConnection{
+			UserID:         userID,
+			Source:         models.SessionConnectionSourceWebSocket,
+			ConnectedAt:    start,
+			LastSeenAt:     end,
+			DisconnectedAt: &end,
+		}
+	}
+	open := func(userID string, start, lastSeen time.Time) *models.SessionConnection {
+		return &models.SessionConnection{
+			UserID:      userID,
+			Source:      models.SessionConnectionSourceWebSocket,
+			ConnectedAt: start,
+			LastSeenAt:  lastSeen,
+		}
+	}
+	from, to := at(10, 0), at(11, 0)
+
+	tests := []struct {
+		name          string
+		connections   []*models.SessionConnection
+		want          map[string][]presenceInterval
+		wantDurations map[string]time.Duration
+	}{
+		{
+			name:          "two overlapping tabs",
+			connections:   []*models.SessionConnection{closed("alice", at(10, 5), at(10, 30)), closed("alice", at(10, 20), at(10, 40))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 5), at(10, 40), false}}},
+			wantDurations: map[string]time.Duration{"alice": 35 * time.Minute},
+		},
+		{
+			name:          "a tab inside another",
+			connections:   []*models.SessionConnection{closed("alice", at(10, 5), at(10, 40)), closed("alice", at(10, 10), at(10, 20))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 5), at(10, 40), false}}},
+			wantDurations: map[string]time.Duration{"alice": 35 * time.Minute},
+		},
+		{
+			name:          "a connection still open",
+			connections:   []*models.SessionConnection{closed("alice", at(10, 0), at(10, 5)), open("alice", at(10, 10), at(10, 59))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 0), at(10, 5), false}, {at(10, 10), at(11, 0), true}}},
+			wantDurations: map[string]time.Duration{"alice": 55 * time.Minute},
+		},
+		{
+			name:          "an open tab overlapping a closed one",
+			connections:   []*models.SessionConnection{open("alice", at(10, 10), at(10, 59)), closed("alice", at(10, 0), at(10, 20))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 0), at(11, 0), true}}},
+			wantDurations: map[string]time.Duration{"alice": time.Hour},
+		},
+		{
+			name:          "an open connection of a crashed node",
+			connections:   []*models.SessionConnection{open("alice", at(10, 10), at(10, 20))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 10), at(10, 20), false}}},
+			wantDurations: map[string]time.Duration{"alice": 10 * time.Minute},
+		},
+		{
+			name:          "a connection started before from",
+			connections:   []*models.SessionConnection{closed("alice", at(9, 50), at(10, 15))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 0), at(10, 15), false}}},
+			wantDurations: map[string]time.Duration{"alice": 15 * time.Minute},
+		},
+		{
+			name:          "a connection ended before from",
+			connections:   []*models.SessionConnection{closed("alice", at(9, 30), at(9, 50))},
+			want:          map[string][]presenceInterval{},
+			wantDurations: map[string]time.Duration{},
+		},
+		{
+			name: "reconnects",
+			connections: []*models.SessionConnection{
+				closed("alice", at(10, 40), at(10, 50)),
+				closed("alice", at(10, 0), at(10, 10)),
+				closed("alice", at(10, 20), at(10, 30)),
+			},
+			want: map[string][]presenceInterval{"alice": {
+				{at(10, 0), at(10, 10), false},
+				{at(10, 20), at(10, 30), false},
+				{at(10, 40), at(10, 50), false},
+			}},
+			wantDurations: map[string]time.Duration{"alice": 30 * time.Minute},
+		},
+		{
+			name:          "a reconnect at the moment of leaving",
+			connections:   []*models.SessionConnection{closed("alice", at(10, 0), at(10, 10)), closed("alice", at(10, 10), at(10, 20))},
+			want:          map[string][]presenceInterval{"alice": {{at(10, 0), at(10, 20), false}}},
+			wantDurations: map[string]time.Duration{"alice": 20 * time.Minute},
+		},
+		{
+			name:        "users are kept apart",
+			connections: []*models.SessionConnection{closed("alice", at(10, 0), at(10, 30)), closed("bob", at(10, 15), at(10, 45))},
+			want: map[string][]presenceInterval{
+				"alice": {{at(10, 0), at(10, 30), false}},
+				"bob":   {{at(10, 15), at(10, 45), false}},
+			},
+			wantDurations: map[string]time.Duration{"alice": 30 * time.Minute, "bob": 30 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := presenceIntervals(tt.connections, from, to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("presenceIntervals() = %v, want %v", got, tt.want)
+			}
+			if durations := connectedDurations(tt.connections, from, to); !reflect.DeepEqual(durations, tt.wantDurations) {
+				t.Errorf("connectedDurations() = %v, want %v", durations, tt.wantDurations)
+			}
+		})
+	}
+}
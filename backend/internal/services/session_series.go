@@ -0,0 +1,640 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"language-exchange/internal/models"
+	"language-exchange/pkg/ics"
+
+	"github.com/google/uuid"
+)
+
+// Number of upcoming occurrences returned with a series
+const seriesUpcomingOccurrences = 10
+
+// createSeries stores a recurring session and creates the session for its
+// first occurrence, which is returned
+func (s *sessionService) createSeries(ctx context.Context, first *models.LanguageSession, invitees []string, rrule string) (*models.LanguageSession, error) {
+	rule, err := ics.ParseRecurrenceRule(rrule)
+	if err != nil {
+		return nil, models.NewAppError("INVALID_RECURRENCE", err.Error(), 400)
+	}
+
+	series := &models.SessionSeries{
+		ID:              uuid.New().String(),
+		CreatedBy:       first.CreatedBy,
+		Name:            first.Name,
+		Description:     first.Description,
+		MaxParticipants: first.MaxParticipants,
+		SessionType:     first.SessionType,
+		TargetLanguage:  first.TargetLanguage,
+		InviteeIDs:      invitees,
+		Timezone:        *first.Timezone,
+		StartsAt:        *first.ScheduledStartAt,
+		DurationMinutes: int(first.ScheduledEndAt.Sub(*first.ScheduledStartAt).Minutes()),
+		RRule:           rule.String(),
+		Status:          models.SeriesStatusActive,
+	}
+
+	if err := s.sessionRepo.CreateSeries(ctx, series); err != nil {
+		return nil, fmt.Errorf("failed to create session series: %w", err)
+	}
+
+	// Always create the first occurrence so there is a session to return
+	until := time.Now().Add(seriesMaterializeHorizon)
+	if !series.StartsAt.Before(until) {
+		until = series.StartsAt.Add(time.Second)
+	}
+
+	// The request paid for the first occurrence
+	sessions, err := s.materializeOccurrences(ctx, series, until, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, models.ErrInvalidRecurrence
+	}
+
+	return sessions[0], nil
+}
+
+// GetSeries returns a series with its exceptions and upcoming occurrences
+func (s *sessionService) GetSeries(ctx context.Context, seriesID, userID string) (*models.SessionSeries, error) {
+	series, err := s.sessionRepo.GetSeriesByID(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	isAttendee := series.CreatedBy == userID
+	for _, inviteeID := range series.InviteeIDs {
+		isAttendee = isAttendee || inviteeID == userID
+	}
+	if !isAttendee {
+		return nil, models.NewAppError("NOT_INVITED", "You are not invited to this session series", 403)
+	}
+
+	exceptions, err := s.sessionRepo.GetSeriesExceptions(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		series.Exceptions = append(series.Exceptions, *exception)
+	}
+
+	rule, location, err := seriesRule(series)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look ahead far enough to find the next occurrences of a monthly series
+	now := time.Now()
+	until := now.AddDate(0, seriesUpcomingOccurrences*rule.Interval+12, 0)
+	occurrences := seriesOccurrences(series, rule, location, exceptions, now, until)
+	if len(occurrences) > seriesUpcomingOccurrences {
+		occurrences = occurrences[:seriesUpcomingOccurrences]
+	}
+
+	sessions, err := s.sessionRepo.GetSeriesSessions(ctx, seriesID, now.Add(-maxSessionDuration))
+	if err != nil {
+		return nil, err
+	}
+	for i := range occurrences {
+		for _, session := range sessions {
+			if session.OccurrenceStart != nil && session.OccurrenceStart.Equal(occurrences[i].OccurrenceStart) {
+				occurrences[i].SessionID = &session.ID
+			}
+		}
+	}
+	series.Occurrences = occurrences
+
+	return series, nil
+}
+
+// UpdateSeries edits a single occurrence of a series, or an occurrence and
+// all later ones. Single occurrence edits are kept as exceptions; editing
+// future occurrences ends the series and continues it as a new one.
+func (s *sessionService) UpdateSeries(ctx context.Context, seriesID, userID string, input models.UpdateSeriesInput) (*models.SessionSeries, error) {
+	series, err := s.sessionRepo.GetSeriesByID(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if series.CreatedBy != userID {
+		return nil, models.NewAppError("UNAUTHORIZED", "Only the series creator can edit the series", 403)
+	}
+
+	rule, location, err := seriesRule(series)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrenceStart, err := parseScheduleTime(input.OccurrenceStart, location)
+	if err != nil {
+		return nil, models.NewAppError("INVALID_SCHEDULE", "Invalid occurrence_start", 400)
+	}
+	if !rule.Includes(series.StartsAt.In(location), occurrenceStart) {
+		return nil, models.ErrOccurrenceNotFound
+	}
+	occurrenceStart = occurrenceStart.UTC()
+
+	session, err := s.occurrenceSession(ctx, series.ID, occurrenceStart)
+	if err != nil {
+		return nil, err
+	}
+	// Cancelled occurrences can still be edited and uncancelled
+	if session != nil && session.Status != models.SessionStatusScheduled && session.Status != models.SessionStatusCancelled {
+		return nil, models.ErrOccurrenceStarted
+	}
+
+	switch input.Scope {
+	case models.SeriesScopeThis:
+		err = s.updateOccurrence(ctx, series, occurrenceStart, session, input, location)
+	case models.SeriesScopeFuture:
+		seriesID, err = s.updateFutureOccurrences(ctx, series, rule, occurrenceStart, input, location)
+	default:
+		return nil, models.NewAppError("INVALID_INPUT", "scope must be this or future", 400)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSeries(ctx, seriesID, userID)
+}
+
+// EndSeries stops a series and cancels its upcoming sessions
+func (s *sessionService) EndSeries(ctx context.Context, seriesID, userID string) error {
+	series, err := s.sessionRepo.GetSeriesByID(ctx, seriesID)
+	if err != nil {
+		return err
+	}
+	if series.CreatedBy != userID {
+		return models.NewAppError("UNAUTHORIZED", "Only the series creator can end the series", 403)
+	}
+
+	series.Status = models.SeriesStatusEnded
+	if err := s.sessionRepo.UpdateSeries(ctx, series); err != nil {
+		return err
+	}
+
+	sessions, err := s.sessionRepo.GetUpcomingSeriesSessions(ctx, seriesID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.cancelOccurrenceSession(ctx, session); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sessionService) updateOccurrence(ctx context.Context, series *models.SessionSeries, occurrenceStart time.Time, session *models.LanguageSession, input models.UpdateSeriesInput, location *time.Location) error {
+	if input.RRule != "" {
+		return models.NewAppError("INVALID_INPUT", "rrule can only be changed for future occurrences", 400)
+	}
+
+	exceptions, err := s.sessionRepo.GetSeriesExceptions(ctx, series.ID)
+	if err != nil {
+		return err
+	}
+
+	exception := &models.SessionSeriesException{
+		ID:              uuid.New().String(),
+		SeriesID:        series.ID,
+		OccurrenceStart: occurrenceStart,
+	}
+	for _, existing := range exceptions {
+		if existing.OccurrenceStart.Equal(occurrenceStart) {
+			exception = existing
+		}
+	}
+
+	if input.Cancelled != nil {
+		exception.Cancelled = *input.Cancelled
+	}
+	if input.Name != nil {
+		exception.Name = input.Name
+	}
+	if input.Description != nil {
+		exception.Description = input.Description
+	}
+
+	start := occurrenceStart
+	if exception.StartsAt != nil {
+		start = *exception.StartsAt
+	}
+	end := start.Add(series.Duration())
+	if exception.EndsAt != nil {
+		end = *exception.EndsAt
+	}
+	start, end, err = rescheduleOccurrence(start, end, input, location)
+	if err != nil {
+		return err
+	}
+	if !start.Equal(occurrenceStart) || end.Sub(start) != series.Duration() {
+		exception.StartsAt = &start
+		exception.EndsAt = &end
+	}
+
+	if err := s.sessionRepo.SaveSeriesException(ctx, exception); err != nil {
+		return err
+	}
+
+	if session == nil {
+		// The materializer skipped the occurrence while it was cancelled
+		if !exception.Cancelled && series.MaterializedUntil != nil && occurrenceStart.Before(*series.MaterializedUntil) {
+			return s.restoreOccurrenceSession(ctx, series, seriesOccurrence(series, occurrenceStart, exception))
+		}
+		// Otherwise the materializer applies the exception when it creates
+		// the session
+		return nil
+	}
+	if exception.Cancelled {
+		if session.Status == models.SessionStatusCancelled {
+			return nil
+		}
+		return s.cancelOccurrenceSession(ctx, session)
+	}
+	if session.Status == models.SessionStatusCancelled {
+		if err := s.sessionRepo.ReopenSession(ctx, session.ID); err != nil {
+			return err
+		}
+		session.Status = models.SessionStatusScheduled
+	}
+
+	applyOccurrence(session, seriesOccurrence(series, occurrenceStart, exception))
+	if err := s.sessionRepo.UpdateSessionSchedule(ctx, session); err != nil {
+		return err
+	}
+	s.notifySessionAttendees(ctx, session, models.WSMessageTypeSessionUpdated)
+	return nil
+}
+
+// updateFutureOccurrences splits the series at occurrenceStart and returns
+// the ID of the series that continues it
+func (s *sessionService) updateFutureOccurrences(ctx context.Context, series *models.SessionSeries, rule *ics.RecurrenceRule, occurrenceStart time.Time, input models.UpdateSeriesInput, location *time.Location) (string, error) {
+	if input.Cancelled != nil && *input.Cancelled {
+		return "", models.NewAppError("INVALID_INPUT", "Use DELETE to cancel all future occurrences", 400)
+	}
+
+	dtstart := series.StartsAt.In(location)
+	start, end, err := rescheduleOccurrence(occurrenceStart, occurrenceStart.Add(series.Duration()), input, location)
+	if err != nil {
+		return "", err
+	}
+
+	nextRule := *rule
+	if input.RRule != "" {
+		parsed, err := ics.ParseRecurrenceRule(input.RRule)
+		if err != nil {
+			return "", models.NewAppError("INVALID_RECURRENCE", err.Error(), 400)
+		}
+		nextRule = *parsed
+	} else if rule.Count > 0 {
+		// The continued series has the occurrences that are left
+		nextRule.Count = rule.Count - rule.CountBefore(dtstart, occurrenceStart)
+	}
+
+	next := *series
+	next.ID = uuid.New().String()
+	next.StartsAt = start.UTC()
+	next.DurationMinutes = int(end.Sub(start).Minutes())
+	next.RRule = nextRule.String()
+	next.Status = models.SeriesStatusActive
+	next.MaterializedUntil = nil
+	next.Exceptions = nil
+	if input.Name != nil {
+		next.Name = *input.Name
+	}
+	if input.Description != nil {
+		next.Description = input.Description
+	}
+
+	// End the original series just before the split
+	previousCount := rule.CountBefore(dtstart, occurrenceStart)
+	until := occurrenceStart.Add(-time.Second)
+	endedRule := *rule
+	endedRule.Count = 0
+	endedRule.Until = &until
+	series.RRule = endedRule.String()
+	if previousCount == 0 {
+		series.Status = models.SeriesStatusEnded
+	}
+
+	keepSchedule := start.Equal(occurrenceStart) && next.DurationMinutes == series.DurationMinutes &&
+		nextRule.Frequency == rule.Frequency && nextRule.Interval == rule.Interval
+	if err := s.sessionRepo.SplitSeries(ctx, series, &next, occurrenceStart, keepSchedule); err != nil {
+		return "", err
+	}
+
+	if keepSchedule {
+		// Sessions already created for these occurrences moved to the new
+		// series; bring their name and description up to date
+		if err := s.refreshSeriesSessions(ctx, &next, occurrenceStart); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := s.materializeOccurrences(ctx, &next, time.Now().Add(seriesMaterializeHorizon), 0); err != nil {
+		return "", err
+	}
+
+	return next.ID, nil
+}
+
+func (s *sessionService) refreshSeriesSessions(ctx context.Context, series *models.SessionSeries, from time.Time) error {
+	sessions, err := s.sessionRepo.GetSeriesSessions(ctx, series.ID, from)
+	if err != nil {
+		return err
+	}
+	exceptions, err := s.sessionRepo.GetSeriesExceptions(ctx, series.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Status != models.SessionStatusScheduled || session.OccurrenceStart == nil {
+			continue
+		}
+
+		var exception *models.SessionSeriesException
+		for _, existing := range exceptions {
+			if existing.OccurrenceStart.Equal(*session.OccurrenceStart) {
+				exception = existing
+			}
+		}
+
+		applyOccurrence(session, seriesOccurrence(series, *session.OccurrenceStart, exception))
+		if err := s.sessionRepo.UpdateSessionSchedule(ctx, session); err != nil {
+			return err
+		}
+		s.notifySessionAttendees(ctx, session, models.WSMessageTypeSessionUpdated)
+	}
+
+	return nil
+}
+
+// materializeSeries creates the sessions for occurrences starting within the
+// materialize horizon
+func (s *sessionService) materializeSeries(ctx context.Context, now time.Time) {
+	until := now.Add(seriesMaterializeHorizon)
+
+	seriesList, err := s.sessionRepo.GetSeriesToMaterialize(ctx, until)
+	if err != nil {
+		log.Printf("Failed to get session series to materialize: %v", err)
+		return
+	}
+
+	for _, series := range seriesList {
+		if _, err := s.materializeOccurrences(ctx, series, until, 0); err != nil {
+			log.Printf("Failed to materialize session series %s: %v", series.ID, err)
+		}
+	}
+}
+
+// materializeOccurrences creates sessions for the series' occurrences up to
+// until and returns the ones it created. Each session counts against the
+// creator's session quota, apart from the first prepaid ones. The creator
+// committed to the series, so occurrences are counted but never refused.
+func (s *sessionService) materializeOccurrences(ctx context.Context, series *models.SessionSeries, until time.Time, prepaid int) ([]*models.LanguageSession, error) {
+	rule, location, err := seriesRule(series)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions, err := s.sessionRepo.GetSeriesExceptions(ctx, series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := series.StartsAt
+	if series.MaterializedUntil != nil {
+		from = *series.MaterializedUntil
+	}
+
+	now := time.Now()
+	var created []*models.LanguageSession
+	for _, occurrence := range seriesOccurrences(series, rule, location, exceptions, from, until) {
+		if occurrence.Cancelled || occurrence.EndsAt.Before(now) {
+			continue
+		}
+
+		session, err := s.createOccurrenceSession(ctx, series, occurrence)
+		if err != nil {
+			return created, err
+		}
+		if session != nil {
+			created = append(created, session)
+		}
+	}
+
+	if unpaid := len(created) - prepaid; unpaid > 0 && s.usageMeter != nil {
+		if err := s.usageMeter.RecordUsage(ctx, series.CreatedBy, models.MeterSessions, int64(unpaid)); err != nil {
+			log.Printf("Failed to record session usage of series %s: %v", series.ID, err)
+		}
+	}
+
+	ended := !rule.HasOccurrencesAfter(series.StartsAt.In(location), until)
+	if err := s.sessionRepo.MarkSeriesMaterialized(ctx, series.ID, until, ended); err != nil {
+		return created, err
+	}
+
+	return created, nil
+}
+
+// createOccurrenceSession creates and invites users to the session for an
+// occurrence. It returns nil if another server already created it.
+func (s *sessionService) createOccurrenceSession(ctx context.Context, series *models.SessionSeries, occurrence models.SeriesOccurrence) (*models.LanguageSession, error) {
+	timezone := series.Timezone
+	occurrenceStart := occurrence.OccurrenceStart
+	session := &models.LanguageSession{
+		ID:              uuid.New().String(),
+		CreatedBy:       series.CreatedBy,
+		Status:          models.SessionStatusScheduled,
+		MaxParticipants: series.MaxParticipants,
+		SessionType:     series.SessionType,
+		TargetLanguage:  series.TargetLanguage,
+		Timezone:        &timezone,
+		SeriesID:        &series.ID,
+		OccurrenceStart: &occurrenceStart,
+	}
+	if len(series.InviteeIDs) > 0 {
+		session.InvitedUserID = &series.InviteeIDs[0]
+	}
+	applyOccurrence(session, occurrence)
+
+	created, err := s.sessionRepo.CreateSeriesOccurrence(ctx, session)
+	if err != nil || !created {
+		return nil, err
+	}
+
+	if err := s.inviteUsers(ctx, session, series.InviteeIDs); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// occurrenceSession returns the session created for an occurrence, or nil
+func (s *sessionService) occurrenceSession(ctx context.Context, seriesID string, occurrenceStart time.Time) (*models.LanguageSession, error) {
+	sessions, err := s.sessionRepo.GetSeriesSessions(ctx, seriesID, occurrenceStart)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.OccurrenceStart != nil && session.OccurrenceStart.Equal(occurrenceStart) {
+			return session, nil
+		}
+	}
+	return nil, nil
+}
+
+// restoreOccurrenceSession creates the session of an uncancelled occurrence
+// that the materializer skipped while it was cancelled. Like materialized
+// occurrences it counts against the creator's session quota.
+func (s *sessionService) restoreOccurrenceSession(ctx context.Context, series *models.SessionSeries, occurrence models.SeriesOccurrence) error {
+	if occurrence.EndsAt.Before(time.Now()) {
+		return nil
+	}
+
+	session, err := s.createOccurrenceSession(ctx, series, occurrence)
+	if err != nil || session == nil {
+		return err
+	}
+	if s.usageMeter != nil {
+		if err := s.usageMeter.RecordUsage(ctx, series.CreatedBy, models.MeterSessions, 1); err != nil {
+			log.Printf("Failed to record session usage of series %s: %v", series.ID, err)
+		}
+	}
+	return nil
+}
+
+// cancelOccurrenceSession calls off the session of a cancelled occurrence.
+// It is recorded as cancelled rather than ended, so it is not mistaken for
+// a session that was held.
+func (s *sessionService) cancelOccurrenceSession(ctx context.Context, session *models.LanguageSession) error {
+	if err := s.sessionRepo.CancelSession(ctx, session.ID); err != nil {
+		return err
+	}
+	session.Status = models.SessionStatusCancelled
+	s.notifySessionAttendees(ctx, session, models.WSMessageTypeSessionCancelled)
+	return nil
+}
+
+// notifySessionAttendees sends a session event to its creator and every
+// invitee who has not declined
+func (s *sessionService) notifySessionAttendees(ctx context.Context, session *models.LanguageSession, messageType string) {
+	if s.wsHub == nil {
+		return
+	}
+
+	invitations, err := s.sessionRepo.GetSessionInvitations(ctx, session.ID)
+	if err != nil {
+		log.Printf("Failed to get invitations for session %s: %v", session.ID, err)
+		return
+	}
+
+	attendees := []string{session.CreatedBy}
+	for _, invitation := range invitations {
+		if invitation.Status != models.InvitationStatusDeclined {
+			attendees = append(attendees, invitation.UserID)
+		}
+	}
+
+	s.wsHub.SendToUsers(attendees, models.WebSocketMessage{
+		Type: messageType,
+		Data: session,
+	})
+}
+
+func seriesRule(series *models.SessionSeries) (*ics.RecurrenceRule, *time.Location, error) {
+	rule, err := ics.ParseRecurrenceRule(series.RRule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid rrule on series %s: %w", series.ID, err)
+	}
+	location, err := time.LoadLocation(series.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timezone on series %s: %w", series.ID, err)
+	}
+	return rule, location, nil
+}
+
+// seriesOccurrences expands the series' occurrences in [from, to) in its
+// timezone and applies their exceptions
+func seriesOccurrences(series *models.SessionSeries, rule *ics.RecurrenceRule, location *time.Location, exceptions []*models.SessionSeriesException, from, to time.Time) []models.SeriesOccurrence {
+	byStart := make(map[int64]*models.SessionSeriesException, len(exceptions))
+	for _, exception := range exceptions {
+		byStart[exception.OccurrenceStart.Unix()] = exception
+	}
+
+	var occurrences []models.SeriesOccurrence
+	for _, start := range rule.Between(series.StartsAt.In(location), from, to) {
+		occurrences = append(occurrences, seriesOccurrence(series, start.UTC(), byStart[start.Unix()]))
+	}
+	return occurrences
+}
+
+func seriesOccurrence(series *models.SessionSeries, occurrenceStart time.Time, exception *models.SessionSeriesException) models.SeriesOccurrence {
+	occurrence := models.SeriesOccurrence{
+		OccurrenceStart: occurrenceStart,
+		StartsAt:        occurrenceStart,
+		EndsAt:          occurrenceStart.Add(series.Duration()),
+		Name:            series.Name,
+		Description:     series.Description,
+	}
+	if exception == nil {
+		return occurrence
+	}
+
+	occurrence.Cancelled = exception.Cancelled
+	if exception.Name != nil {
+		occurrence.Name = *exception.Name
+	}
+	if exception.Description != nil {
+		occurrence.Description = exception.Description
+	}
+	if exception.StartsAt != nil {
+		occurrence.StartsAt = *exception.StartsAt
+	}
+	if exception.EndsAt != nil {
+		occurrence.EndsAt = *exception.EndsAt
+	}
+	return occurrence
+}
+
+func applyOccurrence(session *models.LanguageSession, occurrence models.SeriesOccurrence) {
+	start, end := occurrence.StartsAt, occurrence.EndsAt
+	session.Name = occurrence.Name
+	session.Description = occurrence.Description
+	session.ScheduledStartAt = &start
+	session.ScheduledEndAt = &end
+}
+
+// rescheduleOccurrence applies the requested start and end times. A new start
+// without an end keeps the occurrence's length.
+func rescheduleOccurrence(start, end time.Time, input models.UpdateSeriesInput, location *time.Location) (time.Time, time.Time, error) {
+	duration := end.Sub(start)
+
+	if input.ScheduledStartAt != "" {
+		parsed, err := parseScheduleTime(input.ScheduledStartAt, location)
+		if err != nil {
+			return start, end, models.NewAppError("INVALID_SCHEDULE", "Invalid scheduled_start_at", 400)
+		}
+		start, end = parsed, parsed.Add(duration)
+	}
+	if input.ScheduledEndAt != "" {
+		parsed, err := parseScheduleTime(input.ScheduledEndAt, location)
+		if err != nil {
+			return start, end, models.NewAppError("INVALID_SCHEDULE", "Invalid scheduled_end_at", 400)
+		}
+		end = parsed
+	}
+
+	if !end.After(start) || end.Sub(start) > maxSessionDuration {
+		return start, end, models.ErrInvalidSchedule
+	}
+	return start.UTC(), end.UTC(), nil
+}
@@ -3,61 +3,128 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"language-exchange/internal/models"
 	"language-exchange/internal/repository"
+	"language-exchange/internal/websocket"
 
 	"github.com/google/uuid"
 )
 
+const (
+	// Scheduled sessions without an end time last this long
+	defaultSessionDuration = time.Hour
+	maxSessionDuration     = 8 * time.Hour
+	
+	// How early participants can join a scheduled session
+	sessionEarlyJoinWindow = 10 * time.Minute
+	
+	// How long before the start attendees are reminded
+	sessionReminderLead = 15 * time.Minute
+	
+	// How far ahead sessions are created for occurrences of a series
+	seriesMaterializeHorizon = 72 * time.Hour
+	
+	// How often the scheduler sends reminders, expires unstarted sessions
+	// and ends idle ones
+	sessionSchedulerInterval = time.Minute
+	
+	// Active sessions nobody has been connected to for this long are ended
+	defaultSessionIdleTimeout = 15 * time.Minute
+)
+
+// Number of canvas operations recorded after the latest snapshot before the
+// canvas is snapshotted and compacted again
+const canvasSnapshotInterval = 200
+
+// Number of most recent canvas operations kept out of snapshots so their
+// authors can still undo them
+const canvasUndoDepth = 50
+
+// Number of times a canvas operation is re-resolved against the latest state
+// when another operation is saved first
+const canvasSaveAttempts = 3
 
 type sessionService struct {
 	sessionRepo         repository.SessionRepository
 	userRepo            repository.UserRepository
 	matchRepo           repository.MatchRepository
 	gamificationService GamificationService
+	wsHub               *websocket.Hub
+	idleTimeout         time.Duration
+	languageDetector    LanguageDetector
+	usageMeter          UsageMeter
 }
 
-func NewSessionService(sessionRepo repository.SessionRepository, userRepo repository.UserRepository, matchRepo repository.MatchRepository, gamificationService GamificationService) SessionService {
+// NewSessionService creates a new session service. Active sessions with no
+// connected participants for idleTimeout are ended by the scheduler; zero
+// uses the default. Occurrences of recurring sessions are counted against
+// their creator's plan with usageMeter.
+func NewSessionService(sessionRepo repository.SessionRepository, userRepo repository.UserRepository, matchRepo repository.MatchRepository, gamificationService GamificationService, wsHub *websocket.Hub, idleTimeout time.Duration, languageDetector LanguageDetector, usageMeter UsageMeter) SessionService {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultSessionIdleTimeout
+	}
+	
 	return &sessionService{
 		sessionRepo:         sessionRepo,
 		userRepo:            userRepo,
 		matchRepo:           matchRepo,
 		gamificationService: gamificationService,
+		wsHub:               wsHub,
+		idleTimeout:         idleTimeout,
+		languageDetector:    languageDetector,
+		usageMeter:          usageMeter,
 	}
 }
 
 // Session management
 func (s *sessionService) CreateSession(ctx context.Context, userID string, input models.CreateSessionRequest) (*models.LanguageSession, error) {
 	// Verify user exists
-	_, err := s.userRepo.GetByID(ctx, userID)
+	creator, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify user: %w", err)
 	}
 	
-	// Verify invited user exists
-	_, err = s.userRepo.GetByID(ctx, input.InvitedUserID)
-	if err != nil {
-		return nil, models.NewAppError("INVITED_USER_NOT_FOUND", "Invited user not found", 404)
+	invitees := input.Invitees()
+	if len(invitees) == 0 {
+		return nil, models.NewAppError("INVALID_INPUT", "At least one invited user is required", 400)
+	}
+	if len(invitees)+1 > input.MaxParticipants {
+		return nil, models.NewAppError("TOO_MANY_INVITEES", "More users invited than the session can hold", 400)
+	}
+	
+	// Verify invited users exist
+	for _, inviteeID := range invitees {
+		if inviteeID == userID {
+			return nil, models.NewAppError("INVALID_INPUT", "You cannot invite yourself", 400)
+		}
+		_, err = s.userRepo.GetByID(ctx, inviteeID)
+		if err != nil {
+			return nil, models.NewAppError("INVITED_USER_NOT_FOUND", "Invited user not found", 404)
+		}
 	}
 	
-	// Verify that creator and invited user are matched
+	// Verify that creator and invited users are matched
 	matches, err := s.matchRepo.GetMatchesByUser(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify match relationship: %w", err)
 	}
 	
-	isMatched := false
+	matched := make(map[string]bool)
 	for _, match := range matches {
-		if (match.User1.ID == userID && match.User2.ID == input.InvitedUserID) ||
-		   (match.User2.ID == userID && match.User1.ID == input.InvitedUserID) {
-			isMatched = true
-			break
+		if match.User1.ID == userID {
+			matched[match.User2.ID] = true
+		} else if match.User2.ID == userID {
+			matched[match.User1.ID] = true
 		}
 	}
 	
-	if !isMatched {
-		return nil, models.NewAppError("NOT_MATCHED", "You can only invite users you are matched with", 403)
+	for _, inviteeID := range invitees {
+		if !matched[inviteeID] {
+			return nil, models.NewAppError("NOT_MATCHED", "You can only invite users you are matched with", 403)
+		}
 	}
 	
 	var description *string
@@ -75,21 +142,126 @@ func (s *sessionService) CreateSession(ctx context.Context, userID string, input
 		Name:            input.Name,
 		Description:     description,
 		CreatedBy:       userID,
-		InvitedUserID:   &input.InvitedUserID,
+		InvitedUserID:   &invitees[0],
 		Status:          models.SessionStatusActive,
 		MaxParticipants: input.MaxParticipants,
 		SessionType:     input.SessionType,
 		TargetLanguage:  targetLanguage,
 	}
 	
+	if input.RRule != "" && input.ScheduledStartAt == "" {
+		return nil, models.NewAppError("INVALID_SCHEDULE", "Recurring sessions need a scheduled_start_at", 400)
+	}
+	
+	if input.ScheduledStartAt != "" {
+		if err := applySessionSchedule(session, input, creator.Timezone); err != nil {
+			return nil, err
+		}
+	} else {
+		now := time.Now()
+		session.StartedAt = &now
+	}
+	
+	if input.RRule != "" {
+		return s.createSeries(ctx, session, invitees, input.RRule)
+	}
+	
 	err = s.sessionRepo.CreateSession(ctx, session)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 	
+	if err := s.inviteUsers(ctx, session, invitees); err != nil {
+		return nil, err
+	}
+	
 	return session, nil
 }
 
+// inviteUsers creates pending invitations for a new session and notifies the invitees
+func (s *sessionService) inviteUsers(ctx context.Context, session *models.LanguageSession, invitees []string) error {
+	for _, inviteeID := range invitees {
+		invitation := &models.SessionInvitation{
+			ID:        uuid.New().String(),
+			SessionID: session.ID,
+			UserID:    inviteeID,
+			Status:    models.InvitationStatusPending,
+		}
+		if err := s.sessionRepo.CreateInvitation(ctx, invitation); err != nil {
+			return fmt.Errorf("failed to invite user: %w", err)
+		}
+		session.Invitations = append(session.Invitations, *invitation)
+	}
+	
+	if s.wsHub != nil {
+		s.wsHub.SendToUsers(invitees, models.WebSocketMessage{
+			Type: models.WSMessageTypeSessionInvitation,
+			Data: models.SessionInvitationEvent{
+				Session:   session,
+				InvitedBy: session.CreatedBy,
+			},
+		})
+	}
+	
+	return nil
+}
+
+// applySessionSchedule validates the requested schedule and marks the session
+// as scheduled. Times without an offset are read in the session timezone,
+// which defaults to the creator's.
+func applySessionSchedule(session *models.LanguageSession, input models.CreateSessionRequest, creatorTimezone *string) error {
+	timezone := input.Timezone
+	if timezone == "" && creatorTimezone != nil {
+		timezone = *creatorTimezone
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return models.NewAppError("INVALID_TIMEZONE", "Unknown timezone: "+timezone, 400)
+	}
+	
+	start, err := parseScheduleTime(input.ScheduledStartAt, location)
+	if err != nil {
+		return models.NewAppError("INVALID_SCHEDULE", "Invalid scheduled_start_at", 400)
+	}
+	end := start.Add(defaultSessionDuration)
+	if input.ScheduledEndAt != "" {
+		end, err = parseScheduleTime(input.ScheduledEndAt, location)
+		if err != nil {
+			return models.NewAppError("INVALID_SCHEDULE", "Invalid scheduled_end_at", 400)
+		}
+	}
+	
+	if start.Before(time.Now().Add(-time.Minute)) {
+		return models.NewAppError("INVALID_SCHEDULE", "Scheduled start must be in the future", 400)
+	}
+	if !end.After(start) || end.Sub(start) > maxSessionDuration {
+		return models.ErrInvalidSchedule
+	}
+	
+	start, end = start.UTC(), end.UTC()
+	session.Status = models.SessionStatusScheduled
+	session.ScheduledStartAt = &start
+	session.ScheduledEndAt = &end
+	session.Timezone = &timezone
+	return nil
+}
+
+// parseScheduleTime accepts RFC 3339 timestamps and local date-times
+func parseScheduleTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
+
 func (s *sessionService) GetSession(ctx context.Context, sessionID string) (*models.LanguageSession, error) {
 	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
 	if err != nil {
@@ -108,9 +280,26 @@ func (s *sessionService) GetSession(ctx context.Context, sessionID string) (*mod
 			session.Participants[i] = *p
 		}
 	}
+	
+	if err := s.loadInvitations(ctx, session); err != nil {
+		return nil, err
+	}
 	return session, nil
 }
 
+func (s *sessionService) loadInvitations(ctx context.Context, session *models.LanguageSession) error {
+	invitations, err := s.sessionRepo.GetSessionInvitations(ctx, session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get invitations: %w", err)
+	}
+	
+	session.Invitations = make([]models.SessionInvitation, len(invitations))
+	for i, invitation := range invitations {
+		session.Invitations[i] = *invitation
+	}
+	return nil
+}
+
 func (s *sessionService) GetUserSessions(ctx context.Context, userID string) ([]*models.LanguageSession, error) {
 	sessions, err := s.sessionRepo.GetSessionsByUser(ctx, userID)
 	if err != nil {
@@ -145,63 +334,195 @@ func (s *sessionService) EndSession(ctx context.Context, sessionID, userID strin
 		return models.NewAppError("UNAUTHORIZED", "Only session creator can end the session", 403)
 	}
 	
-	// Check if session is already ended
-	if session.Status == models.SessionStatusEnded {
-		return models.ErrSessionEnded
+	// Check if session is already ended or was cancelled
+	if err := session.CheckOpen(); err != nil {
+		return err
 	}
 	
 	err = s.sessionRepo.EndSession(ctx, sessionID)
 	if err != nil {
+		if err == models.ErrSessionEnded {
+			return err
+		}
 		return fmt.Errorf("failed to end session: %w", err)
 	}
 	
-	// Calculate session duration and award XP to participants
-	if s.gamificationService != nil {
-		// Get updated session to have the EndedAt time
-		endedSession, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
-		if err == nil && endedSession.EndedAt != nil {
-			// Get all participants who were in the session
-			participants, err := s.sessionRepo.GetActiveParticipants(ctx, sessionID)
-			if err == nil {
-				// Calculate session duration in minutes
-				sessionMinutes := int(endedSession.EndedAt.Sub(session.CreatedAt).Minutes())
-				
-				// Award XP to each participant
-				for _, participant := range participants {
-					go func(userID string) {
-						_ = s.gamificationService.OnSessionComplete(context.Background(), userID, sessionMinutes)
-					}(participant.UserID)
-				}
-			}
+	s.settleSession(ctx, sessionID)
+	s.notifySessionEnded(sessionID, models.SessionEndReasonCreator)
+	
+	return nil
+}
+
+// Scheduling
+
+// RespondToInvitation records an invitee's RSVP and lets the creator know
+func (s *sessionService) RespondToInvitation(ctx context.Context, sessionID, userID, status string) (*models.SessionInvitation, error) {
+	if !models.IsValidRSVPStatus(status) {
+		return nil, models.NewAppError("INVALID_STATUS", "Invalid RSVP status", 400)
+	}
+	
+	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err == models.ErrSessionNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if err := session.CheckOpen(); err != nil {
+		return nil, err
+	}
+	
+	invitation, err := s.sessionRepo.UpdateInvitationStatus(ctx, sessionID, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	
+	if s.wsHub != nil {
+		s.wsHub.SendToUser(session.CreatedBy, models.WebSocketMessage{
+			Type: models.WSMessageTypeSessionRSVP,
+			Data: models.SessionRSVPEvent{
+				SessionID: sessionID,
+				UserID:    userID,
+				Status:    status,
+			},
+		})
+	}
+	
+	return invitation, nil
+}
+
+// GetUpcomingSessions returns scheduled sessions the user is attending that
+// have not finished yet, with their invitations
+func (s *sessionService) GetUpcomingSessions(ctx context.Context, userID string) ([]*models.LanguageSession, error) {
+	sessions, err := s.sessionRepo.GetUpcomingSessionsByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upcoming sessions: %w", err)
+	}
+	
+	for _, session := range sessions {
+		if err := s.loadInvitations(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+	
+	return sessions, nil
+}
+
+// RunScheduler creates upcoming occurrences of recurring sessions, sends
+// session reminders, ends scheduled sessions nobody started and ends active
+// sessions everyone left until the context is cancelled
+func (s *sessionService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(sessionSchedulerInterval)
+	defer ticker.Stop()
+	
+	for {
+		s.processScheduledSessions(ctx)
+		
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *sessionService) processScheduledSessions(ctx context.Context) {
+	now := time.Now()
+	
+	sessions, err := s.sessionRepo.GetSessionsDueForReminder(ctx, now.Add(sessionReminderLead))
+	if err != nil {
+		log.Printf("Failed to get sessions due for reminder: %v", err)
+	}
+	for _, session := range sessions {
+		if err := s.sendSessionReminder(ctx, session, now); err != nil {
+			log.Printf("Failed to send reminder for session %s: %v", session.ID, err)
+		}
+	}
+	
+	s.materializeSeries(ctx, now)
+	
+	ended, err := s.sessionRepo.EndExpiredScheduledSessions(ctx, now)
+	if err != nil {
+		log.Printf("Failed to end expired scheduled sessions: %v", err)
+	} else if ended > 0 {
+		log.Printf("Ended %d scheduled sessions that were never started", ended)
+	}
+	
+	s.reapIdleSessions(ctx, now)
+}
+
+func (s *sessionService) sendSessionReminder(ctx context.Context, session *models.LanguageSession, now time.Time) error {
+	// Claim the reminder first so that only one server sends it
+	claimed, err := s.sessionRepo.MarkReminderSent(ctx, session.ID)
+	if err != nil || !claimed {
+		return err
+	}
+	if s.wsHub == nil {
+		return nil
+	}
+	
+	invitations, err := s.sessionRepo.GetSessionInvitations(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	
+	attendees := []string{session.CreatedBy}
+	for _, invitation := range invitations {
+		if invitation.Status != models.InvitationStatusDeclined {
+			attendees = append(attendees, invitation.UserID)
 		}
 	}
 	
+	startsIn := session.ScheduledStartAt.Sub(now)
+	if startsIn < 0 {
+		startsIn = 0
+	}
+	
+	s.wsHub.SendToUsers(attendees, models.WebSocketMessage{
+		Type: models.WSMessageTypeSessionReminder,
+		Data: models.SessionReminderEvent{
+			SessionID:        session.ID,
+			Name:             session.Name,
+			ScheduledStartAt: *session.ScheduledStartAt,
+			StartsInMinutes:  int(startsIn.Round(time.Minute).Minutes()),
+		},
+	})
 	return nil
 }
 
 // Participant management
-func (s *sessionService) JoinSession(ctx context.Context, sessionID, userID string) (*models.SessionParticipant, error) {
+// JoinSession adds the user to the session as a participant, or as an
+// observer if role is RoleObserver
+func (s *sessionService) JoinSession(ctx context.Context, sessionID, userID, role string) (*models.SessionParticipant, error) {
 	// Get session details
 	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 	
-	// Check if session is active
-	if session.Status != models.SessionStatusActive {
-		return nil, models.ErrSessionEnded
+	if err := session.CheckOpen(); err != nil {
+		return nil, err
 	}
 	
-	// Verify user is allowed to join (creator or invited user only)
-	isAuthorized := session.CreatedBy == userID
-	if !isAuthorized && session.InvitedUserID != nil {
-		isAuthorized = *session.InvitedUserID == userID
+	// Verify user is allowed to join (creator or invited users only)
+	if err := s.loadInvitations(ctx, session); err != nil {
+		return nil, err
 	}
-	
-	if !isAuthorized {
+	if session.CreatedBy != userID && !session.IsInvited(userID) {
 		return nil, models.NewAppError("NOT_INVITED", "You are not invited to this session", 403)
 	}
 	
+	// A scheduled session opens shortly before its start time and becomes
+	// active when the first participant joins
+	if session.Status == models.SessionStatusScheduled {
+		if session.ScheduledStartAt != nil && time.Now().Before(session.ScheduledStartAt.Add(-sessionEarlyJoinWindow)) {
+			return nil, models.ErrSessionNotStarted
+		}
+		if err := s.sessionRepo.StartSession(ctx, sessionID); err != nil {
+			return nil, err
+		}
+	}
+	
 	// Check if user is already in session
 	isInSession, err := s.sessionRepo.IsUserInSession(ctx, sessionID, userID)
 	if err != nil {
@@ -221,15 +542,16 @@ func (s *sessionService) JoinSession(ctx context.Context, sessionID, userID stri
 		}
 	}
 	
-	// Check if session is full
-	if session.ParticipantCount >= session.MaxParticipants {
-		return nil, models.ErrSessionFull
-	}
-	
 	// Determine role based on whether user is creator
-	role := models.RoleParticipant
 	if session.CreatedBy == userID {
 		role = models.RoleCreator
+	} else if role != models.RoleObserver {
+		role = models.RoleParticipant
+	}
+	
+	// Check if session is full; observers do not take a place
+	if role != models.RoleObserver && session.ParticipantCount >= session.MaxParticipants {
+		return nil, models.ErrSessionFull
 	}
 	
 	// Add participant
@@ -245,6 +567,8 @@ func (s *sessionService) JoinSession(ctx context.Context, sessionID, userID stri
 		return nil, fmt.Errorf("failed to add participant: %w", err)
 	}
 	
+	s.recordRESTPresence(ctx, sessionID, userID, true)
+	
 	return participant, nil
 }
 
@@ -264,6 +588,8 @@ func (s *sessionService) LeaveSession(ctx context.Context, sessionID, userID str
 		return fmt.Errorf("failed to remove participant: %w", err)
 	}
 	
+	s.recordRESTPresence(ctx, sessionID, userID, false)
+	
 	return nil
 }
 
@@ -280,25 +606,183 @@ func (s *sessionService) IsUserInSession(ctx context.Context, sessionID, userID
 	return s.sessionRepo.IsUserInSession(ctx, sessionID, userID)
 }
 
+// AuthorizeSessionConnection checks that the user may open a WebSocket to the
+// session room: the session must not have ended, the user must be its creator
+// or an invitee and have joined it, and a non-observer needs a free place
+// among the connected participants. It returns the user's participant record.
+func (s *sessionService) AuthorizeSessionConnection(ctx context.Context, sessionID, userID string) (*models.SessionParticipant, error) {
+	session, err := s.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.CheckOpen(); err != nil {
+		return nil, err
+	}
+	
+	if err := s.loadInvitations(ctx, session); err != nil {
+		return nil, err
+	}
+	if session.CreatedBy != userID && !session.IsInvited(userID) {
+		return nil, models.NewAppError("NOT_INVITED", "You are not invited to this session", 403)
+	}
+	
+	participant, err := s.sessionRepo.GetParticipant(ctx, sessionID, userID)
+	if err != nil && err != models.ErrParticipantNotFound {
+		return nil, err
+	}
+	if participant == nil || !participant.IsActive {
+		return nil, models.ErrNotInSession
+	}
+	
+	if participant.Role != models.RoleObserver {
+		connected, err := s.sessionRepo.CountConnectedUsers(ctx, sessionID, userID, time.Now().Add(-models.SessionConnectionStaleAfter))
+		if err != nil {
+			return nil, err
+		}
+		if connected >= session.MaxParticipants {
+			return nil, models.ErrSessionFull
+		}
+	}
+	
+	return participant, nil
+}
+
 // Canvas operations
+
+// SaveCanvasOperation resolves an operation against the current canvas, saves
+// it as the next canvas version and broadcasts it to the session. Operations
+// that are stale against another participant's change are transformed where
+// possible and otherwise rejected with a conflict error. Only active
+// participants who are not observers can change the canvas, and only until
+// the session ends.
 func (s *sessionService) SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation) error {
+	session, err := s.sessionRepo.GetSessionByID(ctx, operation.SessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.CheckOpen(); err != nil {
+		return err
+	}
+	
+	participant, err := s.sessionRepo.GetParticipant(ctx, operation.SessionID, operation.UserID)
+	if err == models.ErrParticipantNotFound || (err == nil && (participant == nil || !participant.IsActive)) {
+		return models.ErrNotInSession
+	}
+	if err != nil {
+		return err
+	}
+	if participant.Role == models.RoleObserver {
+		return models.ErrObserverReadOnly
+	}
+	
 	if operation.ID == "" {
 		operation.ID = uuid.New().String()
 	}
+	request := *operation
+	
+	for attempt := 0; attempt < canvasSaveAttempts; attempt++ {
+		state, history, err := s.loadCanvas(ctx, request.SessionID)
+		if err != nil {
+			return err
+		}
+		
+		*operation = request
+		if err := prepareCanvasOperation(state, history, operation); err != nil {
+			return err
+		}
+		
+		err = s.sessionRepo.SaveCanvasOperation(ctx, operation, state.Version)
+		if err == models.ErrCanvasVersionConflict {
+			// Another operation was saved first, resolve against it and try again
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save canvas operation: %w", err)
+		}
+		
+		// The operation is already saved, so a failed compaction is retried on a later operation
+		if err := s.compactCanvasIfNeeded(ctx, operation); err != nil {
+			log.Printf("Failed to compact canvas for session %s: %v", operation.SessionID, err)
+		}
+		
+		s.broadcastCanvasOperation(operation)
+		return nil
+	}
 	
-	err := s.sessionRepo.SaveCanvasOperation(ctx, operation)
+	return models.ErrCanvasVersionConflict
+}
+
+// loadCanvas returns the current canvas state and the operations recorded
+// after the latest snapshot
+func (s *sessionService) loadCanvas(ctx context.Context, sessionID string) (*models.CanvasState, []*models.CanvasOperation, error) {
+	snapshot, err := s.sessionRepo.GetLatestCanvasSnapshot(ctx, sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to save canvas operation: %w", err)
+		return nil, nil, fmt.Errorf("failed to get canvas snapshot: %w", err)
 	}
 	
-	return nil
+	state := &models.CanvasState{}
+	var fromSequence int64
+	if snapshot != nil {
+		*state = snapshot.State
+		fromSequence = snapshot.SequenceNumber
+	}
+	
+	operations, err := s.sessionRepo.GetCanvasOperations(ctx, sessionID, fromSequence)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get canvas operations: %w", err)
+	}
+	for _, operation := range operations {
+		state.Apply(operation)
+	}
+	
+	return state, operations, nil
+}
+
+// broadcastCanvasOperation sends a saved operation to everyone in the
+// session, including its author, so all clients converge on the server order
+func (s *sessionService) broadcastCanvasOperation(operation *models.CanvasOperation) {
+	if s.wsHub == nil {
+		return
+	}
+	
+	s.wsHub.SendToSession(operation.SessionID, models.WebSocketMessage{
+		Type: models.WSMessageTypeCanvasOperation,
+		Data: map[string]interface{}{
+			"id":              operation.ID,
+			"operation_type":  operation.OperationType,
+			"data":            operation.OperationData,
+			"user_id":         operation.UserID,
+			"session_id":      operation.SessionID,
+			"version":         operation.Version,
+			"sequence_number": operation.SequenceNumber,
+			"element_id":      operation.ElementID,
+			"undo_of":         operation.UndoOf,
+			"redo_of":         operation.RedoOf,
+		},
+	}, nil)
 }
 
 func (s *sessionService) GetCanvasOperations(ctx context.Context, sessionID string, limit, offset int) ([]*models.CanvasOperation, error) {
-	operations, err := s.sessionRepo.GetCanvasOperations(ctx, sessionID, int64(offset))
+	fromSequence := int64(offset)
+	
+	snapshot, err := s.sessionRepo.GetLatestCanvasSnapshot(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get canvas snapshot: %w", err)
+	}
+	
+	// Operations before the snapshot have been compacted, so stand in for
+	// them with operations that recreate the snapshot state
+	var operations []*models.CanvasOperation
+	if snapshot != nil && fromSequence < snapshot.SequenceNumber {
+		operations = snapshot.State.Operations(sessionID)
+		fromSequence = snapshot.SequenceNumber
+	}
+	
+	tail, err := s.sessionRepo.GetCanvasOperations(ctx, sessionID, fromSequence)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get canvas operations: %w", err)
 	}
+	operations = append(operations, tail...)
 	
 	// Apply limit manually if repository doesn't support pagination
 	if limit > 0 && len(operations) > limit {
@@ -308,6 +792,106 @@ func (s *sessionService) GetCanvasOperations(ctx context.Context, sessionID stri
 	return operations, nil
 }
 
+// GetCanvasState returns the latest canvas snapshot and the operations after it
+func (s *sessionService) GetCanvasState(ctx context.Context, sessionID string) (*models.CanvasStateResponse, error) {
+	snapshot, err := s.sessionRepo.GetLatestCanvasSnapshot(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get canvas snapshot: %w", err)
+	}
+	
+	var fromSequence int64
+	if snapshot != nil {
+		fromSequence = snapshot.SequenceNumber
+	}
+	
+	operations, err := s.sessionRepo.GetCanvasOperations(ctx, sessionID, fromSequence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get canvas operations: %w", err)
+	}
+	if operations == nil {
+		operations = []*models.CanvasOperation{}
+	}
+	
+	latestSequence := fromSequence
+	var version int64
+	if snapshot != nil {
+		version = snapshot.State.Version
+	}
+	for _, operation := range operations {
+		latestSequence = operation.SequenceNumber
+		if operation.Version > version {
+			version = operation.Version
+		}
+	}
+	
+	return &models.CanvasStateResponse{
+		Snapshot:       snapshot,
+		Operations:     operations,
+		LatestSequence: latestSequence,
+		Version:        version,
+	}, nil
+}
+
+// compactCanvasIfNeeded snapshots the canvas after a clear or once enough
+// operations have accumulated since the last snapshot
+func (s *sessionService) compactCanvasIfNeeded(ctx context.Context, operation *models.CanvasOperation) error {
+	snapshot, err := s.sessionRepo.GetLatestCanvasSnapshot(ctx, operation.SessionID)
+	if err != nil {
+		return err
+	}
+	
+	var fromSequence int64
+	if snapshot != nil {
+		fromSequence = snapshot.SequenceNumber
+	}
+	
+	// Nothing before a clear can be undone, so it is compacted entirely
+	if operation.OperationType == models.OperationTypeClear {
+		return s.snapshotCanvas(ctx, operation.SessionID, snapshot, 0)
+	}
+	
+	count, err := s.sessionRepo.CountCanvasOperations(ctx, operation.SessionID, fromSequence)
+	if err != nil {
+		return err
+	}
+	if count < canvasSnapshotInterval+canvasUndoDepth {
+		return nil
+	}
+	
+	return s.snapshotCanvas(ctx, operation.SessionID, snapshot, canvasUndoDepth)
+}
+
+// snapshotCanvas folds the operations after the previous snapshot into a new
+// one, leaving the most recent retain operations in the log
+func (s *sessionService) snapshotCanvas(ctx context.Context, sessionID string, previous *models.CanvasSnapshot, retain int) error {
+	next := &models.CanvasSnapshot{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+	}
+	if previous != nil {
+		next.State = previous.State
+		next.SequenceNumber = previous.SequenceNumber
+		next.OperationCount = previous.OperationCount
+	}
+	
+	operations, err := s.sessionRepo.GetCanvasOperations(ctx, sessionID, next.SequenceNumber)
+	if err != nil {
+		return err
+	}
+	if len(operations) <= retain {
+		return nil
+	}
+	operations = operations[:len(operations)-retain]
+	
+	for _, operation := range operations {
+		next.State.Apply(operation)
+	}
+	next.SequenceNumber = operations[len(operations)-1].SequenceNumber
+	next.OperationCount += len(operations)
+	
+	return s.sessionRepo.SaveCanvasSnapshot(ctx, next)
+}
+
 func (s *sessionService) ClearCanvas(ctx context.Context, sessionID, userID string) error {
 	// Verify user is in session
 	isInSession, err := s.sessionRepo.IsUserInSession(ctx, sessionID, userID)
@@ -328,12 +912,8 @@ func (s *sessionService) ClearCanvas(ctx context.Context, sessionID, userID stri
 		OperationData: []byte(`{"type":"clear"}`),
 	}
 	
-	err = s.SaveCanvasOperation(ctx, clearOp)
-	if err != nil {
-		return fmt.Errorf("failed to save clear operation: %w", err)
-	}
-	
-	return nil
+	// Returned as is so an observer gets ErrObserverReadOnly
+	return s.SaveCanvasOperation(ctx, clearOp)
 }
 
 // Session messages
@@ -360,11 +940,11 @@ func (s *sessionService) SendMessage(ctx context.Context, sessionID, userID stri
 		Content:     input.Content,
 		MessageType: messageType,
 	}
-	
 	err = s.sessionRepo.SaveMessage(ctx, message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save message: %w", err)
 	}
+	s.detectMessageLanguage(message)
 	
 	return message, nil
 }
@@ -390,11 +970,24 @@ func (s *sessionService) SaveMessage(ctx context.Context, message *models.Sessio
 	if message.ID == "" {
 		message.ID = uuid.New().String()
 	}
-	
 	err := s.sessionRepo.SaveMessage(ctx, message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save message: %w", err)
 	}
+	s.detectMessageLanguage(message)
 	
 	return message, nil
+}
+
+// detectMessageLanguage records the language of a saved typed chat message
+// in the background; system, file and voice messages have no language of
+// their own
+func (s *sessionService) detectMessageLanguage(message *models.SessionMessage) {
+	if message.MessageType != "" && message.MessageType != models.SessionMessageTypeText {
+		return
+	}
+	messageID := message.ID
+	detectContentLanguageLater(s.languageDetector, message.Content, func(ctx context.Context, language string) error {
+		return s.sessionRepo.UpdateMessageLanguage(ctx, messageID, language)
+	})
 }
\ No newline at end of file
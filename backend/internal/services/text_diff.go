@@ -0,0 +1,214 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"language-exchange/internal/models"
+)
+
+// textToken is a word, a run of whitespace or a single other character,
+// located by its character offset in the tokenized text
+type textToken struct {
+	text  string
+	start int
+}
+
+// textChange is a run of tokens that differs between two texts
+type textChange struct {
+	original        string
+	replacement     string
+	originalSpan    models.TextSpan
+	replacementSpan models.TextSpan
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// tokenizeText splits text into words, whitespace runs and punctuation.
+// Apostrophes and hyphens between letters stay inside the word, so "don't"
+// and "well-known" are single tokens.
+func tokenizeText(text string) []textToken {
+	runes := []rune(text)
+	tokens := make([]textToken, 0, len(runes)/3+1)
+
+	for i := 0; i < len(runes); {
+		j := i + 1
+		switch {
+		case isWordRune(runes[i]):
+			for j < len(runes) {
+				if isWordRune(runes[j]) {
+					j++
+				} else if strings.ContainsRune("'’-", runes[j]) && j+1 < len(runes) && isWordRune(runes[j+1]) {
+					j += 2
+				} else {
+					break
+				}
+			}
+		case unicode.IsSpace(runes[i]):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, textToken{text: string(runes[i:j]), start: i})
+		i = j
+	}
+
+	return tokens
+}
+
+// diffText computes the token-level changes that turn original into
+// improved, using the longest common subsequence of their tokens
+func diffText(original, improved string) []textChange {
+	a := tokenizeText(original)
+	b := tokenizeText(improved)
+
+	// Common prefix and suffix don't need the quadratic table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix].text == b[prefix].text {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix].text == b[len(b)-1-suffix].text {
+		suffix++
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+
+	// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+	lcs := make([][]int32, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i].text == midB[j].text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table, grouping consecutive deletions and insertions into
+	// one change
+	var changes []textChange
+	originalEnd := len([]rune(original))
+	improvedEnd := len([]rune(improved))
+	offsetA := func(i int) int {
+		if i < len(midA) {
+			return midA[i].start
+		}
+		if suffix > 0 {
+			return a[len(a)-suffix].start
+		}
+		return originalEnd
+	}
+	offsetB := func(j int) int {
+		if j < len(midB) {
+			return midB[j].start
+		}
+		if suffix > 0 {
+			return b[len(b)-suffix].start
+		}
+		return improvedEnd
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		if i < len(midA) && j < len(midB) && midA[i].text == midB[j].text {
+			i++
+			j++
+			continue
+		}
+
+		startA, startB := i, j
+		for i < len(midA) || j < len(midB) {
+			if i < len(midA) && j < len(midB) && midA[i].text == midB[j].text {
+				break
+			}
+			if j >= len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+
+		var original, replacement strings.Builder
+		for _, token := range midA[startA:i] {
+			original.WriteString(token.text)
+		}
+		for _, token := range midB[startB:j] {
+			replacement.WriteString(token.text)
+		}
+		changes = append(changes, textChange{
+			original:        original.String(),
+			replacement:     replacement.String(),
+			originalSpan:    models.TextSpan{Start: offsetA(startA), End: offsetA(i)},
+			replacementSpan: models.TextSpan{Start: offsetB(startB), End: offsetB(j)},
+		})
+	}
+
+	return changes
+}
+
+// classifyChange guesses the category of a change the model did not
+// explain: case changes, a single replaced letter (such as a missing accent)
+// and other small edits within a word are spelling, everything else grammar
+func classifyChange(change textChange) string {
+	original := strings.TrimSpace(change.original)
+	replacement := strings.TrimSpace(change.replacement)
+
+	if original != "" && strings.EqualFold(original, replacement) {
+		return models.CorrectionSpelling
+	}
+
+	originalTokens := tokenizeText(original)
+	replacementTokens := tokenizeText(replacement)
+	if len(originalTokens) == 1 && len(replacementTokens) == 1 && isWordRune(firstRune(original)) && isWordRune(firstRune(replacement)) {
+		longest := utf8.RuneCountInString(original)
+		if n := utf8.RuneCountInString(replacement); n > longest {
+			longest = n
+		}
+		distance := editDistance(strings.ToLower(original), strings.ToLower(replacement))
+		sameLength := utf8.RuneCountInString(original) == utf8.RuneCountInString(replacement)
+		if distance*3 <= longest || (sameLength && distance == 1) {
+			return models.CorrectionSpelling
+		}
+	}
+
+	return models.CorrectionGrammar
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// editDistance is the Levenshtein distance between a and b in characters
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"language-exchange/internal/models"
+)
+
+func TestTokenizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []textToken
+	}{
+		{"empty", "", []textToken{}},
+		{"words and spaces", "I  go", []textToken{{"I", 0}, {"  ", 1}, {"go", 3}}},
+		{"punctuation", "Hi, you!", []textToken{{"Hi", 0}, {",", 2}, {" ", 3}, {"you", 4}, {"!", 7}}},
+		{"apostrophe inside a word", "don't", []textToken{{"don't", 0}}},
+		{"hyphen inside a word", "well-known", []textToken{{"well-known", 0}}},
+		{"trailing hyphen", "well- ", []textToken{{"well", 0}, {"-", 4}, {" ", 5}}},
+		{"accents count as one character", "está bien", []textToken{{"está", 0}, {" ", 4}, {"bien", 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizeText(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeText(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		improved string
+		want     []textChange
+	}{
+		{"unchanged", "I go home", "I go home", nil},
+		{
+			name:     "replaced word",
+			original: "I goes home",
+			improved: "I go home",
+			want: []textChange{
+				{"goes", "go", models.TextSpan{Start: 2, End: 6}, models.TextSpan{Start: 2, End: 4}},
+			},
+		},
+		{
+			name:     "inserted word",
+			original: "I go home",
+			improved: "I go to home",
+			want: []textChange{
+				{"", "to ", models.TextSpan{Start: 5, End: 5}, models.TextSpan{Start: 5, End: 8}},
+			},
+		},
+		{
+			name:     "deleted word",
+			original: "I go to home",
+			improved: "I go home",
+			want: []textChange{
+				{"to ", "", models.TextSpan{Start: 5, End: 8}, models.TextSpan{Start: 5, End: 5}},
+			},
+		},
+		{
+			name:     "appended punctuation",
+			original: "Hello",
+			improved: "Hello!",
+			want: []textChange{
+				{"", "!", models.TextSpan{Start: 5, End: 5}, models.TextSpan{Start: 5, End: 6}},
+			},
+		},
+		{
+			name:     "two separate changes",
+			original: "yo es bien",
+			improved: "yo está bien.",
+			want: []textChange{
+				{"es", "está", models.TextSpan{Start: 3, End: 5}, models.TextSpan{Start: 3, End: 7}},
+				{"", ".", models.TextSpan{Start: 10, End: 10}, models.TextSpan{Start: 12, End: 13}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffText(tt.original, tt.improved); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffText(%q, %q) = %+v, want %+v", tt.original, tt.improved, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		original    string
+		replacement string
+		want        string
+	}{
+		{"capitalisation", "paris", "Paris", models.CorrectionSpelling},
+		{"missing accent", "esta", "está", models.CorrectionSpelling},
+		{"swapped letter", "recieve", "receive", models.CorrectionSpelling},
+		{"different word", "goes", "go", models.CorrectionGrammar},
+		{"inserted word", "", "to ", models.CorrectionGrammar},
+		{"deleted word", "the ", "", models.CorrectionGrammar},
+		{"several words", "I has", "I have", models.CorrectionGrammar},
+		{"punctuation", ",", ";", models.CorrectionGrammar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := textChange{original: tt.original, replacement: tt.replacement}
+			if got := classifyChange(change); got != tt.want {
+				t.Errorf("classifyChange(%q -> %q) = %s, want %s", tt.original, tt.replacement, got, tt.want)
+			}
+		})
+	}
+}
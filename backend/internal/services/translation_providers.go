@@ -0,0 +1,436 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// Translation provider names
+const (
+	ProviderLibreTranslate = "libretranslate"
+	ProviderDeepL          = "deepl"
+	ProviderGoogle         = "google"
+)
+
+// Default API endpoints for the hosted providers
+const (
+	DefaultDeepLURL     = "https://api.deepl.com"
+	DefaultDeepLFreeURL = "https://api-free.deepl.com"
+	DefaultGoogleURL    = "https://translation.googleapis.com"
+)
+
+// TranslationProvider translates text with a single translation backend.
+// Providers report a language they cannot handle as ErrUnsupportedLanguage
+// so the chain moves on without counting it as a failure.
+type TranslationProvider interface {
+	Name() string
+	Translate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error)
+	Languages(ctx context.Context) ([]models.SupportedLanguage, error)
+}
+
+// LanguageDetectionProvider is implemented by providers that can detect the
+// language of a text. Candidates are returned with confidence from 0 to 1.
+type LanguageDetectionProvider interface {
+	TranslationProvider
+	Detect(ctx context.Context, text string) ([]models.DetectedLanguage, error)
+}
+
+// translationFormat returns the request's format, defaulting to plain text
+func translationFormat(request models.TranslateRequest) string {
+	if request.Format == "" {
+		return models.TranslationFormatText
+	}
+	return request.Format
+}
+
+func newProviderHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+	}
+}
+
+// doProviderRequest sends a request and decodes a JSON response, turning
+// non-200 responses into errors that include the response body
+func doProviderRequest(client *http.Client, req *http.Request, dest interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, dest); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// libreTranslateProvider uses a (usually self-hosted) LibreTranslate server
+type libreTranslateProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewLibreTranslateProvider creates a LibreTranslate provider
+func NewLibreTranslateProvider(baseURL, apiKey string) TranslationProvider {
+	return &libreTranslateProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: newProviderHTTPClient(),
+	}
+}
+
+func (p *libreTranslateProvider) Name() string {
+	return ProviderLibreTranslate
+}
+
+func (p *libreTranslateProvider) Translate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error) {
+	libreRequest := models.LibreTranslateRequest{
+		Q:      request.Text,
+		Source: request.SourceLang,
+		Target: request.TargetLang,
+		Format: translationFormat(request),
+		APIKey: p.apiKey,
+	}
+
+	requestBody, err := json.Marshal(libreRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/translate", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var libreResponse models.LibreTranslateResponse
+	if err := doProviderRequest(p.httpClient, req, &libreResponse); err != nil {
+		return nil, err
+	}
+
+	response := &models.TranslateResponse{
+		OriginalText:   request.Text,
+		TranslatedText: libreResponse.TranslatedText,
+		SourceLang:     request.SourceLang,
+		TargetLang:     request.TargetLang,
+		Provider:       ProviderLibreTranslate,
+	}
+	if detected := libreResponse.DetectedLanguage; detected != nil && detected.Language != "" {
+		response.DetectedLanguage = &models.DetectedLanguage{
+			Language:   detected.Language,
+			Confidence: detected.Confidence / 100,
+		}
+	}
+	return response, nil
+}
+
+func (p *libreTranslateProvider) Detect(ctx context.Context, text string) ([]models.DetectedLanguage, error) {
+	requestBody, err := json.Marshal(models.LibreTranslateDetectRequest{Q: text, APIKey: p.apiKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/detect", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var detections []models.LibreTranslateDetection
+	if err := doProviderRequest(p.httpClient, req, &detections); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.DetectedLanguage, 0, len(detections))
+	for _, detection := range detections {
+		candidates = append(candidates, models.DetectedLanguage{
+			Language:   detection.Language,
+			Confidence: detection.Confidence / 100,
+		})
+	}
+	return candidates, nil
+}
+
+func (p *libreTranslateProvider) Languages(ctx context.Context) ([]models.SupportedLanguage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/languages", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var languages []models.SupportedLanguage
+	if err := doProviderRequest(p.httpClient, req, &languages); err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+// deepLProvider uses the DeepL API
+type deepLProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewDeepLProvider creates a DeepL provider. An empty baseURL picks the free
+// or pro API endpoint from the key; free keys end in ":fx".
+func NewDeepLProvider(baseURL, apiKey string) TranslationProvider {
+	if baseURL == "" {
+		baseURL = DefaultDeepLURL
+		if strings.HasSuffix(apiKey, ":fx") {
+			baseURL = DefaultDeepLFreeURL
+		}
+	}
+	return &deepLProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: newProviderHTTPClient(),
+	}
+}
+
+func (p *deepLProvider) Name() string {
+	return ProviderDeepL
+}
+
+// deepLTargetLanguage maps a language code to DeepL's target code, which
+// requires a regional variant for English and Portuguese
+func deepLTargetLanguage(code string) string {
+	switch strings.ToLower(code) {
+	case "en":
+		return "EN-US"
+	case "pt":
+		return "PT-BR"
+	}
+	return strings.ToUpper(code)
+}
+
+func (p *deepLProvider) Translate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error) {
+	deepLRequest := models.DeepLTranslateRequest{
+		Text:       []string{request.Text},
+		TargetLang: deepLTargetLanguage(request.TargetLang),
+	}
+	if translationFormat(request) == models.TranslationFormatHTML {
+		deepLRequest.TagHandling = "html"
+	}
+	if request.SourceLang != "auto" {
+		deepLRequest.SourceLang = strings.ToUpper(request.SourceLang)
+	}
+
+	requestBody, err := json.Marshal(deepLRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/v2/translate", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// DeepL answers 400 for language pairs it does not support
+	if resp.StatusCode == http.StatusBadRequest && strings.Contains(string(body), "lang") {
+		return nil, models.ErrUnsupportedLanguage
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("translation API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var deepLResponse models.DeepLTranslateResponse
+	if err := json.Unmarshal(body, &deepLResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(deepLResponse.Translations) == 0 {
+		return nil, fmt.Errorf("translation API returned no translations")
+	}
+
+	translation := deepLResponse.Translations[0]
+	response := &models.TranslateResponse{
+		OriginalText:   request.Text,
+		TranslatedText: translation.Text,
+		SourceLang:     request.SourceLang,
+		TargetLang:     request.TargetLang,
+		Provider:       ProviderDeepL,
+	}
+	if request.SourceLang == "auto" && translation.DetectedSourceLanguage != "" {
+		response.DetectedLanguage = &models.DetectedLanguage{
+			Language: strings.ToLower(translation.DetectedSourceLanguage),
+		}
+	}
+	return response, nil
+}
+
+func (p *deepLProvider) Languages(ctx context.Context) ([]models.SupportedLanguage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/v2/languages?type=target", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+p.apiKey)
+
+	var deepLLanguages []models.DeepLLanguage
+	if err := doProviderRequest(p.httpClient, req, &deepLLanguages); err != nil {
+		return nil, err
+	}
+
+	// Regional variants such as EN-GB collapse into the base language
+	seen := make(map[string]bool)
+	languages := make([]models.SupportedLanguage, 0, len(deepLLanguages))
+	for _, language := range deepLLanguages {
+		code := strings.ToLower(strings.SplitN(language.Language, "-", 2)[0])
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		languages = append(languages, models.SupportedLanguage{Code: code, Name: language.Name})
+	}
+	return languages, nil
+}
+
+// googleProvider uses the Google Cloud Translation basic (v2) API
+type googleProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewGoogleProvider creates a Google Cloud Translation provider
+func NewGoogleProvider(baseURL, apiKey string) TranslationProvider {
+	if baseURL == "" {
+		baseURL = DefaultGoogleURL
+	}
+	return &googleProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: newProviderHTTPClient(),
+	}
+}
+
+func (p *googleProvider) Name() string {
+	return ProviderGoogle
+}
+
+func (p *googleProvider) Translate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error) {
+	googleRequest := models.GoogleTranslateRequest{
+		Q:      []string{request.Text},
+		Target: request.TargetLang,
+		Format: translationFormat(request), // Google defaults to HTML, which escapes plain text
+	}
+	if request.SourceLang != "auto" {
+		googleRequest.Source = request.SourceLang
+	}
+
+	requestBody, err := json.Marshal(googleRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := p.baseURL + "/language/translate/v2?key=" + url.QueryEscape(p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var googleResponse models.GoogleTranslateResponse
+	if err := doProviderRequest(p.httpClient, req, &googleResponse); err != nil {
+		return nil, err
+	}
+	if len(googleResponse.Data.Translations) == 0 {
+		return nil, fmt.Errorf("translation API returned no translations")
+	}
+
+	translation := googleResponse.Data.Translations[0]
+	response := &models.TranslateResponse{
+		OriginalText:   request.Text,
+		TranslatedText: translation.TranslatedText,
+		SourceLang:     request.SourceLang,
+		TargetLang:     request.TargetLang,
+		Provider:       ProviderGoogle,
+	}
+	if translation.DetectedSourceLanguage != "" {
+		response.DetectedLanguage = &models.DetectedLanguage{
+			Language: translation.DetectedSourceLanguage,
+		}
+	}
+	return response, nil
+}
+
+func (p *googleProvider) Detect(ctx context.Context, text string) ([]models.DetectedLanguage, error) {
+	requestBody, err := json.Marshal(models.GoogleDetectRequest{Q: []string{text}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := p.baseURL + "/language/translate/v2/detect?key=" + url.QueryEscape(p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var googleResponse models.GoogleDetectResponse
+	if err := doProviderRequest(p.httpClient, req, &googleResponse); err != nil {
+		return nil, err
+	}
+	if len(googleResponse.Data.Detections) == 0 {
+		return nil, nil
+	}
+
+	detections := googleResponse.Data.Detections[0]
+	candidates := make([]models.DetectedLanguage, 0, len(detections))
+	for _, detection := range detections {
+		candidates = append(candidates, models.DetectedLanguage{
+			Language:   detection.Language,
+			Confidence: detection.Confidence,
+		})
+	}
+	return candidates, nil
+}
+
+func (p *googleProvider) Languages(ctx context.Context) ([]models.SupportedLanguage, error) {
+	endpoint := p.baseURL + "/language/translate/v2/languages?target=en&key=" + url.QueryEscape(p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var googleResponse models.GoogleLanguagesResponse
+	if err := doProviderRequest(p.httpClient, req, &googleResponse); err != nil {
+		return nil, err
+	}
+
+	languages := make([]models.SupportedLanguage, 0, len(googleResponse.Data.Languages))
+	for _, language := range googleResponse.Data.Languages {
+		languages = append(languages, models.SupportedLanguage{Code: language.Language, Name: language.Name})
+	}
+	return languages, nil
+}
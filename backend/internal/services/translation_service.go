@@ -1,38 +1,75 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"language-exchange/internal/cache"
 	"language-exchange/internal/models"
 )
 
+// Provider health tracking: after providerFailureThreshold consecutive
+// failures a provider is skipped for a cooldown that doubles with each
+// further failure, up to providerMaxCooldown
+const (
+	providerFailureThreshold = 3
+	providerCooldown         = 30 * time.Second
+	providerMaxCooldown      = 5 * time.Minute
+)
+
+// DefaultTranslationCacheTTL is how long translations are cached
+const DefaultTranslationCacheTTL = 7 * 24 * time.Hour
+
+// batchTranslateConcurrency limits how many segments of a batch are sent to
+// the providers at once
+const batchTranslateConcurrency = 4
 
 // translationService implements TranslationService
 type translationService struct {
-	libreTranslateURL string
-	apiKey            string
-	httpClient        *http.Client
+	providers []TranslationProvider
+	cache     cache.Cache
+	cacheTTL  time.Duration
+	keys      *cache.CacheKeyBuilder
+
+	mu                 sync.RWMutex
+	health             map[string]*providerHealth
 	supportedLanguages map[string]models.SupportedLanguage
 }
 
-// NewTranslationService creates a new translation service
-func NewTranslationService(libreTranslateURL, apiKey string) TranslationService {
+type providerHealth struct {
+	consecutiveFailures int
+	lastSuccess         time.Time
+	lastFailure         time.Time
+	retryAt             time.Time
+}
+
+// NewTranslationService creates a translation service that tries providers in
+// order until one succeeds. Translations are cached in translationCache when
+// it is not nil.
+func NewTranslationService(providers []TranslationProvider, translationCache cache.Cache, cacheTTL time.Duration) TranslationService {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultTranslationCacheTTL
+	}
+
 	service := &translationService{
-		libreTranslateURL: libreTranslateURL,
-		apiKey:           apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		providers:          providers,
+		cache:              translationCache,
+		cacheTTL:           cacheTTL,
+		keys:               cache.NewCacheKeyBuilder(),
+		health:             make(map[string]*providerHealth),
 		supportedLanguages: make(map[string]models.SupportedLanguage),
 	}
+	for _, provider := range providers {
+		service.health[provider.Name()] = &providerHealth{}
+	}
 
 	// Initialize with common languages (fallback)
 	service.initializeCommonLanguages()
@@ -76,148 +113,391 @@ func (s *translationService) Translate(ctx context.Context, request models.Trans
 	if err := s.validateTranslateRequest(request); err != nil {
 		return nil, err
 	}
+	if err := s.validateLanguagePair(request.SourceLang, request.TargetLang); err != nil {
+		return nil, err
+	}
 
-	// Check if source and target languages are the same (skip if source is "auto")
-	if request.SourceLang != "auto" && request.SourceLang == request.TargetLang {
-		return nil, models.ErrSameLanguage
+	return s.translate(ctx, request)
+}
+
+// TranslateBatch translates every segment between the same pair of
+// languages. Segments are translated independently, so one failing segment
+// is reported in its result without failing the rest.
+func (s *translationService) TranslateBatch(ctx context.Context, request models.BatchTranslateRequest) (*models.BatchTranslateResponse, error) {
+	if len(request.Segments) == 0 || len(request.Segments) > models.MaxBatchSegments {
+		return nil, models.NewAppError("INVALID_INPUT", fmt.Sprintf("A batch must have between 1 and %d segments", models.MaxBatchSegments), 400)
+	}
+	if request.Format == "" {
+		request.Format = models.TranslationFormatText
+	}
+	if err := s.validateLanguagePair(request.SourceLang, request.TargetLang); err != nil {
+		return nil, err
 	}
 
-	// Check if languages are supported (allow "auto" for source language)
-	if (request.SourceLang != "auto" && !s.IsLanguageSupported(request.SourceLang)) || !s.IsLanguageSupported(request.TargetLang) {
-		return nil, models.ErrUnsupportedLanguage
+	response := &models.BatchTranslateResponse{
+		SourceLang: request.SourceLang,
+		TargetLang: request.TargetLang,
+		Format:     request.Format,
+		Results:    make([]models.BatchTranslateResult, len(request.Segments)),
 	}
 
-	// Try LibreTranslate first
-	response, err := s.translateWithLibreTranslate(ctx, request)
-	if err != nil {
-		// Log error but don't fail - could add other providers here
-		return nil, fmt.Errorf("translation failed: %w", err)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batchTranslateConcurrency)
+	for i, segment := range request.Segments {
+		wg.Add(1)
+		go func(i int, segment models.BatchTranslateSegment) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			response.Results[i] = s.translateSegment(ctx, request, i, segment)
+		}(i, segment)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, result := range response.Results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
 	}
 
 	return response, nil
 }
 
-// translateWithLibreTranslate uses LibreTranslate API for translation
-func (s *translationService) translateWithLibreTranslate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error) {
-	if s.libreTranslateURL == "" {
-		return nil, models.ErrTranslationServiceUnavailable
+// translateSegment translates one segment of a batch, turning any error into
+// the result's error
+func (s *translationService) translateSegment(ctx context.Context, batch models.BatchTranslateRequest, index int, segment models.BatchTranslateSegment) models.BatchTranslateResult {
+	result := models.BatchTranslateResult{
+		Index: index,
+		ID:    segment.ID,
 	}
 
-	// Prepare LibreTranslate request
-	libreRequest := models.LibreTranslateRequest{
-		Q:      request.Text,
-		Source: request.SourceLang,
-		Target: request.TargetLang,
-		Format: "text",
+	request := models.TranslateRequest{
+		Text:       segment.Text,
+		SourceLang: batch.SourceLang,
+		TargetLang: batch.TargetLang,
+		Format:     batch.Format,
 	}
 
-	if s.apiKey != "" {
-		libreRequest.APIKey = s.apiKey
+	err := s.validateTranslateRequest(request)
+	if err == nil {
+		var translated *models.TranslateResponse
+		if translated, err = s.translate(ctx, request); err == nil {
+			result.TranslatedText = translated.TranslatedText
+			result.Provider = translated.Provider
+			result.Cached = translated.Cached
+			return result
+		}
 	}
 
-	// Marshal request to JSON
-	requestBody, err := json.Marshal(libreRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	if translationErr, ok := err.(*models.TranslationError); ok {
+		result.Error = translationErr
+	} else {
+		result.Error = models.ErrTranslationFailed
 	}
+	return result
+}
 
-	// Create HTTP request
-	url := fmt.Sprintf("%s/translate", s.libreTranslateURL)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// validateLanguagePair checks that a translation between the languages is
+// possible before any provider is asked
+func (s *translationService) validateLanguagePair(sourceLang, targetLang string) error {
+	if len(sourceLang) < 2 || len(targetLang) < 2 {
+		return errors.New("invalid language codes")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	// Check if source and target languages are the same (skip if source is "auto")
+	if sourceLang != "auto" && sourceLang == targetLang {
+		return models.ErrSameLanguage
+	}
 
-	// Make the request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	// Check if languages are supported (allow "auto" for source language)
+	if (sourceLang != "auto" && !s.IsLanguageSupported(sourceLang)) || !s.IsLanguageSupported(targetLang) {
+		return models.ErrUnsupportedLanguage
+	}
+
+	return nil
+}
+
+// translate serves a validated request from the cache or the provider chain
+func (s *translationService) translate(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error) {
+	if request.Format == "" {
+		request.Format = models.TranslationFormatText
+	}
+
+	key := s.translationKey(request)
+	if s.cache != nil {
+		var cached models.TranslateResponse
+		err := s.cache.Get(ctx, key, &cached)
+		if err == nil {
+			cached.Cached = true
+			return &cached, nil
+		}
+		if err != cache.ErrCacheMiss {
+			log.Printf("Translation cache read failed: %v", err)
+		}
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	response, err := s.translateWithProviders(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
-	// Check for HTTP errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("translation API returned status %d: %s", resp.StatusCode, string(body))
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, key, response, s.cacheTTL); err != nil {
+			log.Printf("Translation cache write failed: %v", err)
+		}
 	}
 
-	// Parse response
-	var libreResponse models.LibreTranslateResponse
-	if err := json.Unmarshal(body, &libreResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	return response, nil
+}
+
+// translationKey identifies a translation by a hash of its text and the
+// language pair, so long texts make short keys
+func (s *translationService) translationKey(request models.TranslateRequest) string {
+	return s.keys.TranslationKey(translationTextHash(request.Text), strings.ToLower(request.SourceLang), strings.ToLower(request.TargetLang), request.Format)
+}
+
+func translationTextHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ForgetTranslations removes every cached translation of text, for when the
+// content it came from is deleted
+func (s *translationService) ForgetTranslations(ctx context.Context, text string) error {
+	if s.cache == nil {
+		return nil
 	}
+	return s.cache.DeletePattern(ctx, s.keys.TranslationKey(translationTextHash(text), "*", "*", "*"))
+}
 
-	// Create our response
-	translationResponse := &models.TranslateResponse{
-		OriginalText:   request.Text,
-		TranslatedText: libreResponse.TranslatedText,
-		SourceLang:     request.SourceLang,
-		TargetLang:     request.TargetLang,
-		Provider:       "libretranslate",
+// translateWithProviders walks the fallback chain. A provider that does not
+// support the language pair is skipped without counting as a failure.
+func (s *translationService) translateWithProviders(ctx context.Context, request models.TranslateRequest) (*models.TranslateResponse, error) {
+	unsupported := 0
+	providers := s.availableProviders(s.providers)
+	for _, provider := range providers {
+		response, err := provider.Translate(ctx, request)
+		if err == nil {
+			s.recordSuccess(provider.Name())
+			return response, nil
+		}
+
+		if err == models.ErrUnsupportedLanguage {
+			unsupported++
+			continue
+		}
+
+		// The caller gave up; that says nothing about the provider
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		log.Printf("Translation provider %s failed: %v", provider.Name(), err)
+		s.recordFailure(provider.Name())
 	}
 
-	return translationResponse, nil
+	if unsupported > 0 && unsupported == len(providers) {
+		return nil, models.ErrUnsupportedLanguage
+	}
+	return nil, models.ErrTranslationServiceUnavailable
 }
 
-// GetSupportedLanguages returns the list of supported languages
-func (s *translationService) GetSupportedLanguages(ctx context.Context) (*models.LanguagesResponse, error) {
-	// Try to fetch from LibreTranslate API if available
-	if s.libreTranslateURL != "" {
-		if languages, err := s.fetchLanguagesFromLibreTranslate(ctx); err == nil {
-			return languages, nil
+// availableProviders returns the given providers that are not cooling down,
+// in chain order. If every provider is cooling down they are all tried
+// anyway rather than failing without a request.
+func (s *translationService) availableProviders(providers []TranslationProvider) []TranslationProvider {
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	available := make([]TranslationProvider, 0, len(providers))
+	for _, provider := range providers {
+		if now.Before(s.health[provider.Name()].retryAt) {
+			continue
 		}
+		available = append(available, provider)
 	}
 
-	// Return fallback languages
-	var languages []models.SupportedLanguage
-	for _, lang := range s.supportedLanguages {
-		languages = append(languages, lang)
+	if len(available) == 0 {
+		return providers
 	}
+	return available
+}
 
-	return &models.LanguagesResponse{
-		Languages: languages,
-	}, nil
+// DetectLanguage asks the first available provider that supports detection
+// which language the text is written in
+func (s *translationService) DetectLanguage(ctx context.Context, text string) (*models.DetectLanguageResponse, error) {
+	var detectors []TranslationProvider
+	for _, provider := range s.providers {
+		if _, ok := provider.(LanguageDetectionProvider); ok {
+			detectors = append(detectors, provider)
+		}
+	}
+	return s.detectLanguage(ctx, text, s.availableProviders(detectors), true)
 }
 
-// fetchLanguagesFromLibreTranslate fetches supported languages from LibreTranslate API
-func (s *translationService) fetchLanguagesFromLibreTranslate(ctx context.Context) (*models.LanguagesResponse, error) {
-	url := fmt.Sprintf("%s/languages", s.libreTranslateURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// DetectContentLanguage is DetectLanguage for background detection of saved
+// content. It only asks providers that are not cooling down and does not
+// count their failures, so the volume of user content cannot take providers
+// out of the translation chain.
+func (s *translationService) DetectContentLanguage(ctx context.Context, text string) (*models.DetectLanguageResponse, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	var detectors []TranslationProvider
+	for _, provider := range s.providers {
+		if _, ok := provider.(LanguageDetectionProvider); ok && !now.Before(s.health[provider.Name()].retryAt) {
+			detectors = append(detectors, provider)
+		}
 	}
+	s.mu.RUnlock()
 
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	return s.detectLanguage(ctx, text, detectors, false)
+}
+
+// detectLanguage tries detectors in order. Their health is only recorded
+// when recordHealth is set.
+func (s *translationService) detectLanguage(ctx context.Context, text string, detectors []TranslationProvider, recordHealth bool) (*models.DetectLanguageResponse, error) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 || len(text) > 5000 {
+		return nil, models.ErrInvalidTextLength
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	for _, provider := range detectors {
+		candidates, err := provider.(LanguageDetectionProvider).Detect(ctx, text)
+		if err != nil {
+			// The caller gave up; that says nothing about the provider
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			log.Printf("Language detection with %s failed: %v", provider.Name(), err)
+			if recordHealth {
+				s.recordFailure(provider.Name())
+			}
+			continue
+		}
+		if recordHealth {
+			s.recordSuccess(provider.Name())
+		}
+
+		if len(candidates) == 0 {
+			return nil, models.ErrLanguageNotDetected
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Confidence > candidates[j].Confidence
+		})
+		return &models.DetectLanguageResponse{
+			Language:   candidates[0].Language,
+			Confidence: candidates[0].Confidence,
+			Candidates: candidates,
+			Provider:   provider.Name(),
+		}, nil
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	return nil, models.ErrTranslationServiceUnavailable
+}
+
+func (s *translationService) recordSuccess(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := s.health[name]
+	health.consecutiveFailures = 0
+	health.lastSuccess = time.Now()
+	health.retryAt = time.Time{}
+}
+
+func (s *translationService) recordFailure(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := s.health[name]
+	health.consecutiveFailures++
+	health.lastFailure = time.Now()
+
+	if health.consecutiveFailures >= providerFailureThreshold {
+		cooldown := providerCooldown
+		for i := providerFailureThreshold; i < health.consecutiveFailures && cooldown < providerMaxCooldown; i++ {
+			cooldown *= 2
+		}
+		if cooldown > providerMaxCooldown {
+			cooldown = providerMaxCooldown
+		}
+		health.retryAt = health.lastFailure.Add(cooldown)
 	}
+}
 
-	var languages []models.SupportedLanguage
-	if err := json.Unmarshal(body, &languages); err != nil {
-		return nil, err
+// GetProviderStatus reports the health of each provider in chain order
+func (s *translationService) GetProviderStatus() []models.TranslationProviderStatus {
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]models.TranslationProviderStatus, 0, len(s.providers))
+	for _, provider := range s.providers {
+		health := s.health[provider.Name()]
+		status := models.TranslationProviderStatus{
+			Name:                provider.Name(),
+			Healthy:             health.consecutiveFailures == 0,
+			ConsecutiveFailures: health.consecutiveFailures,
+		}
+		if !health.lastSuccess.IsZero() {
+			lastSuccess := health.lastSuccess
+			status.LastSuccessAt = &lastSuccess
+		}
+		if !health.lastFailure.IsZero() {
+			lastFailure := health.lastFailure
+			status.LastFailureAt = &lastFailure
+		}
+		if now.Before(health.retryAt) {
+			retryAt := health.retryAt
+			status.RetryAt = &retryAt
+		}
+		statuses = append(statuses, status)
 	}
+	return statuses
+}
 
-	// Update internal cache
-	for _, lang := range languages {
-		s.supportedLanguages[lang.Code] = lang
+// GetSupportedLanguages returns the languages supported by any provider
+func (s *translationService) GetSupportedLanguages(ctx context.Context) (*models.LanguagesResponse, error) {
+	for _, provider := range s.providers {
+		languages, err := provider.Languages(ctx)
+		if err != nil {
+			log.Printf("Failed to fetch languages from %s: %v", provider.Name(), err)
+			continue
+		}
+
+		s.mu.Lock()
+		for _, lang := range languages {
+			code := strings.ToLower(lang.Code)
+			if _, exists := s.supportedLanguages[code]; !exists {
+				s.supportedLanguages[code] = models.SupportedLanguage{Code: code, Name: lang.Name}
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.RLock()
+	languages := make([]models.SupportedLanguage, 0, len(s.supportedLanguages))
+	for _, lang := range s.supportedLanguages {
+		languages = append(languages, lang)
 	}
+	s.mu.RUnlock()
+
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i].Code < languages[j].Code
+	})
 
 	return &models.LanguagesResponse{
 		Languages: languages,
@@ -231,10 +511,22 @@ func (s *translationService) IsLanguageSupported(languageCode string) bool {
 		return true
 	}
 	languageCode = strings.ToLower(languageCode)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	_, exists := s.supportedLanguages[languageCode]
 	return exists
 }
 
+// GetProviderNames returns the configured providers in chain order
+func (s *translationService) GetProviderNames() []string {
+	names := make([]string, 0, len(s.providers))
+	for _, provider := range s.providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
+
 // validateTranslateRequest validates the translation request
 func (s *translationService) validateTranslateRequest(request models.TranslateRequest) error {
 	// Check text length
@@ -246,11 +538,6 @@ func (s *translationService) validateTranslateRequest(request models.TranslateRe
 		return models.ErrInvalidTextLength
 	}
 
-	// Check language codes
-	if len(request.SourceLang) < 2 || len(request.TargetLang) < 2 {
-		return errors.New("invalid language codes")
-	}
-
 	return nil
 }
 
@@ -265,4 +552,4 @@ func GetDefaultLibreTranslateURL() string {
 // GetLibreTranslateDockerCommand returns the Docker command to run LibreTranslate
 func GetLibreTranslateDockerCommand() string {
 	return `docker run -ti --rm -p 5000:5000 libretranslate/libretranslate`
-}
\ No newline at end of file
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"language-exchange/internal/cache"
+	"language-exchange/internal/models"
+)
+
+// providerServer serves handler and counts the requests it receives
+type providerServer struct {
+	*httptest.Server
+	requests int32
+}
+
+func newProviderServer(t *testing.T, handler http.HandlerFunc) *providerServer {
+	t.Helper()
+	server := &providerServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&server.requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *providerServer) count() int {
+	return int(atomic.LoadInt32(&s.requests))
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, body interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		t.Errorf("failed to write response: %v", err)
+	}
+}
+
+func failingServer(t *testing.T) *providerServer {
+	return newProviderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream secret: internal host 10.0.0.7 refused", http.StatusInternalServerError)
+	})
+}
+
+func libreServer(t *testing.T, translated string) *providerServer {
+	return newProviderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/translate" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var request models.LibreTranslateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if request.Format != models.TranslationFormatText {
+			t.Errorf("format = %q, want %q", request.Format, models.TranslationFormatText)
+		}
+		writeJSON(t, w, models.LibreTranslateResponse{TranslatedText: translated})
+	})
+}
+
+var helloRequest = models.TranslateRequest{Text: "Hello", SourceLang: "en", TargetLang: "es"}
+
+func TestLibreTranslateProvider(t *testing.T) {
+	server := libreServer(t, "Hola")
+	provider := NewLibreTranslateProvider(server.URL+"/", "")
+
+	response, err := provider.Translate(context.Background(), helloRequest)
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if response.TranslatedText != "Hola" || response.Provider != ProviderLibreTranslate {
+		t.Errorf("Translate() = %q from %s, want %q from %s", response.TranslatedText, response.Provider, "Hola", ProviderLibreTranslate)
+	}
+}
+
+func TestDeepLProvider(t *testing.T) {
+	server := newProviderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "DeepL-Auth-Key key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var request models.DeepLTranslateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if request.TargetLang == "XX" {
+			http.Error(w, `{"message":"Value for 'target_lang' not supported."}`, http.StatusBadRequest)
+			return
+		}
+		if request.TargetLang != "EN-US" {
+			t.Errorf("target_lang = %q, want EN-US", request.TargetLang)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"translations": []map[string]string{{"detected_source_language": "ES", "text": "Hello"}},
+		})
+	})
+	provider := NewDeepLProvider(server.URL, "key")
+
+	response, err := provider.Translate(context.Background(), models.TranslateRequest{Text: "Hola", SourceLang: "auto", TargetLang: "en"})
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if response.TranslatedText != "Hello" {
+		t.Errorf("TranslatedText = %q, want Hello", response.TranslatedText)
+	}
+	if response.DetectedLanguage == nil || response.DetectedLanguage.Language != "es" {
+		t.Errorf("DetectedLanguage = %+v, want es", response.DetectedLanguage)
+	}
+
+	_, err = provider.Translate(context.Background(), models.TranslateRequest{Text: "Hola", SourceLang: "es", TargetLang: "xx"})
+	if err != models.ErrUnsupportedLanguage {
+		t.Errorf("Translate() to an unsupported language error = %v, want ErrUnsupportedLanguage", err)
+	}
+}
+
+func TestGoogleProvider(t *testing.T) {
+	server := newProviderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("key"); got != "key" {
+			t.Errorf("key = %q", got)
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"data": map[string]interface{}{
+				"translations": []map[string]string{{"translatedText": "Hola"}},
+			},
+		})
+	})
+	provider := NewGoogleProvider(server.URL, "key")
+
+	response, err := provider.Translate(context.Background(), helloRequest)
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if response.TranslatedText != "Hola" || response.Provider != ProviderGoogle {
+		t.Errorf("Translate() = %q from %s, want %q from %s", response.TranslatedText, response.Provider, "Hola", ProviderGoogle)
+	}
+}
+
+func TestTranslateFallsBackToNextProvider(t *testing.T) {
+	primary := failingServer(t)
+	secondary := libreServer(t, "Hola")
+	service := NewTranslationService([]TranslationProvider{
+		NewDeepLProvider(primary.URL, "key"),
+		NewLibreTranslateProvider(secondary.URL, ""),
+	}, nil, 0)
+
+	response, err := service.Translate(context.Background(), helloRequest)
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if response.Provider != ProviderLibreTranslate {
+		t.Errorf("Provider = %s, want %s", response.Provider, ProviderLibreTranslate)
+	}
+
+	statuses := service.GetProviderStatus()
+	if statuses[0].Healthy || statuses[0].ConsecutiveFailures != 1 {
+		t.Errorf("failed provider status = %+v, want one failure", statuses[0])
+	}
+	if !statuses[1].Healthy || statuses[1].LastSuccessAt == nil {
+		t.Errorf("working provider status = %+v, want healthy", statuses[1])
+	}
+}
+
+func TestFailingProviderCoolsDown(t *testing.T) {
+	primary := failingServer(t)
+	secondary := libreServer(t, "Hola")
+	service := NewTranslationService([]TranslationProvider{
+		NewDeepLProvider(primary.URL, "key"),
+		NewLibreTranslateProvider(secondary.URL, ""),
+	}, nil, 0)
+
+	for i := 0; i < providerFailureThreshold+2; i++ {
+		if _, err := service.Translate(context.Background(), helloRequest); err != nil {
+			t.Fatalf("Translate() error = %v", err)
+		}
+	}
+
+	if got := primary.count(); got != providerFailureThreshold {
+		t.Errorf("failing provider got %d requests, want %d before cooling down", got, providerFailureThreshold)
+	}
+	if status := service.GetProviderStatus()[0]; status.RetryAt == nil {
+		t.Errorf("failing provider status = %+v, want a retry time", status)
+	}
+}
+
+func TestTranslateWhenEveryProviderFails(t *testing.T) {
+	service := NewTranslationService([]TranslationProvider{
+		NewLibreTranslateProvider(failingServer(t).URL, ""),
+		NewGoogleProvider(failingServer(t).URL, "key"),
+	}, nil, 0)
+
+	_, err := service.Translate(context.Background(), helloRequest)
+	if err != models.ErrTranslationServiceUnavailable {
+		t.Errorf("Translate() error = %v, want ErrTranslationServiceUnavailable", err)
+	}
+}
+
+func TestTranslateServesCachedTranslation(t *testing.T) {
+	server := libreServer(t, "Hola")
+	service := NewTranslationService([]TranslationProvider{
+		NewLibreTranslateProvider(server.URL, ""),
+	}, cache.NewMemoryCache(), 0)
+
+	if _, err := service.Translate(context.Background(), helloRequest); err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+
+	response, err := service.Translate(context.Background(), helloRequest)
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if !response.Cached || server.count() != 1 {
+		t.Errorf("Cached = %v after %d provider requests, want a cached translation after 1", response.Cached, server.count())
+	}
+}
+
+func TestProviderStatusHidesProviderErrors(t *testing.T) {
+	service := NewTranslationService([]TranslationProvider{
+		NewLibreTranslateProvider(failingServer(t).URL, ""),
+	}, nil, 0)
+	service.Translate(context.Background(), helloRequest)
+
+	body, err := json.Marshal(service.GetProviderStatus())
+	if err != nil {
+		t.Fatalf("failed to marshal status: %v", err)
+	}
+	if strings.Contains(string(body), "10.0.0.7") {
+		t.Errorf("provider status %s exposes the provider's error", body)
+	}
+}
@@ -148,6 +148,25 @@ func (s *UploadService) DeleteFile(filename string) error {
 	return nil
 }
 
+// StorageUsed adds up the size of the files a user has uploaded, found by
+// the user ID in their names
+func (s *UploadService) StorageUsed(userID string) (int64, error) {
+	matches, err := filepath.Glob(filepath.Join(s.uploadsDir, "*_"+userID+"_*"))
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue // deleted since the glob
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
 func (s *UploadService) generateFilename(originalFilename, userID, uploadType string) (string, error) {
 	// Get file extension
 	ext := filepath.Ext(originalFilename)
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+	"language-exchange/internal/repository"
+)
+
+// Page size limits for vocabulary listings
+const (
+	defaultVocabularyLimit = 50
+	maxVocabularyLimit     = 200
+)
+
+type vocabularyService struct {
+	vocabularyRepo      repository.VocabularyRepository
+	gamificationService GamificationService
+}
+
+func NewVocabularyService(vocabularyRepo repository.VocabularyRepository, gamificationService GamificationService) VocabularyService {
+	return &vocabularyService{
+		vocabularyRepo:      vocabularyRepo,
+		gamificationService: gamificationService,
+	}
+}
+
+// SaveVocabulary adds a word or phrase to the user's notebook. New words are
+// due for review straight away.
+func (s *vocabularyService) SaveVocabulary(ctx context.Context, userID string, input models.CreateVocabularyInput) (*models.VocabularyItem, error) {
+	item, err := newVocabularyItem(userID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.vocabularyRepo.Create(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// SaveLookup saves a word the user has just translated. Looking up a word
+// that is already saved counts against it instead of adding a duplicate.
+func (s *vocabularyService) SaveLookup(ctx context.Context, userID string, input models.CreateVocabularyInput) (*models.VocabularyItem, error) {
+	item, err := newVocabularyItem(userID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.vocabularyRepo.SaveLookup(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func newVocabularyItem(userID string, input models.CreateVocabularyInput) (*models.VocabularyItem, error) {
+	term := strings.TrimSpace(input.Term)
+	translation := strings.TrimSpace(input.Translation)
+	if term == "" || translation == "" {
+		return nil, models.NewAppError("INVALID_INPUT", "Term and translation are required", 400)
+	}
+	if input.OriginID != "" && input.OriginType == "" {
+		return nil, models.NewAppError("INVALID_INPUT", "origin_type is required with origin_id", 400)
+	}
+
+	item := &models.VocabularyItem{
+		UserID:          userID,
+		Term:            term,
+		Translation:     translation,
+		SourceLanguage:  strings.ToLower(input.SourceLanguage),
+		TargetLanguage:  strings.ToLower(input.TargetLanguage),
+		ExampleSentence: input.ExampleSentence,
+		LookupCount:     1,
+		EaseFactor:      models.VocabularyInitialEase,
+		DueAt:           time.Now(),
+	}
+	if input.OriginType != "" {
+		item.OriginType = &input.OriginType
+		item.OriginID = &input.OriginID
+	}
+	return item, nil
+}
+
+func (s *vocabularyService) GetVocabulary(ctx context.Context, userID string, filters models.VocabularyFilters) ([]*models.VocabularyItem, error) {
+	if filters.Limit <= 0 {
+		filters.Limit = defaultVocabularyLimit
+	}
+	if filters.Limit > maxVocabularyLimit {
+		filters.Limit = maxVocabularyLimit
+	}
+	if filters.Offset < 0 {
+		filters.Offset = 0
+	}
+	filters.SourceLanguage = strings.ToLower(filters.SourceLanguage)
+	filters.TargetLanguage = strings.ToLower(filters.TargetLanguage)
+
+	return s.vocabularyRepo.List(ctx, userID, filters)
+}
+
+func (s *vocabularyService) GetVocabularyItem(ctx context.Context, userID, itemID string) (*models.VocabularyItem, error) {
+	return s.vocabularyRepo.GetByID(ctx, userID, itemID)
+}
+
+// GetReviewQueue returns the most overdue words and the number of words due
+func (s *vocabularyService) GetReviewQueue(ctx context.Context, userID string, limit int) ([]*models.VocabularyItem, int, error) {
+	items, err := s.GetVocabulary(ctx, userID, models.VocabularyFilters{DueOnly: true, Limit: limit})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	due, err := s.vocabularyRepo.CountDue(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, due, nil
+}
+
+func (s *vocabularyService) UpdateVocabularyItem(ctx context.Context, userID, itemID string, input models.UpdateVocabularyInput) (*models.VocabularyItem, error) {
+	item, err := s.vocabularyRepo.GetByID(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Term != nil {
+		if item.Term = strings.TrimSpace(*input.Term); item.Term == "" {
+			return nil, models.NewAppError("INVALID_INPUT", "Term cannot be empty", 400)
+		}
+	}
+	if input.Translation != nil {
+		if item.Translation = strings.TrimSpace(*input.Translation); item.Translation == "" {
+			return nil, models.NewAppError("INVALID_INPUT", "Translation cannot be empty", 400)
+		}
+	}
+	if input.ExampleSentence != nil {
+		item.ExampleSentence = input.ExampleSentence
+		if *input.ExampleSentence == "" {
+			item.ExampleSentence = nil
+		}
+	}
+
+	if err := s.vocabularyRepo.Update(ctx, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *vocabularyService) DeleteVocabularyItem(ctx context.Context, userID, itemID string) error {
+	return s.vocabularyRepo.Delete(ctx, userID, itemID)
+}
+
+// ReviewVocabularyItem grades a flashcard answer, reschedules the word and
+// credits the review to the user's stats and daily challenges. Words can only
+// be reviewed once they are due, and learning a term is rewarded once per
+// user however many times it is saved again.
+func (s *vocabularyService) ReviewVocabularyItem(ctx context.Context, userID, itemID string, quality int) (*models.VocabularyReviewResult, error) {
+	if quality < 0 || quality > 5 {
+		return nil, models.NewAppError("INVALID_INPUT", "Quality must be between 0 and 5", 400)
+	}
+
+	item, err := s.vocabularyRepo.GetByID(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Before(item.DueAt) {
+		return nil, models.ErrVocabularyNotDue
+	}
+
+	previousReview := item.LastReviewedAt
+	learned := item.Review(quality, now)
+	learned, err = s.vocabularyRepo.SaveReview(ctx, item, quality, previousReview, learned)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.gamificationService != nil {
+		go func() {
+			_ = s.gamificationService.OnVocabularyReviewed(context.Background(), userID, item.ID, learned)
+		}()
+	}
+
+	return &models.VocabularyReviewResult{
+		Item:    item,
+		Learned: learned,
+	}, nil
+}
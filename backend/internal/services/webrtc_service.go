@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// webRTCService implements WebRTCService
+type webRTCService struct {
+	sessionService SessionService
+	turnURLs       []string
+	stunURLs       []string
+	turnSecret     []byte
+	credentialTTL  time.Duration
+}
+
+// NewWebRTCService creates a new WebRTC service. turnSecret must match the
+// static-auth-secret of the coturn server at turnURLs.
+func NewWebRTCService(sessionService SessionService, turnURLs, stunURLs []string, turnSecret string, credentialTTL time.Duration) WebRTCService {
+	return &webRTCService{
+		sessionService: sessionService,
+		turnURLs:       turnURLs,
+		stunURLs:       stunURLs,
+		turnSecret:     []byte(turnSecret),
+		credentialTTL:  credentialTTL,
+	}
+}
+
+// IssueTURNCredentials returns TURN credentials for a participant of the
+// session. The username is the expiry time and user ID, and the password is
+// its HMAC-SHA1 under the shared secret, so coturn can verify them without
+// calling back.
+func (s *webRTCService) IssueTURNCredentials(ctx context.Context, sessionID, userID string) (*models.TURNCredentials, error) {
+	if len(s.turnSecret) == 0 || len(s.turnURLs) == 0 {
+		return nil, models.NewAppError("TURN_NOT_CONFIGURED", "TURN relay is not configured", 503)
+	}
+
+	inSession, err := s.sessionService.IsUserInSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inSession {
+		return nil, models.NewAppError("NOT_IN_SESSION", "Join the session before requesting TURN credentials", 403)
+	}
+
+	expiresAt := time.Now().Add(s.credentialTTL).Truncate(time.Second)
+	username := strconv.FormatInt(expiresAt.Unix(), 10) + ":" + userID
+
+	mac := hmac.New(sha1.New, s.turnSecret)
+	mac.Write([]byte(username))
+	credential := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	credentials := &models.TURNCredentials{
+		Username:   username,
+		Credential: credential,
+		TTL:        int(s.credentialTTL.Seconds()),
+		ExpiresAt:  expiresAt,
+	}
+	if len(s.stunURLs) > 0 {
+		credentials.ICEServers = append(credentials.ICEServers, models.ICEServer{URLs: s.stunURLs})
+	}
+	credentials.ICEServers = append(credentials.ICEServers, models.ICEServer{
+		URLs:       s.turnURLs,
+		Username:   username,
+		Credential: credential,
+	})
+
+	return credentials, nil
+}
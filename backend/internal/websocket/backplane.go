@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// Envelope targets
+const (
+	EnvelopeTargetUser    = "user"
+	EnvelopeTargetSession = "session"
+	// EnvelopeTargetSessionUser addresses one user's clients in a session room
+	EnvelopeTargetSessionUser = "session_user"
+	EnvelopeTargetAll         = "all"
+)
+
+// Envelope is a message relayed between hub nodes through the backplane
+type Envelope struct {
+	// NodeID is the hub that published the envelope
+	NodeID string `json:"node_id"`
+
+	// Target is one of the EnvelopeTarget constants
+	Target string `json:"target"`
+
+	// TargetID is the user or session ID the payload is addressed to
+	TargetID string `json:"target_id,omitempty"`
+
+	// UserID narrows a session target down to one user's clients
+	UserID string `json:"user_id,omitempty"`
+
+	// Seq is the payload's event log sequence number, if it has one
+	Seq int64 `json:"seq,omitempty"`
+
+	// Payload is the already encoded WebSocket frame
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Backplane relays messages and presence between hub nodes so that clients
+// connected to different API replicas can reach each other
+type Backplane interface {
+	// Publish sends an envelope to every subscribed node
+	Publish(ctx context.Context, envelope *Envelope) error
+
+	// Subscribe registers a handler that is called for every published envelope
+	Subscribe(ctx context.Context, handler func(*Envelope)) error
+
+	// SetPresence records whether a user has at least one connection on a node
+	SetPresence(ctx context.Context, nodeID, userID string, online bool) error
+
+	// IsUserOnline reports whether a user is connected to any node
+	IsUserOnline(ctx context.Context, userID string) (bool, error)
+
+	// OnlineUsers returns the users connected to any node
+	OnlineUsers(ctx context.Context) ([]string, error)
+
+	// Close releases the backplane's resources
+	Close() error
+}
+
+// MemoryBackplane is a Backplane for a single process. Hubs sharing one
+// instance behave like separate nodes.
+type MemoryBackplane struct {
+	mutex       sync.RWMutex
+	subscribers []func(*Envelope)
+	presence    map[string]map[string]bool // nodeID -> userID set
+}
+
+// NewMemoryBackplane creates a new in-memory backplane
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{
+		presence: make(map[string]map[string]bool),
+	}
+}
+
+// Publish delivers the envelope to every subscriber
+func (b *MemoryBackplane) Publish(ctx context.Context, envelope *Envelope) error {
+	b.mutex.RLock()
+	subscribers := make([]func(*Envelope), len(b.subscribers))
+	copy(subscribers, b.subscribers)
+	b.mutex.RUnlock()
+
+	for _, handler := range subscribers {
+		handler(envelope)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for published envelopes
+func (b *MemoryBackplane) Subscribe(ctx context.Context, handler func(*Envelope)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscribers = append(b.subscribers, handler)
+	return nil
+}
+
+// SetPresence records a user as online or offline on a node
+func (b *MemoryBackplane) SetPresence(ctx context.Context, nodeID, userID string, online bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	users := b.presence[nodeID]
+	if online {
+		if users == nil {
+			users = make(map[string]bool)
+			b.presence[nodeID] = users
+		}
+		users[userID] = true
+		return nil
+	}
+
+	if users != nil {
+		delete(users, userID)
+		if len(users) == 0 {
+			delete(b.presence, nodeID)
+		}
+	}
+	return nil
+}
+
+// IsUserOnline reports whether any node has the user online
+func (b *MemoryBackplane) IsUserOnline(ctx context.Context, userID string) (bool, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, users := range b.presence {
+		if users[userID] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// OnlineUsers returns the users online on any node
+func (b *MemoryBackplane) OnlineUsers(ctx context.Context) ([]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, users := range b.presence {
+		for userID := range users {
+			seen[userID] = true
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for userID := range seen {
+		result = append(result, userID)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Close drops all subscribers and presence
+func (b *MemoryBackplane) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscribers = nil
+	b.presence = make(map[string]map[string]bool)
+	return nil
+}
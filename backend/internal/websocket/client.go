@@ -1,7 +1,11 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,8 +22,9 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Maximum message size allowed from peer. Canvas operations carry whole
+	// drawing paths, so this is well above a typical chat frame.
+	maxMessageSize = 64 * 1024
 )
 
 // Client represents a WebSocket client
@@ -36,6 +41,14 @@ type Client struct {
 	// Current session ID if the client is in a session
 	CurrentSession string
 
+	// ID of the recorded session room connection, guarded by the hub mutex
+	sessionConnectionID string
+
+	// Users of the current session this connection has exchanged a WebRTC
+	// offer or answer with, known to be participants. Only the read pump
+	// uses it.
+	signalPeers map[string]bool
+
 	// The hub that manages this client
 	hub *Hub
 
@@ -44,8 +57,28 @@ type Client struct {
 
 	// Buffered channel of outbound messages
 	send chan []byte
+
+	// Guards the event log sequence state below
+	replayMutex sync.Mutex
+
+	// Whether sequenced frames are being recovered from the event log
+	// instead of queued on send
+	replayPending bool
+
+	// Sequence number of the last event queued or replayed
+	lastSeq int64
+
+	// Sequence number the client asked to resume from, if any
+	resuming  bool
+	resumeSeq int64
+
+	// Signals the write pump to replay missed events
+	replay chan struct{}
 }
 
+// errClientClosed is returned when the hub closed the send channel mid-replay
+var errClientClosed = errors.New("client send channel closed")
+
 // NewClient creates a new WebSocket client
 func NewClient(hub *Hub, conn *websocket.Conn, userID string) *Client {
 	return &Client{
@@ -55,9 +88,17 @@ func NewClient(hub *Hub, conn *websocket.Conn, userID string) *Client {
 		hub:    hub,
 		conn:   conn,
 		send:   make(chan []byte, 256),
+		replay: make(chan struct{}, 1),
 	}
 }
 
+// ResumeFrom makes the client replay events after seq when it starts. It must
+// be called before Start.
+func (c *Client) ResumeFrom(seq int64) {
+	c.resuming = true
+	c.resumeSeq = seq
+}
+
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
@@ -81,11 +122,179 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Handle incoming messages (for future use)
-		log.Printf("Received message from client %s: %s", c.ID, message)
+		c.handleCommand(message)
+	}
+}
+
+// sendMessage queues a message for this client only. It is a no-op once the
+// hub has unregistered the client and closed its send channel.
+func (c *Client) sendMessage(message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling client message: %v", err)
+		return
+	}
+
+	c.hub.mutex.RLock()
+	defer c.hub.mutex.RUnlock()
+
+	if _, ok := c.hub.clients[c]; !ok {
+		return
+	}
+
+	c.queue(data, 0)
+}
+
+// queue puts a frame on the send buffer. Sequenced frames (seq > 0) that do
+// not fit are left in the event log and replayed once the buffer drains,
+// rather than dropping the client. A sequenced frame at or below the last
+// sequence sent was already delivered, e.g. by a replay, and is dropped.
+// Concurrent senders can deliver a user's frames out of order, so a frame
+// that skips a sequence number is also left to a replay, which fills the
+// gap from the event log in order.
+func (c *Client) queue(data []byte, seq int64) {
+	if seq > 0 {
+		c.replayMutex.Lock()
+		defer c.replayMutex.Unlock()
+
+		// The write pump will pick this frame up from the event log
+		if c.replayPending {
+			return
+		}
+		if seq <= c.lastSeq {
+			return
+		}
+		if seq != c.lastSeq+1 && c.hub.eventLog != nil {
+			c.replayPending = true
+			c.signalReplay()
+			return
+		}
+	}
+
+	select {
+	case c.send <- data:
+		if seq > 0 {
+			c.lastSeq = seq
+		}
+	default:
+		if seq > 0 && c.hub.eventLog != nil {
+			log.Printf("Client send channel full for client %s, replaying from seq %d", c.ID, c.lastSeq)
+			c.replayPending = true
+			c.signalReplay()
+			return
+		}
+		log.Printf("Client send channel full for client %s, dropping message", c.ID)
+	}
+}
+
+// signalReplay wakes the write pump to replay missed events
+func (c *Client) signalReplay() {
+	select {
+	case c.replay <- struct{}{}:
+	default:
 	}
 }
 
+// prepareReplay greets the client and schedules recovery of events sent while
+// it was registering, or since the sequence it resumed from
+func (c *Client) prepareReplay() {
+	eventLog := c.hub.eventLog
+	if eventLog == nil || c.userID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	latest, err := eventLog.LatestSeq(ctx, c.userID)
+	if err != nil {
+		log.Printf("Error reading event sequence for user %s: %v", c.userID, err)
+		return
+	}
+
+	from := latest
+	if c.resuming {
+		from = c.resumeSeq
+	}
+
+	c.replayMutex.Lock()
+	c.replayPending = true
+	c.lastSeq = from
+	c.replayMutex.Unlock()
+
+	if data, err := json.Marshal(NewConnectedEvent(c.ID, latest)); err == nil {
+		c.send <- data
+	}
+	c.signalReplay()
+}
+
+// replayMissed writes the sequenced frames that were not queued, then returns
+// the client to live delivery
+func (c *Client) replayMissed() error {
+	// Flush frames queued before the gap so ordering is preserved
+	for n := len(c.send); n > 0; n-- {
+		message, ok := <-c.send
+		if !ok {
+			return errClientClosed
+		}
+		if err := c.writeFrame(message); err != nil {
+			return err
+		}
+	}
+
+	eventLog := c.hub.eventLog
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+
+		c.replayMutex.Lock()
+		from := c.lastSeq
+		events, err := eventLog.Since(ctx, c.userID, from, replayBatchSize)
+		if err != nil {
+			latest, latestErr := eventLog.LatestSeq(ctx, c.userID)
+			if latestErr != nil || latest < from {
+				latest = from
+			}
+			c.lastSeq = latest
+			c.replayPending = false
+			c.replayMutex.Unlock()
+			cancel()
+
+			if !errors.Is(err, ErrEventGap) {
+				log.Printf("Error replaying events for user %s: %v", c.userID, err)
+			}
+
+			data, err := json.Marshal(NewResyncRequiredEvent(from, latest))
+			if err != nil {
+				return err
+			}
+			return c.writeFrame(data)
+		}
+
+		if len(events) == 0 {
+			c.replayPending = false
+			c.replayMutex.Unlock()
+			cancel()
+			return nil
+		}
+
+		c.lastSeq = events[len(events)-1].Seq
+		c.replayMutex.Unlock()
+		cancel()
+
+		for _, event := range events {
+			if err := c.writeFrame(withSequence(event.Payload, event.Seq)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeFrame writes a single text frame to the connection
+func (c *Client) writeFrame(data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
 // writePump pumps messages from the hub to the websocket connection
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -121,6 +330,14 @@ func (c *Client) writePump() {
 				return
 			}
 
+		case <-c.replay:
+			if err := c.replayMissed(); err != nil {
+				if err == errClientClosed {
+					c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				}
+				return
+			}
+
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -132,6 +349,9 @@ func (c *Client) writePump() {
 
 // Start initializes the client by registering it with the hub and starting read/write pumps
 func (c *Client) Start() {
+	// Greet the client and schedule replay before any live frame can arrive
+	c.prepareReplay()
+
 	// Register client with hub
 	c.hub.register <- c
 	
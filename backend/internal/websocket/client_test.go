@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient connects a client of hub to a test server and returns it
+// with the connection its frames are read from
+func newTestClient(t *testing.T, hub *Hub, userID string) (*Client, *websocket.Conn) {
+	t.Helper()
+
+	accepted := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	client := NewClient(hub, <-accepted, userID)
+	t.Cleanup(func() { client.conn.Close() })
+	return client, peer
+}
+
+// readSeqs reads frames from peer until it has n sequenced events
+func readSeqs(t *testing.T, peer *websocket.Conn, n int) []int64 {
+	t.Helper()
+
+	var seqs []int64
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(seqs) < n {
+		_, data, err := peer.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed after seqs %v: %v", seqs, err)
+		}
+		for _, frame := range bytes.Split(data, []byte{'\n'}) {
+			var event struct {
+				Seq int64 `json:"seq"`
+			}
+			if err := json.Unmarshal(frame, &event); err != nil {
+				t.Fatalf("invalid frame %s: %v", frame, err)
+			}
+			if event.Seq > 0 {
+				seqs = append(seqs, event.Seq)
+			}
+		}
+	}
+	return seqs
+}
+
+func TestQueueOutOfOrderDeliversEveryEvent(t *testing.T) {
+	hub := NewHub()
+	eventLog := NewMemoryEventLog()
+	hub.SetEventLog(eventLog)
+	client, peer := newTestClient(t, hub, "user-1")
+	go client.writePump()
+
+	ctx := context.Background()
+	var frames [][]byte
+	for _, payload := range []string{`{"type":"first"}`, `{"type":"second"}`} {
+		seq, err := eventLog.Append(ctx, "user-1", []byte(payload))
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		frames = append(frames, withSequence([]byte(payload), seq))
+	}
+
+	// Two senders raced, and the second one's frame got here first
+	client.queue(frames[1], 2)
+	client.queue(frames[0], 1)
+
+	seqs := readSeqs(t, peer, 2)
+	if seqs[0] != 1 || seqs[1] != 2 {
+		t.Errorf("client received seqs %v, want [1 2]", seqs)
+	}
+}
+
+func TestQueueDropsDeliveredEvents(t *testing.T) {
+	hub := NewHub()
+	eventLog := NewMemoryEventLog()
+	hub.SetEventLog(eventLog)
+	client, peer := newTestClient(t, hub, "user-1")
+	go client.writePump()
+
+	for i := 0; i < 3; i++ {
+		payload := []byte(`{"type":"event"}`)
+		seq, err := eventLog.Append(context.Background(), "user-1", payload)
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		client.queue(withSequence(payload, seq), seq)
+		if seq == 2 {
+			// Redelivered, e.g. by another node's envelope
+			client.queue(withSequence(payload, seq), seq)
+		}
+	}
+
+	seqs := readSeqs(t, peer, 3)
+	if seqs[0] != 1 || seqs[1] != 2 || seqs[2] != 3 {
+		t.Errorf("client received seqs %v, want [1 2 3]", seqs)
+	}
+}
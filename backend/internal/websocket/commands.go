@@ -0,0 +1,416 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"language-exchange/internal/models"
+)
+
+// Time allowed for a single inbound command to complete
+const commandTimeout = 10 * time.Second
+
+// Error codes sent back to clients for rejected commands
+const (
+	CommandErrorInvalidJSON    = "INVALID_JSON"
+	CommandErrorUnknownCommand = "UNKNOWN_COMMAND"
+	CommandErrorInvalidPayload = "INVALID_PAYLOAD"
+	CommandErrorUnavailable    = "COMMAND_UNAVAILABLE"
+	CommandErrorNotInSession   = "NOT_IN_SESSION"
+	CommandErrorFailed         = "COMMAND_FAILED"
+)
+
+// InboundCommand represents a command sent by the browser over the WebSocket
+type InboundCommand struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ConversationCommand is the payload for typing, stop_typing and message_read
+type ConversationCommand struct {
+	ConversationID string `json:"conversation_id"`
+}
+
+// SessionMessageCommand is the payload for session_message
+type SessionMessageCommand struct {
+	SessionID   string `json:"session_id"`
+	Content     string `json:"content"`
+	MessageType string `json:"message_type"`
+}
+
+// CanvasOperationCommand is the payload for canvas_operation
+type CanvasOperationCommand struct {
+	SessionID     string          `json:"session_id"`
+	OperationType string          `json:"operation_type"`
+	OperationData json.RawMessage `json:"operation_data"`
+	// Data is accepted as an alias of OperationData for older clients
+	Data json.RawMessage `json:"data"`
+	// BaseVersion is the canvas version the client had applied
+	BaseVersion int64 `json:"base_version"`
+}
+
+// CursorPositionCommand is the payload for cursor_position
+type CursorPositionCommand struct {
+	SessionID string  `json:"session_id"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+}
+
+// WebRTCSignalCommand is the payload for webrtc_offer, webrtc_answer,
+// webrtc_ice_candidate and webrtc_hangup
+type WebRTCSignalCommand struct {
+	SessionID    string          `json:"session_id"`
+	TargetUserID string          `json:"target_user_id"`
+	SDP          string          `json:"sdp"`
+	Candidate    json.RawMessage `json:"candidate"`
+}
+
+// commandError is a validation or authorization failure reported to the client
+type commandError struct {
+	code    string
+	message string
+}
+
+func (e *commandError) Error() string {
+	return e.message
+}
+
+func newCommandError(code, format string, args ...interface{}) *commandError {
+	return &commandError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// handleCommand decodes, validates and dispatches a single inbound frame
+func (c *Client) handleCommand(raw []byte) {
+	var cmd InboundCommand
+	if err := json.Unmarshal(raw, &cmd); err != nil {
+		c.sendMessage(NewCommandErrorEvent("", "Malformed command", CommandErrorInvalidJSON))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	result, err := c.dispatchCommand(ctx, &cmd)
+	if err != nil {
+		code, message := commandErrorDetails(err)
+		if code == CommandErrorFailed {
+			log.Printf("WebSocket command %s from user %s failed: %v", cmd.Type, c.userID, err)
+		}
+		c.sendMessage(NewCommandErrorEvent(cmd.RequestID, message, code))
+		return
+	}
+
+	c.sendMessage(NewAckEvent(cmd.Type, cmd.RequestID, result))
+}
+
+// dispatchCommand routes a command to its handler
+func (c *Client) dispatchCommand(ctx context.Context, cmd *InboundCommand) (interface{}, error) {
+	switch cmd.Type {
+	case models.WSMessageTypeTyping, models.WSMessageTypeStopTyping:
+		return c.handleTyping(ctx, cmd)
+	case models.WSMessageTypeMessageRead:
+		return c.handleMessageRead(ctx, cmd)
+	case models.WSMessageTypeSessionMessage:
+		return c.handleSessionMessage(ctx, cmd)
+	case models.WSMessageTypeCanvasOperation:
+		return c.handleCanvasOperation(ctx, cmd)
+	case models.WSMessageTypeCursorPosition:
+		return c.handleCursorPosition(cmd)
+	case models.WSMessageTypeWebRTCOffer, models.WSMessageTypeWebRTCAnswer,
+		models.WSMessageTypeWebRTCICECandidate, models.WSMessageTypeWebRTCHangup:
+		return c.handleWebRTCSignal(ctx, cmd)
+	default:
+		return nil, newCommandError(CommandErrorUnknownCommand, "Unknown command type: %s", cmd.Type)
+	}
+}
+
+func (c *Client) handleTyping(ctx context.Context, cmd *InboundCommand) (interface{}, error) {
+	var payload ConversationCommand
+	if err := decodeCommand(cmd, &payload); err != nil {
+		return nil, err
+	}
+	if payload.ConversationID == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "conversation_id is required")
+	}
+	if c.hub.messageService == nil {
+		return nil, newCommandError(CommandErrorUnavailable, "Messaging is not available")
+	}
+
+	isTyping := cmd.Type == models.WSMessageTypeTyping
+	if err := c.hub.messageService.SendTypingIndicator(ctx, payload.ConversationID, c.userID, isTyping); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (c *Client) handleMessageRead(ctx context.Context, cmd *InboundCommand) (interface{}, error) {
+	var payload ConversationCommand
+	if err := decodeCommand(cmd, &payload); err != nil {
+		return nil, err
+	}
+	if payload.ConversationID == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "conversation_id is required")
+	}
+	if c.hub.messageService == nil {
+		return nil, newCommandError(CommandErrorUnavailable, "Messaging is not available")
+	}
+
+	if err := c.hub.messageService.MarkAsRead(ctx, payload.ConversationID, c.userID); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (c *Client) handleSessionMessage(ctx context.Context, cmd *InboundCommand) (interface{}, error) {
+	var payload SessionMessageCommand
+	if err := decodeCommand(cmd, &payload); err != nil {
+		return nil, err
+	}
+
+	sessionID := c.resolveSessionID(payload.SessionID)
+	if sessionID == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "session_id is required")
+	}
+
+	content := strings.TrimSpace(payload.Content)
+	if content == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "content is required")
+	}
+	if len(content) > 1000 {
+		return nil, newCommandError(CommandErrorInvalidPayload, "content too long (max 1000 characters)")
+	}
+
+	messageType := payload.MessageType
+	if messageType == "" {
+		messageType = models.SessionMessageTypeText
+	}
+	if !models.IsValidSessionMessageType(messageType) {
+		return nil, newCommandError(CommandErrorInvalidPayload, "invalid message_type: %s", messageType)
+	}
+	if c.hub.sessionService == nil {
+		return nil, newCommandError(CommandErrorUnavailable, "Sessions are not available")
+	}
+
+	message, err := c.hub.sessionService.SendMessage(ctx, sessionID, c.userID, models.SendMessageInput{
+		Content:     content,
+		MessageType: messageType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.SendToSession(sessionID, models.WebSocketMessage{
+		Type: models.WSMessageTypeSessionMessage,
+		Data: map[string]interface{}{
+			"id":           message.ID,
+			"session_id":   message.SessionID,
+			"user_id":      message.UserID,
+			"content":      message.Content,
+			"message_type": message.MessageType,
+			"created_at":   message.CreatedAt,
+		},
+	}, nil)
+
+	return map[string]interface{}{"id": message.ID}, nil
+}
+
+func (c *Client) handleCanvasOperation(ctx context.Context, cmd *InboundCommand) (interface{}, error) {
+	var payload CanvasOperationCommand
+	if err := decodeCommand(cmd, &payload); err != nil {
+		return nil, err
+	}
+
+	sessionID := c.resolveSessionID(payload.SessionID)
+	if sessionID == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "session_id is required")
+	}
+	if !models.IsValidOperationType(payload.OperationType) {
+		return nil, newCommandError(CommandErrorInvalidPayload, "invalid operation_type: %s", payload.OperationType)
+	}
+
+	operationData := payload.OperationData
+	if len(operationData) == 0 {
+		operationData = payload.Data
+	}
+	requiresData := payload.OperationType != models.OperationTypeUndo &&
+		payload.OperationType != models.OperationTypeRedo &&
+		payload.OperationType != models.OperationTypeClear
+	if requiresData && (len(operationData) == 0 || string(operationData) == "null") {
+		return nil, newCommandError(CommandErrorInvalidPayload, "operation_data is required")
+	}
+	if c.hub.sessionService == nil {
+		return nil, newCommandError(CommandErrorUnavailable, "Sessions are not available")
+	}
+
+	inSession, err := c.hub.sessionService.IsUserInSession(ctx, sessionID, c.userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inSession {
+		return nil, newCommandError(CommandErrorNotInSession, "User not in session")
+	}
+
+	operation := &models.CanvasOperation{
+		SessionID:     sessionID,
+		UserID:        c.userID,
+		OperationType: payload.OperationType,
+		OperationData: operationData,
+		BaseVersion:   payload.BaseVersion,
+	}
+	// The session service broadcasts the saved operation to the session
+	if err := c.hub.sessionService.SaveCanvasOperation(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"id":              operation.ID,
+		"sequence_number": operation.SequenceNumber,
+		"version":         operation.Version,
+		"operation_type":  operation.OperationType,
+	}, nil
+}
+
+// handleCursorPosition relays cursor moves without persisting them. Cursor
+// updates are high frequency, so they are only accepted on a connection that
+// has already joined the session room.
+func (c *Client) handleCursorPosition(cmd *InboundCommand) (interface{}, error) {
+	var payload CursorPositionCommand
+	if err := decodeCommand(cmd, &payload); err != nil {
+		return nil, err
+	}
+
+	sessionID := c.resolveSessionID(payload.SessionID)
+	if sessionID == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "session_id is required")
+	}
+	if sessionID != c.CurrentSession {
+		return nil, newCommandError(CommandErrorNotInSession, "Connection has not joined this session")
+	}
+
+	c.hub.SendToSession(sessionID, models.WebSocketMessage{
+		Type: models.WSMessageTypeCursorPosition,
+		Data: models.CursorPosition{
+			SessionID: sessionID,
+			UserID:    c.userID,
+			X:         payload.X,
+			Y:         payload.Y,
+		},
+	}, c)
+
+	return nil, nil
+}
+
+// handleWebRTCSignal relays an SDP offer or answer, ICE candidate or hangup
+// to one peer in the session. Both ends must be session participants, and
+// the message only reaches the peer's connections in the session room. The
+// sender was authorized when the room connection opened, so only the target
+// is looked up, once per offer or answer: the ICE candidates and hangup that
+// follow go to a peer already checked.
+func (c *Client) handleWebRTCSignal(ctx context.Context, cmd *InboundCommand) (interface{}, error) {
+	var payload WebRTCSignalCommand
+	if err := decodeCommand(cmd, &payload); err != nil {
+		return nil, err
+	}
+
+	sessionID := c.resolveSessionID(payload.SessionID)
+	if sessionID == "" {
+		return nil, newCommandError(CommandErrorInvalidPayload, "session_id is required")
+	}
+	if sessionID != c.CurrentSession {
+		return nil, newCommandError(CommandErrorNotInSession, "Connection has not joined this session")
+	}
+	if payload.TargetUserID == "" || payload.TargetUserID == c.userID {
+		return nil, newCommandError(CommandErrorInvalidPayload, "target_user_id must be another participant")
+	}
+
+	switch cmd.Type {
+	case models.WSMessageTypeWebRTCOffer, models.WSMessageTypeWebRTCAnswer:
+		if payload.SDP == "" {
+			return nil, newCommandError(CommandErrorInvalidPayload, "sdp is required")
+		}
+	case models.WSMessageTypeWebRTCICECandidate:
+		if len(payload.Candidate) == 0 {
+			return nil, newCommandError(CommandErrorInvalidPayload, "candidate is required")
+		}
+	}
+
+	if c.hub.sessionService == nil {
+		return nil, newCommandError(CommandErrorUnavailable, "Sessions are not available")
+	}
+	isOffer := cmd.Type == models.WSMessageTypeWebRTCOffer || cmd.Type == models.WSMessageTypeWebRTCAnswer
+	if isOffer || !c.signalPeers[payload.TargetUserID] {
+		inSession, err := c.hub.sessionService.IsUserInSession(ctx, sessionID, payload.TargetUserID)
+		if err != nil {
+			return nil, err
+		}
+		if !inSession {
+			delete(c.signalPeers, payload.TargetUserID)
+			return nil, newCommandError(CommandErrorNotInSession, "Both users must be participants of the session")
+		}
+	}
+	switch {
+	case cmd.Type == models.WSMessageTypeWebRTCHangup:
+		delete(c.signalPeers, payload.TargetUserID)
+	case isOffer:
+		if c.signalPeers == nil {
+			c.signalPeers = make(map[string]bool)
+		}
+		c.signalPeers[payload.TargetUserID] = true
+	}
+
+	c.hub.SendToSessionUser(sessionID, payload.TargetUserID, models.WebSocketMessage{
+		Type: cmd.Type,
+		Data: models.WebRTCSignal{
+			SessionID:  sessionID,
+			FromUserID: c.userID,
+			SDP:        payload.SDP,
+			Candidate:  payload.Candidate,
+		},
+	})
+
+	return nil, nil
+}
+
+// resolveSessionID falls back to the session the connection was opened for
+func (c *Client) resolveSessionID(sessionID string) string {
+	if sessionID != "" {
+		return sessionID
+	}
+	return c.CurrentSession
+}
+
+// decodeCommand unmarshals the command payload into dest
+func decodeCommand(cmd *InboundCommand, dest interface{}) error {
+	if len(cmd.Data) == 0 {
+		return newCommandError(CommandErrorInvalidPayload, "data is required")
+	}
+	if err := json.Unmarshal(cmd.Data, dest); err != nil {
+		return newCommandError(CommandErrorInvalidPayload, "Invalid %s payload", cmd.Type)
+	}
+	return nil
+}
+
+// commandErrorDetails maps an error to the code and message sent to the
+// client. Unexpected errors get a generic message, as their details are
+// only for the log.
+func commandErrorDetails(err error) (string, string) {
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.code, cmdErr.message
+	}
+
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code, appErr.Message
+	}
+
+	return CommandErrorFailed, "Command failed"
+}
@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"language-exchange/internal/models"
+)
+
+// participantLookups is a session service that counts participant lookups
+type participantLookups struct {
+	SessionService
+	participants map[string]bool
+	lookups      int
+}
+
+func (s *participantLookups) IsUserInSession(ctx context.Context, sessionID, userID string) (bool, error) {
+	s.lookups++
+	return s.participants[userID], nil
+}
+
+func TestWebRTCSignalLooksUpTheTargetOncePerExchange(t *testing.T) {
+	service := &participantLookups{participants: map[string]bool{"user-2": true}}
+	hub := NewHub()
+	hub.SetSessionService(service)
+	client := NewClient(hub, nil, "user-1")
+	client.CurrentSession = "session-1"
+
+	signal := func(messageType string, data string) error {
+		_, err := client.handleWebRTCSignal(context.Background(), &InboundCommand{
+			Type: messageType,
+			Data: json.RawMessage(data),
+		})
+		return err
+	}
+
+	steps := []struct {
+		messageType string
+		data        string
+		wantLookups int
+	}{
+		{models.WSMessageTypeWebRTCOffer, `{"target_user_id":"user-2","sdp":"offer"}`, 1},
+		{models.WSMessageTypeWebRTCICECandidate, `{"target_user_id":"user-2","candidate":{"candidate":"a"}}`, 1},
+		{models.WSMessageTypeWebRTCICECandidate, `{"target_user_id":"user-2","candidate":{"candidate":"b"}}`, 1},
+		{models.WSMessageTypeWebRTCHangup, `{"target_user_id":"user-2"}`, 1},
+		{models.WSMessageTypeWebRTCICECandidate, `{"target_user_id":"user-2","candidate":{"candidate":"c"}}`, 2},
+	}
+	for _, step := range steps {
+		if err := signal(step.messageType, step.data); err != nil {
+			t.Fatalf("%s error = %v", step.messageType, err)
+		}
+		if service.lookups != step.wantLookups {
+			t.Fatalf("after %s, %d participant lookups, want %d", step.messageType, service.lookups, step.wantLookups)
+		}
+	}
+
+	if err := signal(models.WSMessageTypeWebRTCOffer, `{"target_user_id":"user-3","sdp":"offer"}`); err == nil {
+		t.Error("offer to a non-participant succeeded, want an error")
+	}
+}
@@ -0,0 +1,187 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Maximum number of events kept per user
+	eventLogMaxEvents = 500
+
+	// A user's log is discarded after this long without new events
+	eventLogRetention = 24 * time.Hour
+
+	// Idle logs are swept at most this often on appends, so the events of
+	// users who receive nothing more do not stay in memory
+	eventLogSweepInterval = time.Minute
+
+	// Number of events fetched per replay batch
+	replayBatchSize = 100
+)
+
+// ErrEventGap is returned when the requested events are no longer retained
+var ErrEventGap = errors.New("requested events are outside the retention window")
+
+// LoggedEvent is a frame recorded in a user's event log
+type LoggedEvent struct {
+	Seq     int64           `json:"seq"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// EventLog records the frames sent to each user with a monotonically
+// increasing per-user sequence number so reconnecting clients can catch up
+type EventLog interface {
+	// Append records a frame for a user and returns its sequence number
+	Append(ctx context.Context, userID string, payload []byte) (int64, error)
+
+	// Since returns up to limit events after seq, oldest first. It returns
+	// ErrEventGap if events after seq have been discarded.
+	Since(ctx context.Context, userID string, seq int64, limit int) ([]LoggedEvent, error)
+
+	// LatestSeq returns the sequence number of the user's most recent event
+	LatestSeq(ctx context.Context, userID string) (int64, error)
+}
+
+// withSequence adds a "seq" field to an encoded JSON object
+func withSequence(data []byte, seq int64) []byte {
+	if len(data) < 2 || data[0] != '{' {
+		return data
+	}
+
+	prefix := `{"seq":` + strconv.FormatInt(seq, 10)
+	body := data[1:]
+	if len(body) > 1 {
+		prefix += ","
+	}
+
+	result := make([]byte, 0, len(prefix)+len(body))
+	result = append(result, prefix...)
+	return append(result, body...)
+}
+
+// checkEventGap reports whether events after seq are still fully retained
+func checkEventGap(seq, latest, oldest int64) error {
+	if seq > latest {
+		return ErrEventGap
+	}
+	if seq < latest && (oldest == 0 || seq+1 < oldest) {
+		return ErrEventGap
+	}
+	return nil
+}
+
+// MemoryEventLog is an EventLog for a single process
+type MemoryEventLog struct {
+	mutex     sync.Mutex
+	logs      map[string]*memoryUserLog
+	lastSweep time.Time
+}
+
+type memoryUserLog struct {
+	latest    int64
+	events    []LoggedEvent
+	updatedAt time.Time
+}
+
+// NewMemoryEventLog creates a new in-memory event log
+func NewMemoryEventLog() *MemoryEventLog {
+	return &MemoryEventLog{
+		logs:      make(map[string]*memoryUserLog),
+		lastSweep: time.Now(),
+	}
+}
+
+// Append records a frame for a user
+func (l *MemoryEventLog) Append(ctx context.Context, userID string, payload []byte) (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.sweep()
+	userLog := l.logs[userID]
+	if userLog == nil {
+		userLog = &memoryUserLog{}
+		l.logs[userID] = userLog
+	}
+	l.expire(userLog)
+
+	userLog.latest++
+	userLog.updatedAt = time.Now()
+	userLog.events = append(userLog.events, LoggedEvent{
+		Seq:     userLog.latest,
+		Payload: append(json.RawMessage(nil), payload...),
+	})
+	if len(userLog.events) > eventLogMaxEvents {
+		userLog.events = append([]LoggedEvent(nil), userLog.events[len(userLog.events)-eventLogMaxEvents:]...)
+	}
+
+	return userLog.latest, nil
+}
+
+// Since returns the user's events after seq
+func (l *MemoryEventLog) Since(ctx context.Context, userID string, seq int64, limit int) ([]LoggedEvent, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	userLog := l.logs[userID]
+	if userLog == nil {
+		return nil, checkEventGap(seq, 0, 0)
+	}
+	l.expire(userLog)
+
+	var oldest int64
+	if len(userLog.events) > 0 {
+		oldest = userLog.events[0].Seq
+	}
+	if err := checkEventGap(seq, userLog.latest, oldest); err != nil {
+		return nil, err
+	}
+
+	var result []LoggedEvent
+	for _, event := range userLog.events {
+		if event.Seq <= seq {
+			continue
+		}
+		result = append(result, event)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// LatestSeq returns the user's most recent sequence number
+func (l *MemoryEventLog) LatestSeq(ctx context.Context, userID string) (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if userLog := l.logs[userID]; userLog != nil {
+		return userLog.latest, nil
+	}
+	return 0, nil
+}
+
+// sweep expires the events of every idle log if a sweep is due. Only the
+// sequence numbers of idle users stay in memory. Callers hold mutex.
+func (l *MemoryEventLog) sweep() {
+	now := time.Now()
+	if now.Sub(l.lastSweep) < eventLogSweepInterval {
+		return
+	}
+	for _, userLog := range l.logs {
+		l.expire(userLog)
+	}
+	l.lastSweep = now
+}
+
+// expire drops a user's events once the log has been idle past retention.
+// The sequence number is kept so it never goes backwards.
+func (l *MemoryEventLog) expire(userLog *memoryUserLog) {
+	if len(userLog.events) > 0 && time.Since(userLog.updatedAt) > eventLogRetention {
+		userLog.events = nil
+	}
+}
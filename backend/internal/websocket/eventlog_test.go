@@ -0,0 +1,40 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryEventLogSweepsIdleLogs(t *testing.T) {
+	ctx := context.Background()
+	eventLog := NewMemoryEventLog()
+	for i := 0; i < 3; i++ {
+		if _, err := eventLog.Append(ctx, "idle", []byte(`{"type":"event"}`)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// The idle user's log is not read again, only another user's is written
+	eventLog.logs["idle"].updatedAt = time.Now().Add(-eventLogRetention - time.Minute)
+	eventLog.lastSweep = time.Now().Add(-eventLogSweepInterval)
+	if _, err := eventLog.Append(ctx, "active", []byte(`{"type":"event"}`)); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	if events := eventLog.logs["idle"].events; events != nil {
+		t.Errorf("idle log still holds %d events", len(events))
+	}
+
+	// Sequence numbers keep increasing after the events are dropped
+	seq, err := eventLog.Append(ctx, "idle", []byte(`{"type":"event"}`))
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if seq != 4 {
+		t.Errorf("Append() after the sweep = seq %d, want 4", seq)
+	}
+	if _, err := eventLog.Since(ctx, "idle", 1, replayBatchSize); err != ErrEventGap {
+		t.Errorf("Since() a swept seq error = %v, want ErrEventGap", err)
+	}
+}
@@ -23,6 +23,11 @@ const (
 	// System events
 	EventSystemMessage = "system_message"
 	EventError         = "error"
+	EventAck           = "ack"
+
+	// Connection events
+	EventConnected      = "connected"
+	EventResyncRequired = "resync_required"
 )
 
 // WebSocketMessage represents a generic WebSocket message
@@ -83,9 +88,33 @@ type SystemEvent struct {
 
 // ErrorEvent represents error messages
 type ErrorEvent struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
-	Code    string `json:"code,omitempty"`
+	Type      string `json:"type"`
+	Message   string `json:"message"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// AckEvent acknowledges a successfully handled inbound command
+type AckEvent struct {
+	Type      string      `json:"type"`
+	Command   string      `json:"command"`
+	RequestID string      `json:"request_id,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// ConnectedEvent is the first frame sent on a new connection
+type ConnectedEvent struct {
+	Type      string `json:"type"`
+	ClientID  string `json:"client_id"`
+	LatestSeq int64  `json:"latest_seq"`
+}
+
+// ResyncRequiredEvent tells a reconnecting client that the events it missed
+// are no longer retained and it should reload its state over REST
+type ResyncRequiredEvent struct {
+	Type      string `json:"type"`
+	Since     int64  `json:"since"`
+	LatestSeq int64  `json:"latest_seq"`
 }
 
 // NewWebSocketMessage creates a new WebSocket message
@@ -183,4 +212,42 @@ func NewErrorEvent(message, code string) *WebSocketMessage {
 		Code:    code,
 	}
 	return NewWebSocketMessage(EventError, event)
-}
\ No newline at end of file
+}
+
+// NewCommandErrorEvent creates an error event in reply to an inbound command
+func NewCommandErrorEvent(requestID, message, code string) *WebSocketMessage {
+	msg := NewErrorEvent(message, code)
+	msg.Data.(*ErrorEvent).RequestID = requestID
+	return msg
+}
+
+// NewAckEvent creates an acknowledgement for an inbound command
+func NewAckEvent(command, requestID string, data interface{}) *WebSocketMessage {
+	event := &AckEvent{
+		Type:      EventAck,
+		Command:   command,
+		RequestID: requestID,
+		Data:      data,
+	}
+	return NewWebSocketMessage(EventAck, event)
+}
+
+// NewConnectedEvent creates the greeting for a new connection
+func NewConnectedEvent(clientID string, latestSeq int64) *WebSocketMessage {
+	event := &ConnectedEvent{
+		Type:      EventConnected,
+		ClientID:  clientID,
+		LatestSeq: latestSeq,
+	}
+	return NewWebSocketMessage(EventConnected, event)
+}
+
+// NewResyncRequiredEvent creates a resync notice for a reconnecting client
+func NewResyncRequiredEvent(since, latestSeq int64) *WebSocketMessage {
+	event := &ResyncRequiredEvent{
+		Type:      EventResyncRequired,
+		Since:     since,
+		LatestSeq: latestSeq,
+	}
+	return NewWebSocketMessage(EventResyncRequired, event)
+}
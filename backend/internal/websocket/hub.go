@@ -6,13 +6,24 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"language-exchange/internal/models"
 )
 
+// Time allowed for a single backplane call
+const backplaneTimeout = 2 * time.Second
+
+// Time allowed to record a session room connection
+const sessionConnectionTimeout = 5 * time.Second
+
 // Hub maintains the set of active clients and broadcasts messages to the clients
 type Hub struct {
+	// Unique ID of this hub node
+	nodeID string
+
 	// Registered clients
 	clients map[*Client]bool
 
@@ -35,25 +46,62 @@ type Hub struct {
 	mutex sync.RWMutex
 
 	// Session service for database operations
-	sessionService interface {
-		GetSessionParticipants(ctx context.Context, sessionID string) ([]*models.SessionParticipant, error)
-	}
+	sessionService SessionService
+
+	// Message service used by inbound client commands
+	messageService MessageService
+
+	// Backplane relaying messages and presence to other nodes
+	backplane Backplane
+
+	// Per-user log of sequenced events for replay on reconnect
+	eventLog EventLog
+
+	// Presence changes waiting to be written to the backplane, latest per
+	// user, so Run never waits on the backplane
+	presenceMutex   sync.Mutex
+	pendingPresence map[string]bool
+	presenceReady   chan struct{}
+}
+
+// SessionService is the subset of the session service the hub depends on
+type SessionService interface {
+	GetSessionParticipants(ctx context.Context, sessionID string) ([]*models.SessionParticipant, error)
+	IsUserInSession(ctx context.Context, sessionID, userID string) (bool, error)
+	SendMessage(ctx context.Context, sessionID, userID string, input models.SendMessageInput) (*models.SessionMessage, error)
+	SaveCanvasOperation(ctx context.Context, operation *models.CanvasOperation) error
+	OpenSessionConnection(ctx context.Context, connection *models.SessionConnection) error
+	CloseSessionConnection(ctx context.Context, connectionID string) error
+	RefreshSessionConnections(ctx context.Context, nodeID string) error
+}
+
+// MessageService is the subset of the message service used by inbound client commands
+type MessageService interface {
+	MarkAsRead(ctx context.Context, conversationID, userID string) error
+	SendTypingIndicator(ctx context.Context, conversationID, userID string, isTyping bool) error
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	return &Hub{
+		nodeID:         uuid.New().String(),
 		clients:        make(map[*Client]bool),
 		userClients:    make(map[string][]*Client),
 		sessionClients: make(map[string][]*Client),
 		broadcast:      make(chan []byte),
 		register:       make(chan *Client),
 		unregister:     make(chan *Client),
+
+		pendingPresence: make(map[string]bool),
+		presenceReady:   make(chan struct{}, 1),
 	}
 }
 
 // Run starts the hub and handles client connections
 func (h *Hub) Run() {
+	go h.refreshSessionConnections()
+	go h.writePresence()
+	
 	for {
 		select {
 		case client := <-h.register:
@@ -61,14 +109,21 @@ func (h *Hub) Run() {
 			h.clients[client] = true
 			
 			// Add to user mapping
+			firstConnection := false
 			if client.UserID != "" {
+				firstConnection = len(h.userClients[client.UserID]) == 0
 				h.userClients[client.UserID] = append(h.userClients[client.UserID], client)
 			}
 			
 			h.mutex.Unlock()
 			log.Printf("Client registered: %s (User: %s)", client.ID, client.UserID)
 			
+			if firstConnection {
+				h.queuePresence(client.UserID, true)
+			}
+			
 		case client := <-h.unregister:
+			lastConnection := false
 			h.mutex.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
@@ -85,6 +140,7 @@ func (h *Hub) Run() {
 					}
 					if len(h.userClients[client.UserID]) == 0 {
 						delete(h.userClients, client.UserID)
+						lastConnection = true
 					}
 				}
 				
@@ -102,18 +158,23 @@ func (h *Hub) Run() {
 					}
 				}
 			}
+			connectionID := client.sessionConnectionID
+			client.sessionConnectionID = ""
 			h.mutex.Unlock()
 			log.Printf("Client unregistered: %s (User: %s)", client.ID, client.UserID)
 			
+			if connectionID != "" {
+				go h.closeSessionConnection(connectionID)
+			}
+			
+			if lastConnection {
+				h.queuePresence(client.UserID, false)
+			}
+			
 		case message := <-h.broadcast:
 			h.mutex.RLock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+				client.queue(message, 0)
 			}
 			h.mutex.RUnlock()
 		}
@@ -128,6 +189,12 @@ func (h *Hub) BroadcastToAll(message interface{}) {
 		return
 	}
 	
+	h.deliverToAll(data)
+	h.publish(EnvelopeTargetAll, "", data)
+}
+
+// deliverToAll queues a message for every client connected to this node
+func (h *Hub) deliverToAll(data []byte) {
 	select {
 	case h.broadcast <- data:
 	default:
@@ -135,7 +202,9 @@ func (h *Hub) BroadcastToAll(message interface{}) {
 	}
 }
 
-// SendToUser sends a message to all clients of a specific user
+// SendToUser sends a message to all clients of a specific user. The message is
+// recorded in the user's event log and carries a "seq" field so clients that
+// miss it can catch up on reconnect.
 func (h *Hub) SendToUser(userID string, message interface{}) {
 	log.Printf("SendToUser called for userID: %s", userID)
 	
@@ -145,21 +214,47 @@ func (h *Hub) SendToUser(userID string, message interface{}) {
 		return
 	}
 	
+	var seq int64
+	if h.eventLog != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		seq, err = h.eventLog.Append(ctx, userID, data)
+		cancel()
+		if err != nil {
+			log.Printf("Error appending event for user %s: %v", userID, err)
+			seq = 0
+		} else {
+			data = withSequence(data, seq)
+		}
+	}
+	
+	h.deliverToUser(userID, data, seq)
+	h.publishSequenced(EnvelopeTargetUser, userID, data, seq)
+}
+
+// SendEphemeralToUser sends a message to all clients of a specific user
+// without recording it for replay. Use it for transient state such as typing
+// indicators that is meaningless once missed.
+func (h *Hub) SendEphemeralToUser(userID string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+	
+	h.deliverToUser(userID, data, 0)
+	h.publish(EnvelopeTargetUser, userID, data)
+}
+
+// deliverToUser sends a message to the user's clients connected to this node
+func (h *Hub) deliverToUser(userID string, data []byte, seq int64) {
 	h.mutex.RLock()
-	clients := h.userClients[userID]
-	h.mutex.RUnlock()
+	defer h.mutex.RUnlock()
 	
+	clients := h.userClients[userID]
 	log.Printf("Found %d clients for user %s", len(clients), userID)
 	
 	for _, client := range clients {
-		select {
-		case client.send <- data:
-			log.Printf("Successfully sent message to client for user %s", userID)
-		default:
-			// Client send channel is full, remove it
-			log.Printf("Client send channel full for user %s, unregistering", userID)
-			h.unregister <- client
-		}
+		client.queue(data, seq)
 	}
 }
 
@@ -171,8 +266,24 @@ func (h *Hub) SendToUsers(userIDs []string, message interface{}) {
 	}
 }
 
-// GetConnectedUsers returns a list of currently connected user IDs
+// GetConnectedUsers returns a list of user IDs connected to any node
 func (h *Hub) GetConnectedUsers() []string {
+	if h.backplane != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		defer cancel()
+		
+		users, err := h.backplane.OnlineUsers(ctx)
+		if err == nil {
+			return users
+		}
+		log.Printf("Error fetching online users from backplane, using local presence: %v", err)
+	}
+	
+	return h.localUsers()
+}
+
+// localUsers returns the user IDs connected to this node
+func (h *Hub) localUsers() []string {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 	
@@ -185,13 +296,29 @@ func (h *Hub) GetConnectedUsers() []string {
 	return users
 }
 
-// IsUserOnline checks if a user is currently connected
+// IsUserOnline checks if a user is currently connected to any node
 func (h *Hub) IsUserOnline(userID string) bool {
 	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-	
 	clients, exists := h.userClients[userID]
-	return exists && len(clients) > 0
+	h.mutex.RUnlock()
+	
+	if exists && len(clients) > 0 {
+		return true
+	}
+	
+	if h.backplane == nil {
+		return false
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	
+	online, err := h.backplane.IsUserOnline(ctx, userID)
+	if err != nil {
+		log.Printf("Error checking presence for user %s: %v", userID, err)
+		return false
+	}
+	return online
 }
 
 // GetConnectionCount returns the total number of connected clients
@@ -219,20 +346,150 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, userID str
 	}
 	
 	client := NewClient(h, conn, userID)
-	h.register <- client
-	
-	// Start client goroutines
-	go client.writePump()
-	go client.readPump()
+	client.Start()
 }
 
 // SetSessionService sets the session service for database operations
-func (h *Hub) SetSessionService(service interface {
-	GetSessionParticipants(ctx context.Context, sessionID string) ([]*models.SessionParticipant, error)
-}) {
+func (h *Hub) SetSessionService(service SessionService) {
 	h.sessionService = service
 }
 
+// SetMessageService sets the message service used by inbound client commands
+func (h *Hub) SetMessageService(service MessageService) {
+	h.messageService = service
+}
+
+// SetBackplane connects the hub to other nodes. Messages for users and
+// sessions are delivered locally and published for the other nodes to deliver
+// to their own clients.
+func (h *Hub) SetBackplane(backplane Backplane) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	
+	if err := backplane.Subscribe(ctx, h.handleEnvelope); err != nil {
+		return err
+	}
+	
+	h.mutex.Lock()
+	h.backplane = backplane
+	users := make([]string, 0, len(h.userClients))
+	for userID := range h.userClients {
+		users = append(users, userID)
+	}
+	h.mutex.Unlock()
+	
+	// Publish presence for clients that connected before the backplane was set
+	for _, userID := range users {
+		h.setPresence(userID, true)
+	}
+	
+	return nil
+}
+
+// SetEventLog enables sequenced delivery and replay of missed user events.
+// Nodes sharing a backplane must share the event log as well.
+func (h *Hub) SetEventLog(eventLog EventLog) {
+	h.eventLog = eventLog
+}
+
+// NodeID returns the unique ID of this hub node
+func (h *Hub) NodeID() string {
+	return h.nodeID
+}
+
+// publish relays an encoded message to the other nodes
+func (h *Hub) publish(target, targetID string, data []byte) {
+	h.publishSequenced(target, targetID, data, 0)
+}
+
+// publishSequenced relays an encoded message and its event log sequence number
+func (h *Hub) publishSequenced(target, targetID string, data []byte, seq int64) {
+	h.publishEnvelope(&Envelope{
+		Target:   target,
+		TargetID: targetID,
+		Seq:      seq,
+		Payload:  data,
+	})
+}
+
+// publishEnvelope sends an envelope from this node to the other nodes
+func (h *Hub) publishEnvelope(envelope *Envelope) {
+	if h.backplane == nil {
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	
+	envelope.NodeID = h.nodeID
+	if err := h.backplane.Publish(ctx, envelope); err != nil {
+		log.Printf("Error publishing %s message to backplane: %v", envelope.Target, err)
+	}
+}
+
+// handleEnvelope delivers a message published by another node to local clients
+func (h *Hub) handleEnvelope(envelope *Envelope) {
+	// Messages from this node were already delivered locally
+	if envelope.NodeID == h.nodeID {
+		return
+	}
+	
+	switch envelope.Target {
+	case EnvelopeTargetUser:
+		h.deliverToUser(envelope.TargetID, envelope.Payload, envelope.Seq)
+	case EnvelopeTargetSession:
+		h.deliverToSession(envelope.TargetID, envelope.Payload, nil)
+	case EnvelopeTargetSessionUser:
+		h.deliverToSessionUser(envelope.TargetID, envelope.UserID, envelope.Payload)
+	case EnvelopeTargetAll:
+		h.deliverToAll(envelope.Payload)
+	default:
+		log.Printf("Unknown backplane envelope target: %s", envelope.Target)
+	}
+}
+
+// queuePresence schedules a presence change for writePresence. A change
+// replaces any earlier one for the same user that has not been written, so
+// the user's latest presence is always the one that ends up recorded.
+func (h *Hub) queuePresence(userID string, online bool) {
+	h.presenceMutex.Lock()
+	h.pendingPresence[userID] = online
+	h.presenceMutex.Unlock()
+
+	select {
+	case h.presenceReady <- struct{}{}:
+	default:
+	}
+}
+
+// writePresence writes queued presence changes to the backplane
+func (h *Hub) writePresence() {
+	for range h.presenceReady {
+		h.presenceMutex.Lock()
+		pending := h.pendingPresence
+		h.pendingPresence = make(map[string]bool)
+		h.presenceMutex.Unlock()
+
+		for userID, online := range pending {
+			h.setPresence(userID, online)
+		}
+	}
+}
+
+// setPresence records the user's presence on this node in the backplane
+func (h *Hub) setPresence(userID string, online bool) {
+	if h.backplane == nil {
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	
+	if err := h.backplane.SetPresence(ctx, h.nodeID, userID, online); err != nil {
+		log.Printf("Error updating presence for user %s: %v", userID, err)
+	}
+}
+
 // SendToSession sends a message to all clients in a specific session
 func (h *Hub) SendToSession(sessionID string, message interface{}, excludeClient *Client) {
 	data, err := json.Marshal(message)
@@ -241,28 +498,59 @@ func (h *Hub) SendToSession(sessionID string, message interface{}, excludeClient
 		return
 	}
 	
+	h.deliverToSession(sessionID, data, excludeClient)
+	h.publish(EnvelopeTargetSession, sessionID, data)
+}
+
+// deliverToSession sends a message to the session's clients connected to this node
+func (h *Hub) deliverToSession(sessionID string, data []byte, excludeClient *Client) {
 	h.mutex.RLock()
-	clients := h.sessionClients[sessionID]
-	h.mutex.RUnlock()
+	defer h.mutex.RUnlock()
 	
-	for _, client := range clients {
+	for _, client := range h.sessionClients[sessionID] {
 		if excludeClient != nil && client == excludeClient {
 			continue
 		}
 		
-		select {
-		case client.send <- data:
-		default:
-			// Client send channel is full, remove it
-			h.unregister <- client
+		client.queue(data, 0)
+	}
+}
+
+// SendToSessionUser sends a message to one user's clients in a session room
+// on any node, e.g. to relay WebRTC signaling to a single peer
+func (h *Hub) SendToSessionUser(sessionID, userID string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling session user message: %v", err)
+		return
+	}
+	
+	h.deliverToSessionUser(sessionID, userID, data)
+	h.publishEnvelope(&Envelope{
+		Target:   EnvelopeTargetSessionUser,
+		TargetID: sessionID,
+		UserID:   userID,
+		Payload:  data,
+	})
+}
+
+// deliverToSessionUser sends a message to the user's clients in a session
+// room connected to this node
+func (h *Hub) deliverToSessionUser(sessionID, userID string, data []byte) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	
+	for _, client := range h.sessionClients[sessionID] {
+		if client.userID == userID {
+			client.queue(data, 0)
 		}
 	}
 }
 
-// JoinSession adds a client to a session room
+// JoinSession adds a client to a session room. The connection is recorded
+// before the client starts, so its close is always recorded after it.
 func (h *Hub) JoinSession(sessionID string, client *Client) {
 	h.mutex.Lock()
-	defer h.mutex.Unlock()
 	
 	if h.sessionClients[sessionID] == nil {
 		h.sessionClients[sessionID] = make([]*Client, 0)
@@ -271,19 +559,41 @@ func (h *Hub) JoinSession(sessionID string, client *Client) {
 	// Check if client is already in the session
 	for _, c := range h.sessionClients[sessionID] {
 		if c == client {
+			h.mutex.Unlock()
 			return // Already in session
 		}
 	}
 	
 	h.sessionClients[sessionID] = append(h.sessionClients[sessionID], client)
 	log.Printf("Client %s joined session %s", client.userID, sessionID)
+	
+	connection := &models.SessionConnection{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		UserID:    client.userID,
+		Source:    models.SessionConnectionSourceWebSocket,
+		NodeID:    h.nodeID,
+	}
+	client.sessionConnectionID = connection.ID
+	h.mutex.Unlock()
+	
+	if h.sessionService == nil {
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), sessionConnectionTimeout)
+	defer cancel()
+	
+	if err := h.sessionService.OpenSessionConnection(ctx, connection); err != nil {
+		log.Printf("Error recording connection of user %s to session %s: %v", client.userID, sessionID, err)
+	}
 }
 
 // LeaveSession removes a client from a session room
 func (h *Hub) LeaveSession(sessionID string, client *Client) {
 	h.mutex.Lock()
-	defer h.mutex.Unlock()
 	
+	connectionID := ""
 	clients := h.sessionClients[sessionID]
 	for i, c := range clients {
 		if c == client {
@@ -295,10 +605,50 @@ func (h *Hub) LeaveSession(sessionID string, client *Client) {
 				delete(h.sessionClients, sessionID)
 			}
 			
+			connectionID = client.sessionConnectionID
+			client.sessionConnectionID = ""
 			log.Printf("Client %s left session %s", client.userID, sessionID)
 			break
 		}
 	}
+	h.mutex.Unlock()
+	
+	if connectionID != "" {
+		h.closeSessionConnection(connectionID)
+	}
+}
+
+// closeSessionConnection records that a session room connection ended
+func (h *Hub) closeSessionConnection(connectionID string) {
+	if h.sessionService == nil {
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), sessionConnectionTimeout)
+	defer cancel()
+	
+	if err := h.sessionService.CloseSessionConnection(ctx, connectionID); err != nil {
+		log.Printf("Error recording end of session connection %s: %v", connectionID, err)
+	}
+}
+
+// refreshSessionConnections periodically marks this node's open session
+// connections as alive so they are not mistaken for a crashed node's
+func (h *Hub) refreshSessionConnections() {
+	ticker := time.NewTicker(models.SessionConnectionHeartbeat)
+	defer ticker.Stop()
+	
+	for range ticker.C {
+		if h.sessionService == nil {
+			continue
+		}
+		
+		ctx, cancel := context.WithTimeout(context.Background(), sessionConnectionTimeout)
+		if err := h.sessionService.RefreshSessionConnections(ctx, h.nodeID); err != nil {
+			log.Printf("Error refreshing session connections: %v", err)
+		}
+		cancel()
+	}
 }
 
 // NotifySessionJoin notifies other participants that a user joined a session
@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slowBackplane is a MemoryBackplane whose presence writes wait for release
+type slowBackplane struct {
+	*MemoryBackplane
+	release chan struct{}
+}
+
+func (b *slowBackplane) SetPresence(ctx context.Context, nodeID, userID string, online bool) error {
+	<-b.release
+	return b.MemoryBackplane.SetPresence(context.Background(), nodeID, userID, online)
+}
+
+func TestSlowPresenceDoesNotBlockHub(t *testing.T) {
+	backplane := &slowBackplane{MemoryBackplane: NewMemoryBackplane(), release: make(chan struct{})}
+	hub := NewHub()
+	if err := hub.SetBackplane(backplane); err != nil {
+		t.Fatalf("SetBackplane() error = %v", err)
+	}
+	go hub.Run()
+
+	first := NewClient(hub, nil, "user-1")
+	second := NewClient(hub, nil, "user-2")
+	done := make(chan struct{})
+	go func() {
+		hub.register <- first
+		hub.register <- second
+		hub.unregister <- first
+		hub.register <- NewClient(hub, nil, "user-1")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hub stopped handling clients while presence was being written")
+	}
+
+	close(backplane.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		users, err := backplane.OnlineUsers(context.Background())
+		if err != nil {
+			t.Fatalf("OnlineUsers() error = %v", err)
+		}
+		if len(users) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("online users = %v, want user-1 and user-2", users)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
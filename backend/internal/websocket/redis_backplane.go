@@ -0,0 +1,253 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"language-exchange/internal/cache"
+)
+
+const (
+	// Pub/sub channel shared by all hub nodes
+	redisBackplaneChannel = "ws:backplane"
+
+	// Sorted set of node IDs scored by their last heartbeat (unix seconds)
+	redisNodesKey = "ws:nodes"
+
+	// Set of user IDs connected to a node
+	redisNodePresenceKey = "ws:presence:%s"
+
+	// How often a node refreshes its presence
+	presenceHeartbeat = 15 * time.Second
+
+	// How long a node's presence survives without a heartbeat
+	presenceTTL = 3 * presenceHeartbeat
+)
+
+// RedisBackplane is a Backplane backed by Redis pub/sub. Presence is kept in
+// one set per node which expires if the node stops heartbeating, so users on a
+// crashed replica drop off within presenceTTL.
+type RedisBackplane struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+
+	mutex sync.Mutex
+	nodes map[string]bool // local node IDs to heartbeat
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRedisBackplane creates a backplane using the given Redis cache's client
+func NewRedisBackplane(redisCache *cache.RedisCache) *RedisBackplane {
+	b := &RedisBackplane{
+		client: redisCache.Client(),
+		nodes:  make(map[string]bool),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go b.heartbeat()
+	return b
+}
+
+// Publish sends the envelope to every node
+func (b *RedisBackplane) Publish(ctx context.Context, envelope *Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+
+	return b.client.Publish(ctx, redisBackplaneChannel, data).Err()
+}
+
+// Subscribe starts delivering envelopes published by any node to handler
+func (b *RedisBackplane) Subscribe(ctx context.Context, handler func(*Envelope)) error {
+	pubsub := b.client.Subscribe(ctx, redisBackplaneChannel)
+
+	// Wait for the subscription to be confirmed so no message is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to backplane: %w", err)
+	}
+
+	b.mutex.Lock()
+	b.pubsub = pubsub
+	b.mutex.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var envelope Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				log.Printf("Error unmarshaling backplane envelope: %v", err)
+				continue
+			}
+			handler(&envelope)
+		}
+	}()
+
+	return nil
+}
+
+// SetPresence adds or removes a user from a node's presence set
+func (b *RedisBackplane) SetPresence(ctx context.Context, nodeID, userID string, online bool) error {
+	key := fmt.Sprintf(redisNodePresenceKey, nodeID)
+
+	b.mutex.Lock()
+	b.nodes[nodeID] = true
+	b.mutex.Unlock()
+
+	pipe := b.client.TxPipeline()
+	if online {
+		pipe.SAdd(ctx, key, userID)
+		pipe.Expire(ctx, key, presenceTTL)
+	} else {
+		pipe.SRem(ctx, key, userID)
+	}
+	pipe.ZAdd(ctx, redisNodesKey, redis.Z{Score: float64(time.Now().Unix()), Member: nodeID})
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsUserOnline reports whether any live node has the user in its presence set
+func (b *RedisBackplane) IsUserOnline(ctx context.Context, userID string) (bool, error) {
+	nodes, err := b.liveNodes(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if len(nodes) == 0 {
+		return false, nil
+	}
+
+	pipe := b.client.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(nodes))
+	for i, nodeID := range nodes {
+		cmds[i] = pipe.SIsMember(ctx, fmt.Sprintf(redisNodePresenceKey, nodeID), userID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	for _, cmd := range cmds {
+		if cmd.Val() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// OnlineUsers returns the union of all live nodes' presence sets
+func (b *RedisBackplane) OnlineUsers(ctx context.Context) ([]string, error) {
+	nodes, err := b.liveNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 0 {
+		return []string{}, nil
+	}
+
+	keys := make([]string, len(nodes))
+	for i, nodeID := range nodes {
+		keys[i] = fmt.Sprintf(redisNodePresenceKey, nodeID)
+	}
+
+	users, err := b.client.SUnion(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(users)
+	return users, nil
+}
+
+// Close stops the heartbeat, unsubscribes and clears this process's presence
+func (b *RedisBackplane) Close() error {
+	close(b.stop)
+	<-b.done
+
+	b.mutex.Lock()
+	pubsub := b.pubsub
+	nodes := make([]string, 0, len(b.nodes))
+	for nodeID := range b.nodes {
+		nodes = append(nodes, nodeID)
+	}
+	b.mutex.Unlock()
+
+	if pubsub != nil {
+		pubsub.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, nodeID := range nodes {
+		pipe := b.client.TxPipeline()
+		pipe.Del(ctx, fmt.Sprintf(redisNodePresenceKey, nodeID))
+		pipe.ZRem(ctx, redisNodesKey, nodeID)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// liveNodes returns nodes that heartbeated within presenceTTL and prunes the rest
+func (b *RedisBackplane) liveNodes(ctx context.Context) ([]string, error) {
+	cutoff := time.Now().Add(-presenceTTL).Unix()
+
+	if err := b.client.ZRemRangeByScore(ctx, redisNodesKey, "-inf", "("+strconv.FormatInt(cutoff, 10)).Err(); err != nil {
+		return nil, err
+	}
+
+	return b.client.ZRangeByScore(ctx, redisNodesKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(cutoff, 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// heartbeat keeps this process's nodes and presence sets alive
+func (b *RedisBackplane) heartbeat() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.mutex.Lock()
+			nodes := make([]string, 0, len(b.nodes))
+			for nodeID := range b.nodes {
+				nodes = append(nodes, nodeID)
+			}
+			b.mutex.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			pipe := b.client.Pipeline()
+			now := float64(time.Now().Unix())
+			for _, nodeID := range nodes {
+				pipe.Expire(ctx, fmt.Sprintf(redisNodePresenceKey, nodeID), presenceTTL)
+				pipe.ZAdd(ctx, redisNodesKey, redis.Z{Score: now, Member: nodeID})
+			}
+			if len(nodes) > 0 {
+				if _, err := pipe.Exec(ctx); err != nil {
+					log.Printf("Backplane heartbeat failed: %v", err)
+				}
+			}
+			cancel()
+		case <-b.stop:
+			return
+		}
+	}
+}
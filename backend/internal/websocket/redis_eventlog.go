@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"language-exchange/internal/cache"
+)
+
+const (
+	// Per-user sequence counter. It never expires so sequences stay monotonic.
+	redisEventSeqKey = "ws:events:seq:%s"
+
+	// Per-user sorted set of LoggedEvent JSON scored by sequence number
+	redisEventLogKey = "ws:events:%s"
+)
+
+// RedisEventLog is an EventLog shared by all hub nodes
+type RedisEventLog struct {
+	client *redis.Client
+}
+
+// NewRedisEventLog creates an event log using the given Redis cache's client
+func NewRedisEventLog(redisCache *cache.RedisCache) *RedisEventLog {
+	return &RedisEventLog{client: redisCache.Client()}
+}
+
+// Append records a frame for a user
+func (l *RedisEventLog) Append(ctx context.Context, userID string, payload []byte) (int64, error) {
+	seq, err := l.client.Incr(ctx, fmt.Sprintf(redisEventSeqKey, userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate event sequence: %w", err)
+	}
+
+	data, err := json.Marshal(LoggedEvent{Seq: seq, Payload: payload})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	key := fmt.Sprintf(redisEventLogKey, userID)
+	pipe := l.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(seq), Member: data})
+	pipe.ZRemRangeByRank(ctx, key, 0, -eventLogMaxEvents-1)
+	pipe.Expire(ctx, key, eventLogRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to append event: %w", err)
+	}
+
+	return seq, nil
+}
+
+// Since returns the user's events after seq
+func (l *RedisEventLog) Since(ctx context.Context, userID string, seq int64, limit int) ([]LoggedEvent, error) {
+	key := fmt.Sprintf(redisEventLogKey, userID)
+
+	latest, err := l.LatestSeq(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldestEntries, err := l.client.ZRangeWithScores(ctx, key, 0, 0).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	var oldest int64
+	if len(oldestEntries) > 0 {
+		oldest = int64(oldestEntries[0].Score)
+	}
+
+	if err := checkEventGap(seq, latest, oldest); err != nil {
+		return nil, err
+	}
+
+	members, err := l.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min:   "(" + strconv.FormatInt(seq, 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	events := make([]LoggedEvent, 0, len(members))
+	for _, member := range members {
+		var event LoggedEvent
+		if err := json.Unmarshal([]byte(member), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// LatestSeq returns the user's most recent sequence number
+func (l *RedisEventLog) LatestSeq(ctx context.Context, userID string) (int64, error) {
+	seq, err := l.client.Get(ctx, fmt.Sprintf(redisEventSeqKey, userID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event sequence: %w", err)
+	}
+	return seq, nil
+}
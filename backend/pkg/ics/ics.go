@@ -0,0 +1,149 @@
+// Package ics writes iCalendar (RFC 5545) feeds
+package ics
+
+import (
+	"strings"
+	"time"
+)
+
+const dateTimeFormat = "20060102T150405Z"
+
+// Calendar is a VCALENDAR with its events
+type Calendar struct {
+	ProdID string
+	Name   string
+	// Timezone is a display hint for clients (X-WR-TIMEZONE); event times are
+	// always written in UTC
+	Timezone string
+	Events   []Event
+}
+
+// Event is a VEVENT
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Created     time.Time
+	Updated     time.Time
+	Cancelled   bool
+	Organizer   *Attendee
+	Attendees   []Attendee
+}
+
+// Attendee is an ORGANIZER or ATTENDEE of an event
+type Attendee struct {
+	Name  string
+	Email string
+	// PartStat is the RFC 5545 participation status, e.g. ACCEPTED
+	PartStat string
+}
+
+// String renders the calendar with CRLF line endings and folded lines
+func (c *Calendar) String() string {
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + c.ProdID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + Escape(c.Name))
+	}
+	if c.Timezone != "" {
+		w.line("X-WR-TIMEZONE:" + c.Timezone)
+	}
+
+	stamp := time.Now()
+	for _, event := range c.Events {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + event.UID)
+		w.line("DTSTAMP:" + formatTime(stamp))
+		w.line("DTSTART:" + formatTime(event.Start))
+		w.line("DTEND:" + formatTime(event.End))
+		w.line("SUMMARY:" + Escape(event.Summary))
+		if event.Description != "" {
+			w.line("DESCRIPTION:" + Escape(event.Description))
+		}
+		if event.URL != "" {
+			w.line("URL:" + event.URL)
+		}
+		if !event.Created.IsZero() {
+			w.line("CREATED:" + formatTime(event.Created))
+		}
+		if !event.Updated.IsZero() {
+			w.line("LAST-MODIFIED:" + formatTime(event.Updated))
+		}
+		if event.Cancelled {
+			w.line("STATUS:CANCELLED")
+		} else {
+			w.line("STATUS:CONFIRMED")
+		}
+		if event.Organizer != nil {
+			w.line("ORGANIZER" + attendeeParams(*event.Organizer) + ":mailto:" + event.Organizer.Email)
+		}
+		for _, attendee := range event.Attendees {
+			w.line("ATTENDEE" + attendeeParams(attendee) + ":mailto:" + attendee.Email)
+		}
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.String()
+}
+
+// Escape escapes a TEXT property value
+func Escape(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+func attendeeParams(attendee Attendee) string {
+	params := ""
+	if attendee.Name != "" {
+		params += `;CN="` + strings.ReplaceAll(attendee.Name, `"`, "'") + `"`
+	}
+	if attendee.PartStat != "" {
+		params += ";PARTSTAT=" + attendee.PartStat
+	}
+	return params
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat)
+}
+
+// writer folds content lines at 75 octets as RFC 5545 requires
+type writer struct {
+	b strings.Builder
+}
+
+func (w *writer) line(content string) {
+	// Continuation lines start with a space, which counts towards the limit
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		// Never split a multi-byte UTF-8 sequence
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.b.WriteString(content[:cut])
+		w.b.WriteString("\r\n ")
+		content = content[cut:]
+		limit = 74
+	}
+	w.b.WriteString(content)
+	w.b.WriteString("\r\n")
+}
+
+func (w *writer) String() string {
+	return w.b.String()
+}
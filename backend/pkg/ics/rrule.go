@@ -0,0 +1,177 @@
+package ics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported recurrence frequencies
+const (
+	FrequencyWeekly  = "WEEKLY"
+	FrequencyMonthly = "MONTHLY"
+)
+
+const (
+	untilFormat = "20060102T150405Z"
+
+	maxInterval = 12
+	maxCount    = 520
+)
+
+// RecurrenceRule is the subset of RFC 5545 RRULE used for sessions: weekly or
+// monthly repetition every Interval periods, bounded by Count or Until
+type RecurrenceRule struct {
+	Frequency string
+	Interval  int
+	Count     int
+	Until     *time.Time
+}
+
+// ParseRecurrenceRule parses a rule such as "FREQ=WEEKLY;INTERVAL=2;COUNT=10".
+// An "RRULE:" prefix is accepted.
+func ParseRecurrenceRule(value string) (*RecurrenceRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("empty recurrence rule")
+	}
+
+	rule := &RecurrenceRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence rule part: %s", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Frequency = strings.ToUpper(val)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 || interval > maxInterval {
+				return nil, fmt.Errorf("invalid INTERVAL: %s", val)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 || count > maxCount {
+				return nil, fmt.Errorf("invalid COUNT: %s", val)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part: %s", name)
+		}
+	}
+
+	if rule.Frequency != FrequencyWeekly && rule.Frequency != FrequencyMonthly {
+		return nil, fmt.Errorf("unsupported FREQ: %s", rule.Frequency)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(untilFormat, value); err == nil {
+		return until, nil
+	}
+	// A date-only UNTIL includes the whole day
+	if until, err := time.Parse("20060102", value); err == nil {
+		return until.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL: %s", value)
+}
+
+// String formats the rule without the "RRULE:" prefix
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Frequency}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilFormat))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the occurrence start times in [from, to) of a series whose
+// first occurrence is dtstart. Occurrences keep dtstart's wall-clock time in
+// dtstart's location across daylight saving changes. Monthly occurrences on
+// days a month does not have are skipped, as RFC 5545 requires.
+func (r *RecurrenceRule) Between(dtstart, from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	count := 0
+
+	for i := 0; ; i++ {
+		candidate, ok := r.nth(dtstart, i)
+		if !candidate.Before(to) {
+			break
+		}
+		if r.Until != nil && candidate.After(*r.Until) {
+			break
+		}
+		if !ok {
+			continue
+		}
+
+		count++
+		if r.Count > 0 && count > r.Count {
+			break
+		}
+		if !candidate.Before(from) {
+			occurrences = append(occurrences, candidate)
+		}
+	}
+
+	return occurrences
+}
+
+// CountBefore returns the number of occurrences that start before t
+func (r *RecurrenceRule) CountBefore(dtstart, t time.Time) int {
+	return len(r.Between(dtstart, dtstart, t))
+}
+
+// Includes reports whether t is an occurrence of the series
+func (r *RecurrenceRule) Includes(dtstart, t time.Time) bool {
+	return len(r.Between(dtstart, t, t.Add(time.Second))) == 1
+}
+
+// HasOccurrencesAfter reports whether the series has any occurrence at or after t
+func (r *RecurrenceRule) HasOccurrencesAfter(dtstart, t time.Time) bool {
+	if r.Count == 0 && r.Until == nil {
+		return true
+	}
+	if r.Until != nil {
+		return !t.After(*r.Until) && len(r.Between(dtstart, t, r.Until.Add(time.Second))) > 0
+	}
+	// COUNT bounded series end within Count periods (plus skipped months)
+	end, _ := r.nth(dtstart, r.Count*2+12)
+	return len(r.Between(dtstart, t, end)) > 0
+}
+
+// nth returns the i-th period start after dtstart. ok is false when the
+// period has no occurrence (a monthly day past the end of the month).
+func (r *RecurrenceRule) nth(dtstart time.Time, i int) (time.Time, bool) {
+	switch r.Frequency {
+	case FrequencyMonthly:
+		year, month, day := dtstart.Date()
+		hour, min, sec := dtstart.Clock()
+		first := time.Date(year, month+time.Month(i*r.Interval), 1, hour, min, sec, dtstart.Nanosecond(), dtstart.Location())
+		candidate := time.Date(first.Year(), first.Month(), day, hour, min, sec, dtstart.Nanosecond(), dtstart.Location())
+		return candidate, candidate.Month() == first.Month()
+	default:
+		return dtstart.AddDate(0, 0, 7*r.Interval*i), true
+	}
+}
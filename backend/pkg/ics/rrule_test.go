@@ -0,0 +1,221 @@
+package ics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, value string) *RecurrenceRule {
+	t.Helper()
+	rule, err := ParseRecurrenceRule(value)
+	if err != nil {
+		t.Fatalf("ParseRecurrenceRule(%q) error = %v", value, err)
+	}
+	return rule
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	until := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	endOfDay := time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    *RecurrenceRule
+		wantErr bool
+	}{
+		{value: "FREQ=WEEKLY", want: &RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1}},
+		{value: "RRULE:freq=monthly;interval=2", want: &RecurrenceRule{Frequency: FrequencyMonthly, Interval: 2}},
+		{value: "FREQ=WEEKLY;COUNT=10", want: &RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Count: 10}},
+		{value: "FREQ=WEEKLY;UNTIL=20260301T090000Z", want: &RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Until: &until}},
+		{value: "FREQ=WEEKLY;UNTIL=20260301", want: &RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Until: &endOfDay}},
+		{value: "", wantErr: true},
+		{value: "FREQ=DAILY", wantErr: true},
+		{value: "FREQ=WEEKLY;BYDAY=MO", wantErr: true},
+		{value: "FREQ=WEEKLY;INTERVAL=0", wantErr: true},
+		{value: "FREQ=WEEKLY;INTERVAL=13", wantErr: true},
+		{value: "FREQ=WEEKLY;COUNT=0", wantErr: true},
+		{value: "FREQ=WEEKLY;COUNT=521", wantErr: true},
+		{value: "FREQ=WEEKLY;UNTIL=tomorrow", wantErr: true},
+		{value: "FREQ=WEEKLY;COUNT=2;UNTIL=20260301", wantErr: true},
+		{value: "FREQ=WEEKLY;COUNT", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRecurrenceRule(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRecurrenceRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRecurrenceRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceRuleString(t *testing.T) {
+	for _, value := range []string{
+		"FREQ=WEEKLY",
+		"FREQ=MONTHLY;INTERVAL=3;COUNT=4",
+		"FREQ=WEEKLY;INTERVAL=2;UNTIL=20260301T090000Z",
+	} {
+		if got := mustParse(t, value).String(); got != value {
+			t.Errorf("String() = %q, want %q", got, value)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	utc := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 18, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		from    time.Time
+		to      time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "weekly",
+			rule:    "FREQ=WEEKLY",
+			dtstart: utc(2026, 1, 5),
+			from:    utc(2026, 1, 5),
+			to:      utc(2026, 1, 26),
+			want:    []time.Time{utc(2026, 1, 5), utc(2026, 1, 12), utc(2026, 1, 19)},
+		},
+		{
+			name:    "interval",
+			rule:    "FREQ=WEEKLY;INTERVAL=2",
+			dtstart: utc(2026, 1, 5),
+			from:    utc(2026, 1, 5),
+			to:      utc(2026, 2, 10),
+			want:    []time.Time{utc(2026, 1, 5), utc(2026, 1, 19), utc(2026, 2, 2)},
+		},
+		{
+			name:    "count",
+			rule:    "FREQ=WEEKLY;COUNT=3",
+			dtstart: utc(2026, 1, 5),
+			from:    utc(2026, 1, 5),
+			to:      utc(2026, 6, 1),
+			want:    []time.Time{utc(2026, 1, 5), utc(2026, 1, 12), utc(2026, 1, 19)},
+		},
+		{
+			name:    "count from a later window",
+			rule:    "FREQ=WEEKLY;COUNT=3",
+			dtstart: utc(2026, 1, 5),
+			from:    utc(2026, 1, 10),
+			to:      utc(2026, 6, 1),
+			want:    []time.Time{utc(2026, 1, 12), utc(2026, 1, 19)},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    "FREQ=WEEKLY;UNTIL=20260119T180000Z",
+			dtstart: utc(2026, 1, 5),
+			from:    utc(2026, 1, 5),
+			to:      utc(2026, 6, 1),
+			want:    []time.Time{utc(2026, 1, 5), utc(2026, 1, 12), utc(2026, 1, 19)},
+		},
+		{
+			name:    "date-only until",
+			rule:    "FREQ=WEEKLY;UNTIL=20260119",
+			dtstart: utc(2026, 1, 5),
+			from:    utc(2026, 1, 5),
+			to:      utc(2026, 6, 1),
+			want:    []time.Time{utc(2026, 1, 5), utc(2026, 1, 12), utc(2026, 1, 19)},
+		},
+		{
+			name:    "monthly",
+			rule:    "FREQ=MONTHLY;INTERVAL=2",
+			dtstart: utc(2026, 1, 15),
+			from:    utc(2026, 1, 1),
+			to:      utc(2026, 7, 1),
+			want:    []time.Time{utc(2026, 1, 15), utc(2026, 3, 15), utc(2026, 5, 15)},
+		},
+		{
+			name:    "month end skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: utc(2026, 1, 31),
+			from:    utc(2026, 1, 1),
+			to:      utc(2026, 6, 1),
+			want:    []time.Time{utc(2026, 1, 31), utc(2026, 3, 31), utc(2026, 5, 31)},
+		},
+		{
+			name:    "skipped months do not count",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: utc(2026, 1, 31),
+			from:    utc(2026, 1, 1),
+			to:      utc(2027, 1, 1),
+			want:    []time.Time{utc(2026, 1, 31), utc(2026, 3, 31), utc(2026, 5, 31)},
+		},
+		{
+			name:    "leap day",
+			rule:    "FREQ=MONTHLY;INTERVAL=12",
+			dtstart: utc(2024, 2, 29),
+			from:    utc(2024, 1, 1),
+			to:      utc(2029, 1, 1),
+			want:    []time.Time{utc(2024, 2, 29), utc(2028, 2, 29)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustParse(t, tt.rule).Between(tt.dtstart, tt.from, tt.to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetweenKeepsWallClockAcrossDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	dtstart := time.Date(2026, 3, 22, 18, 0, 0, 0, berlin)
+	rule := mustParse(t, "FREQ=WEEKLY;COUNT=2")
+	got := rule.Between(dtstart, dtstart, dtstart.AddDate(0, 1, 0))
+
+	if len(got) != 2 {
+		t.Fatalf("Between() = %v, want 2 occurrences", got)
+	}
+	if hour := got[1].In(berlin).Hour(); hour != 18 {
+		t.Errorf("occurrence after the clocks change starts at %d:00, want 18:00", hour)
+	}
+	if got[1].Sub(got[0]) != 7*24*time.Hour-time.Hour {
+		t.Errorf("occurrences are %v apart, want a week less the lost hour", got[1].Sub(got[0]))
+	}
+}
+
+func TestCountBeforeAndHasOccurrencesAfter(t *testing.T) {
+	dtstart := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		rule      string
+		at        time.Time
+		wantCount int
+		wantAfter bool
+	}{
+		{"open ended", "FREQ=WEEKLY", dtstart.Add(100 * week), 100, true},
+		{"before the last of a count", "FREQ=WEEKLY;COUNT=3", dtstart.Add(2 * week), 2, true},
+		{"after the last of a count", "FREQ=WEEKLY;COUNT=3", dtstart.Add(2*week + time.Minute), 3, false},
+		{"at until", "FREQ=WEEKLY;UNTIL=20260119T180000Z", dtstart.Add(2 * week), 2, true},
+		{"after until", "FREQ=WEEKLY;UNTIL=20260119T180000Z", dtstart.Add(2*week + time.Minute), 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := mustParse(t, tt.rule)
+			if got := rule.CountBefore(dtstart, tt.at); got != tt.wantCount {
+				t.Errorf("CountBefore() = %d, want %d", got, tt.wantCount)
+			}
+			if got := rule.HasOccurrencesAfter(dtstart, tt.at); got != tt.wantAfter {
+				t.Errorf("HasOccurrencesAfter() = %v, want %v", got, tt.wantAfter)
+			}
+		})
+	}
+}